@@ -31,6 +31,8 @@ import (
 	"github.com/nuts-foundation/nuts-auth/pkg"
 	"github.com/nuts-foundation/nuts-auth/pkg/contract"
 	"github.com/nuts-foundation/nuts-auth/pkg/services"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/qrcode"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/ratelimit"
 )
 
 var _ ServerInterface = (*Wrapper)(nil)
@@ -43,6 +45,8 @@ var _ ServerInterface = (*Wrapper)(nil)
 // This is the experimental API. It is used to tests APIs is the wild.
 type Wrapper struct {
 	Auth pkg.AuthClient
+	// TrustClientCertHeader identifies clients by the ratelimit.ClientCertHeader set by a TLS terminating proxy
+	TrustClientCertHeader bool
 }
 
 // VerifySignature handles the VerifySignature http request.
@@ -64,12 +68,21 @@ func (w Wrapper) VerifySignature(ctx echo.Context) error {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("could not parse checkTime: %s", err.Error()))
 		}
 	}
-	validationResult, err := w.Auth.ContractClient().VerifyVP(rawVP, &checkTime)
+	var expectedContractHash *string
+	if requestParams.ContractHash != nil {
+		hash := string(*requestParams.ContractHash)
+		expectedContractHash = &hash
+	}
+	validationResult, err := w.Auth.ContractClient().VerifyVP(rawVP, &checkTime, expectedContractHash)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to verify the verifiable presentation: %s", err.Error()))
 	}
 	// Convert internal validationResult to api SignatureVerificationResponse
 	response := SignatureVerificationResponse{}
+	if validationResult.ContractHash != "" {
+		contractHash := ContractHash(validationResult.ContractHash)
+		response.ContractHash = &contractHash
+	}
 	if validationResult.Validity == contract.Valid {
 		response.Validity = true
 
@@ -89,6 +102,9 @@ func (w Wrapper) VerifySignature(ctx echo.Context) error {
 		response.VpType = &vpType
 	} else {
 		response.Validity = false
+		if validationResult.Reason != "" {
+			response.Reason = &validationResult.Reason
+		}
 	}
 	return ctx.JSON(http.StatusOK, response)
 }
@@ -99,12 +115,33 @@ func (w Wrapper) CreateSignSession(ctx echo.Context) error {
 	if err := ctx.Bind(requestParams); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("could not parse request body: %s", err.Error()))
 	}
+	legalEntity, err := core.ParsePartyID(string(requestParams.LegalEntity))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid value for param legalEntity: '%s', make sure its in the form 'urn:oid:1.2.3.4:foo'", requestParams.LegalEntity))
+	}
+	// the session is bound to the certificate of the client, without one any client could retrieve the session
+	caller := ratelimit.ClientCertificate(ctx.Request(), w.TrustClientCertHeader)
+	if caller == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unable to create sign challenge: a client certificate is required")
+	}
 	createSessionRequest := services.CreateSessionRequest{
 		SigningMeans: contract.SigningMeans(requestParams.Means),
 		Message:      requestParams.Payload,
+		Params:       requestParams.Params,
+		ClientID:     ratelimit.ClientID(ctx.Request(), w.TrustClientCertHeader),
+		Caller:       caller,
+		LegalEntity:  legalEntity,
+	}
+	if requestParams.Callback != nil {
+		createSessionRequest.Callback = &services.SessionCallback{URL: requestParams.Callback.Url, Secret: requestParams.Callback.Secret}
 	}
 	sessionPtr, err := w.Auth.ContractClient().CreateSigningSession(createSessionRequest)
 	if err != nil {
+		var rateLimitErr services.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			ctx.Response().Header().Set("Retry-After", ratelimit.RetryAfter(rateLimitErr.RetryAfter))
+			return echo.NewHTTPError(http.StatusTooManyRequests, fmt.Sprintf("unable to create sign challenge: %s", err.Error()))
+		}
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to create sign challenge: %s", err.Error()))
 	}
 
@@ -122,8 +159,22 @@ func (w Wrapper) CreateSignSession(ctx echo.Context) error {
 	return ctx.JSON(http.StatusCreated, response)
 }
 
+// authorizeSignSession returns a 404 error when the signing session does not exist or was created by another client
+func (w Wrapper) authorizeSignSession(ctx echo.Context, sessionID string) error {
+	if err := w.Auth.ContractClient().AuthorizeSigningSession(sessionID, ratelimit.ClientCertificate(ctx.Request(), w.TrustClientCertHeader)); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no active signing session for sessionID: '%s' found", sessionID))
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("unable to retrieve session: %s", err.Error()))
+	}
+	return nil
+}
+
 // GetSignSessionStatus handles the http requests for getting the current status of a signing session.
 func (w Wrapper) GetSignSessionStatus(ctx echo.Context, sessionID string) error {
+	if err := w.authorizeSignSession(ctx, sessionID); err != nil {
+		return err
+	}
 	sessionStatus, err := w.Auth.ContractClient().SigningSessionStatus(sessionID)
 	if err != nil {
 		if errors.Is(err, services.ErrSessionNotFound) {
@@ -143,10 +194,90 @@ func (w Wrapper) GetSignSessionStatus(ctx echo.Context, sessionID string) error
 			return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("unable to convert verifiable presentation: %s", err.Error()))
 		}
 	}
-	response := GetSignSessionStatusResponse{Status: sessionStatus.Status(), VerifiablePresentation: apiVp}
+	response := GetSignSessionStatusResponse{
+		Status:                 SignSessionStatus(sessionStatus.Status()),
+		NativeStatus:           sessionStatus.NativeStatus(),
+		VerifiablePresentation: apiVp,
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// CancelSignSession handles the http request for cancelling a signing session.
+func (w Wrapper) CancelSignSession(ctx echo.Context, sessionID string) error {
+	if err := w.authorizeSignSession(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := w.Auth.ContractClient().CancelSigningSession(sessionID); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no active signing session for sessionID: '%s' found", sessionID))
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("unable to cancel session: %s", err.Error()))
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// GetSignSessionCallbackDeliveries handles the http request for the delivery log of the callback of a signing session.
+func (w Wrapper) GetSignSessionCallbackDeliveries(ctx echo.Context, sessionID string) error {
+	if err := w.authorizeSignSession(ctx, sessionID); err != nil {
+		return err
+	}
+	deliveries, err := w.Auth.ContractClient().SigningSessionCallbacks(sessionID)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no signing session with a callback for sessionID: '%s' found", sessionID))
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("unable to retrieve callback deliveries: %s", err.Error()))
+	}
+	response := make([]CallbackDelivery, len(deliveries))
+	for i, delivery := range deliveries {
+		response[i] = CallbackDelivery{
+			Attempt:   delivery.Attempt,
+			Delivered: delivery.Delivered,
+			Status:    SignSessionStatus(delivery.Status),
+			Time:      delivery.Time,
+		}
+		if delivery.StatusCode != 0 {
+			statusCode := delivery.StatusCode
+			response[i].StatusCode = &statusCode
+		}
+		if delivery.Error != "" {
+			deliveryError := delivery.Error
+			response[i].Error = &deliveryError
+		}
+	}
 	return ctx.JSON(http.StatusOK, response)
 }
 
+// GetSignSessionQrCode renders the session pointer of a signing session as QR code image
+func (w Wrapper) GetSignSessionQrCode(ctx echo.Context, sessionID string, params GetSignSessionQrCodeParams) error {
+	format := qrcode.FormatPNG
+	if params.Format != nil {
+		format = *params.Format
+	}
+	size := qrcode.DefaultSize
+	if params.Size != nil {
+		size = *params.Size
+	}
+	if err := w.authorizeSignSession(ctx, sessionID); err != nil {
+		return err
+	}
+	payload, err := w.Auth.ContractClient().SigningSessionPointer(sessionID)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no active signing session for sessionID: '%s' found", sessionID))
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("unable to retrieve the session pointer: %s", err.Error()))
+	}
+	image, contentType, err := qrcode.Render(payload, format, size)
+	if err != nil {
+		if errors.Is(err, qrcode.ErrUnsupportedFormat) || errors.Is(err, qrcode.ErrInvalidSize) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("unable to render QR code: %s", err.Error()))
+	}
+	return ctx.Blob(http.StatusOK, contentType, image)
+}
+
 // DrawUpContract handles the http request for drawing up a contract for a given contract template identified by type, language and version.
 func (w Wrapper) DrawUpContract(ctx echo.Context) error {
 	params := new(DrawUpContractRequest)
@@ -175,7 +306,7 @@ func (w Wrapper) DrawUpContract(ctx echo.Context) error {
 		}
 	}
 
-	template := contract.StandardContractTemplates.Get(contract.Type(params.Type), contract.Language(params.Language), contract.Version(params.Version))
+	template := w.Auth.ContractTemplates().Get(contract.Type(params.Type), contract.Language(params.Language), contract.Version(params.Version))
 	if template == nil {
 		return echo.NewHTTPError(http.StatusNotFound, "no contract found for given combination of type, version and language")
 	}
@@ -184,16 +315,28 @@ func (w Wrapper) DrawUpContract(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid value for param legalEntity: '%s', make sure its in the form 'urn:oid:1.2.3.4:foo'", params.LegalEntity))
 	}
 
-	drawnUpContract, err := w.Auth.ContractNotary().DrawUpContract(*template, orgID, vf, validDuration)
+	attributes := map[string]string{}
+	if params.Subject != nil {
+		attributes[contract.SubjectAttr] = *params.Subject
+	}
+	if params.PurposeOfUse != nil {
+		attributes[contract.PurposeOfUseAttr] = *params.PurposeOfUse
+	}
+
+	drawnUpContract, err := w.Auth.ContractNotary().DrawUpContract(*template, orgID, vf, validDuration, attributes)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("error while drawing up the contract: %s", err.Error()))
 	}
 
 	response := ContractResponse{
-		Language: ContractLanguage(drawnUpContract.Template.Language),
-		Message:  drawnUpContract.RawContractText,
-		Type:     ContractType(drawnUpContract.Template.Type),
-		Version:  ContractVersion(drawnUpContract.Template.Version),
+		Language:     ContractLanguage(drawnUpContract.Template.Language),
+		Message:      drawnUpContract.RawContractText,
+		Type:         ContractType(drawnUpContract.Template.Type),
+		Version:      ContractVersion(drawnUpContract.Template.Version),
+		ContractHash: ContractHash(drawnUpContract.Hash()),
+	}
+	if len(drawnUpContract.Warnings) > 0 {
+		response.Warnings = &drawnUpContract.Warnings
 	}
 	return ctx.JSON(http.StatusOK, response)
 
@@ -19,10 +19,13 @@
 package experimental
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
@@ -38,6 +41,8 @@ import (
 	"github.com/nuts-foundation/nuts-auth/pkg/contract"
 	"github.com/nuts-foundation/nuts-auth/pkg/services"
 	"github.com/nuts-foundation/nuts-auth/pkg/services/dummy"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/ratelimit"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/session"
 )
 
 type TestContext struct {
@@ -67,6 +72,10 @@ func (m mockAuthClient) ContractNotary() services.ContractNotary {
 	return m.mockContractNotary
 }
 
+func (m mockAuthClient) ContractTemplates() contract.TemplateStore {
+	return contract.StandardContractTemplates
+}
+
 func createContext(t *testing.T) TestContext {
 	t.Helper()
 	ctrl := gomock.NewController(t)
@@ -83,13 +92,28 @@ func createContext(t *testing.T) TestContext {
 	}
 }
 
+// expectAuthorizedSignSession makes the contract client accept the client of the request as the caller of the signing session
+func expectAuthorizedSignSession(ctx TestContext) {
+	request := clientRequest()
+	ctx.echoMock.EXPECT().Request().Return(request).AnyTimes()
+	ctx.contractClientMock.EXPECT().AuthorizeSigningSession(gomock.Any(), ratelimit.ClientCertificate(request, false)).Return(nil)
+}
+
+// clientRequest returns a request of a client which presented a certificate on the TLS connection
+func clientRequest() *http.Request {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Raw: []byte("client certificate")}}}
+	return request
+}
+
 func TestWrapper_GetSignSessionStatus(t *testing.T) {
 	t.Run("ok - started without VP", func(t *testing.T) {
 		ctx := createContext(t)
 		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
 
 		signingSessionID := "123"
-		signingSessionStatus := "started"
+		signingSessionStatus := contract.SessionCreated
 
 		signingSessionResult := mock_contract.NewMockSigningSessionResult(ctx.ctrl)
 
@@ -97,11 +121,13 @@ func TestWrapper_GetSignSessionStatus(t *testing.T) {
 		signingSessionResult.EXPECT().VerifiablePresentation().Return(vp, nil)
 
 		signingSessionResult.EXPECT().Status().Return(signingSessionStatus)
+		signingSessionResult.EXPECT().NativeStatus().Return("native")
 
 		ctx.contractClientMock.EXPECT().SigningSessionStatus(signingSessionID).Return(signingSessionResult, nil)
 
 		response := GetSignSessionStatusResponse{
-			Status:                 signingSessionStatus,
+			Status:                 SignSessionStatus(signingSessionStatus),
+			NativeStatus:           "native",
 			VerifiablePresentation: nil,
 		}
 
@@ -114,9 +140,10 @@ func TestWrapper_GetSignSessionStatus(t *testing.T) {
 	t.Run("ok - completed with VP", func(t *testing.T) {
 		ctx := createContext(t)
 		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
 
 		signingSessionID := "123"
-		signingSessionStatus := "completed"
+		signingSessionStatus := contract.SessionCompleted
 
 		signingSessionResult := mock_contract.NewMockSigningSessionResult(ctx.ctrl)
 
@@ -126,11 +153,13 @@ func TestWrapper_GetSignSessionStatus(t *testing.T) {
 		signingSessionResult.EXPECT().VerifiablePresentation().Return(vp, nil)
 
 		signingSessionResult.EXPECT().Status().Return(signingSessionStatus)
+		signingSessionResult.EXPECT().NativeStatus().Return("native")
 
 		ctx.contractClientMock.EXPECT().SigningSessionStatus(signingSessionID).Return(signingSessionResult, nil)
 
 		response := GetSignSessionStatusResponse{
-			Status:                 signingSessionStatus,
+			Status:                 SignSessionStatus(signingSessionStatus),
+			NativeStatus:           "native",
 			VerifiablePresentation: &VerifiablePresentation{Context: []string{"http://example.com"}},
 		}
 
@@ -143,6 +172,7 @@ func TestWrapper_GetSignSessionStatus(t *testing.T) {
 	t.Run("nok - session not found", func(t *testing.T) {
 		ctx := createContext(t)
 		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
 
 		signingSessionID := "123"
 		ctx.contractClientMock.EXPECT().SigningSessionStatus(signingSessionID).Return(nil, services.ErrSessionNotFound)
@@ -157,6 +187,7 @@ func TestWrapper_GetSignSessionStatus(t *testing.T) {
 	t.Run("nok - unable to build a VP", func(t *testing.T) {
 		ctx := createContext(t)
 		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
 
 		signingSessionID := "123"
 		signingSessionResult := mock_contract.NewMockSigningSessionResult(ctx.ctrl)
@@ -171,6 +202,210 @@ func TestWrapper_GetSignSessionStatus(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, httpError.Code)
 		assert.Equal(t, "error while building verifiable presentation: could not build VP", httpError.Message)
 	})
+
+	t.Run("nok - session of another client", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set(ratelimit.ClientCertHeader, "other client")
+		ctx.wrapper.TrustClientCertHeader = true
+		ctx.echoMock.EXPECT().Request().Return(request)
+		ctx.contractClientMock.EXPECT().AuthorizeSigningSession("123", ratelimit.ClientCertificate(request, true)).Return(services.ErrSessionNotFound)
+
+		err := ctx.wrapper.GetSignSessionStatus(ctx.echoMock, "123")
+
+		assert.IsType(t, &echo.HTTPError{}, err)
+		assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+	})
+}
+
+func TestWrapper_CancelSignSession(t *testing.T) {
+	t.Run("ok - session is cancelled", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		ctx.contractClientMock.EXPECT().CancelSigningSession("123").Return(nil)
+		ctx.echoMock.EXPECT().NoContent(http.StatusNoContent)
+
+		err := ctx.wrapper.CancelSignSession(ctx.echoMock, "123")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("error - unknown session", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		ctx.contractClientMock.EXPECT().CancelSigningSession("123").Return(services.ErrSessionNotFound)
+
+		err := ctx.wrapper.CancelSignSession(ctx.echoMock, "123")
+
+		if assert.IsType(t, &echo.HTTPError{}, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+		}
+	})
+
+	t.Run("error - cancel fails", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		ctx.contractClientMock.EXPECT().CancelSigningSession("123").Return(errors.New("b00m!"))
+
+		err := ctx.wrapper.CancelSignSession(ctx.echoMock, "123")
+
+		if assert.IsType(t, &echo.HTTPError{}, err) {
+			assert.Equal(t, http.StatusInternalServerError, err.(*echo.HTTPError).Code)
+		}
+	})
+}
+
+func TestWrapper_GetSignSessionCallbackDeliveries(t *testing.T) {
+	t.Run("ok - deliveries are returned", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		moment := time.Now()
+		ctx.contractClientMock.EXPECT().SigningSessionCallbacks("123").Return([]services.CallbackDelivery{
+			{Attempt: 1, Time: moment, Status: "DONE", Error: "unexpected status code 500", StatusCode: 500},
+			{Attempt: 2, Time: moment, Status: "DONE", StatusCode: 200, Delivered: true},
+		}, nil)
+		errorMessage := "unexpected status code 500"
+		failedCode := 500
+		okCode := 200
+		ctx.echoMock.EXPECT().JSON(http.StatusOK, []CallbackDelivery{
+			{Attempt: 1, Time: moment, Status: "DONE", Error: &errorMessage, StatusCode: &failedCode},
+			{Attempt: 2, Time: moment, Status: "DONE", StatusCode: &okCode, Delivered: true},
+		})
+
+		err := ctx.wrapper.GetSignSessionCallbackDeliveries(ctx.echoMock, "123")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("error - unknown session", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		ctx.contractClientMock.EXPECT().SigningSessionCallbacks("123").Return(nil, services.ErrSessionNotFound)
+
+		err := ctx.wrapper.GetSignSessionCallbackDeliveries(ctx.echoMock, "123")
+
+		if assert.IsType(t, &echo.HTTPError{}, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+		}
+	})
+
+	t.Run("error - other error", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		ctx.contractClientMock.EXPECT().SigningSessionCallbacks("123").Return(nil, errors.New("b00m!"))
+
+		err := ctx.wrapper.GetSignSessionCallbackDeliveries(ctx.echoMock, "123")
+
+		if assert.IsType(t, &echo.HTTPError{}, err) {
+			assert.Equal(t, http.StatusInternalServerError, err.(*echo.HTTPError).Code)
+		}
+	})
+}
+
+func TestWrapper_GetSignSessionQrCode(t *testing.T) {
+	pointer := []byte(`{"u":"https://nuts.example.com/auth/irmaclient/123","irmaqr":"signing"}`)
+
+	t.Run("ok - png by default", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		ctx.contractClientMock.EXPECT().SigningSessionPointer("123").Return(pointer, nil)
+		ctx.echoMock.EXPECT().Blob(http.StatusOK, "image/png", gomock.Any())
+
+		err := ctx.wrapper.GetSignSessionQrCode(ctx.echoMock, "123", GetSignSessionQrCodeParams{})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok - svg", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		format := "svg"
+		size := 512
+		ctx.contractClientMock.EXPECT().SigningSessionPointer("123").Return(pointer, nil)
+		ctx.echoMock.EXPECT().Blob(http.StatusOK, "image/svg+xml", gomock.Any()).Do(func(_ int, _ string, image []byte) {
+			assert.Contains(t, string(image), `width="512"`)
+		})
+
+		err := ctx.wrapper.GetSignSessionQrCode(ctx.echoMock, "123", GetSignSessionQrCodeParams{Format: &format, Size: &size})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("error - unsupported format", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		format := "gif"
+		ctx.contractClientMock.EXPECT().SigningSessionPointer("123").Return(pointer, nil)
+
+		err := ctx.wrapper.GetSignSessionQrCode(ctx.echoMock, "123", GetSignSessionQrCodeParams{Format: &format})
+
+		if assert.IsType(t, &echo.HTTPError{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+		}
+	})
+
+	t.Run("error - invalid size", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		size := 0
+		ctx.contractClientMock.EXPECT().SigningSessionPointer("123").Return(pointer, nil)
+
+		err := ctx.wrapper.GetSignSessionQrCode(ctx.echoMock, "123", GetSignSessionQrCodeParams{Size: &size})
+
+		if assert.IsType(t, &echo.HTTPError{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+		}
+	})
+
+	t.Run("error - unknown session", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectAuthorizedSignSession(ctx)
+
+		ctx.contractClientMock.EXPECT().SigningSessionPointer("123").Return(nil, services.ErrSessionNotFound)
+
+		err := ctx.wrapper.GetSignSessionQrCode(ctx.echoMock, "123", GetSignSessionQrCodeParams{})
+
+		if assert.IsType(t, &echo.HTTPError{}, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+		}
+	})
+
+	t.Run("error - session of another client", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.echoMock.EXPECT().Request().Return(clientRequest()).AnyTimes()
+		ctx.contractClientMock.EXPECT().AuthorizeSigningSession("123", ratelimit.ClientCertificate(clientRequest(), false)).Return(services.ErrSessionNotFound)
+
+		err := ctx.wrapper.GetSignSessionQrCode(ctx.echoMock, "123", GetSignSessionQrCodeParams{})
+
+		if assert.IsType(t, &echo.HTTPError{}, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+		}
+	})
 }
 
 func TestWrapper_DrawUpContract(t *testing.T) {
@@ -199,13 +434,77 @@ func TestWrapper_DrawUpContract(t *testing.T) {
 			Template:        template,
 			Params:          nil,
 		}
-		ctx.notaryMock.EXPECT().DrawUpContract(*template, gomock.Any(), gomock.Any(), gomock.Any()).Return(drawnUpContract, nil)
+		ctx.notaryMock.EXPECT().DrawUpContract(*template, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(drawnUpContract, nil)
+
+		expectedResponse := ContractResponse{
+			Language:     ContractLanguage("EN"),
+			Message:      "drawn up contract text",
+			Type:         ContractType("PractitionerLogin"),
+			Version:      ContractVersion("v3"),
+			ContractHash: ContractHash(drawnUpContract.Hash()),
+		}
+		ctx.echoMock.EXPECT().JSON(http.StatusOK, expectedResponse)
+		err := ctx.wrapper.DrawUpContract(ctx.echoMock)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok - subject and purpose of use are passed to the notary", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		subject := "urn:oid:2.16.840.1.113883.2.4.6.3:999999990"
+		purposeOfUse := "TREAT"
+		params := DrawUpContractRequest{
+			Language:     ContractLanguage("EN"),
+			Type:         ContractType("PractitionerPatientAccess"),
+			Version:      ContractVersion("v1"),
+			LegalEntity:  LegalEntity("urn:oid:1.2.3.4:foo"),
+			Subject:      &subject,
+			PurposeOfUse: &purposeOfUse,
+		}
+		bindPostBody(&ctx, params)
+
+		template := contract.StandardContractTemplates["EN"]["PractitionerPatientAccess"]["v1"]
+		drawnUpContract := &contract.Contract{
+			RawContractText: "drawn up contract text",
+			Template:        template,
+		}
+		expectedAttributes := map[string]string{contract.SubjectAttr: subject, contract.PurposeOfUseAttr: purposeOfUse}
+		ctx.notaryMock.EXPECT().DrawUpContract(*template, gomock.Any(), gomock.Any(), gomock.Any(), expectedAttributes).Return(drawnUpContract, nil)
+		ctx.echoMock.EXPECT().JSON(http.StatusOK, gomock.Any())
+
+		err := ctx.wrapper.DrawUpContract(ctx.echoMock)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok - drawing up a contract of a deprecated template returns warnings", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		params := DrawUpContractRequest{
+			Language:    ContractLanguage("EN"),
+			Type:        ContractType("PractitionerLogin"),
+			Version:     ContractVersion("v3"),
+			LegalEntity: LegalEntity("urn:oid:1.2.3.4:foo"),
+		}
+		bindPostBody(&ctx, params)
+
+		template := contract.StandardContractTemplates["EN"]["PractitionerLogin"]["v3"]
+		warnings := []string{"contract template EN:PractitionerLogin:v3 is deprecated since 2020-10-01T00:00:00Z"}
+		drawnUpContract := &contract.Contract{
+			RawContractText: "drawn up contract text",
+			Template:        template,
+			Warnings:        warnings,
+		}
+		ctx.notaryMock.EXPECT().DrawUpContract(*template, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(drawnUpContract, nil)
 
 		expectedResponse := ContractResponse{
-			Language: ContractLanguage("EN"),
-			Message:  "drawn up contract text",
-			Type:     ContractType("PractitionerLogin"),
-			Version:  ContractVersion("v3"),
+			Language:     ContractLanguage("EN"),
+			Message:      "drawn up contract text",
+			Type:         ContractType("PractitionerLogin"),
+			Version:      ContractVersion("v3"),
+			ContractHash: ContractHash(drawnUpContract.Hash()),
+			Warnings:     &warnings,
 		}
 		ctx.echoMock.EXPECT().JSON(http.StatusOK, expectedResponse)
 		err := ctx.wrapper.DrawUpContract(ctx.echoMock)
@@ -304,7 +603,7 @@ func TestWrapper_DrawUpContract(t *testing.T) {
 		}
 		bindPostBody(&ctx, params)
 
-		ctx.notaryMock.EXPECT().DrawUpContract(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("unknown error while drawing up the contract"))
+		ctx.notaryMock.EXPECT().DrawUpContract(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("unknown error while drawing up the contract"))
 
 		err := ctx.wrapper.DrawUpContract(ctx.echoMock)
 
@@ -337,6 +636,7 @@ func TestWrapper_CreateSignSession(t *testing.T) {
 		ctx.echoMock.EXPECT().Bind(gomock.Any()).Do(func(f interface{}) {
 			_ = json.Unmarshal(jsonData, f)
 		})
+		ctx.echoMock.EXPECT().Request().Return(clientRequest()).AnyTimes()
 	}
 
 	t.Run("create a dummy signing session", func(t *testing.T) {
@@ -345,18 +645,18 @@ func TestWrapper_CreateSignSession(t *testing.T) {
 
 		dummyMeans := dummy.Dummy{
 			InStrictMode: false,
-			Sessions:     map[string]string{},
-			Status:       map[string]string{},
+			Sessions:     session.NewMemoryStore(),
 		}
 
 		ctx.contractClientMock.EXPECT().CreateSigningSession(gomock.Any()).DoAndReturn(
 			func(sessionRequest services.CreateSessionRequest) (contract.SessionPointer, error) {
-				return dummyMeans.StartSigningSession(sessionRequest.Message)
+				return dummyMeans.StartSigningSession(sessionRequest.Message, nil)
 			})
 
 		postParams := CreateSignSessionRequest{
-			Means:   "dummy",
-			Payload: "this is the contract message to agree to",
+			Means:       "dummy",
+			Payload:     "this is the contract message to agree to",
+			LegalEntity: LegalEntity("urn:oid:1.2.3.4:foo"),
 		}
 		bindPostBody(&ctx, postParams)
 
@@ -365,11 +665,37 @@ func TestWrapper_CreateSignSession(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("ok - callback is passed to the contract client", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		dummyMeans := dummy.Dummy{Sessions: session.NewMemoryStore()}
+		ctx.contractClientMock.EXPECT().CreateSigningSession(gomock.Any()).DoAndReturn(
+			func(sessionRequest services.CreateSessionRequest) (contract.SessionPointer, error) {
+				assert.Equal(t, &services.SessionCallback{URL: "https://example.com/callback", Secret: "secret"}, sessionRequest.Callback)
+				assert.Equal(t, "urn:oid:1.2.3.4:foo", sessionRequest.LegalEntity.String())
+				assert.Equal(t, ratelimit.ClientCertificate(clientRequest(), false), sessionRequest.Caller)
+				return dummyMeans.StartSigningSession(sessionRequest.Message, nil)
+			})
+		postParams := CreateSignSessionRequest{
+			Means:       "dummy",
+			Payload:     "this is the contract message to agree to",
+			LegalEntity: LegalEntity("urn:oid:1.2.3.4:foo"),
+			Callback:    &SignSessionCallback{Url: "https://example.com/callback", Secret: "secret"},
+		}
+		bindPostBody(&ctx, postParams)
+		ctx.echoMock.EXPECT().JSON(http.StatusCreated, signSessionResponseMatcher{means: "dummy"})
+
+		err := ctx.wrapper.CreateSignSession(ctx.echoMock)
+
+		assert.NoError(t, err)
+	})
+
 	t.Run("nok - error while creating signing session", func(t *testing.T) {
 		ctx := createContext(t)
 		defer ctx.ctrl.Finish()
 
-		postParams := CreateSignSessionRequest{}
+		postParams := CreateSignSessionRequest{LegalEntity: LegalEntity("urn:oid:1.2.3.4:foo")}
 		bindPostBody(&ctx, postParams)
 
 		ctx.contractClientMock.EXPECT().CreateSigningSession(gomock.Any()).Return(nil, errors.New("some error"))
@@ -381,6 +707,60 @@ func TestWrapper_CreateSignSession(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, httpError.Code)
 		assert.Equal(t, "unable to create sign challenge: some error", httpError.Message)
 	})
+
+	t.Run("nok - client without certificate", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		postParams := CreateSignSessionRequest{Means: "dummy", Payload: "this is the contract message to agree to", LegalEntity: LegalEntity("urn:oid:1.2.3.4:foo")}
+		jsonData, _ := json.Marshal(postParams)
+		ctx.echoMock.EXPECT().Bind(gomock.Any()).Do(func(f interface{}) {
+			_ = json.Unmarshal(jsonData, f)
+		})
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.Header.Set(ratelimit.ClientCertHeader, "certificate of another client")
+		ctx.echoMock.EXPECT().Request().Return(request).AnyTimes()
+
+		err := ctx.wrapper.CreateSignSession(ctx.echoMock)
+
+		assert.IsType(t, &echo.HTTPError{}, err)
+		httpError := err.(*echo.HTTPError)
+		assert.Equal(t, http.StatusUnauthorized, httpError.Code)
+		assert.Equal(t, "unable to create sign challenge: a client certificate is required", httpError.Message)
+	})
+
+	t.Run("nok - invalid legal entity", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		postParams := CreateSignSessionRequest{Means: "dummy", Payload: "this is the contract message to agree to", LegalEntity: LegalEntity("ZorgId:15")}
+		bindPostBody(&ctx, postParams)
+
+		err := ctx.wrapper.CreateSignSession(ctx.echoMock)
+
+		assert.IsType(t, &echo.HTTPError{}, err)
+		httpError := err.(*echo.HTTPError)
+		assert.Equal(t, http.StatusBadRequest, httpError.Code)
+		assert.Equal(t, "invalid value for param legalEntity: 'ZorgId:15', make sure its in the form 'urn:oid:1.2.3.4:foo'", httpError.Message)
+	})
+
+	t.Run("nok - rate limited", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		postParams := CreateSignSessionRequest{Means: "dummy", Payload: "this is the contract message to agree to", LegalEntity: LegalEntity("urn:oid:1.2.3.4:foo")}
+		bindPostBody(&ctx, postParams)
+		recorder := httptest.NewRecorder()
+		ctx.echoMock.EXPECT().Response().Return(echo.NewResponse(recorder, echo.New()))
+		ctx.contractClientMock.EXPECT().CreateSigningSession(gomock.Any()).Return(nil, services.RateLimitError{Reason: "too many signing sessions started", RetryAfter: 1500 * time.Millisecond})
+
+		err := ctx.wrapper.CreateSignSession(ctx.echoMock)
+
+		assert.IsType(t, &echo.HTTPError{}, err)
+		httpError := err.(*echo.HTTPError)
+		assert.Equal(t, http.StatusTooManyRequests, httpError.Code)
+		assert.Equal(t, "2", recorder.Header().Get("Retry-After"))
+	})
 }
 
 func TestWrapper_VerifySignature(t *testing.T) {
@@ -422,7 +802,7 @@ func TestWrapper_VerifySignature(t *testing.T) {
 			VpType:           &vpType,
 		}
 
-		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), gomock.Any()).Return(verificationResult, nil)
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), gomock.Any(), nil).Return(verificationResult, nil)
 		ctx.echoMock.EXPECT().JSON(http.StatusOK, expectedResponse)
 
 		err := ctx.wrapper.VerifySignature(ctx.echoMock)
@@ -446,7 +826,68 @@ func TestWrapper_VerifySignature(t *testing.T) {
 			Validity: false,
 		}
 
-		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), gomock.Any()).Return(verificationResult, nil)
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), gomock.Any(), nil).Return(verificationResult, nil)
+		ctx.echoMock.EXPECT().JSON(http.StatusOK, expectedResponse)
+
+		err := ctx.wrapper.VerifySignature(ctx.echoMock)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok - invalid VP with reason", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		postParams := SignatureVerificationRequest{
+			VerifiablePresentation: VerifiablePresentation{}}
+
+		bindPostBody(&ctx, postParams)
+
+		reason := "contract template is sunset: NL:BehandelaarLogin:v1 is no longer accepted since 2021-01-01T00:00:00Z"
+		verificationResult := &contract.VPVerificationResult{
+			Validity: contract.Invalid,
+			Reason:   reason,
+		}
+
+		expectedResponse := SignatureVerificationResponse{
+			Validity: false,
+			Reason:   &reason,
+		}
+
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), gomock.Any(), nil).Return(verificationResult, nil)
+		ctx.echoMock.EXPECT().JSON(http.StatusOK, expectedResponse)
+
+		err := ctx.wrapper.VerifySignature(ctx.echoMock)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok - expected contract hash is passed", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		contractHash := ContractHash("abc")
+		postParams := SignatureVerificationRequest{
+			ContractHash:           &contractHash,
+			VerifiablePresentation: VerifiablePresentation{},
+		}
+
+		bindPostBody(&ctx, postParams)
+
+		reason := "signed contract does not match the expected contract hash"
+		verificationResult := &contract.VPVerificationResult{
+			Validity:     contract.Invalid,
+			ContractHash: "def",
+			Reason:       reason,
+		}
+
+		signedHash := ContractHash("def")
+		expectedResponse := SignatureVerificationResponse{
+			Validity:     false,
+			Reason:       &reason,
+			ContractHash: &signedHash,
+		}
+
+		expectedHash := "abc"
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), gomock.Any(), &expectedHash).Return(verificationResult, nil)
 		ctx.echoMock.EXPECT().JSON(http.StatusOK, expectedResponse)
 
 		err := ctx.wrapper.VerifySignature(ctx.echoMock)
@@ -488,7 +929,7 @@ func TestWrapper_VerifySignature(t *testing.T) {
 			return
 		}
 
-		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), &checkTime).Return(verificationResult, nil)
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), &checkTime, nil).Return(verificationResult, nil)
 		ctx.echoMock.EXPECT().JSON(http.StatusOK, expectedResponse)
 
 		err = ctx.wrapper.VerifySignature(ctx.echoMock)
@@ -524,7 +965,7 @@ func TestWrapper_VerifySignature(t *testing.T) {
 
 		bindPostBody(&ctx, postParams)
 
-		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), gomock.Any()).Return(nil, errors.New("verification error"))
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), gomock.Any(), nil).Return(nil, errors.New("verification error"))
 
 		err := ctx.wrapper.VerifySignature(ctx.echoMock)
 		if !assert.Error(t, err) {
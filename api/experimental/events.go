@@ -0,0 +1,164 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package experimental
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+)
+
+// KeepAliveInterval is the interval in which a comment is sent on an idle event stream, so proxies keep the connection open
+var KeepAliveInterval = 15 * time.Second
+
+// eventBufferSize is the number of status transitions which are buffered for a slow client
+const eventBufferSize = 16
+
+// GetSignSessionEvents handles the http request for streaming the status transitions of a signing session as server-sent events.
+// The stream is driven by the notifications of the signer, it ends after the final status or when the client disconnects.
+func (w Wrapper) GetSignSessionEvents(ctx echo.Context, sessionID string) error {
+	if err := w.authorizeSignSession(ctx, sessionID); err != nil {
+		return err
+	}
+	events := make(chan contract.SigningSessionResult, eventBufferSize)
+	// the final status has its own slot, so it is never dropped and the stream always ends
+	final := make(chan contract.SigningSessionResult, 1)
+	unsubscribe, err := w.Auth.ContractClient().SubscribeSigningSession(sessionID, func(result contract.SigningSessionResult) {
+		transitions := events
+		if result.Status().Final() {
+			transitions = final
+		}
+		select {
+		case transitions <- result:
+		default:
+			// the client does not keep up, intermediate transitions are skipped but the final status is still delivered
+		}
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no active signing session for sessionID: '%s' found", sessionID))
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Sprintf("unable to subscribe to session: %s", err.Error()))
+	}
+	defer unsubscribe()
+
+	// the subscription is in place before the current status is retrieved, so no transition is missed
+	current, err := w.Auth.ContractClient().SigningSessionStatus(sessionID)
+	if err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no active signing session for sessionID: '%s' found", sessionID))
+		}
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unable to retrieve a session status: %s", err.Error()))
+	}
+
+	response := ctx.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set("Cache-Control", "no-cache")
+	response.Header().Set("Connection", "keep-alive")
+	response.WriteHeader(http.StatusOK)
+
+	stream := eventStream{response: response}
+	if err := stream.status(current); err != nil || stream.done {
+		return err
+	}
+
+	keepAlive := time.NewTicker(KeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-ctx.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if err := stream.write(": keep-alive\n\n"); err != nil {
+				return err
+			}
+		case result := <-events:
+			if err := stream.status(result); err != nil || stream.done {
+				return err
+			}
+		case result := <-final:
+			// the transitions before the final status are written first
+			for len(events) > 0 {
+				if err := stream.status(<-events); err != nil || stream.done {
+					return err
+				}
+			}
+			return stream.status(result)
+		}
+	}
+}
+
+// eventStream writes the status transitions of a signing session as server-sent events
+type eventStream struct {
+	response         *echo.Response
+	lastNativeStatus string
+	done             bool
+}
+
+// status writes a status event when the native status differs from the previous one. For a completed session, the
+// verifiable presentation is written as final event.
+func (e *eventStream) status(result contract.SigningSessionResult) error {
+	nativeStatus := result.NativeStatus()
+	if nativeStatus == e.lastNativeStatus {
+		return nil
+	}
+	e.lastNativeStatus = nativeStatus
+	status := result.Status()
+	if err := e.event("status", GetSignSessionStatusResponse{Status: SignSessionStatus(status), NativeStatus: nativeStatus}); err != nil {
+		return err
+	}
+	if !status.Final() {
+		return nil
+	}
+	e.done = true
+	if status != contract.SessionCompleted {
+		return nil
+	}
+	vp, err := result.VerifiablePresentation()
+	if err != nil {
+		return e.event("error", map[string]string{"error": fmt.Sprintf("error while building verifiable presentation: %s", err.Error())})
+	}
+	if vp == nil {
+		return nil
+	}
+	return e.event("verifiablePresentation", vp)
+}
+
+func (e *eventStream) event(name string, data interface{}) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return e.write(fmt.Sprintf("event: %s\ndata: %s\n\n", name, js))
+}
+
+func (e *eventStream) write(data string) error {
+	if _, err := e.response.Write([]byte(data)); err != nil {
+		return err
+	}
+	e.response.Flush()
+	return nil
+}
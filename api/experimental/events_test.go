@@ -0,0 +1,202 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package experimental
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	mock_contract "github.com/nuts-foundation/nuts-auth/mock/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/ratelimit"
+)
+
+func TestWrapper_GetSignSessionEvents(t *testing.T) {
+	sessionResult := func(ctx TestContext, status contract.SessionStatus, vp interface{}) contract.SigningSessionResult {
+		result := mock_contract.NewMockSigningSessionResult(ctx.ctrl)
+		result.EXPECT().Status().Return(status).AnyTimes()
+		result.EXPECT().NativeStatus().Return("native-" + string(status)).AnyTimes()
+		result.EXPECT().VerifiablePresentation().Return(vp, nil).AnyTimes()
+		return result
+	}
+	eventContext := func() (echo.Context, *httptest.ResponseRecorder) {
+		request := httptest.NewRequest(http.MethodGet, "/internal/auth/experimental/signature/session/123/events", nil)
+		request.TLS = clientRequest().TLS
+		recorder := httptest.NewRecorder()
+		return echo.New().NewContext(request, recorder), recorder
+	}
+
+	t.Run("ok - status transitions are streamed until the session is completed", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.contractClientMock.EXPECT().AuthorizeSigningSession("123", ratelimit.ClientCertificate(clientRequest(), false)).Return(nil)
+		var listener contract.SessionListener
+		unsubscribed := false
+		ctx.contractClientMock.EXPECT().SubscribeSigningSession("123", gomock.Any()).DoAndReturn(
+			func(sessionID string, l contract.SessionListener) (func(), error) {
+				listener = l
+				return func() { unsubscribed = true }, nil
+			})
+		ctx.contractClientMock.EXPECT().SigningSessionStatus("123").DoAndReturn(
+			func(sessionID string) (contract.SigningSessionResult, error) {
+				// transitions during the retrieval of the current status are buffered
+				listener(sessionResult(ctx, contract.SessionCreated, nil))
+				listener(sessionResult(ctx, contract.SessionInProgress, nil))
+				listener(sessionResult(ctx, contract.SessionCompleted, map[string]string{"type": "NutsDummyVerifiablePresentation"}))
+				return sessionResult(ctx, contract.SessionCreated, nil), nil
+			})
+		echoCtx, recorder := eventContext()
+
+		err := ctx.wrapper.GetSignSessionEvents(echoCtx, "123")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, unsubscribed)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/event-stream", recorder.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "event: status\ndata: {\"nativeStatus\":\"native-created\",\"status\":\"created\"}\n\n"+
+			"event: status\ndata: {\"nativeStatus\":\"native-in-progress\",\"status\":\"in-progress\"}\n\n"+
+			"event: status\ndata: {\"nativeStatus\":\"native-completed\",\"status\":\"completed\"}\n\n"+
+			"event: verifiablePresentation\ndata: {\"type\":\"NutsDummyVerifiablePresentation\"}\n\n", recorder.Body.String())
+	})
+
+	t.Run("ok - final status is delivered to a client which does not keep up", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.contractClientMock.EXPECT().AuthorizeSigningSession("123", ratelimit.ClientCertificate(clientRequest(), false)).Return(nil)
+		var listener contract.SessionListener
+		ctx.contractClientMock.EXPECT().SubscribeSigningSession("123", gomock.Any()).DoAndReturn(
+			func(sessionID string, l contract.SessionListener) (func(), error) {
+				listener = l
+				return func() {}, nil
+			})
+		ctx.contractClientMock.EXPECT().SigningSessionStatus("123").DoAndReturn(
+			func(sessionID string) (contract.SigningSessionResult, error) {
+				// more transitions than fit in the buffer
+				for i := 0; i <= eventBufferSize; i++ {
+					listener(sessionResult(ctx, contract.SessionInProgress, nil))
+				}
+				listener(sessionResult(ctx, contract.SessionCancelled, nil))
+				return sessionResult(ctx, contract.SessionCreated, nil), nil
+			})
+		echoCtx, recorder := eventContext()
+
+		err := ctx.wrapper.GetSignSessionEvents(echoCtx, "123")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "event: status\ndata: {\"nativeStatus\":\"native-created\",\"status\":\"created\"}\n\n"+
+			"event: status\ndata: {\"nativeStatus\":\"native-in-progress\",\"status\":\"in-progress\"}\n\n"+
+			"event: status\ndata: {\"nativeStatus\":\"native-cancelled\",\"status\":\"cancelled\"}\n\n", recorder.Body.String())
+	})
+
+	t.Run("ok - stream of a finished session ends immediately", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.contractClientMock.EXPECT().AuthorizeSigningSession("123", ratelimit.ClientCertificate(clientRequest(), false)).Return(nil)
+		ctx.contractClientMock.EXPECT().SubscribeSigningSession("123", gomock.Any()).Return(func() {}, nil)
+		ctx.contractClientMock.EXPECT().SigningSessionStatus("123").Return(sessionResult(ctx, contract.SessionCancelled, nil), nil)
+		echoCtx, recorder := eventContext()
+
+		err := ctx.wrapper.GetSignSessionEvents(echoCtx, "123")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "event: status\ndata: {\"nativeStatus\":\"native-cancelled\",\"status\":\"cancelled\"}\n\n", recorder.Body.String())
+	})
+
+	t.Run("ok - stream ends when the client disconnects", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.contractClientMock.EXPECT().AuthorizeSigningSession("123", ratelimit.ClientCertificate(clientRequest(), false)).Return(nil)
+		ctx.contractClientMock.EXPECT().SubscribeSigningSession("123", gomock.Any()).Return(func() {}, nil)
+		ctx.contractClientMock.EXPECT().SigningSessionStatus("123").Return(sessionResult(ctx, contract.SessionCreated, nil), nil)
+		echoCtx, recorder := eventContext()
+		cancelledCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+		echoCtx.SetRequest(echoCtx.Request().WithContext(cancelledCtx))
+
+		err := ctx.wrapper.GetSignSessionEvents(echoCtx, "123")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "event: status\ndata: {\"nativeStatus\":\"native-created\",\"status\":\"created\"}\n\n", recorder.Body.String())
+	})
+
+	t.Run("error - unknown session", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.contractClientMock.EXPECT().AuthorizeSigningSession("123", ratelimit.ClientCertificate(clientRequest(), false)).Return(nil)
+		ctx.contractClientMock.EXPECT().SubscribeSigningSession("123", gomock.Any()).Return(nil, services.ErrSessionNotFound)
+		echoCtx, _ := eventContext()
+
+		err := ctx.wrapper.GetSignSessionEvents(echoCtx, "123")
+
+		if !assert.Error(t, err) {
+			return
+		}
+		assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+	})
+
+	t.Run("error - subscription failed", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.contractClientMock.EXPECT().AuthorizeSigningSession("123", ratelimit.ClientCertificate(clientRequest(), false)).Return(nil)
+		ctx.contractClientMock.EXPECT().SubscribeSigningSession("123", gomock.Any()).Return(nil, errors.New("error"))
+		echoCtx, _ := eventContext()
+
+		err := ctx.wrapper.GetSignSessionEvents(echoCtx, "123")
+
+		if !assert.Error(t, err) {
+			return
+		}
+		assert.Equal(t, http.StatusInternalServerError, err.(*echo.HTTPError).Code)
+	})
+
+	t.Run("error - session disappeared after subscribing", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.contractClientMock.EXPECT().AuthorizeSigningSession("123", ratelimit.ClientCertificate(clientRequest(), false)).Return(nil)
+		unsubscribed := false
+		ctx.contractClientMock.EXPECT().SubscribeSigningSession("123", gomock.Any()).Return(func() { unsubscribed = true }, nil)
+		ctx.contractClientMock.EXPECT().SigningSessionStatus("123").Return(nil, services.ErrSessionNotFound)
+		echoCtx, _ := eventContext()
+
+		err := ctx.wrapper.GetSignSessionEvents(echoCtx, "123")
+
+		if !assert.Error(t, err) {
+			return
+		}
+		assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+		assert.True(t, unsubscribed)
+	})
+}
@@ -8,14 +8,44 @@ import (
 	"github.com/deepmap/oapi-codegen/pkg/runtime"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"time"
 )
 
+// CallbackDelivery defines model for CallbackDelivery.
+type CallbackDelivery struct {
+
+	// Number of the attempt, starting at 1.
+	Attempt int `json:"attempt"`
+
+	// True when the webhook accepted the callback with a 2xx status code.
+	Delivered bool `json:"delivered"`
+
+	// Reason why the attempt failed.
+	Error *string `json:"error,omitempty"`
+
+	// Status of the signing process, it is the same for every signing means. A session starts as created, is in-progress
+	// while the user is signing and ends as completed (signed), cancelled, expired or failed.
+	Status SignSessionStatus `json:"status"`
+
+	// HTTP status code returned by the webhook, absent when no response was received.
+	StatusCode *int `json:"statusCode,omitempty"`
+
+	// Moment of the attempt.
+	Time time.Time `json:"time"`
+}
+
+// ContractHash defines model for ContractHash.
+type ContractHash string
+
 // ContractLanguage defines model for ContractLanguage.
 type ContractLanguage string
 
 // ContractResponse defines model for ContractResponse.
 type ContractResponse struct {
 
+	// Hex encoded SHA-256 hash over the template identifier and the normalized contract text. When given in a verification request, the signature is only valid for a contract with this hash.
+	ContractHash ContractHash `json:"contractHash"`
+
 	// Language of the contract in all caps.
 	Language ContractLanguage `json:"language"`
 
@@ -27,6 +57,9 @@ type ContractResponse struct {
 
 	// Version of the contract.
 	Version ContractVersion `json:"version"`
+
+	// Remarks about the drawn up contract, like the use of a deprecated contract template.
+	Warnings *[]string `json:"warnings,omitempty"`
 }
 
 // ContractType defines model for ContractType.
@@ -37,7 +70,17 @@ type ContractVersion string
 
 // CreateSignSessionRequest defines model for CreateSignSessionRequest.
 type CreateSignSessionRequest struct {
-	Means string `json:"means"`
+
+	// Webhook which receives a POST request when the session reaches its final status. The JSON body contains the sessionID,
+	// status, nativeStatus and, when the contract was signed, the verifiablePresentation. The X-Nuts-Timestamp header contains
+	// the moment of the attempt in seconds since the Unix epoch. The X-Nuts-Signature header contains "sha256=" followed by
+	// the hex encoded HMAC-SHA256 of the timestamp, a "." and the body, keyed with the secret. Receivers reject requests of
+	// which the timestamp differs more than 5 minutes from their clock.
+	Callback *SignSessionCallback `json:"callback,omitempty"`
+
+	// Identifier of the legalEntity as registered in the Nuts registry.
+	LegalEntity LegalEntity `json:"legalEntity"`
+	Means       string      `json:"means"`
 
 	// Params are passed to the means. Should be documented in the means documentation.
 	Params map[string]interface{} `json:"params"`
@@ -68,6 +111,12 @@ type DrawUpContractRequest struct {
 	// Identifier of the legalEntity as registered in the Nuts registry.
 	LegalEntity LegalEntity `json:"legalEntity"`
 
+	// HL7 PurposeOfUse code of the reason why the records of the subject are accessed. Required for patient context contracts like PractitionerPatientAccess. Supported codes are: 'TREAT', 'ETREAT', 'COC', 'PATRQT'
+	PurposeOfUse *string `json:"purposeOfUse,omitempty"`
+
+	// Identifier of the patient whose records are accessed. Required for patient context contracts like PractitionerPatientAccess.
+	Subject *string `json:"subject,omitempty"`
+
 	// Type of which contract to sign.
 	Type ContractType `json:"type"`
 
@@ -84,8 +133,12 @@ type DrawUpContractRequest struct {
 // GetSignSessionStatusResponse defines model for GetSignSessionStatusResponse.
 type GetSignSessionStatusResponse struct {
 
-	// Status indicates the status of the signing proces. Values depend on the implementation of the signing means.
-	Status string `json:"status"`
+	// Status of the signing process as used by the signing means, please consult the documentation of the means for its values.
+	NativeStatus string `json:"nativeStatus"`
+
+	// Status of the signing process, it is the same for every signing means. A session starts as created, is in-progress
+	// while the user is signing and ends as completed (signed), cancelled, expired or failed.
+	Status SignSessionStatus `json:"status"`
 
 	// If the signature session is completed, this property contains the signature embedded in an w3c verifiable presentation.
 	VerifiablePresentation *VerifiablePresentation `json:"verifiablePresentation,omitempty"`
@@ -94,6 +147,29 @@ type GetSignSessionStatusResponse struct {
 // LegalEntity defines model for LegalEntity.
 type LegalEntity string
 
+// SignSessionCallback defines model for SignSessionCallback.
+type SignSessionCallback struct {
+
+	// Shared secret used to sign the callback request.
+	Secret string `json:"secret"`
+
+	// Absolute http or https URL of the webhook.
+	Url string `json:"url"`
+}
+
+// SignSessionStatus defines model for SignSessionStatus.
+type SignSessionStatus string
+
+// List of SignSessionStatus
+const (
+	SignSessionStatus_cancelled   SignSessionStatus = "cancelled"
+	SignSessionStatus_completed   SignSessionStatus = "completed"
+	SignSessionStatus_created     SignSessionStatus = "created"
+	SignSessionStatus_expired     SignSessionStatus = "expired"
+	SignSessionStatus_failed      SignSessionStatus = "failed"
+	SignSessionStatus_in_progress SignSessionStatus = "in-progress"
+)
+
 // SignatureVerificationRequest defines model for SignatureVerificationRequest.
 type SignatureVerificationRequest struct {
 
@@ -102,17 +178,26 @@ type SignatureVerificationRequest struct {
 
 	// Moment in time to check the validity of the signature. If omitted, the current time is used.
 	CheckTime *string `json:"checkTime,omitempty"`
+
+	// Hex encoded SHA-256 hash over the template identifier and the normalized contract text. When given in a verification request, the signature is only valid for a contract with this hash.
+	ContractHash *ContractHash `json:"contractHash,omitempty"`
 }
 
 // SignatureVerificationResponse defines model for SignatureVerificationResponse.
 type SignatureVerificationResponse struct {
 
+	// Hex encoded SHA-256 hash over the template identifier and the normalized contract text. When given in a verification request, the signature is only valid for a contract with this hash.
+	ContractHash *ContractHash `json:"contractHash,omitempty"`
+
 	// Key value pairs containing claims and their values.
 	Credentials *map[string]interface{} `json:"credentials,omitempty"`
 
 	// Key vale pairs containing the attributes of the issuer.
 	IssuerAttributes *map[string]interface{} `json:"issuerAttributes,omitempty"`
 
+	// Explains why the signature is invalid, if known.
+	Reason *string `json:"reason,omitempty"`
+
 	// Indicates the validity of the signature.
 	Validity bool `json:"validity"`
 
@@ -133,6 +218,16 @@ type DrawUpContractJSONBody DrawUpContractRequest
 // CreateSignSessionJSONBody defines parameters for CreateSignSession.
 type CreateSignSessionJSONBody CreateSignSessionRequest
 
+// GetSignSessionQrCodeParams defines parameters for GetSignSessionQrCode.
+type GetSignSessionQrCodeParams struct {
+
+	// Image format of the QR code, defaults to png.
+	Format *string `json:"format,omitempty"`
+
+	// Width and height of the image in pixels, defaults to 256. A PNG image is rounded down to a whole number of pixels per module.
+	Size *int `json:"size,omitempty"`
+}
+
 // VerifySignatureJSONBody defines parameters for VerifySignature.
 type VerifySignatureJSONBody SignatureVerificationRequest
 
@@ -153,9 +248,21 @@ type ServerInterface interface {
 	// Create a signing session for a supported means.
 	// (POST /internal/auth/experimental/signature/session)
 	CreateSignSession(ctx echo.Context) error
+	// Cancel a signing session. Later status requests return the cancelled status.
+	// (DELETE /internal/auth/experimental/signature/session/{sessionID})
+	CancelSignSession(ctx echo.Context, sessionID string) error
 	// Get the current status of a signing session
 	// (GET /internal/auth/experimental/signature/session/{sessionID})
 	GetSignSessionStatus(ctx echo.Context, sessionID string) error
+	// Get the delivery log of the callback of a signing session.
+	// (GET /internal/auth/experimental/signature/session/{sessionID}/callback)
+	GetSignSessionCallbackDeliveries(ctx echo.Context, sessionID string) error
+	// Stream the status transitions of a signing session as server-sent events.
+	// (GET /internal/auth/experimental/signature/session/{sessionID}/events)
+	GetSignSessionEvents(ctx echo.Context, sessionID string) error
+	// Render the session pointer of a signing session as QR code image.
+	// (GET /internal/auth/experimental/signature/session/{sessionID}/qr)
+	GetSignSessionQrCode(ctx echo.Context, sessionID string, params GetSignSessionQrCodeParams) error
 	// Verify a signature in the form of a verifiable presentation
 	// (PUT /internal/auth/experimental/signature/verify)
 	VerifySignature(ctx echo.Context) error
@@ -184,6 +291,22 @@ func (w *ServerInterfaceWrapper) CreateSignSession(ctx echo.Context) error {
 	return err
 }
 
+// CancelSignSession converts echo context to params.
+func (w *ServerInterfaceWrapper) CancelSignSession(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "sessionID" -------------
+	var sessionID string
+
+	err = runtime.BindStyledParameter("simple", false, "sessionID", ctx.Param("sessionID"), &sessionID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sessionID: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.CancelSignSession(ctx, sessionID)
+	return err
+}
+
 // GetSignSessionStatus converts echo context to params.
 func (w *ServerInterfaceWrapper) GetSignSessionStatus(ctx echo.Context) error {
 	var err error
@@ -200,6 +323,70 @@ func (w *ServerInterfaceWrapper) GetSignSessionStatus(ctx echo.Context) error {
 	return err
 }
 
+// GetSignSessionCallbackDeliveries converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSignSessionCallbackDeliveries(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "sessionID" -------------
+	var sessionID string
+
+	err = runtime.BindStyledParameter("simple", false, "sessionID", ctx.Param("sessionID"), &sessionID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sessionID: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetSignSessionCallbackDeliveries(ctx, sessionID)
+	return err
+}
+
+// GetSignSessionEvents converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSignSessionEvents(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "sessionID" -------------
+	var sessionID string
+
+	err = runtime.BindStyledParameter("simple", false, "sessionID", ctx.Param("sessionID"), &sessionID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sessionID: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetSignSessionEvents(ctx, sessionID)
+	return err
+}
+
+// GetSignSessionQrCode converts echo context to params.
+func (w *ServerInterfaceWrapper) GetSignSessionQrCode(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "sessionID" -------------
+	var sessionID string
+
+	err = runtime.BindStyledParameter("simple", false, "sessionID", ctx.Param("sessionID"), &sessionID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter sessionID: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params GetSignSessionQrCodeParams
+	// ------------- Optional query parameter "format" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "format", ctx.QueryParams(), &params.Format)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter format: %s", err))
+	}
+
+	// ------------- Optional query parameter "size" -------------
+
+	err = runtime.BindQueryParameter("form", true, false, "size", ctx.QueryParams(), &params.Size)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter size: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetSignSessionQrCode(ctx, sessionID, params)
+	return err
+}
+
 // VerifySignature converts echo context to params.
 func (w *ServerInterfaceWrapper) VerifySignature(ctx echo.Context) error {
 	var err error
@@ -228,7 +415,11 @@ func RegisterHandlers(router interface {
 
 	router.PUT("/internal/auth/experimental/contract/drawup", wrapper.DrawUpContract)
 	router.POST("/internal/auth/experimental/signature/session", wrapper.CreateSignSession)
+	router.DELETE("/internal/auth/experimental/signature/session/:sessionID", wrapper.CancelSignSession)
 	router.GET("/internal/auth/experimental/signature/session/:sessionID", wrapper.GetSignSessionStatus)
+	router.GET("/internal/auth/experimental/signature/session/:sessionID/callback", wrapper.GetSignSessionCallbackDeliveries)
+	router.GET("/internal/auth/experimental/signature/session/:sessionID/events", wrapper.GetSignSessionEvents)
+	router.GET("/internal/auth/experimental/signature/session/:sessionID/qr", wrapper.GetSignSessionQrCode)
 	router.PUT("/internal/auth/experimental/signature/verify", wrapper.VerifySignature)
 
 }
@@ -19,6 +19,7 @@
 package v0
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -28,7 +29,9 @@ import (
 	"time"
 
 	"github.com/nuts-foundation/nuts-auth/logging"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/expiring"
 	"github.com/nuts-foundation/nuts-auth/pkg/services/irma"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/ratelimit"
 	"github.com/nuts-foundation/nuts-auth/pkg/services/validator"
 	nutsRegistry "github.com/nuts-foundation/nuts-registry/pkg"
 
@@ -48,11 +51,18 @@ import (
 // This wrapper handles the unversioned, so called v0, API requests. Most of them wil be deprecated and moved to a v1 version
 type Wrapper struct {
 	Auth pkg.AuthClient
+	// TrustClientCertHeader identifies clients by the ratelimit.ClientCertHeader set by a TLS terminating proxy
+	TrustClientCertHeader bool
+	// OAuthIssuer is the public URL of the OAuth endpoints, the authorization server metadata is not published when empty
+	OAuthIssuer string
 }
 
 const errOauthInvalidRequest = "invalid_request"
 const errOauthInvalidGrant = "invalid_grant"
 const errOauthUnsupportedGrant = "unsupported_grant_type"
+const errOauthInvalidClient = "invalid_client"
+const errOauthUnauthorizedClient = "unauthorized_client"
+const errOauthTemporarilyUnavailable = "temporarily_unavailable"
 
 // CreateSession translates http params to internal format, creates a IRMA signing session
 // and returns the session pointer to the HTTP stack.
@@ -73,11 +83,11 @@ func (api *Wrapper) CreateSession(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid value for param legalEntity: '%s', make sure its in the form 'urn:oid:1.2.3.4:foo'", params.LegalEntity))
 	}
 
-	template := contract.StandardContractTemplates.Get(contract.Type(params.Type), contract.Language(params.Language), contract.Version(params.Version))
+	template := api.Auth.ContractTemplates().Get(contract.Type(params.Type), contract.Language(params.Language), contract.Version(params.Version))
 	if template == nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unable to find contract: %s", params.Type))
 	}
-	drawnUpContract, err := api.Auth.ContractNotary().DrawUpContract(*template, orgID, validFrom, validDuration)
+	drawnUpContract, err := api.Auth.ContractNotary().DrawUpContract(*template, orgID, validFrom, validDuration, nil)
 	if err != nil {
 		if errors.Is(err, validator.ErrMissingOrganizationKey) {
 			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unknown legalEntity, this Nuts node does not seem to be managing '%s'", orgID))
@@ -88,10 +98,19 @@ func (api *Wrapper) CreateSession(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Unable to draw up contract: %s", err.Error()))
 	}
 
-	sessionRequest := services.CreateSessionRequest{SigningMeans: "irma", Message: drawnUpContract.RawContractText}
+	sessionRequest := services.CreateSessionRequest{
+		SigningMeans: "irma",
+		Message:      drawnUpContract.RawContractText,
+		ClientID:     ratelimit.ClientID(ctx.Request(), api.TrustClientCertHeader),
+	}
 	// Initiate the actual session
 	result, err := api.Auth.ContractClient().CreateSigningSession(sessionRequest)
 	if err != nil {
+		var rateLimitErr services.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			ctx.Response().Header().Set("Retry-After", ratelimit.RetryAfter(rateLimitErr.RetryAfter))
+			return echo.NewHTTPError(http.StatusTooManyRequests, err.Error())
+		}
 		logging.Log().WithError(err).Error("error while creating contract session")
 		return err
 	}
@@ -233,7 +252,7 @@ func (api *Wrapper) GetContractByType(ctx echo.Context, contractType string, par
 	}
 
 	// get contract
-	authContract := contract.StandardContractTemplates.Get(contract.Type(contractType), contractLanguage, contractVersion)
+	authContract := api.Auth.ContractTemplates().Get(contract.Type(contractType), contractLanguage, contractVersion)
 	if authContract == nil {
 		return echo.NewHTTPError(http.StatusNotFound, "could not found contract template")
 	}
@@ -254,23 +273,32 @@ func (api *Wrapper) GetContractByType(ctx echo.Context, contractType string, par
 // It consumes and checks the JWT and returns a smaller sessionToken
 func (api *Wrapper) CreateAccessToken(ctx echo.Context, params CreateAccessTokenParams) (err error) {
 	// Can't use echo.Bind() here since it requires extra tags on generated code
-	request := new(CreateAccessTokenRequest)
-	request.Assertion = ctx.FormValue("assertion")
-	request.GrantType = ctx.FormValue("grant_type")
-
-	if request.GrantType != pkg.JwtBearerGrantType {
-		errDesc := fmt.Sprintf("grant_type must be: '%s'", pkg.JwtBearerGrantType)
+	grantType := ctx.FormValue("grant_type")
+
+	// the assertion is a jwt, the refresh token is an opaque handle
+	var rawToken string
+	switch grantType {
+	case pkg.JwtBearerGrantType:
+		rawToken = ctx.FormValue("assertion")
+		const jwtPattern = `^[A-Za-z0-9-_=]+\.[A-Za-z0-9-_=]+\.?[A-Za-z0-9-_.+/=]*$`
+		if matched, err := regexp.Match(jwtPattern, []byte(rawToken)); !matched || err != nil {
+			errDesc := "Assertion must be a valid encoded jwt"
+			errorResponse := AccessTokenRequestFailedResponse{Error: errOauthInvalidGrant, ErrorDescription: errDesc}
+			return ctx.JSON(http.StatusBadRequest, errorResponse)
+		}
+	case pkg.RefreshTokenGrantType:
+		rawToken = ctx.FormValue("refresh_token")
+		if rawToken == "" {
+			errDesc := "Refresh token is missing"
+			errorResponse := AccessTokenRequestFailedResponse{Error: errOauthInvalidRequest, ErrorDescription: errDesc}
+			return ctx.JSON(http.StatusBadRequest, errorResponse)
+		}
+	default:
+		errDesc := fmt.Sprintf("grant_type must be: '%s' or '%s'", pkg.JwtBearerGrantType, pkg.RefreshTokenGrantType)
 		errorResponse := AccessTokenRequestFailedResponse{Error: errOauthUnsupportedGrant, ErrorDescription: errDesc}
 		return ctx.JSON(http.StatusBadRequest, errorResponse)
 	}
 
-	const jwtPattern = `^[A-Za-z0-9-_=]+\.[A-Za-z0-9-_=]+\.?[A-Za-z0-9-_.+/=]*$`
-	if matched, err := regexp.Match(jwtPattern, []byte(request.Assertion)); !matched || err != nil {
-		errDesc := "Assertion must be a valid encoded jwt"
-		errorResponse := AccessTokenRequestFailedResponse{Error: errOauthInvalidGrant, ErrorDescription: errDesc}
-		return ctx.JSON(http.StatusBadRequest, errorResponse)
-	}
-
 	if params.XSslClientCert == "" {
 		errDesc := "Client certificate missing in header"
 		errorResponse := AccessTokenRequestFailedResponse{Error: errOauthInvalidRequest, ErrorDescription: errDesc}
@@ -284,14 +312,27 @@ func (api *Wrapper) CreateAccessToken(ctx echo.Context, params CreateAccessToken
 		return ctx.JSON(http.StatusBadRequest, errorResponse)
 	}
 
-	catRequest := services.CreateAccessTokenRequest{RawJwtBearerToken: request.Assertion, VendorIdentifier: params.XNutsLegalEntity, ClientCert: cert}
-	acResponse, err := api.Auth.OAuthClient().CreateAccessToken(catRequest)
+	var acResponse *services.AccessTokenResult
+	if grantType == pkg.RefreshTokenGrantType {
+		ratRequest := services.RefreshAccessTokenRequest{RawRefreshToken: rawToken, ClientCert: cert}
+		acResponse, err = api.Auth.OAuthClient().RefreshAccessToken(ratRequest)
+	} else {
+		catRequest := services.CreateAccessTokenRequest{RawJwtBearerToken: rawToken, VendorIdentifier: params.XNutsLegalEntity, ClientCert: cert}
+		acResponse, err = api.Auth.OAuthClient().CreateAccessToken(catRequest)
+	}
 	if err != nil {
 		errDesc := err.Error()
 		errorResponse := AccessTokenRequestFailedResponse{Error: errOauthInvalidRequest, ErrorDescription: errDesc}
+		if errors.Is(err, services.ErrInvalidAudience) || errors.Is(err, services.ErrReplayedToken) ||
+			errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrTokenRevoked) {
+			errorResponse.Error = errOauthInvalidGrant
+		}
 		return ctx.JSON(http.StatusBadRequest, errorResponse)
 	}
 	response := AccessTokenResponse{AccessToken: acResponse.AccessToken}
+	if acResponse.RefreshToken != "" {
+		response.RefreshToken = &acResponse.RefreshToken
+	}
 
 	return ctx.JSON(http.StatusOK, response)
 }
@@ -382,3 +423,115 @@ func (api *Wrapper) VerifyAccessToken(ctx echo.Context, params VerifyAccessToken
 
 	return ctx.NoContent(200)
 }
+
+// RevokeAccessToken takes the access token from the request form value and passes it to the auth client to be revoked
+// for the client certificate of the request.
+func (api *Wrapper) RevokeAccessToken(ctx echo.Context) error {
+	token := ctx.FormValue("token")
+	if len(token) == 0 {
+		errorResponse := AccessTokenRequestFailedResponse{Error: errOauthInvalidRequest, ErrorDescription: "token is missing"}
+		return ctx.JSON(http.StatusBadRequest, errorResponse)
+	}
+
+	request := services.RevokeTokenRequest{Token: token, ClientCert: ratelimit.ClientCertificatePEM(ctx.Request(), api.TrustClientCertHeader)}
+	if err := api.Auth.OAuthClient().RevokeAccessToken(request); err != nil {
+		errorResponse := AccessTokenRequestFailedResponse{Error: errOauthInvalidRequest, ErrorDescription: err.Error()}
+		switch {
+		case errors.Is(err, services.ErrInvalidClient):
+			errorResponse.Error = errOauthInvalidClient
+			return ctx.JSON(http.StatusUnauthorized, errorResponse)
+		case errors.Is(err, services.ErrUnauthorizedClient):
+			errorResponse.Error = errOauthUnauthorizedClient
+			return ctx.JSON(http.StatusForbidden, errorResponse)
+		case errors.Is(err, expiring.ErrFull):
+			errorResponse.Error = errOauthTemporarilyUnavailable
+			return ctx.JSON(http.StatusServiceUnavailable, errorResponse)
+		}
+		return ctx.JSON(http.StatusBadRequest, errorResponse)
+	}
+
+	return ctx.NoContent(http.StatusOK)
+}
+
+// RevokeContract revokes all access tokens derived from the signed contract in the request body, for the client
+// certificate of the request.
+func (api *Wrapper) RevokeContract(ctx echo.Context) error {
+	request := &ContractRevocationRequest{}
+	if err := ctx.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Could not parse request body: %s", err))
+	}
+	if request.ContractHash == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "contract_hash is missing")
+	}
+
+	revokeRequest := services.RevokeContractRequest{ContractHash: request.ContractHash, ClientCert: ratelimit.ClientCertificatePEM(ctx.Request(), api.TrustClientCertHeader)}
+	if err := api.Auth.OAuthClient().RevokeContract(revokeRequest); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidClient):
+			return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+		case errors.Is(err, services.ErrUnauthorizedClient):
+			return echo.NewHTTPError(http.StatusForbidden, err.Error())
+		case errors.Is(err, services.ErrUnknownContract):
+			return echo.NewHTTPError(http.StatusNotFound, err.Error())
+		case errors.Is(err, expiring.ErrFull):
+			return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.NoContent(http.StatusOK)
+}
+
+// GetAuthorizationServerMetadata returns the RFC8414 metadata of the authorization server of this node.
+// The issuer and the endpoint URLs are derived from the configured OAuthIssuer, never from the request, since the
+// Host header is chosen by the client. Without OAuthIssuer the metadata is not published.
+func (api *Wrapper) GetAuthorizationServerMetadata(ctx echo.Context) error {
+	if api.OAuthIssuer == "" {
+		return echo.NewHTTPError(http.StatusNotFound, "authorization server metadata is not published, the OAuth issuer is not configured")
+	}
+	signingKey, err := api.Auth.OAuthClient().SigningKey()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	baseURL := strings.TrimSuffix(api.OAuthIssuer, "/")
+	introspectionEndpoint := baseURL + "/auth/token_introspection"
+	revocationEndpoint := baseURL + "/auth/token/revoke"
+	grantTypes := []string{pkg.JwtBearerGrantType}
+	if api.Auth.OAuthClient().RefreshTokensEnabled() {
+		grantTypes = append(grantTypes, pkg.RefreshTokenGrantType)
+	}
+	authMethods := []string{"tls_client_auth"}
+	algorithms := []string{signingKey.Algorithm()}
+
+	return ctx.JSON(http.StatusOK, AuthorizationServerMetadata{
+		Issuer:                               baseURL,
+		TokenEndpoint:                        baseURL + "/auth/accesstoken",
+		IntrospectionEndpoint:                &introspectionEndpoint,
+		RevocationEndpoint:                   &revocationEndpoint,
+		JwksUri:                              baseURL + "/.well-known/jwks.json",
+		GrantTypesSupported:                  grantTypes,
+		TokenEndpointAuthMethodsSupported:    &authMethods,
+		AccessTokenSigningAlgValuesSupported: &algorithms,
+	})
+}
+
+// GetJWKS returns the public key the access tokens of this node are signed with as JSON Web Key Set.
+func (api *Wrapper) GetJWKS(ctx echo.Context) error {
+	signingKey, err := api.Auth.OAuthClient().SigningKey()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// the JWK encodes its members itself
+	var jwk map[string]interface{}
+	data, err := json.Marshal(signingKey)
+	if err == nil {
+		err = json.Unmarshal(data, &jwk)
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(http.StatusOK, JSONWebKeySet{Keys: []map[string]interface{}{jwk}})
+}
@@ -28,10 +28,32 @@ type AccessTokenResponse struct {
 	// The lifetime in seconds of the access token.
 	ExpiresIn float32 `json:"expires_in"`
 
+	// The refresh token which can be used to obtain a new access token with the refresh_token grant.
+	// It is only issued when refresh tokens are enabled and the signed login contract outlives the access token.
+	RefreshToken *string `json:"refresh_token,omitempty"`
+
 	// The type of the token issued
 	TokenType string `json:"token_type"`
 }
 
+// AuthorizationServerMetadata defines model for AuthorizationServerMetadata.
+type AuthorizationServerMetadata struct {
+
+	// The algorithms the access tokens are signed with.
+	AccessTokenSigningAlgValuesSupported *[]string `json:"access_token_signing_alg_values_supported,omitempty"`
+	GrantTypesSupported                  []string  `json:"grant_types_supported"`
+	IntrospectionEndpoint                *string   `json:"introspection_endpoint,omitempty"`
+
+	// The base URL of the authorization server.
+	Issuer             string  `json:"issuer"`
+	JwksUri            string  `json:"jwks_uri"`
+	RevocationEndpoint *string `json:"revocation_endpoint,omitempty"`
+	TokenEndpoint      string  `json:"token_endpoint"`
+
+	// Clients authenticate with a TLS client certificate, as described by RFC8705.
+	TokenEndpointAuthMethodsSupported *[]string `json:"token_endpoint_auth_methods_supported,omitempty"`
+}
+
 // Contract defines model for Contract.
 type Contract struct {
 
@@ -48,6 +70,13 @@ type Contract struct {
 	Version Version `json:"version"`
 }
 
+// ContractRevocationRequest defines model for ContractRevocationRequest.
+type ContractRevocationRequest struct {
+
+	// The hex encoded SHA-256 hash of the signed contract, as recorded in the audit log
+	ContractHash string `json:"contract_hash"`
+}
+
 // ContractSigningRequest defines model for ContractSigningRequest.
 type ContractSigningRequest struct {
 
@@ -73,11 +102,14 @@ type ContractSigningRequest struct {
 // CreateAccessTokenRequest defines model for CreateAccessTokenRequest.
 type CreateAccessTokenRequest struct {
 
-	// Base64 encoded JWT following rfc7523 and the Nuts documentation
-	Assertion string `json:"assertion"`
+	// Base64 encoded JWT following rfc7523 and the Nuts documentation, required for the jwt-bearer grant
+	Assertion *string `json:"assertion,omitempty"`
 
-	// always must contain the value "urn:ietf:params:oauth:grant-type:jwt-bearer"
+	// either "urn:ietf:params:oauth:grant-type:jwt-bearer" or "refresh_token"
 	GrantType string `json:"grant_type"`
+
+	// The refresh token issued with an earlier access token, required for the refresh_token grant
+	RefreshToken *string `json:"refresh_token,omitempty"`
 }
 
 // CreateJwtBearerTokenRequest defines model for CreateJwtBearerTokenRequest.
@@ -128,6 +160,11 @@ type IrmaQR struct {
 	U string `json:"u"`
 }
 
+// JSONWebKeySet defines model for JSONWebKeySet.
+type JSONWebKeySet struct {
+	Keys []map[string]interface{} `json:"keys"`
+}
+
 // JwtBearerTokenResponse defines model for JwtBearerTokenResponse.
 type JwtBearerTokenResponse struct {
 	BearerToken string `json:"bearer_token"`
@@ -262,6 +299,16 @@ type TokenIntrospectionResponse struct {
 	Usi *string `json:"usi,omitempty"`
 }
 
+// TokenRevocationRequest defines model for TokenRevocationRequest.
+type TokenRevocationRequest struct {
+
+	// The access or refresh token to revoke
+	Token string `json:"token"`
+
+	// Access and refresh tokens are told apart by their format, the hint is ignored
+	TokenTypeHint *string `json:"token_type_hint,omitempty"`
+}
+
 // Type defines model for Type.
 type Type string
 
@@ -319,6 +366,9 @@ type GetContractByTypeParams struct {
 // CreateJwtBearerTokenJSONBody defines parameters for CreateJwtBearerToken.
 type CreateJwtBearerTokenJSONBody CreateJwtBearerTokenRequest
 
+// RevokeContractJSONBody defines parameters for RevokeContract.
+type RevokeContractJSONBody ContractRevocationRequest
+
 // CreateAccessTokenRequestBody defines body for CreateAccessToken for application/json ContentType.
 type CreateAccessTokenJSONRequestBody CreateAccessTokenJSONBody
 
@@ -331,12 +381,25 @@ type ValidateContractJSONRequestBody ValidateContractJSONBody
 // CreateJwtBearerTokenRequestBody defines body for CreateJwtBearerToken for application/json ContentType.
 type CreateJwtBearerTokenJSONRequestBody CreateJwtBearerTokenJSONBody
 
+// RevokeContractRequestBody defines body for RevokeContract for application/json ContentType.
+type RevokeContractJSONRequestBody RevokeContractJSONBody
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// The JSON Web Key Set with the public key the access tokens of this node are signed with, identified by the kid header of the access tokens.
+	// Resource servers can use it to validate access tokens without calling the introspection endpoint.
+	// (GET /.well-known/jwks.json)
+	GetJWKS(ctx echo.Context) error
+	// Authorization server metadata as described by RFC8414. It advertises the OAuth endpoints of this node, the supported grants
+	// and the algorithms the access tokens are signed with. The URLs are derived from the configured oauthIssuer.
+	// (GET /.well-known/oauth-authorization-server)
+	GetAuthorizationServerMetadata(ctx echo.Context) error
 	// Create an access token based on the OAuth JWT Bearer flow.
 	// This endpoint must be available to the outside world for other applications to request access tokens.
 	// It requires a two-way TLS connection. The client certificate must be a sibling of the signing certificate of the given JWT.
 	// The client certificate must be passed using a X-Ssl-Client-Cert header, PEM encoded and urlescaped.
+	// When refresh tokens are enabled, a refresh token is issued with the access token. A new access token can be requested
+	// with the refresh_token grant, using the same client certificate, for as long as the signed login contract is valid.
 	// (POST /auth/accesstoken)
 	CreateAccessToken(ctx echo.Context, params CreateAccessTokenParams) error
 	// Verifies the access token given in the Authorization header (as bearer token). If it's a valid access token issued by this server, it'll return a 200 status code.
@@ -358,6 +421,17 @@ type ServerInterface interface {
 	// Create a JWT Bearer Token which can be used in the createAccessToken request in the assertion field
 	// (POST /auth/jwtbearertoken)
 	CreateJwtBearerToken(ctx echo.Context) error
+	// Revocation endpoint as described by RFC7009. The revoked access token is rejected by the introspection and verification endpoints until it expires.
+	// A revoked refresh token is removed from the node. Invalid and expired tokens are ignored.
+	// The client must authenticate with its TLS client certificate: an access token can only be revoked with a certificate of the vendor of its actor,
+	// a refresh token only with the certificate it is bound to.
+	// (POST /auth/token/revoke)
+	RevokeAccessToken(ctx echo.Context) error
+	// Revokes all access tokens derived from the signed contract with the given hash, for example when the contract is abused.
+	// No new access tokens are issued for the contract until it expires. Only contracts this node issued access tokens for can be revoked,
+	// by a TLS client certificate of the vendor of the actor which used the contract.
+	// (POST /auth/token/revoke/contract)
+	RevokeContract(ctx echo.Context) error
 	// Introspection endpoint to retrieve information from an Access Token as described by RFC7662
 	// (POST /auth/token_introspection)
 	IntrospectAccessToken(ctx echo.Context) error
@@ -368,6 +442,24 @@ type ServerInterfaceWrapper struct {
 	Handler ServerInterface
 }
 
+// GetJWKS converts echo context to params.
+func (w *ServerInterfaceWrapper) GetJWKS(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetJWKS(ctx)
+	return err
+}
+
+// GetAuthorizationServerMetadata converts echo context to params.
+func (w *ServerInterfaceWrapper) GetAuthorizationServerMetadata(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.GetAuthorizationServerMetadata(ctx)
+	return err
+}
+
 // CreateAccessToken converts echo context to params.
 func (w *ServerInterfaceWrapper) CreateAccessToken(ctx echo.Context) error {
 	var err error
@@ -520,6 +612,24 @@ func (w *ServerInterfaceWrapper) CreateJwtBearerToken(ctx echo.Context) error {
 	return err
 }
 
+// RevokeAccessToken converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeAccessToken(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RevokeAccessToken(ctx)
+	return err
+}
+
+// RevokeContract converts echo context to params.
+func (w *ServerInterfaceWrapper) RevokeContract(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshalled arguments
+	err = w.Handler.RevokeContract(ctx)
+	return err
+}
+
 // IntrospectAccessToken converts echo context to params.
 func (w *ServerInterfaceWrapper) IntrospectAccessToken(ctx echo.Context) error {
 	var err error
@@ -546,6 +656,8 @@ func RegisterHandlers(router interface {
 		Handler: si,
 	}
 
+	router.GET("/.well-known/jwks.json", wrapper.GetJWKS)
+	router.GET("/.well-known/oauth-authorization-server", wrapper.GetAuthorizationServerMetadata)
 	router.POST("/auth/accesstoken", wrapper.CreateAccessToken)
 	router.HEAD("/auth/accesstoken/verify", wrapper.VerifyAccessToken)
 	router.POST("/auth/contract/session", wrapper.CreateSession)
@@ -553,6 +665,8 @@ func RegisterHandlers(router interface {
 	router.POST("/auth/contract/validate", wrapper.ValidateContract)
 	router.GET("/auth/contract/:contractType", wrapper.GetContractByType)
 	router.POST("/auth/jwtbearertoken", wrapper.CreateJwtBearerToken)
+	router.POST("/auth/token/revoke", wrapper.RevokeAccessToken)
+	router.POST("/auth/token/revoke/contract", wrapper.RevokeContract)
 	router.POST("/auth/token_introspection", wrapper.IntrospectAccessToken)
 
 }
@@ -0,0 +1,191 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/nuts-foundation/nuts-auth/pkg"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+)
+
+var _ ServerInterface = (*Wrapper)(nil)
+
+// Wrapper bridges the generated api types and http logic to the internal types and logic.
+// It checks required parameters and message body. It converts data from api to internal types.
+// Then passes the internal formats to the AuthClient. Converts internal results back to the generated
+// Api types. Handles errors and returns the correct http response. It does not perform any business logic.
+//
+// This wrapper handles the v1 API requests.
+type Wrapper struct {
+	Auth pkg.AuthClient
+}
+
+// ListContractTemplates handles the http request for listing all contract templates known to this node.
+func (w Wrapper) ListContractTemplates(ctx echo.Context) error {
+	response := []ContractTemplate{}
+	for _, template := range w.Auth.ContractTemplates().List() {
+		response = append(response, convertTemplate(template))
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// GetContractTemplate handles the http request for a single contract template identified by type, language and version.
+func (w Wrapper) GetContractTemplate(ctx echo.Context, pType ContractType, language ContractLanguage, version ContractVersion) error {
+	template := w.Auth.ContractTemplates().Get(contract.Type(pType), contract.Language(language), contract.Version(version))
+	if template == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "no contract template found for given combination of type, language and version")
+	}
+	return ctx.JSON(http.StatusOK, convertTemplate(template))
+}
+
+// ValidateContractTemplate handles the http request for validating a candidate contract template.
+// The template is drawn up with sample values which are parsed back from the resulting contract text.
+func (w Wrapper) ValidateContractTemplate(ctx echo.Context) error {
+	params := new(ContractTemplateDefinition)
+	if err := ctx.Bind(params); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("could not parse request body: %s", err.Error()))
+	}
+
+	candidate := contract.Template{
+		Type:             contract.Type(params.Type),
+		Language:         contract.Language(params.Language),
+		Version:          contract.Version(params.Version),
+		Template:         params.Template,
+		SignerAttributes: contract.StandardSignerAttributes,
+	}
+	if params.SignerAttributes != nil {
+		candidate.SignerAttributes = *params.SignerAttributes
+	}
+	if params.TimeZone != nil {
+		candidate.TimeZone = *params.TimeZone
+	}
+	candidate.DeprecatedFrom = params.DeprecatedFrom
+	candidate.SunsetAt = params.SunsetAt
+	if params.Attributes != nil {
+		candidate.Attributes = make(map[string]contract.Attribute, len(*params.Attributes))
+		for _, attribute := range *params.Attributes {
+			candidate.Attributes[attribute.Name] = convertAttributeFromAPI(attribute)
+		}
+	}
+
+	template, sample, err := contract.ValidateTemplate(candidate)
+	if err != nil {
+		reason := err.Error()
+		return ctx.JSON(http.StatusOK, ContractTemplateValidationResponse{Valid: false, Reason: &reason})
+	}
+
+	validated := convertTemplate(template)
+	response := ContractTemplateValidationResponse{
+		Valid:    true,
+		Message:  &sample.RawContractText,
+		Template: &validated,
+	}
+	return ctx.JSON(http.StatusOK, response)
+}
+
+// convertTemplate converts an internal contract template to the api format
+func convertTemplate(template *contract.Template) ContractTemplate {
+	timeZone := template.TimeZone
+	if timeZone == "" {
+		timeZone = contract.AmsterdamTimeZone
+	}
+	response := ContractTemplate{
+		Type:               ContractType(template.Type),
+		Language:           ContractLanguage(template.Language),
+		Version:            ContractVersion(template.Version),
+		Template:           template.Template,
+		TemplateAttributes: template.TemplateAttributes,
+		SignerAttributes:   template.SignerAttributes,
+		TimeZone:           timeZone,
+		DeprecatedFrom:     template.DeprecatedFrom,
+		SunsetAt:           template.SunsetAt,
+	}
+	if len(template.Attributes) > 0 {
+		names := make([]string, 0, len(template.Attributes))
+		for name := range template.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		attributes := make([]ContractTemplateAttribute, 0, len(names))
+		for _, name := range names {
+			attributes = append(attributes, convertAttributeToAPI(name, template.Attributes[name]))
+		}
+		response.Attributes = &attributes
+	}
+	return response
+}
+
+// ConvertTemplate converts a contract template in the api format to the internal format. It is used by clients of the API.
+func ConvertTemplate(template ContractTemplate) *contract.Template {
+	result := &contract.Template{
+		Type:             contract.Type(template.Type),
+		Language:         contract.Language(template.Language),
+		Version:          contract.Version(template.Version),
+		Template:         template.Template,
+		SignerAttributes: template.SignerAttributes,
+		TimeZone:         template.TimeZone,
+		DeprecatedFrom:   template.DeprecatedFrom,
+		SunsetAt:         template.SunsetAt,
+	}
+	if template.Attributes != nil {
+		result.Attributes = make(map[string]contract.Attribute, len(*template.Attributes))
+		for _, attribute := range *template.Attributes {
+			result.Attributes[attribute.Name] = convertAttributeFromAPI(attribute)
+		}
+	}
+	return result
+}
+
+// convertAttributeFromAPI converts an attribute declaration in the api format to the internal format
+func convertAttributeFromAPI(attribute ContractTemplateAttribute) contract.Attribute {
+	result := contract.Attribute{Type: contract.AttributeType(attribute.Type)}
+	if attribute.Values != nil {
+		result.Values = *attribute.Values
+	}
+	if attribute.MaxLength != nil {
+		result.MaxLength = *attribute.MaxLength
+	}
+	if attribute.Escaping != nil {
+		result.Escaping = contract.Escaping(*attribute.Escaping)
+	}
+	return result
+}
+
+// convertAttributeToAPI converts an internal attribute declaration to the api format
+func convertAttributeToAPI(name string, attribute contract.Attribute) ContractTemplateAttribute {
+	result := ContractTemplateAttribute{Name: name, Type: string(attribute.Type)}
+	if len(attribute.Values) > 0 {
+		values := attribute.Values
+		result.Values = &values
+	}
+	if attribute.MaxLength > 0 {
+		maxLength := attribute.MaxLength
+		result.MaxLength = &maxLength
+	}
+	if attribute.Escaping != "" {
+		escaping := string(attribute.Escaping)
+		result.Escaping = &escaping
+	}
+	return result
+}
@@ -0,0 +1,212 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	coreMock "github.com/nuts-foundation/nuts-go-core/mock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nuts-foundation/nuts-auth/mock"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+)
+
+type testContext struct {
+	ctrl     *gomock.Controller
+	echoMock *coreMock.MockContext
+	authMock *mock_auth.MockAuthClient
+	wrapper  Wrapper
+}
+
+func createContext(t *testing.T) *testContext {
+	ctrl := gomock.NewController(t)
+	authMock := mock_auth.NewMockAuthClient(ctrl)
+	authMock.EXPECT().ContractTemplates().AnyTimes().Return(contract.StandardContractTemplates)
+
+	return &testContext{
+		ctrl:     ctrl,
+		echoMock: coreMock.NewMockContext(ctrl),
+		authMock: authMock,
+		wrapper:  Wrapper{Auth: authMock},
+	}
+}
+
+func TestWrapper_ListContractTemplates(t *testing.T) {
+	t.Run("ok - all templates are returned", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		var response []ContractTemplate
+		ctx.echoMock.EXPECT().JSON(http.StatusOK, gomock.Any()).Do(func(_ int, body interface{}) {
+			response = body.([]ContractTemplate)
+		})
+
+		err := ctx.wrapper.ListContractTemplates(ctx.echoMock)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, response, len(contract.StandardContractTemplates.List()))
+		assert.Equal(t, ContractType("BehandelaarLogin"), response[0].Type)
+		assert.Equal(t, ContractVersion("v1"), response[0].Version)
+		assert.Equal(t, contract.AmsterdamTimeZone, response[0].TimeZone)
+	})
+}
+
+func TestWrapper_GetContractTemplate(t *testing.T) {
+	t.Run("ok - known template", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		template := contract.StandardContractTemplates.Get("PractitionerLogin", "EN", "v3")
+		ctx.echoMock.EXPECT().JSON(http.StatusOK, ContractTemplate{
+			Type:               "PractitionerLogin",
+			Language:           "EN",
+			Version:            "v3",
+			Template:           template.Template,
+			TemplateAttributes: []string{contract.LegalEntityAttr, contract.ValidFromAttr, contract.ValidToAttr},
+			SignerAttributes:   contract.StandardSignerAttributes,
+			TimeZone:           contract.AmsterdamTimeZone,
+		})
+
+		err := ctx.wrapper.GetContractTemplate(ctx.echoMock, "PractitionerLogin", "EN", "v3")
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("error - unknown template", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		err := ctx.wrapper.GetContractTemplate(ctx.echoMock, "PractitionerLogin", "EN", "v99")
+
+		if assert.IsType(t, &echo.HTTPError{}, err) {
+			assert.Equal(t, http.StatusNotFound, err.(*echo.HTTPError).Code)
+		}
+	})
+}
+
+func TestWrapper_ValidateContractTemplate(t *testing.T) {
+	bindPostBody := func(ctx *testContext, body ContractTemplateDefinition) {
+		jsonData, _ := json.Marshal(body)
+		ctx.echoMock.EXPECT().Bind(gomock.Any()).Do(func(f interface{}) {
+			_ = json.Unmarshal(jsonData, f)
+		})
+	}
+
+	t.Run("ok - valid template", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		timeZone := "America/Kralendijk"
+		bindPostBody(ctx, ContractTemplateDefinition{
+			Type:     "Opname",
+			Language: "NL",
+			Version:  "v1",
+			Template: "NL:Opname:v1 Namens {{legal_entity}} op {{ward}}, geldig van {{valid_from}} tot {{valid_to}}.",
+			TimeZone: &timeZone,
+			Attributes: &[]ContractTemplateAttribute{
+				{Name: "ward", Type: "enum", Values: &[]string{"Noord", "Zuid"}},
+			},
+		})
+		var response ContractTemplateValidationResponse
+		ctx.echoMock.EXPECT().JSON(http.StatusOK, gomock.Any()).Do(func(_ int, body interface{}) {
+			response = body.(ContractTemplateValidationResponse)
+		})
+
+		err := ctx.wrapper.ValidateContractTemplate(ctx.echoMock)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, response.Valid)
+		assert.Nil(t, response.Reason)
+		if assert.NotNil(t, response.Message) {
+			assert.Contains(t, *response.Message, "NL:Opname:v1 Namens [legal_entity] op Noord, geldig van ")
+		}
+		if assert.NotNil(t, response.Template) {
+			assert.Equal(t, []string{contract.LegalEntityAttr, "ward", contract.ValidFromAttr, contract.ValidToAttr}, response.Template.TemplateAttributes)
+			if assert.NotNil(t, response.Template.Attributes) {
+				assert.Equal(t, []ContractTemplateAttribute{{Name: "ward", Type: "enum", Values: &[]string{"Noord", "Zuid"}}}, *response.Template.Attributes)
+			}
+			assert.Equal(t, contract.StandardSignerAttributes, response.Template.SignerAttributes)
+			assert.Equal(t, timeZone, response.Template.TimeZone)
+		}
+	})
+
+	t.Run("ok - invalid template", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		bindPostBody(ctx, ContractTemplateDefinition{
+			Type:     "Opname",
+			Language: "NL",
+			Version:  "v1",
+			Template: "NL:Opname:v1 Namens {{legal_entity}}",
+		})
+		var response ContractTemplateValidationResponse
+		ctx.echoMock.EXPECT().JSON(http.StatusOK, gomock.Any()).Do(func(_ int, body interface{}) {
+			response = body.(ContractTemplateValidationResponse)
+		})
+
+		err := ctx.wrapper.ValidateContractTemplate(ctx.echoMock)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.False(t, response.Valid)
+		if assert.NotNil(t, response.Reason) {
+			assert.Equal(t, "invalid contract template: template text of NL:Opname:v1 is missing the required attribute 'valid_from'", *response.Reason)
+		}
+		assert.Nil(t, response.Template)
+	})
+
+	t.Run("error - invalid request body", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.echoMock.EXPECT().Bind(gomock.Any()).Return(errors.New("unable to parse body"))
+
+		err := ctx.wrapper.ValidateContractTemplate(ctx.echoMock)
+
+		if assert.IsType(t, &echo.HTTPError{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+		}
+	})
+}
+
+func TestConvertTemplate(t *testing.T) {
+	t.Run("ok - round trip of a template with declared attributes", func(t *testing.T) {
+		template := contract.StandardContractTemplates.Get("PractitionerPatientAccess", "EN", "v1")
+
+		converted := ConvertTemplate(convertTemplate(template))
+
+		assert.Equal(t, template.Identifier(), converted.Identifier())
+		assert.Equal(t, template.Template, converted.Template)
+		assert.Equal(t, template.SignerAttributes, converted.SignerAttributes)
+		assert.Equal(t, template.Attributes[contract.PurposeOfUseAttr], converted.Attributes[contract.PurposeOfUseAttr])
+		assert.Equal(t, template.Attributes[contract.SubjectAttr], converted.Attributes[contract.SubjectAttr])
+	})
+}
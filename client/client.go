@@ -0,0 +1,116 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	apiExperimental "github.com/nuts-foundation/nuts-auth/api/experimental"
+	apiV1 "github.com/nuts-foundation/nuts-auth/api/v1"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+)
+
+// DefaultTimeout is used for requests to the node when the HttpClient has no timeout
+const DefaultTimeout = 10 * time.Second
+
+// HttpClient calls the auth API of a running node. It is used by the CLI in client mode.
+type HttpClient struct {
+	ServerAddress string
+	Timeout       time.Duration
+}
+
+// ContractTemplates retrieves the contract templates which are accepted by the node and compiles them into a TemplateStore,
+// so contracts can be parsed and verified as the node would.
+func (hb HttpClient) ContractTemplates() (contract.TemplateStore, error) {
+	var response []apiV1.ContractTemplate
+	if err := hb.do(http.MethodGet, "/internal/auth/v1/contract/template", nil, &response); err != nil {
+		return nil, fmt.Errorf("could not list contract templates: %w", err)
+	}
+	templates := make([]*contract.Template, 0, len(response))
+	for _, template := range response {
+		templates = append(templates, apiV1.ConvertTemplate(template))
+	}
+	store, err := contract.NewTemplateStore(templates)
+	if err != nil {
+		return nil, fmt.Errorf("could not list contract templates: %w", err)
+	}
+	return store, nil
+}
+
+// DrawUpContract lets the node draw up a contract for the given request.
+func (hb HttpClient) DrawUpContract(request apiExperimental.DrawUpContractRequest) (*apiExperimental.ContractResponse, error) {
+	response := &apiExperimental.ContractResponse{}
+	if err := hb.do(http.MethodPut, "/internal/auth/experimental/contract/drawup", request, response); err != nil {
+		return nil, fmt.Errorf("could not draw up contract: %w", err)
+	}
+	return response, nil
+}
+
+func (hb HttpClient) do(method string, path string, body interface{}, target interface{}) error {
+	timeout := hb.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, hb.url()+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned HTTP %d (expected: %d), response: %s", response.StatusCode, http.StatusOK, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, target)
+}
+
+func (hb HttpClient) url() string {
+	if strings.HasPrefix(hb.ServerAddress, "http://") || strings.HasPrefix(hb.ServerAddress, "https://") {
+		return strings.TrimSuffix(hb.ServerAddress, "/")
+	}
+	return "http://" + strings.TrimSuffix(hb.ServerAddress, "/")
+}
@@ -0,0 +1,73 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nuts-foundation/nuts-auth/pkg"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/audit"
+)
+
+func auditCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "commands to inspect the audit log",
+	}
+	cmd.AddCommand(auditVerifyCmd())
+	return cmd
+}
+
+func auditVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [file]",
+		Short: "Verify the integrity of the audit log",
+		Long: "Verify that no entry of the hash chained audit log was changed, removed or inserted. The file defaults to the configured auditLogPath. " +
+			"It prints the number of entries and the hash of the last entry. Keep this hash elsewhere and pass it with --last-hash at the next verification to also detect the removal of the last entries.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := pkg.AuthInstance().Config.AuditLogPath
+			if len(args) == 1 {
+				path = args[0]
+			}
+			if path == "" {
+				return errors.New("no audit log given and no auditLogPath configured")
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("could not open audit log: %w", err)
+			}
+			defer file.Close()
+
+			lastHash, _ := cmd.Flags().GetString("last-hash")
+			result, err := audit.Verify(file, lastHash)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Entries:   %d\nLast hash: %s\nThe audit log is intact.\n", result.Entries, result.LastHash)
+			return nil
+		},
+	}
+	cmd.Flags().String("last-hash", "", "Hash of the last entry printed by an earlier verification, the log must still contain this entry")
+	return cmd
+}
@@ -0,0 +1,103 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testIo "github.com/nuts-foundation/nuts-go-test/io"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nuts-foundation/nuts-auth/pkg/services/audit"
+)
+
+func executeAuditCmd(args ...string) (string, error) {
+	command := cmd()
+	out := new(bytes.Buffer)
+	command.SetOut(out)
+	command.SetErr(ioutil.Discard)
+	command.SetArgs(append([]string{"audit"}, args...))
+	err := command.Execute()
+	return out.String(), err
+}
+
+func writeAuditLog(t *testing.T) (string, *audit.VerifyResult) {
+	path := filepath.Join(testIo.TestDirectory(t), "audit.log")
+	sink, err := audit.NewFileSink(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_ = sink.Write(audit.Entry{Event: audit.EventSessionCreated, SessionID: "123"})
+	_ = sink.Write(audit.Entry{Event: audit.EventSessionStatus, SessionID: "123", Status: "completed"})
+	_ = sink.Close()
+	data, _ := ioutil.ReadFile(path)
+	result, _ := audit.Verify(bytes.NewReader(data), "")
+	return path, result
+}
+
+func TestAuditVerifyCmd(t *testing.T) {
+	t.Run("ok - intact log", func(t *testing.T) {
+		path, expected := writeAuditLog(t)
+
+		out, err := executeAuditCmd("verify", path)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, out, "Entries:   2")
+		assert.Contains(t, out, expected.LastHash)
+	})
+
+	t.Run("ok - expected last hash", func(t *testing.T) {
+		path, expected := writeAuditLog(t)
+
+		_, err := executeAuditCmd("verify", path, "--last-hash", expected.LastHash)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("error - unknown last hash", func(t *testing.T) {
+		path, _ := writeAuditLog(t)
+
+		_, err := executeAuditCmd("verify", path, "--last-hash", "1234")
+
+		assert.True(t, errors.Is(err, audit.ErrBrokenChain))
+	})
+
+	t.Run("error - changed log", func(t *testing.T) {
+		path, _ := writeAuditLog(t)
+		data, _ := ioutil.ReadFile(path)
+		_ = ioutil.WriteFile(path, []byte(strings.Replace(string(data), "completed", "cancelled", 1)), 0600)
+
+		_, err := executeAuditCmd("verify", path)
+
+		assert.True(t, errors.Is(err, audit.ErrBrokenChain))
+	})
+
+	t.Run("error - unknown file", func(t *testing.T) {
+		_, err := executeAuditCmd("verify", filepath.Join(testIo.TestDirectory(t), "missing.log"))
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,285 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	apiExperimental "github.com/nuts-foundation/nuts-auth/api/experimental"
+	"github.com/nuts-foundation/nuts-auth/client"
+	"github.com/nuts-foundation/nuts-auth/pkg"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+)
+
+const templatesFlag = "templates"
+const localFlag = "local"
+
+// contractClientCreator is a variable to aid testability
+var contractClientCreator = func() client.HttpClient {
+	return client.HttpClient{ServerAddress: pkg.AuthInstance().Config.Address}
+}
+
+func contractCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "contract",
+		Short: "commands to list contract templates and to draw up and parse contracts",
+		Long: "Commands to list contract templates and to draw up and parse contracts. By default the commands use the API of a running node. " +
+			"With --local or --templates the commands run locally against the standard templates and the templates in the given directory.",
+	}
+	cmd.PersistentFlags().String(templatesFlag, "", "Directory with additional contract template definitions, implies --local.")
+	cmd.PersistentFlags().Bool(localFlag, false, "Use the standard contract templates instead of the API of a running node.")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the contract templates",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := contractTemplates(cmd)
+			if err != nil {
+				return err
+			}
+			printTemplates(cmd.OutOrStdout(), store.List(), time.Now())
+			return nil
+		},
+	})
+	cmd.AddCommand(drawUpCmd())
+	cmd.AddCommand(parseCmd())
+
+	return cmd
+}
+
+func drawUpCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drawup",
+		Short: "Draw up a contract from a contract template",
+		Long: "Draw up a contract from a contract template. In client mode the node draws up the contract for the legal entity with the given identifier. " +
+			"In local mode the legal entity is used as organization name, since the registry is not available.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := cmd.Flags()
+			cType, _ := flags.GetString("type")
+			language, _ := flags.GetString("language")
+			version, _ := flags.GetString("version")
+			legalEntity, _ := flags.GetString("legal-entity")
+			validFromStr, _ := flags.GetString("valid-from")
+			duration, _ := flags.GetDuration("duration")
+			subject, _ := flags.GetString("subject")
+			purposeOfUse, _ := flags.GetString("purpose-of-use")
+
+			validFrom := time.Now()
+			if validFromStr != "" {
+				var err error
+				if validFrom, err = time.Parse(time.RFC3339, validFromStr); err != nil {
+					return fmt.Errorf("could not parse valid-from: %w", err)
+				}
+			}
+
+			if !isLocal(cmd) {
+				request := apiExperimental.DrawUpContractRequest{
+					Type:        apiExperimental.ContractType(cType),
+					Language:    apiExperimental.ContractLanguage(language),
+					Version:     apiExperimental.ContractVersion(version),
+					LegalEntity: apiExperimental.LegalEntity(legalEntity),
+				}
+				validFromStr = validFrom.Format(time.RFC3339)
+				request.ValidFrom = &validFromStr
+				if duration != 0 {
+					durationStr := duration.String()
+					request.ValidDuration = &durationStr
+				}
+				if subject != "" {
+					request.Subject = &subject
+				}
+				if purposeOfUse != "" {
+					request.PurposeOfUse = &purposeOfUse
+				}
+				response, err := contractClientCreator().DrawUpContract(request)
+				if err != nil {
+					return err
+				}
+				var warnings []string
+				if response.Warnings != nil {
+					warnings = *response.Warnings
+				}
+				printContract(cmd.OutOrStdout(), response.Message, string(response.ContractHash), warnings)
+				return nil
+			}
+
+			store, err := contractTemplates(cmd)
+			if err != nil {
+				return err
+			}
+			template := store.Get(contract.Type(cType), contract.Language(language), contract.Version(version))
+			if template == nil {
+				return fmt.Errorf("no contract template found for %s:%s:%s", language, cType, version)
+			}
+			if duration == 0 {
+				duration = pkg.DefaultAuthConfig().ContractValidDuration
+			}
+			vars := map[string]string{contract.LegalEntityAttr: legalEntity}
+			if subject != "" {
+				vars[contract.SubjectAttr] = subject
+			}
+			if purposeOfUse != "" {
+				vars[contract.PurposeOfUseAttr] = purposeOfUse
+			}
+			drawnUp, err := template.Render(vars, validFrom, duration)
+			if err != nil {
+				return err
+			}
+			printContract(cmd.OutOrStdout(), drawnUp.RawContractText, drawnUp.Hash(), drawnUp.Warnings)
+			return nil
+		},
+	}
+	cmd.Flags().String("type", "", "Type of the contract template, like PractitionerLogin")
+	cmd.Flags().String("language", "", "Language of the contract template, like EN")
+	cmd.Flags().String("version", "", "Version of the contract template, like v3")
+	cmd.Flags().String("legal-entity", "", "Identifier of the legal entity, or its name in local mode")
+	cmd.Flags().String("valid-from", "", "Start of the validity of the contract in RFC3339 format, defaults to now")
+	cmd.Flags().Duration("duration", 0, "Validity of the contract, like 2h. Defaults to the validity configured on the node")
+	cmd.Flags().String("subject", "", "Identifier of the patient, for patient context contracts")
+	cmd.Flags().String("purpose-of-use", "", "HL7 PurposeOfUse code, for patient context contracts")
+	for _, name := range []string{"type", "language", "version", "legal-entity"} {
+		_ = cmd.MarkFlagRequired(name)
+	}
+	return cmd
+}
+
+func parseCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "parse [file|-]",
+		Short: "Parse a contract text and check its validity",
+		Long:  "Parse a contract text from a file, or from stdin when the file is '-'. It prints the matching template, the extracted params and the validity of the contract.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			text, err := readContractText(cmd.InOrStdin(), args[0])
+			if err != nil {
+				return err
+			}
+			checkTime := time.Now()
+			if checkTimeStr, _ := cmd.Flags().GetString("check-time"); checkTimeStr != "" {
+				if checkTime, err = time.Parse(time.RFC3339, checkTimeStr); err != nil {
+					return fmt.Errorf("could not parse check-time: %w", err)
+				}
+			}
+			store, err := contractTemplates(cmd)
+			if err != nil {
+				return err
+			}
+			parsed, err := contract.ParseContractString(text, store)
+			if err != nil {
+				return err
+			}
+			printParsedContract(cmd.OutOrStdout(), parsed, checkTime)
+			return nil
+		},
+	}
+	cmd.Flags().String("check-time", "", "Moment to check the validity of the contract in RFC3339 format, defaults to now")
+	return cmd
+}
+
+func isLocal(cmd *cobra.Command) bool {
+	templatesPath, _ := cmd.Flags().GetString(templatesFlag)
+	local, _ := cmd.Flags().GetBool(localFlag)
+	return local || templatesPath != ""
+}
+
+// contractTemplates returns the templates of the running node, or the locally loaded templates in local mode
+func contractTemplates(cmd *cobra.Command) (contract.TemplateStore, error) {
+	if isLocal(cmd) {
+		templatesPath, _ := cmd.Flags().GetString(templatesFlag)
+		return contract.LoadTemplateStore(templatesPath)
+	}
+	return contractClientCreator().ContractTemplates()
+}
+
+func readContractText(stdin io.Reader, fileName string) (string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if fileName == "-" {
+		data, err = ioutil.ReadAll(stdin)
+	} else {
+		data, err = ioutil.ReadFile(fileName)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not read contract text: %w", err)
+	}
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return "", errors.New("could not read contract text: empty input")
+	}
+	return text, nil
+}
+
+func printTemplates(out io.Writer, templates []*contract.Template, moment time.Time) {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "TEMPLATE\tTIME ZONE\tATTRIBUTES\tSTATUS")
+	for _, template := range templates {
+		timeZone := template.TimeZone
+		if timeZone == "" {
+			timeZone = contract.AmsterdamTimeZone
+		}
+		status := "active"
+		if template.IsSunset(moment) {
+			status = "sunset since " + template.SunsetAt.Format(time.RFC3339)
+		} else if template.IsDeprecated(moment) {
+			status = "deprecated since " + template.DeprecatedFrom.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", template.Identifier(), timeZone, strings.Join(template.TemplateAttributes, ", "), status)
+	}
+	_ = writer.Flush()
+}
+
+func printContract(out io.Writer, message string, hash string, warnings []string) {
+	_, _ = fmt.Fprintln(out, message)
+	_, _ = fmt.Fprintf(out, "\nContract hash: %s\n", hash)
+	for _, warning := range warnings {
+		_, _ = fmt.Fprintf(out, "Warning: %s\n", warning)
+	}
+}
+
+func printParsedContract(out io.Writer, parsed *contract.Contract, checkTime time.Time) {
+	_, _ = fmt.Fprintf(out, "Template:      %s\n", parsed.Template.Identifier())
+	_, _ = fmt.Fprintf(out, "Contract hash: %s\n", parsed.Hash())
+	_, _ = fmt.Fprintln(out, "Params:")
+	names := make([]string, 0, len(parsed.Params))
+	for name := range parsed.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(out, "  %s: %s\n", name, parsed.Params[name])
+	}
+	if err := parsed.VerifyForGivenTime(checkTime); err != nil {
+		_, _ = fmt.Fprintf(out, "Validity:      INVALID at %s: %s\n", checkTime.Format(time.RFC3339), err)
+		return
+	}
+	_, _ = fmt.Fprintf(out, "Validity:      VALID at %s\n", checkTime.Format(time.RFC3339))
+}
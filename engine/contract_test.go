@@ -0,0 +1,245 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package engine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	testIo "github.com/nuts-foundation/nuts-go-test/io"
+	"github.com/stretchr/testify/assert"
+
+	apiExperimental "github.com/nuts-foundation/nuts-auth/api/experimental"
+	apiV1 "github.com/nuts-foundation/nuts-auth/api/v1"
+	"github.com/nuts-foundation/nuts-auth/client"
+	"github.com/nuts-foundation/nuts-auth/mock"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+)
+
+const loginContract = "EN:PractitionerLogin:v3 I hereby declare to act on behalf of Zorgcentrum. This declaration is valid from Wednesday, 1 January 2020 12:00:00 until Wednesday, 1 January 2020 14:00:00."
+
+func executeContractCmd(t *testing.T, stdin string, args ...string) (string, error) {
+	command := cmd()
+	out := new(bytes.Buffer)
+	command.SetOut(out)
+	command.SetErr(ioutil.Discard)
+	command.SetIn(strings.NewReader(stdin))
+	command.SetArgs(append([]string{"contract"}, args...))
+	err := command.Execute()
+	return out.String(), err
+}
+
+func withTestServer(t *testing.T, drawUpHandler echo.HandlerFunc) func() {
+	ctrl := gomock.NewController(t)
+	authMock := mock_auth.NewMockAuthClient(ctrl)
+	authMock.EXPECT().ContractTemplates().AnyTimes().Return(contract.StandardContractTemplates)
+
+	e := echo.New()
+	apiV1.RegisterHandlers(e, &apiV1.Wrapper{Auth: authMock})
+	e.PUT("/internal/auth/experimental/contract/drawup", drawUpHandler)
+	server := httptest.NewServer(e)
+
+	original := contractClientCreator
+	contractClientCreator = func() client.HttpClient {
+		return client.HttpClient{ServerAddress: server.URL}
+	}
+	return func() {
+		contractClientCreator = original
+		server.Close()
+		ctrl.Finish()
+	}
+}
+
+func TestContractCmd_List(t *testing.T) {
+	t.Run("ok - local mode lists the standard templates", func(t *testing.T) {
+		out, err := executeContractCmd(t, "", "list", "--local")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, out, "TEMPLATE")
+		assert.Contains(t, out, "EN:PractitionerLogin:v3")
+		assert.Contains(t, out, "legal_entity, valid_from, valid_to")
+		assert.Contains(t, out, contract.AmsterdamTimeZone)
+	})
+
+	t.Run("ok - local mode includes the templates from the directory", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		definition := `{"type": "Opname", "language": "NL", "version": "v1", "sunsetAt": "2020-01-01T00:00:00Z",
+			"template": "NL:Opname:v1 Hierbij verklaar ik te handelen in naam van {{legal_entity}}. Deze verklaring is geldig van {{valid_from}} tot {{valid_to}}."}`
+		_ = ioutil.WriteFile(filepath.Join(dir, "opname.json"), []byte(definition), 0644)
+
+		out, err := executeContractCmd(t, "", "list", "--templates", dir)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, out, "NL:Opname:v1")
+		assert.Contains(t, out, "sunset since 2020-01-01T00:00:00Z")
+	})
+
+	t.Run("ok - client mode lists the templates of the node", func(t *testing.T) {
+		defer withTestServer(t, nil)()
+
+		out, err := executeContractCmd(t, "", "list")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, out, "NL:BehandelaarLogin:v3")
+		assert.Contains(t, out, "EN:PractitionerPatientAccess:v1")
+	})
+
+	t.Run("error - node is not available", func(t *testing.T) {
+		original := contractClientCreator
+		defer func() { contractClientCreator = original }()
+		contractClientCreator = func() client.HttpClient {
+			return client.HttpClient{ServerAddress: "localhost:1", Timeout: time.Second}
+		}
+
+		_, err := executeContractCmd(t, "", "list")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestContractCmd_DrawUp(t *testing.T) {
+	t.Run("ok - local mode draws up the contract", func(t *testing.T) {
+		out, err := executeContractCmd(t, "", "drawup", "--local", "--type", "PractitionerLogin", "--language", "EN", "--version", "v3",
+			"--legal-entity", "Zorgcentrum", "--valid-from", "2020-01-01T12:00:00+01:00", "--duration", "2h")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, out, loginContract)
+		parsed, _ := contract.ParseContractString(loginContract, contract.StandardContractTemplates)
+		assert.Contains(t, out, "Contract hash: "+parsed.Hash())
+	})
+
+	t.Run("ok - local mode draws up a patient context contract", func(t *testing.T) {
+		out, err := executeContractCmd(t, "", "drawup", "--local", "--type", "PractitionerPatientAccess", "--language", "EN", "--version", "v1",
+			"--legal-entity", "Zorgcentrum", "--subject", "999999990", "--purpose-of-use", "TREAT")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, out, "patient 999999990 for purpose of use TREAT")
+	})
+
+	t.Run("error - unknown template", func(t *testing.T) {
+		_, err := executeContractCmd(t, "", "drawup", "--local", "--type", "Unknown", "--language", "EN", "--version", "v3", "--legal-entity", "Zorgcentrum")
+
+		assert.EqualError(t, err, "no contract template found for EN:Unknown:v3")
+	})
+
+	t.Run("error - invalid valid-from", func(t *testing.T) {
+		_, err := executeContractCmd(t, "", "drawup", "--local", "--type", "PractitionerLogin", "--language", "EN", "--version", "v3",
+			"--legal-entity", "Zorgcentrum", "--valid-from", "tomorrow")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "could not parse valid-from")
+	})
+
+	t.Run("ok - client mode lets the node draw up the contract", func(t *testing.T) {
+		var request apiExperimental.DrawUpContractRequest
+		defer withTestServer(t, func(ctx echo.Context) error {
+			_ = ctx.Bind(&request)
+			warnings := []string{"deprecated"}
+			return ctx.JSON(http.StatusOK, apiExperimental.ContractResponse{
+				Message:      loginContract,
+				ContractHash: "abc",
+				Warnings:     &warnings,
+			})
+		})()
+
+		out, err := executeContractCmd(t, "", "drawup", "--type", "PractitionerLogin", "--language", "EN", "--version", "v3",
+			"--legal-entity", "urn:oid:2.16.840.1.113883.2.4.6.1:00000001", "--duration", "2h", "--subject", "999999990")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, out, loginContract)
+		assert.Contains(t, out, "Contract hash: abc")
+		assert.Contains(t, out, "Warning: deprecated")
+		assert.Equal(t, apiExperimental.LegalEntity("urn:oid:2.16.840.1.113883.2.4.6.1:00000001"), request.LegalEntity)
+		assert.Equal(t, "2h0m0s", *request.ValidDuration)
+		assert.Equal(t, "999999990", *request.Subject)
+		assert.Nil(t, request.PurposeOfUse)
+	})
+
+	t.Run("error - node returns an error", func(t *testing.T) {
+		defer withTestServer(t, func(ctx echo.Context) error {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid legal entity")
+		})()
+
+		_, err := executeContractCmd(t, "", "drawup", "--type", "PractitionerLogin", "--language", "EN", "--version", "v3", "--legal-entity", "foo")
+
+		if !assert.Error(t, err) {
+			return
+		}
+		assert.Contains(t, err.Error(), "server returned HTTP 400")
+	})
+}
+
+func TestContractCmd_Parse(t *testing.T) {
+	t.Run("ok - parse from stdin", func(t *testing.T) {
+		out, err := executeContractCmd(t, loginContract+"\n", "parse", "-", "--local", "--check-time", "2020-01-01T13:00:00+01:00")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, out, "Template:      EN:PractitionerLogin:v3")
+		assert.Contains(t, out, "  legal_entity: Zorgcentrum")
+		assert.Contains(t, out, "Validity:      VALID at 2020-01-01T13:00:00+01:00")
+	})
+
+	t.Run("ok - parse from file using the templates of the node", func(t *testing.T) {
+		defer withTestServer(t, nil)()
+		fileName := filepath.Join(testIo.TestDirectory(t), "contract.txt")
+		_ = ioutil.WriteFile(fileName, []byte(loginContract), 0644)
+
+		out, err := executeContractCmd(t, "", "parse", fileName, "--check-time", "2020-01-02T13:00:00+01:00")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, out, "Template:      EN:PractitionerLogin:v3")
+		assert.Contains(t, out, "Validity:      INVALID at 2020-01-02T13:00:00+01:00")
+	})
+
+	t.Run("error - empty input", func(t *testing.T) {
+		_, err := executeContractCmd(t, " \n", "parse", "-", "--local")
+
+		assert.EqualError(t, err, "could not read contract text: empty input")
+	})
+
+	t.Run("error - unknown contract", func(t *testing.T) {
+		_, err := executeContractCmd(t, "Not a contract", "parse", "-", "--local")
+
+		assert.Error(t, err)
+	})
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	apiExperimental "github.com/nuts-foundation/nuts-auth/api/experimental"
 	apiV0 "github.com/nuts-foundation/nuts-auth/api/v0"
+	apiV1 "github.com/nuts-foundation/nuts-auth/api/v1"
 	"github.com/nuts-foundation/nuts-auth/pkg"
 	"github.com/nuts-foundation/nuts-auth/pkg/services/irma"
 	nutsGo "github.com/nuts-foundation/nuts-go-core"
@@ -59,6 +60,8 @@ func NewAuthEngine() *nutsGo.Engine {
 		Config:    &authBackend.Config,
 		ConfigKey: "auth",
 		Configure: authBackend.Configure,
+		Start:     authBackend.Start,
+		Shutdown:  authBackend.Shutdown,
 		FlagSet:   flagSet(),
 		Name:      "Auth",
 		Routes: func(router nutsGo.EchoRouter) {
@@ -78,8 +81,9 @@ func NewAuthEngine() *nutsGo.Engine {
 			routerWithAny.Any(irma.IrmaMountPath+"/*", irmaEchoHandler)
 
 			// Mount the Auth-api routes
-			apiV0.RegisterHandlers(router, &apiV0.Wrapper{Auth: authBackend})
-			apiExperimental.RegisterHandlers(router, &apiExperimental.Wrapper{Auth: authBackend})
+			apiV0.RegisterHandlers(router, &apiV0.Wrapper{Auth: authBackend, TrustClientCertHeader: authBackend.Config.TrustClientCertHeader, OAuthIssuer: authBackend.Config.OAuthIssuer})
+			apiExperimental.RegisterHandlers(router, &apiExperimental.Wrapper{Auth: authBackend, TrustClientCertHeader: authBackend.Config.TrustClientCertHeader})
+			apiV1.RegisterHandlers(router, &apiV1.Wrapper{Auth: authBackend})
 
 			checkConfig(authBackend.Config)
 
@@ -113,8 +117,9 @@ func initEcho(auth *pkg.Auth) (*echo.Echo, error) {
 	echoServer.Any(irma.IrmaMountPath+"/*", irmaEchoHandler)
 
 	// Mount the Nuts-Auth routes
-	apiV0.RegisterHandlers(echoServer, &apiV0.Wrapper{Auth: auth})
-	apiExperimental.RegisterHandlers(echoServer, &apiExperimental.Wrapper{Auth: auth})
+	apiV0.RegisterHandlers(echoServer, &apiV0.Wrapper{Auth: auth, TrustClientCertHeader: auth.Config.TrustClientCertHeader, OAuthIssuer: auth.Config.OAuthIssuer})
+	apiExperimental.RegisterHandlers(echoServer, &apiExperimental.Wrapper{Auth: auth, TrustClientCertHeader: auth.Config.TrustClientCertHeader})
+	apiV1.RegisterHandlers(echoServer, &apiV1.Wrapper{Auth: auth})
 
 	// Start the server
 	return echoServer, nil
@@ -141,6 +146,8 @@ func cmd() *cobra.Command {
 			return echo.Start(authEngine.Config.Address)
 		},
 	})
+	cmd.AddCommand(contractCmd())
+	cmd.AddCommand(auditCmd())
 
 	return cmd
 }
@@ -165,8 +172,30 @@ func flagSet() *pflag.FlagSet {
 	flags.String(irma.ConfIrmaConfigPath, defs.IrmaConfigPath, "path to IRMA config folder. If not set, a tmp folder is created.")
 	flags.String(pkg.ConfActingPartyCN, defs.ActingPartyCn, "The acting party Common name used in contracts")
 	flags.Bool(irma.ConfSkipAutoUpdateIrmaSchemas, defs.SkipAutoUpdateIrmaSchemas, "set if you want to skip the auto download of the irma schemas every 60 minutes.")
+	flags.Bool(irma.ConfIrmaPrintQrCode, defs.IrmaPrintQrCode, "Print the QR code of every IRMA signing session to stdout. Only meant for development, the QR code can be retrieved from the API instead.")
 	flags.Bool(pkg.ConfEnableCORS, defs.EnableCORS, "Set if you want to allow CORS requests. This is useful when you want browsers to directly communicate with the nuts node.")
 	flags.StringSlice(pkg.ConfContractValidators, defs.ContractValidators, "Sets the different contract validators to use")
+	flags.String(pkg.ConfContractTemplatesPath, defs.ContractTemplatesPath, "Path to a directory with additional contract template definitions in JSON or YAML format.")
+	flags.StringSlice(pkg.ConfContractTimeZones, defs.ContractTimeZones, "Time zones in which legal entities draw up their contracts, in the form <legal entity>=<time zone>. Overrides the time zone of the contract template.")
+	flags.String(pkg.ConfSessionStorePath, defs.SessionStorePath, "Path of the file in which signing sessions are persisted. Sessions are kept in memory when not set.")
+	flags.Bool(pkg.ConfSharedStores, defs.SharedStores, "Only lock the files of the session store, replay cache, revocation list and refresh token store during a transaction, so nodes behind a load balancer can share them on a volume.")
+	flags.Duration(pkg.ConfSessionTTL, defs.SessionTTL, "Time in which a signing session must be finished, after which the session expires.")
+	flags.Int(pkg.ConfSessionRateLimit, defs.SessionRateLimit, "Number of signing sessions a client can start per minute, 0 disables the rate limit.")
+	flags.Int(pkg.ConfSessionRateBurst, defs.SessionRateBurst, "Number of signing sessions a client can start at once before the rate limit applies.")
+	flags.String(pkg.ConfSessionRateLimitBy, defs.SessionRateLimitBy, "How clients are identified by the rate limit: 'certificate' (fingerprint of the client certificate) or 'legalEntity' (legal entity in the contract).")
+	flags.Bool(pkg.ConfTrustClientCertHeader, defs.TrustClientCertHeader, "Identify clients by the certificate in the X-Ssl-Client-Cert header. Only set when all requests pass a TLS terminating proxy which sets the header.")
+	flags.Int(pkg.ConfMaxOpenSessions, defs.MaxOpenSessions, "Number of signing sessions per signing means which can be open at the same time, 0 means unlimited.")
+	flags.String(pkg.ConfAuditLogPath, defs.AuditLogPath, "Path of the file to which the audit log of signing sessions, verified presentations and access token requests is appended. No audit log is kept when not set.")
+	flags.String(pkg.ConfReplayCachePath, defs.ReplayCachePath, "Path of the file in which the jti's of used jwt bearer tokens are remembered. They are kept in memory when not set.")
+	flags.Int(pkg.ConfReplayCacheSize, defs.ReplayCacheSize, "Number of jwt bearer tokens which can be remembered at the same time. Access token requests are rejected when the cache is full.")
+	flags.String(pkg.ConfRevocationListPath, defs.RevocationListPath, "Path of the file in which the revoked access tokens and contracts, and the contracts which can be revoked, are kept. They are kept in memory when not set.")
+	flags.Int(pkg.ConfRevocationListSize, defs.RevocationListSize, "Number of revocations, and of contracts which can be revoked, which are kept at the same time.")
+	flags.Bool(pkg.ConfEnableRefreshTokens, defs.EnableRefreshTokens, "Issue refresh tokens with the access tokens. A refresh token is valid for as long as the signed login contract, but at most 24 hours.")
+	flags.String(pkg.ConfOAuthIssuer, defs.OAuthIssuer, "Public URL of the OAuth endpoints of the node, the issuer of its RFC8414 authorization server metadata. The metadata is not published when not set.")
+	flags.String(pkg.ConfRefreshTokenStorePath, defs.RefreshTokenStorePath, "Path of the file in which the issued refresh tokens are kept. They are kept in memory when not set.")
+	flags.Int(pkg.ConfRefreshTokenStoreSize, defs.RefreshTokenStoreSize, "Number of refresh tokens which can be valid at the same time. No refresh token is issued when the store is full.")
+	flags.Bool(pkg.ConfSkipAudienceCheck, defs.SkipAudienceCheck, "Accept jwt bearer tokens of which the aud is not an OAuth endpoint of the custodian, the mismatch is only logged. Only meant for the migration of nodes which do not yet set the aud.")
+	flags.String(pkg.ConfDummyPersonasPath, defs.DummyPersonasPath, "Path of a YAML file which maps the names of test personas to the attributes they disclose when signing with the dummy means.")
 
 	return flags
 }
@@ -7,6 +7,7 @@ require (
 	github.com/cbroglie/mustache v1.2.0
 	github.com/deepmap/oapi-codegen v1.4.1
 	github.com/dgrijalva/jwt-go v3.2.1-0.20200107013213-dc14462fd587+incompatible
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/golang/mock v1.4.4
 	github.com/goodsign/monday v0.0.0-20190708072354-9bcb46af8546
 	github.com/jasonlvhit/gocron v0.0.0-20191007145845-57f89394836a // indirect
@@ -21,9 +22,13 @@ require (
 	github.com/nuts-foundation/nuts-registry v0.16.0
 	github.com/pkg/errors v0.9.1
 	github.com/privacybydesign/irmago v0.6.0
+	github.com/prometheus/client_golang v0.9.4
 	github.com/sirupsen/logrus v1.7.0
 	github.com/spf13/cobra v1.1.1
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.6.1
+	go.etcd.io/bbolt v1.3.5
 	golang.org/x/tools v0.0.0-20200928201943-a0ef9b62deab // indirect
+	gopkg.in/yaml.v2 v2.3.0
+	rsc.io/qr v0.2.0
 )
@@ -49,18 +49,46 @@ func (mr *MockSignerMockRecorder) SigningSessionStatus(sessionID interface{}) *g
 }
 
 // StartSigningSession mocks base method
-func (m *MockSigner) StartSigningSession(rawContractText string) (contract.SessionPointer, error) {
+func (m *MockSigner) StartSigningSession(rawContractText string, params map[string]interface{}) (contract.SessionPointer, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "StartSigningSession", rawContractText)
+	ret := m.ctrl.Call(m, "StartSigningSession", rawContractText, params)
 	ret0, _ := ret[0].(contract.SessionPointer)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // StartSigningSession indicates an expected call of StartSigningSession
-func (mr *MockSignerMockRecorder) StartSigningSession(rawContractText interface{}) *gomock.Call {
+func (mr *MockSignerMockRecorder) StartSigningSession(rawContractText, params interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartSigningSession", reflect.TypeOf((*MockSigner)(nil).StartSigningSession), rawContractText)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartSigningSession", reflect.TypeOf((*MockSigner)(nil).StartSigningSession), rawContractText, params)
+}
+
+// CancelSigningSession mocks base method
+func (m *MockSigner) CancelSigningSession(sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelSigningSession", sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelSigningSession indicates an expected call of CancelSigningSession
+func (mr *MockSignerMockRecorder) CancelSigningSession(sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelSigningSession", reflect.TypeOf((*MockSigner)(nil).CancelSigningSession), sessionID)
+}
+
+// Subscribe mocks base method
+func (m *MockSigner) Subscribe(sessionID string, listener contract.SessionListener) func() {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Subscribe", sessionID, listener)
+	ret0, _ := ret[0].(func())
+	return ret0
+}
+
+// Subscribe indicates an expected call of Subscribe
+func (mr *MockSignerMockRecorder) Subscribe(sessionID, listener interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockSigner)(nil).Subscribe), sessionID, listener)
 }
 
 // MockSessionPointer is a mock of SessionPointer interface
@@ -138,10 +166,10 @@ func (m *MockSigningSessionResult) EXPECT() *MockSigningSessionResultMockRecorde
 }
 
 // Status mocks base method
-func (m *MockSigningSessionResult) Status() string {
+func (m *MockSigningSessionResult) Status() contract.SessionStatus {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Status")
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(contract.SessionStatus)
 	return ret0
 }
 
@@ -151,6 +179,20 @@ func (mr *MockSigningSessionResultMockRecorder) Status() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockSigningSessionResult)(nil).Status))
 }
 
+// NativeStatus mocks base method
+func (m *MockSigningSessionResult) NativeStatus() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NativeStatus")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// NativeStatus indicates an expected call of NativeStatus
+func (mr *MockSigningSessionResultMockRecorder) NativeStatus() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NativeStatus", reflect.TypeOf((*MockSigningSessionResult)(nil).NativeStatus))
+}
+
 // VerifiablePresentation mocks base method
 func (m *MockSigningSessionResult) VerifiablePresentation() (contract.VerifiablePresentation, error) {
 	m.ctrl.T.Helper()
@@ -6,6 +6,7 @@ package mock_auth
 
 import (
 	gomock "github.com/golang/mock/gomock"
+	contract "github.com/nuts-foundation/nuts-auth/pkg/contract"
 	services "github.com/nuts-foundation/nuts-auth/pkg/services"
 	reflect "reflect"
 )
@@ -74,3 +75,17 @@ func (mr *MockAuthClientMockRecorder) ContractNotary() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContractNotary", reflect.TypeOf((*MockAuthClient)(nil).ContractNotary))
 }
+
+// ContractTemplates mocks base method
+func (m *MockAuthClient) ContractTemplates() contract.TemplateStore {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContractTemplates")
+	ret0, _ := ret[0].(contract.TemplateStore)
+	return ret0
+}
+
+// ContractTemplates indicates an expected call of ContractTemplates
+func (mr *MockAuthClientMockRecorder) ContractTemplates() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContractTemplates", reflect.TypeOf((*MockAuthClient)(nil).ContractTemplates))
+}
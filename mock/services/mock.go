@@ -6,6 +6,7 @@ package mock_services
 
 import (
 	gomock "github.com/golang/mock/gomock"
+	jwk "github.com/lestrrat-go/jwx/jwk"
 	contract "github.com/nuts-foundation/nuts-auth/pkg/contract"
 	services "github.com/nuts-foundation/nuts-auth/pkg/services"
 	core "github.com/nuts-foundation/nuts-go-core"
@@ -205,6 +206,78 @@ func (mr *MockOAuthClientMockRecorder) IntrospectAccessToken(token interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IntrospectAccessToken", reflect.TypeOf((*MockOAuthClient)(nil).IntrospectAccessToken), token)
 }
 
+// RefreshAccessToken mocks base method
+func (m *MockOAuthClient) RefreshAccessToken(request services.RefreshAccessTokenRequest) (*services.AccessTokenResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshAccessToken", request)
+	ret0, _ := ret[0].(*services.AccessTokenResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshAccessToken indicates an expected call of RefreshAccessToken
+func (mr *MockOAuthClientMockRecorder) RefreshAccessToken(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshAccessToken", reflect.TypeOf((*MockOAuthClient)(nil).RefreshAccessToken), request)
+}
+
+// RevokeAccessToken mocks base method
+func (m *MockOAuthClient) RevokeAccessToken(request services.RevokeTokenRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAccessToken", request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAccessToken indicates an expected call of RevokeAccessToken
+func (mr *MockOAuthClientMockRecorder) RevokeAccessToken(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAccessToken", reflect.TypeOf((*MockOAuthClient)(nil).RevokeAccessToken), request)
+}
+
+// RevokeContract mocks base method
+func (m *MockOAuthClient) RevokeContract(request services.RevokeContractRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeContract", request)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeContract indicates an expected call of RevokeContract
+func (mr *MockOAuthClientMockRecorder) RevokeContract(request interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeContract", reflect.TypeOf((*MockOAuthClient)(nil).RevokeContract), request)
+}
+
+// SigningKey mocks base method
+func (m *MockOAuthClient) SigningKey() (jwk.Key, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SigningKey")
+	ret0, _ := ret[0].(jwk.Key)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SigningKey indicates an expected call of SigningKey
+func (mr *MockOAuthClientMockRecorder) SigningKey() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SigningKey", reflect.TypeOf((*MockOAuthClient)(nil).SigningKey))
+}
+
+// RefreshTokensEnabled mocks base method
+func (m *MockOAuthClient) RefreshTokensEnabled() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshTokensEnabled")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// RefreshTokensEnabled indicates an expected call of RefreshTokensEnabled
+func (mr *MockOAuthClientMockRecorder) RefreshTokensEnabled() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshTokensEnabled", reflect.TypeOf((*MockOAuthClient)(nil).RefreshTokensEnabled))
+}
+
 // Configure mocks base method
 func (m *MockOAuthClient) Configure() error {
 	m.ctrl.T.Helper()
@@ -400,18 +473,18 @@ func (m *MockContractNotary) EXPECT() *MockContractNotaryMockRecorder {
 }
 
 // DrawUpContract mocks base method
-func (m *MockContractNotary) DrawUpContract(template contract.Template, orgID core.PartyID, validFrom time.Time, validDuration time.Duration) (*contract.Contract, error) {
+func (m *MockContractNotary) DrawUpContract(template contract.Template, orgID core.PartyID, validFrom time.Time, validDuration time.Duration, attributes map[string]string) (*contract.Contract, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DrawUpContract", template, orgID, validFrom, validDuration)
+	ret := m.ctrl.Call(m, "DrawUpContract", template, orgID, validFrom, validDuration, attributes)
 	ret0, _ := ret[0].(*contract.Contract)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // DrawUpContract indicates an expected call of DrawUpContract
-func (mr *MockContractNotaryMockRecorder) DrawUpContract(template, orgID, validFrom, validDuration interface{}) *gomock.Call {
+func (mr *MockContractNotaryMockRecorder) DrawUpContract(template, orgID, validFrom, validDuration, attributes interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrawUpContract", reflect.TypeOf((*MockContractNotary)(nil).DrawUpContract), template, orgID, validFrom, validDuration)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrawUpContract", reflect.TypeOf((*MockContractNotary)(nil).DrawUpContract), template, orgID, validFrom, validDuration, attributes)
 }
 
 // ValidateContract mocks base method
@@ -453,18 +526,18 @@ func (m *MockContractClient) EXPECT() *MockContractClientMockRecorder {
 }
 
 // VerifyVP mocks base method
-func (m *MockContractClient) VerifyVP(rawVerifiablePresentation []byte, checkTime *time.Time) (*contract.VPVerificationResult, error) {
+func (m *MockContractClient) VerifyVP(rawVerifiablePresentation []byte, checkTime *time.Time, expectedContractHash *string) (*contract.VPVerificationResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "VerifyVP", rawVerifiablePresentation, checkTime)
+	ret := m.ctrl.Call(m, "VerifyVP", rawVerifiablePresentation, checkTime, expectedContractHash)
 	ret0, _ := ret[0].(*contract.VPVerificationResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // VerifyVP indicates an expected call of VerifyVP
-func (mr *MockContractClientMockRecorder) VerifyVP(rawVerifiablePresentation, checkTime interface{}) *gomock.Call {
+func (mr *MockContractClientMockRecorder) VerifyVP(rawVerifiablePresentation, checkTime, expectedContractHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyVP", reflect.TypeOf((*MockContractClient)(nil).VerifyVP), rawVerifiablePresentation, checkTime)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyVP", reflect.TypeOf((*MockContractClient)(nil).VerifyVP), rawVerifiablePresentation, checkTime, expectedContractHash)
 }
 
 // CreateSigningSession mocks base method
@@ -497,6 +570,79 @@ func (mr *MockContractClientMockRecorder) SigningSessionStatus(sessionID interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SigningSessionStatus", reflect.TypeOf((*MockContractClient)(nil).SigningSessionStatus), sessionID)
 }
 
+// CancelSigningSession mocks base method
+func (m *MockContractClient) CancelSigningSession(sessionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelSigningSession", sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelSigningSession indicates an expected call of CancelSigningSession
+func (mr *MockContractClientMockRecorder) CancelSigningSession(sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelSigningSession", reflect.TypeOf((*MockContractClient)(nil).CancelSigningSession), sessionID)
+}
+
+// SubscribeSigningSession mocks base method
+func (m *MockContractClient) SubscribeSigningSession(sessionID string, listener contract.SessionListener) (func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeSigningSession", sessionID, listener)
+	ret0, _ := ret[0].(func())
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeSigningSession indicates an expected call of SubscribeSigningSession
+func (mr *MockContractClientMockRecorder) SubscribeSigningSession(sessionID, listener interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeSigningSession", reflect.TypeOf((*MockContractClient)(nil).SubscribeSigningSession), sessionID, listener)
+}
+
+// AuthorizeSigningSession mocks base method
+func (m *MockContractClient) AuthorizeSigningSession(sessionID, caller string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuthorizeSigningSession", sessionID, caller)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AuthorizeSigningSession indicates an expected call of AuthorizeSigningSession
+func (mr *MockContractClientMockRecorder) AuthorizeSigningSession(sessionID, caller interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizeSigningSession", reflect.TypeOf((*MockContractClient)(nil).AuthorizeSigningSession), sessionID, caller)
+}
+
+// SigningSessionPointer mocks base method
+func (m *MockContractClient) SigningSessionPointer(sessionID string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SigningSessionPointer", sessionID)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SigningSessionPointer indicates an expected call of SigningSessionPointer
+func (mr *MockContractClientMockRecorder) SigningSessionPointer(sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SigningSessionPointer", reflect.TypeOf((*MockContractClient)(nil).SigningSessionPointer), sessionID)
+}
+
+// SigningSessionCallbacks mocks base method
+func (m *MockContractClient) SigningSessionCallbacks(sessionID string) ([]services.CallbackDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SigningSessionCallbacks", sessionID)
+	ret0, _ := ret[0].([]services.CallbackDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SigningSessionCallbacks indicates an expected call of SigningSessionCallbacks
+func (mr *MockContractClientMockRecorder) SigningSessionCallbacks(sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SigningSessionCallbacks", reflect.TypeOf((*MockContractClient)(nil).SigningSessionCallbacks), sessionID)
+}
+
 // Configure mocks base method
 func (m *MockContractClient) Configure() error {
 	m.ctrl.T.Helper()
@@ -19,6 +19,10 @@
 package pkg
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,10 +30,18 @@ import (
 	core "github.com/nuts-foundation/nuts-go-core"
 	registry "github.com/nuts-foundation/nuts-registry/pkg"
 
+	"github.com/nuts-foundation/nuts-auth/logging"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
 	"github.com/nuts-foundation/nuts-auth/pkg/services"
-	"github.com/nuts-foundation/nuts-auth/pkg/services/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/audit"
+	servicesContract "github.com/nuts-foundation/nuts-auth/pkg/services/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/dummy"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/expiring"
 	"github.com/nuts-foundation/nuts-auth/pkg/services/oauth"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/ratelimit"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/session"
 	"github.com/nuts-foundation/nuts-auth/pkg/services/validator"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/webhook"
 )
 
 // ConfAddress is the config key for the address the http server listens on
@@ -49,6 +61,69 @@ const ConfActingPartyCN = "actingPartyCn"
 // ConfContractValidators is the config key for defining which contract validators to use
 const ConfContractValidators = "contractValidators"
 
+// ConfContractTemplatesPath is the config key for the directory containing additional contract templates
+const ConfContractTemplatesPath = "contractTemplatesPath"
+
+// ConfContractTimeZones is the config key for the time zones in which legal entities draw up their contracts
+const ConfContractTimeZones = "contractTimeZones"
+
+// ConfSessionStorePath is the config key for the file in which signing sessions are persisted
+const ConfSessionStorePath = "sessionStorePath"
+
+// ConfSharedStores is the config key for sharing the store files with other nodes on a volume
+const ConfSharedStores = "sharedStores"
+
+// ConfSessionTTL is the config key for the time in which a signing session must be finished
+const ConfSessionTTL = "sessionTTL"
+
+// ConfSessionRateLimit is the config key for the number of signing sessions a client can start per minute
+const ConfSessionRateLimit = "sessionRateLimit"
+
+// ConfSessionRateBurst is the config key for the number of signing sessions a client can start at once
+const ConfSessionRateBurst = "sessionRateBurst"
+
+// ConfSessionRateLimitBy is the config key for how clients are identified by the rate limit
+const ConfSessionRateLimitBy = "sessionRateLimitBy"
+
+// ConfTrustClientCertHeader is the config key to identify clients by the certificate in the header set by a TLS terminating proxy
+const ConfTrustClientCertHeader = "trustClientCertHeader"
+
+// ConfMaxOpenSessions is the config key for the number of signing sessions per means which can be open at the same time
+const ConfMaxOpenSessions = "maxOpenSessions"
+
+// ConfAuditLogPath is the config key for the file to which the audit log is appended
+const ConfAuditLogPath = "auditLogPath"
+
+// ConfSkipAudienceCheck is the config key to accept jwt bearer tokens of which the aud is not an OAuth endpoint of the custodian
+const ConfSkipAudienceCheck = "skipAudienceCheck"
+
+// ConfReplayCachePath is the config key for the file in which the jti's of used jwt bearer tokens are remembered
+const ConfReplayCachePath = "replayCachePath"
+
+// ConfReplayCacheSize is the config key for the number of jwt bearer tokens which can be remembered at the same time
+const ConfReplayCacheSize = "replayCacheSize"
+
+// ConfRevocationListPath is the config key for the file in which the revoked access tokens and contracts are kept
+const ConfRevocationListPath = "revocationListPath"
+
+// ConfRevocationListSize is the config key for the number of revocations, and of contracts which can be revoked, which are kept at the same time
+const ConfRevocationListSize = "revocationListSize"
+
+// ConfEnableRefreshTokens is the config key for issuing refresh tokens with the access tokens
+const ConfEnableRefreshTokens = "enableRefreshTokens"
+
+// ConfOAuthIssuer is the config key for the public URL of the OAuth authorization server of the node
+const ConfOAuthIssuer = "oauthIssuer"
+
+// ConfRefreshTokenStorePath is the config key for the file in which the issued refresh tokens are kept
+const ConfRefreshTokenStorePath = "refreshTokenStorePath"
+
+// ConfRefreshTokenStoreSize is the config key for the number of refresh tokens which can be valid at the same time
+const ConfRefreshTokenStoreSize = "refreshTokenStoreSize"
+
+// ConfDummyPersonasPath is the config key for the file containing the personas of the dummy means
+const ConfDummyPersonasPath = "dummyPersonasPath"
+
 // AuthClient is the interface which should be implemented for clients or mocks
 type AuthClient interface {
 	// OAuthClient returns an instance of OAuthClient
@@ -57,6 +132,8 @@ type AuthClient interface {
 	ContractClient() services.ContractClient
 	// ContractNotary returns an instance of ContractNotary
 	ContractNotary() services.ContractNotary
+	// ContractTemplates returns the contract templates known to this node
+	ContractTemplates() contract.TemplateStore
 }
 
 // Auth is the main struct of the Auth service
@@ -71,6 +148,18 @@ type Auth struct {
 	Crypto              nutscrypto.Client
 	Registry            registry.RegistryClient
 	contractNotary      services.ContractNotary
+	contractTemplates   contract.TemplateProvider
+	templateWatcher     *contract.TemplateWatcher
+	sessionStore        services.SessionStore
+	sessionNotifier     *contract.SessionNotifier
+	sessionReaper       *session.Reaper
+	sessionCallbacks    *webhook.Dispatcher
+	dummyPersonas       map[string]dummy.Persona
+	auditor             *audit.Auditor
+	replayCache         expiring.Set
+	revocationList      expiring.Set
+	issuedContracts     expiring.Map
+	refreshTokenStore   expiring.Map
 }
 
 // ContractNotary returns an implementation of the ContractNotary interface.
@@ -78,6 +167,17 @@ func (auth Auth) ContractNotary() services.ContractNotary {
 	return auth.contractNotary
 }
 
+// Auditor returns the auditor which records the signing sessions, verified presentations and access token requests.
+// Additional sinks can be added to it. It is nil when the engine is not configured in server mode.
+func (auth *Auth) Auditor() *audit.Auditor {
+	return auth.auditor
+}
+
+// ContractTemplates returns the standard contract templates merged with the templates from the configured contractTemplatesPath.
+func (auth *Auth) ContractTemplates() contract.TemplateStore {
+	return auth.contractTemplates.Templates()
+}
+
 // DefaultAuthConfig returns an instance of AuthConfig with the default values.
 func DefaultAuthConfig() AuthConfig {
 	return AuthConfig{
@@ -85,6 +185,12 @@ func DefaultAuthConfig() AuthConfig {
 		IrmaSchemeManager:     "pbdf",
 		ContractValidators:    []string{"irma", "uzi", "dummy"},
 		ContractValidDuration: 60 * time.Minute,
+		SessionTTL:            15 * time.Minute,
+		SessionRateBurst:      5,
+		SessionRateLimitBy:    ratelimit.ByLegalEntity,
+		ReplayCacheSize:       oauth.DefaultReplayCacheSize,
+		RevocationListSize:    oauth.DefaultRevocationListSize,
+		RefreshTokenStoreSize: oauth.DefaultRefreshTokenStoreSize,
 	}
 }
 
@@ -102,17 +208,27 @@ func AuthInstance() *Auth {
 // NewAuthInstance accepts a AuthConfig with several Nuts Engines and returns an instance of Auth
 func NewAuthInstance(config AuthConfig, cryptoClient nutscrypto.Client, registryClient registry.RegistryClient) *Auth {
 	return &Auth{
-		Config:         config,
-		Crypto:         cryptoClient,
-		Registry:       registryClient,
-		contractNotary: contract.NewContractNotary(registryClient, cryptoClient, config.ContractValidDuration),
+		Config:            config,
+		Crypto:            cryptoClient,
+		Registry:          registryClient,
+		contractNotary:    servicesContract.NewContractNotary(registryClient, cryptoClient, config.ContractValidDuration, nil),
+		contractTemplates: contract.StandardContractTemplates,
 	}
 }
 
 // OAuthClient returns an instance of OAuthClient
 func (auth *Auth) OAuthClient() services.OAuthClient {
 	auth.oneOauthInstance.Do(func() {
-		auth.OAuth = oauth.NewOAuthService(core.NutsConfig().VendorID(), auth.Crypto, auth.Registry, auth.Contract)
+		cfg := oauth.Config{
+			Auditor:           auth.auditor,
+			SkipAudienceCheck: auth.Config.SkipAudienceCheck,
+			ReplayCache:       auth.replayCache,
+			RevocationList:    auth.revocationList,
+			IssuedContracts:   auth.issuedContracts,
+			RefreshTokens:     auth.Config.EnableRefreshTokens,
+			RefreshTokenStore: auth.refreshTokenStore,
+		}
+		auth.OAuth = oauth.NewOAuthService(core.NutsConfig().VendorID(), auth.Crypto, auth.Registry, auth.Contract, cfg)
 	})
 	return auth.OAuth
 }
@@ -127,8 +243,21 @@ func (auth *Auth) ContractClient() services.ContractClient {
 			IrmaConfigPath:            auth.Config.IrmaConfigPath,
 			IrmaSchemeManager:         auth.Config.IrmaSchemeManager,
 			SkipAutoUpdateIrmaSchemas: auth.Config.SkipAutoUpdateIrmaSchemas,
+			IrmaPrintQrCode:           auth.Config.IrmaPrintQrCode,
 			ActingPartyCn:             auth.Config.ActingPartyCn,
 			ContractValidators:        auth.Config.ContractValidators,
+			ContractTemplates:         auth.contractTemplates,
+			SessionStore:              auth.sessionStore,
+			SessionNotifier:           auth.sessionNotifier,
+			CallbackDispatcher:        auth.sessionCallbacks,
+			DummyPersonas:             auth.dummyPersonas,
+			ContractNotary:            auth.contractNotary,
+			Auditor:                   auth.auditor,
+			SessionTTL:                auth.Config.SessionTTL,
+			SessionRateLimit:          auth.Config.SessionRateLimit,
+			SessionRateBurst:          auth.Config.SessionRateBurst,
+			SessionRateLimitBy:        auth.Config.SessionRateLimitBy,
+			MaxOpenSessions:           auth.Config.MaxOpenSessions,
 		}
 		auth.Contract = validator.NewContractInstance(cfg, auth.Crypto, auth.Registry)
 	})
@@ -140,6 +269,49 @@ func (auth *Auth) Configure() (err error) {
 	auth.configOnce.Do(func() {
 		auth.Config.Mode = core.NutsConfig().GetEngineMode(auth.Config.Mode)
 		if auth.Config.Mode == core.ServerEngineMode {
+			if err = auth.configureContractTemplates(); err != nil {
+				return
+			}
+
+			var timeZones map[core.PartyID]*time.Location
+			if timeZones, err = parseContractTimeZones(auth.Config.ContractTimeZones); err != nil {
+				return
+			}
+			auth.contractNotary = servicesContract.NewContractNotary(auth.Registry, auth.Crypto, auth.Config.ContractValidDuration, timeZones)
+
+			if err = auth.configureSessionStore(); err != nil {
+				return
+			}
+
+			if err = auth.checkSessionLimits(); err != nil {
+				return
+			}
+
+			if err = auth.configureAuditLog(); err != nil {
+				return
+			}
+
+			if err = auth.configureReplayCache(); err != nil {
+				return
+			}
+
+			if err = auth.configureRevocationList(); err != nil {
+				return
+			}
+
+			if err = auth.configureRefreshTokenStore(); err != nil {
+				return
+			}
+
+			if err = auth.checkOAuthIssuer(); err != nil {
+				return
+			}
+
+			if auth.Config.DummyPersonasPath != "" {
+				if auth.dummyPersonas, err = dummy.LoadPersonas(auth.Config.DummyPersonasPath); err != nil {
+					return
+				}
+			}
 
 			auth.ContractClient()
 			if err = auth.Contract.Configure(); err != nil {
@@ -156,3 +328,218 @@ func (auth *Auth) Configure() (err error) {
 
 	return err
 }
+
+// configureContractTemplates loads the contract templates. When a contractTemplatesPath is configured, the templates are
+// reloaded when the contents of the directory change.
+func (auth *Auth) configureContractTemplates() error {
+	if auth.Config.ContractTemplatesPath == "" {
+		auth.contractTemplates = contract.StandardContractTemplates
+		return nil
+	}
+	watcher, err := contract.NewTemplateWatcher(auth.Config.ContractTemplatesPath)
+	if err != nil {
+		return err
+	}
+	auth.templateWatcher = watcher
+	auth.contractTemplates = watcher
+	return nil
+}
+
+// configureSessionStore persists the signing sessions in the configured sessionStorePath. Without a path, the sessions are kept in memory.
+// Sessions which are not finished within the sessionTTL are expired by a reaper. Their callbacks are delivered by a dispatcher.
+func (auth *Auth) configureSessionStore() (err error) {
+	if auth.Config.SessionTTL < 0 {
+		return fmt.Errorf("invalid %s '%s', it must be positive", ConfSessionTTL, auth.Config.SessionTTL)
+	}
+	if auth.Config.SessionTTL == 0 {
+		auth.Config.SessionTTL = DefaultAuthConfig().SessionTTL
+	}
+	if auth.Config.SessionStorePath == "" {
+		auth.sessionStore = session.NewMemoryStore()
+	} else if auth.sessionStore, err = session.NewBboltStore(auth.Config.SessionStorePath, auth.Config.SharedStores); err != nil {
+		return err
+	}
+	auth.sessionNotifier = contract.NewSessionNotifier()
+	auth.sessionReaper = session.NewReaper(auth.sessionStore, auth.Config.SessionTTL, auth.sessionNotifier, auth.contractTemplates)
+	auth.sessionCallbacks = webhook.NewDispatcher()
+	return nil
+}
+
+// configureAuditLog creates the auditor, which appends to the hash chained audit log at the auditLogPath when configured
+func (auth *Auth) configureAuditLog() error {
+	auth.auditor = audit.NewAuditor()
+	if auth.Config.AuditLogPath == "" {
+		return nil
+	}
+	sink, err := audit.NewFileSink(auth.Config.AuditLogPath)
+	if err != nil {
+		return err
+	}
+	auth.auditor.AddSink(sink)
+	return nil
+}
+
+// configureReplayCache creates the cache of used jwt bearer tokens in the configured replayCachePath.
+// Without a path, the cache is kept in memory.
+func (auth *Auth) configureReplayCache() (err error) {
+	if auth.Config.ReplayCacheSize < 0 {
+		return fmt.Errorf("invalid %s '%d', it must not be negative", ConfReplayCacheSize, auth.Config.ReplayCacheSize)
+	}
+	if auth.Config.ReplayCacheSize == 0 {
+		auth.Config.ReplayCacheSize = DefaultAuthConfig().ReplayCacheSize
+	}
+	if auth.Config.ReplayCachePath == "" {
+		auth.replayCache = expiring.NewMemorySet(auth.Config.ReplayCacheSize)
+		return nil
+	}
+	auth.replayCache, err = expiring.NewBboltSet(auth.Config.ReplayCachePath, "jti", auth.Config.ReplayCacheSize, auth.Config.SharedStores)
+	return err
+}
+
+// configureRevocationList creates the list of revoked access tokens and contracts, and the contracts which can be revoked,
+// in the configured revocationListPath. Without a path, they are kept in memory.
+func (auth *Auth) configureRevocationList() (err error) {
+	if auth.Config.RevocationListSize < 0 {
+		return fmt.Errorf("invalid %s '%d', it must not be negative", ConfRevocationListSize, auth.Config.RevocationListSize)
+	}
+	if auth.Config.RevocationListSize == 0 {
+		auth.Config.RevocationListSize = DefaultAuthConfig().RevocationListSize
+	}
+	if auth.Config.RevocationListPath == "" || !auth.Config.SharedStores {
+		logging.Log().Warnf("Revoked tokens and contracts are only known to this node, nodes behind a load balancer must use the same %s on a volume and set %s", ConfRevocationListPath, ConfSharedStores)
+	}
+	if auth.Config.RevocationListPath == "" {
+		auth.revocationList = expiring.NewMemorySet(auth.Config.RevocationListSize)
+		auth.issuedContracts = expiring.NewMemoryMap(auth.Config.RevocationListSize)
+		return nil
+	}
+	if auth.revocationList, err = expiring.NewBboltSet(auth.Config.RevocationListPath, "revoked", auth.Config.RevocationListSize, auth.Config.SharedStores); err != nil {
+		return err
+	}
+	auth.issuedContracts, err = expiring.NewBboltMap(auth.Config.RevocationListPath, "contracts", auth.Config.RevocationListSize, auth.Config.SharedStores)
+	return err
+}
+
+// configureRefreshTokenStore creates the store of issued refresh tokens in the configured refreshTokenStorePath.
+// Without a path, the refresh tokens are kept in memory.
+func (auth *Auth) configureRefreshTokenStore() (err error) {
+	if auth.Config.RefreshTokenStoreSize < 0 {
+		return fmt.Errorf("invalid %s '%d', it must not be negative", ConfRefreshTokenStoreSize, auth.Config.RefreshTokenStoreSize)
+	}
+	if auth.Config.RefreshTokenStoreSize == 0 {
+		auth.Config.RefreshTokenStoreSize = DefaultAuthConfig().RefreshTokenStoreSize
+	}
+	if auth.Config.RefreshTokenStorePath == "" {
+		auth.refreshTokenStore = expiring.NewMemoryMap(auth.Config.RefreshTokenStoreSize)
+		return nil
+	}
+	auth.refreshTokenStore, err = expiring.NewBboltMap(auth.Config.RefreshTokenStorePath, "refresh", auth.Config.RefreshTokenStoreSize, auth.Config.SharedStores)
+	return err
+}
+
+// checkOAuthIssuer checks the configured oauthIssuer is an absolute http(s) URL without query and fragment, like RFC8414
+// requires of the issuer of the authorization server metadata. A trailing slash is removed.
+func (auth *Auth) checkOAuthIssuer() error {
+	if auth.Config.OAuthIssuer == "" {
+		return nil
+	}
+	issuer, err := url.Parse(auth.Config.OAuthIssuer)
+	if err != nil || (issuer.Scheme != "https" && issuer.Scheme != "http") || issuer.Host == "" || issuer.RawQuery != "" || issuer.Fragment != "" {
+		return fmt.Errorf("invalid %s '%s', it must be an absolute http(s) URL without query and fragment", ConfOAuthIssuer, auth.Config.OAuthIssuer)
+	}
+	auth.Config.OAuthIssuer = strings.TrimSuffix(auth.Config.OAuthIssuer, "/")
+	return nil
+}
+
+// checkSessionLimits checks the config of the rate limit and quota of signing sessions
+func (auth *Auth) checkSessionLimits() error {
+	if auth.Config.SessionRateLimit < 0 {
+		return fmt.Errorf("invalid %s '%d', it must not be negative", ConfSessionRateLimit, auth.Config.SessionRateLimit)
+	}
+	if auth.Config.SessionRateBurst < 0 {
+		return fmt.Errorf("invalid %s '%d', it must not be negative", ConfSessionRateBurst, auth.Config.SessionRateBurst)
+	}
+	if auth.Config.MaxOpenSessions < 0 {
+		return fmt.Errorf("invalid %s '%d', it must not be negative", ConfMaxOpenSessions, auth.Config.MaxOpenSessions)
+	}
+	switch auth.Config.SessionRateLimitBy {
+	case "":
+		auth.Config.SessionRateLimitBy = DefaultAuthConfig().SessionRateLimitBy
+	case ratelimit.ByCertificate, ratelimit.ByLegalEntity:
+	default:
+		return fmt.Errorf("invalid %s '%s', expected %s or %s", ConfSessionRateLimitBy, auth.Config.SessionRateLimitBy, ratelimit.ByCertificate, ratelimit.ByLegalEntity)
+	}
+	return nil
+}
+
+// parseContractTimeZones parses the configured time zones in the form <legal entity>=<IANA time zone name>
+func parseContractTimeZones(values []string) (map[core.PartyID]*time.Location, error) {
+	timeZones := make(map[core.PartyID]*time.Location, len(values))
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid %s entry '%s', expected <legal entity>=<time zone>", ConfContractTimeZones, value)
+		}
+		legalEntity, err := core.ParsePartyID(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry '%s': %w", ConfContractTimeZones, value, err)
+		}
+		location, err := time.LoadLocation(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry '%s': unknown time zone", ConfContractTimeZones, value)
+		}
+		timeZones[legalEntity] = location
+	}
+	return timeZones, nil
+}
+
+// Start starts the background processes of the Auth engine
+func (auth *Auth) Start() error {
+	if auth.sessionReaper != nil {
+		auth.sessionReaper.Start()
+	}
+	if auth.templateWatcher != nil {
+		return auth.templateWatcher.Start()
+	}
+	return nil
+}
+
+// Shutdown stops the background processes of the Auth engine and closes its stores. A store which can not be closed
+// does not keep the others open, the errors of all stores are returned together.
+func (auth *Auth) Shutdown() error {
+	if auth.sessionReaper != nil {
+		auth.sessionReaper.Stop()
+	}
+	if auth.sessionCallbacks != nil {
+		auth.sessionCallbacks.Stop()
+	}
+	var errs []string
+	closeStore := func(name string, close func() error) {
+		if err := close(); err != nil {
+			errs = append(errs, fmt.Sprintf("could not close %s: %v", name, err))
+		}
+	}
+	if auth.sessionStore != nil {
+		closeStore("session store", auth.sessionStore.Close)
+	}
+	if auth.replayCache != nil {
+		closeStore("replay cache", auth.replayCache.Close)
+	}
+	if auth.revocationList != nil {
+		closeStore("revocation list", auth.revocationList.Close)
+	}
+	if auth.issuedContracts != nil {
+		closeStore("issued contracts", auth.issuedContracts.Close)
+	}
+	if auth.refreshTokenStore != nil {
+		closeStore("refresh token store", auth.refreshTokenStore.Close)
+	}
+	closeStore("audit log", auth.auditor.Close)
+	if auth.templateWatcher != nil {
+		closeStore("contract template watcher", auth.templateWatcher.Stop)
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -19,9 +19,15 @@
 package pkg
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/audit"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/expiring"
 	"github.com/nuts-foundation/nuts-auth/pkg/services/validator"
 	crypto "github.com/nuts-foundation/nuts-crypto/pkg"
 	core "github.com/nuts-foundation/nuts-go-core"
@@ -65,6 +71,290 @@ func TestAuth_Configure(t *testing.T) {
 		assert.Equal(t, validator.ErrMissingPublicURL, i.Configure())
 	})
 
+	t.Run("error - invalid contract templates path", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:                  core.ServerEngineMode,
+			PublicUrl:             "url",
+			ContractTemplatesPath: "non-existing",
+		})
+
+		assert.Error(t, i.Configure())
+	})
+
+	t.Run("error - invalid contract time zones", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:              core.ServerEngineMode,
+			PublicUrl:         "url",
+			ContractTimeZones: []string{"Europe/Amsterdam"},
+		})
+
+		assert.EqualError(t, i.Configure(), "invalid contractTimeZones entry 'Europe/Amsterdam', expected <legal entity>=<time zone>")
+	})
+
+	t.Run("error - invalid session store path", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:             core.ServerEngineMode,
+			PublicUrl:        "url",
+			SessionStorePath: filepath.Join(testIo.TestDirectory(t), "non-existing", "sessions.db"),
+		})
+
+		assert.Error(t, i.Configure())
+	})
+
+	t.Run("error - negative session TTL", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:       core.ServerEngineMode,
+			PublicUrl:  "url",
+			SessionTTL: -time.Minute,
+		})
+
+		assert.EqualError(t, i.Configure(), "invalid sessionTTL '-1m0s', it must be positive")
+	})
+
+	t.Run("error - invalid sessionRateLimitBy", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:               core.ServerEngineMode,
+			PublicUrl:          "url",
+			SessionRateLimitBy: "ip",
+		})
+
+		assert.EqualError(t, i.Configure(), "invalid sessionRateLimitBy 'ip', expected certificate or legalEntity")
+	})
+
+	t.Run("error - negative maxOpenSessions", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:            core.ServerEngineMode,
+			PublicUrl:       "url",
+			MaxOpenSessions: -1,
+		})
+
+		assert.EqualError(t, i.Configure(), "invalid maxOpenSessions '-1', it must not be negative")
+	})
+
+	t.Run("error - invalid dummy personas path", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:              core.ServerEngineMode,
+			PublicUrl:         "url",
+			DummyPersonasPath: filepath.Join(testIo.TestDirectory(t), "non-existing.yaml"),
+		})
+
+		assert.Error(t, i.Configure())
+	})
+
+	t.Run("ok - sessions are persisted in the session store path", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "sessions.db")
+		i := testInstance(t, AuthConfig{
+			Mode:                      core.ServerEngineMode,
+			PublicUrl:                 "url",
+			IrmaConfigPath:            "../testdata/irma",
+			SkipAutoUpdateIrmaSchemas: true,
+			ContractValidators:        []string{"dummy"},
+			SessionStorePath:          path,
+		})
+
+		if !assert.NoError(t, i.Configure()) {
+			return
+		}
+		defer i.Shutdown()
+		pointer, err := i.ContractClient().CreateSigningSession(services.CreateSessionRequest{SigningMeans: "dummy", Message: "contract"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.FileExists(t, path)
+		assert.Equal(t, DefaultAuthConfig().SessionTTL, i.Config.SessionTTL)
+		stored, err := i.sessionStore.Get(pointer.SessionID())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "contract", stored.Contract)
+		}
+	})
+
+	t.Run("ok - sessions are recorded in the audit log", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "audit.log")
+		i := testInstance(t, AuthConfig{
+			Mode:                      core.ServerEngineMode,
+			PublicUrl:                 "url",
+			IrmaConfigPath:            "../testdata/irma",
+			SkipAutoUpdateIrmaSchemas: true,
+			ContractValidators:        []string{"dummy"},
+			AuditLogPath:              path,
+		})
+
+		if !assert.NoError(t, i.Configure()) {
+			return
+		}
+		_, err := i.ContractClient().CreateSigningSession(services.CreateSessionRequest{SigningMeans: "dummy", Message: "contract"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, i.Shutdown())
+		file, err := os.Open(path)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer file.Close()
+		result, err := audit.Verify(file, "")
+		if assert.NoError(t, err) {
+			assert.True(t, result.Entries > 0)
+		}
+	})
+
+	t.Run("error - invalid audit log path", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:         core.ServerEngineMode,
+			PublicUrl:    "url",
+			AuditLogPath: filepath.Join(testIo.TestDirectory(t), "non-existing", "audit.log"),
+		})
+
+		assert.Error(t, i.Configure())
+	})
+
+	t.Run("ok - replay cache in the replay cache path", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "replay.db")
+		i := testInstance(t, AuthConfig{
+			Mode:            core.ServerEngineMode,
+			PublicUrl:       "url",
+			ReplayCachePath: path,
+		})
+
+		if !assert.NoError(t, i.Configure()) {
+			return
+		}
+		defer i.Shutdown()
+		assert.FileExists(t, path)
+		assert.Equal(t, DefaultAuthConfig().ReplayCacheSize, i.Config.ReplayCacheSize)
+	})
+
+	t.Run("error - invalid replay cache path", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:            core.ServerEngineMode,
+			PublicUrl:       "url",
+			ReplayCachePath: filepath.Join(testIo.TestDirectory(t), "non-existing", "replay.db"),
+		})
+
+		assert.Error(t, i.Configure())
+	})
+
+	t.Run("error - negative replayCacheSize", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:            core.ServerEngineMode,
+			PublicUrl:       "url",
+			ReplayCacheSize: -1,
+		})
+
+		assert.EqualError(t, i.Configure(), "invalid replayCacheSize '-1', it must not be negative")
+	})
+
+	t.Run("ok - revocation list in the revocation list path", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "revoked.db")
+		i := testInstance(t, AuthConfig{
+			Mode:               core.ServerEngineMode,
+			PublicUrl:          "url",
+			RevocationListPath: path,
+		})
+
+		if !assert.NoError(t, i.Configure()) {
+			return
+		}
+		defer i.Shutdown()
+		assert.FileExists(t, path)
+		assert.Equal(t, DefaultAuthConfig().RevocationListSize, i.Config.RevocationListSize)
+	})
+
+	t.Run("ok - nodes share the revocation list with sharedStores", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "revoked.db")
+		config := AuthConfig{
+			Mode:               core.ServerEngineMode,
+			PublicUrl:          "url",
+			RevocationListPath: path,
+			SharedStores:       true,
+		}
+		node1 := testInstance(t, config)
+		node2 := testInstance(t, config)
+		if !assert.NoError(t, node1.Configure()) || !assert.NoError(t, node2.Configure()) {
+			return
+		}
+		defer node1.Shutdown()
+		defer node2.Shutdown()
+
+		_, _ = node1.revocationList.Add("token", time.Now().Add(time.Minute))
+		_ = node1.issuedContracts.Put("hash", []byte("contract"), time.Now().Add(time.Minute))
+
+		revoked, _ := node2.revocationList.Contains("token")
+		assert.True(t, revoked)
+		issued, _ := node2.issuedContracts.Get("hash")
+		assert.Equal(t, []byte("contract"), issued)
+	})
+
+	t.Run("error - invalid revocation list path", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:               core.ServerEngineMode,
+			PublicUrl:          "url",
+			RevocationListPath: filepath.Join(testIo.TestDirectory(t), "non-existing", "revoked.db"),
+		})
+
+		assert.Error(t, i.Configure())
+	})
+
+	t.Run("error - negative revocationListSize", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:               core.ServerEngineMode,
+			PublicUrl:          "url",
+			RevocationListSize: -1,
+		})
+
+		assert.EqualError(t, i.Configure(), "invalid revocationListSize '-1', it must not be negative")
+	})
+
+	t.Run("ok - refresh tokens in the refresh token store path", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "refresh.db")
+		i := testInstance(t, AuthConfig{
+			Mode:                  core.ServerEngineMode,
+			PublicUrl:             "url",
+			RefreshTokenStorePath: path,
+		})
+
+		if !assert.NoError(t, i.Configure()) {
+			return
+		}
+		defer i.Shutdown()
+		assert.FileExists(t, path)
+		assert.Equal(t, DefaultAuthConfig().RefreshTokenStoreSize, i.Config.RefreshTokenStoreSize)
+	})
+
+	t.Run("error - negative refreshTokenStoreSize", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:                  core.ServerEngineMode,
+			PublicUrl:             "url",
+			RefreshTokenStoreSize: -1,
+		})
+
+		assert.EqualError(t, i.Configure(), "invalid refreshTokenStoreSize '-1', it must not be negative")
+	})
+
+	t.Run("ok - trailing slash of oauthIssuer is removed", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:        core.ServerEngineMode,
+			PublicUrl:   "url",
+			OAuthIssuer: "https://nuts.example.com/",
+		})
+
+		if !assert.NoError(t, i.Configure()) {
+			return
+		}
+		defer i.Shutdown()
+		assert.Equal(t, "https://nuts.example.com", i.Config.OAuthIssuer)
+	})
+
+	t.Run("error - invalid oauthIssuer", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{
+			Mode:        core.ServerEngineMode,
+			PublicUrl:   "url",
+			OAuthIssuer: "nuts.example.com",
+		})
+
+		assert.EqualError(t, i.Configure(), "invalid oauthIssuer 'nuts.example.com', it must be an absolute http(s) URL without query and fragment")
+	})
+
 	t.Run("error - IRMA config failure", func(t *testing.T) {
 		i := testInstance(t, AuthConfig{
 			Mode:                      core.ServerEngineMode,
@@ -80,6 +370,64 @@ func TestAuth_Configure(t *testing.T) {
 	})
 }
 
+// closeRecorder is a Set which records whether it is closed and fails to close when err is set
+type closeRecorder struct {
+	expiring.Set
+	err    error
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return c.err
+}
+
+func TestAuth_Shutdown(t *testing.T) {
+	registerTestDependencies(t)
+	t.Run("error - the other stores are closed when a store can not be closed", func(t *testing.T) {
+		i := testInstance(t, AuthConfig{Mode: core.ServerEngineMode, PublicUrl: "url"})
+		if !assert.NoError(t, i.Configure()) {
+			return
+		}
+		replayCache := &closeRecorder{Set: expiring.NewMemorySet(1), err: errors.New("disk failure")}
+		revocationList := &closeRecorder{Set: expiring.NewMemorySet(1), err: errors.New("disk failure")}
+		i.replayCache = replayCache
+		i.revocationList = revocationList
+
+		err := i.Shutdown()
+
+		assert.EqualError(t, err, "could not close replay cache: disk failure, could not close revocation list: disk failure")
+		assert.True(t, replayCache.closed)
+		assert.True(t, revocationList.closed)
+	})
+}
+
+func Test_parseContractTimeZones(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		timeZones, err := parseContractTimeZones([]string{"urn:oid:2.16.840.1.113883.2.4.6.1:00000001=America/Kralendijk"})
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		legalEntity, _ := core.NewPartyID("2.16.840.1.113883.2.4.6.1", "00000001")
+		if assert.Contains(t, timeZones, legalEntity) {
+			assert.Equal(t, "America/Kralendijk", timeZones[legalEntity].String())
+		}
+	})
+
+	t.Run("error - invalid legal entity", func(t *testing.T) {
+		_, err := parseContractTimeZones([]string{"00000001=America/Kralendijk"})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("error - unknown time zone", func(t *testing.T) {
+		_, err := parseContractTimeZones([]string{"urn:oid:2.16.840.1.113883.2.4.6.1:00000001=Europe/Atlantis"})
+
+		assert.EqualError(t, err, "invalid contractTimeZones entry 'urn:oid:2.16.840.1.113883.2.4.6.1:00000001=Europe/Atlantis': unknown time zone")
+	})
+}
+
 const vendorID = "urn:oid:1.3.6.1.4.1.54851.4:vendorId"
 
 // RegisterTestDependencies registers minimal dependencies
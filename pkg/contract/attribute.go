@@ -0,0 +1,210 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package contract
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AttributeType defines which values are allowed for a template attribute
+type AttributeType string
+
+const (
+	// OrganizationNameAttribute is used for names of organizations like the legal entity
+	OrganizationNameAttribute AttributeType = "organizationName"
+	// DateTimeAttribute is used for moments in time which are formatted in the language and time zone of the template
+	DateTimeAttribute AttributeType = "datetime"
+	// EnumAttribute is used for attributes which only allow a fixed set of values
+	EnumAttribute AttributeType = "enum"
+	// TextAttribute is used for free text with a maximum length
+	TextAttribute AttributeType = "text"
+)
+
+// Escaping defines how the value of a template attribute is escaped in the contract text
+type Escaping string
+
+const (
+	// EscapeHTML escapes the characters <, >, &, ' and " like mustache does by default
+	EscapeHTML Escaping = "html"
+	// EscapeNone inserts the value in the contract text as is
+	EscapeNone Escaping = "none"
+)
+
+// DefaultMaxLength is the maximum length of organization names and text attributes which do not declare a max length
+const DefaultMaxLength = 256
+
+// Attribute declares the type of a template attribute and how its value is escaped in the contract text
+type Attribute struct {
+	Type AttributeType `json:"type"`
+	// Values contains the allowed values of an enum attribute
+	Values []string `json:"values,omitempty"`
+	// MaxLength is the maximum amount of characters of an organization name or text attribute, defaults to DefaultMaxLength
+	MaxLength int `json:"max_length,omitempty"`
+	// Escaping defaults to EscapeHTML
+	Escaping Escaping `json:"escaping,omitempty"`
+}
+
+// standardAttributes contains the attributes of the standard templates. Attributes which are not declared by a
+// template and are not in this list are text attributes.
+var standardAttributes = map[string]Attribute{
+	ValidFromAttr:   {Type: DateTimeAttribute},
+	ValidToAttr:     {Type: DateTimeAttribute},
+	LegalEntityAttr: {Type: OrganizationNameAttribute},
+	ActingPartyAttr: {Type: OrganizationNameAttribute},
+}
+
+// PurposesOfUse contains the HL7 PurposeOfUse codes which are accepted by the standard patient context templates
+var PurposesOfUse = []string{"TREAT", "ETREAT", "COC", "PATRQT"}
+
+// patientContextAttributes contains the attributes of the standard patient context templates
+var patientContextAttributes = map[string]Attribute{
+	SubjectAttr:      {Type: TextAttribute, MaxLength: 128},
+	PurposeOfUseAttr: {Type: EnumAttribute, Values: PurposesOfUse},
+}
+
+// ErrInvalidAttributeValue is returned when the value of a template attribute does not match its declared type
+var ErrInvalidAttributeValue = errors.New("invalid attribute value")
+
+// attribute returns the declaration of the attribute with the given name
+func (c Template) attribute(name string) Attribute {
+	if attribute, ok := c.Attributes[name]; ok {
+		return attribute
+	}
+	if attribute, ok := standardAttributes[name]; ok {
+		return attribute
+	}
+	return Attribute{Type: TextAttribute}
+}
+
+// compileAttributes checks the attributes declared by the template
+func (c Template) compileAttributes(templateAttributes []string) error {
+	for name, attribute := range c.Attributes {
+		if !containsString(templateAttributes, name) {
+			return fmt.Errorf("%w: declared attribute '%s' is not used in the template text of %s", ErrInvalidContractTemplate, name, c.Identifier())
+		}
+		if standard, ok := standardAttributes[name]; ok && standard.Type == DateTimeAttribute && attribute.Type != DateTimeAttribute {
+			return fmt.Errorf("%w: attribute '%s' of %s must be of type '%s'", ErrInvalidContractTemplate, name, c.Identifier(), DateTimeAttribute)
+		}
+		switch attribute.Type {
+		case OrganizationNameAttribute, DateTimeAttribute, TextAttribute:
+			if len(attribute.Values) > 0 {
+				return fmt.Errorf("%w: attribute '%s' of %s can only have values when it is of type '%s'", ErrInvalidContractTemplate, name, c.Identifier(), EnumAttribute)
+			}
+		case EnumAttribute:
+			if len(attribute.Values) == 0 {
+				return fmt.Errorf("%w: enum attribute '%s' of %s must have values", ErrInvalidContractTemplate, name, c.Identifier())
+			}
+		default:
+			return fmt.Errorf("%w: attribute '%s' of %s has unknown type '%s'", ErrInvalidContractTemplate, name, c.Identifier(), attribute.Type)
+		}
+		switch attribute.Escaping {
+		case "", EscapeHTML, EscapeNone:
+		default:
+			return fmt.Errorf("%w: attribute '%s' of %s has unknown escaping '%s'", ErrInvalidContractTemplate, name, c.Identifier(), attribute.Escaping)
+		}
+		if attribute.MaxLength < 0 {
+			return fmt.Errorf("%w: attribute '%s' of %s has a negative max length", ErrInvalidContractTemplate, name, c.Identifier())
+		}
+	}
+	return nil
+}
+
+// validateValue checks if the value of an attribute matches its declared type
+func (c Template) validateValue(name string, value string) error {
+	attribute := c.attribute(name)
+	if value == "" {
+		return fmt.Errorf("%w: value of '%s' is empty", ErrInvalidAttributeValue, name)
+	}
+	if !utf8.ValidString(value) {
+		return fmt.Errorf("%w: value of '%s' is not valid UTF-8", ErrInvalidAttributeValue, name)
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%w: value of '%s' contains control characters", ErrInvalidAttributeValue, name)
+		}
+	}
+
+	switch attribute.Type {
+	case OrganizationNameAttribute:
+		if strings.TrimSpace(value) != value {
+			return fmt.Errorf("%w: value of '%s' has leading or trailing whitespace", ErrInvalidAttributeValue, name)
+		}
+		return attribute.validateLength(name, value)
+	case DateTimeAttribute:
+		location, err := c.timeLocation()
+		if err != nil {
+			return err
+		}
+		if _, err := parseTime(value, c.Language, location); err != nil {
+			return fmt.Errorf("%w: value of '%s' is not a valid datetime: %s", ErrInvalidAttributeValue, name, err)
+		}
+	case EnumAttribute:
+		if !containsString(attribute.Values, value) {
+			return fmt.Errorf("%w: value of '%s' must be one of [%s]", ErrInvalidAttributeValue, name, strings.Join(attribute.Values, ", "))
+		}
+	default:
+		return attribute.validateLength(name, value)
+	}
+	return nil
+}
+
+func (a Attribute) validateLength(name string, value string) error {
+	maxLength := a.MaxLength
+	if maxLength == 0 {
+		maxLength = DefaultMaxLength
+	}
+	if utf8.RuneCountInString(value) > maxLength {
+		return fmt.Errorf("%w: value of '%s' is longer than %d characters", ErrInvalidAttributeValue, name, maxLength)
+	}
+	return nil
+}
+
+// sampleValue returns a valid value for the attribute which is used to validate templates
+func (a Attribute) sampleValue(name string) string {
+	if a.Type == EnumAttribute && len(a.Values) > 0 {
+		return a.Values[0]
+	}
+	sample := []rune("[" + name + "]")
+	if a.MaxLength > 0 && len(sample) > a.MaxLength {
+		sample = sample[:a.MaxLength]
+	}
+	return string(sample)
+}
+
+// escape escapes the value of an attribute for use in the contract text
+func (a Attribute) escape(value string) string {
+	if a.Escaping == EscapeNone {
+		return value
+	}
+	return template.HTMLEscapeString(value)
+}
+
+// unescape reverts the escaping of an attribute value which was extracted from the contract text
+func (a Attribute) unescape(value string) string {
+	if a.Escaping == EscapeNone {
+		return value
+	}
+	return html.UnescapeString(value)
+}
@@ -0,0 +1,178 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package contract
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func attributeTestTemplate(attributes map[string]Attribute) *Template {
+	return &Template{
+		Type:       "Opname",
+		Language:   "NL",
+		Version:    "v1",
+		Template:   "NL:Opname:v1 Namens {{legal_entity}} voor {{reason}}, geldig van {{valid_from}} tot {{valid_to}}.",
+		Attributes: attributes,
+	}
+}
+
+func TestTemplate_compileAttributes(t *testing.T) {
+	t.Run("ok - no declared attributes", func(t *testing.T) {
+		assert.NoError(t, attributeTestTemplate(nil).compile())
+	})
+
+	t.Run("ok - declared attributes", func(t *testing.T) {
+		template := attributeTestTemplate(map[string]Attribute{
+			"reason":        {Type: EnumAttribute, Values: []string{"opname", "ontslag"}},
+			LegalEntityAttr: {Type: TextAttribute, MaxLength: 10, Escaping: EscapeNone},
+		})
+
+		assert.NoError(t, template.compile())
+	})
+
+	tests := []struct {
+		name       string
+		attributes map[string]Attribute
+		expected   string
+	}{
+		{"unused attribute", map[string]Attribute{"ward": {Type: TextAttribute}}, "declared attribute 'ward' is not used in the template text of NL:Opname:v1"},
+		{"datetime attribute with other type", map[string]Attribute{ValidFromAttr: {Type: TextAttribute}}, "attribute 'valid_from' of NL:Opname:v1 must be of type 'datetime'"},
+		{"unknown type", map[string]Attribute{"reason": {Type: "number"}}, "attribute 'reason' of NL:Opname:v1 has unknown type 'number'"},
+		{"values for text attribute", map[string]Attribute{"reason": {Type: TextAttribute, Values: []string{"opname"}}}, "attribute 'reason' of NL:Opname:v1 can only have values when it is of type 'enum'"},
+		{"enum without values", map[string]Attribute{"reason": {Type: EnumAttribute}}, "enum attribute 'reason' of NL:Opname:v1 must have values"},
+		{"unknown escaping", map[string]Attribute{"reason": {Type: TextAttribute, Escaping: "url"}}, "attribute 'reason' of NL:Opname:v1 has unknown escaping 'url'"},
+		{"negative max length", map[string]Attribute{"reason": {Type: TextAttribute, MaxLength: -1}}, "attribute 'reason' of NL:Opname:v1 has a negative max length"},
+	}
+	for _, test := range tests {
+		t.Run("error - "+test.name, func(t *testing.T) {
+			err := attributeTestTemplate(test.attributes).compile()
+
+			assert.True(t, errors.Is(err, ErrInvalidContractTemplate))
+			assert.EqualError(t, err, "invalid contract template: "+test.expected)
+		})
+	}
+}
+
+func TestTemplate_validateValue(t *testing.T) {
+	template := attributeTestTemplate(map[string]Attribute{
+		"reason": {Type: EnumAttribute, Values: []string{"opname", "ontslag"}},
+		"remark": {Type: TextAttribute, MaxLength: 5},
+	})
+
+	valid := map[string]string{
+		LegalEntityAttr: "Zorg & Co. B.V.",
+		ValidFromAttr:   "dinsdag, 1 oktober 2019 13:30:42",
+		"reason":        "ontslag",
+		"remark":        "kort",
+		"undeclared":    strings.Repeat("a", DefaultMaxLength),
+	}
+	for name, value := range valid {
+		t.Run("ok - "+name, func(t *testing.T) {
+			assert.NoError(t, template.validateValue(name, value))
+		})
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{LegalEntityAttr, "", "value of 'legal_entity' is empty"},
+		{LegalEntityAttr, "Zorg\xff", "value of 'legal_entity' is not valid UTF-8"},
+		{LegalEntityAttr, "Zorg\nB.V.", "value of 'legal_entity' contains control characters"},
+		{LegalEntityAttr, " Zorg B.V.", "value of 'legal_entity' has leading or trailing whitespace"},
+		{LegalEntityAttr, strings.Repeat("a", DefaultMaxLength+1), "value of 'legal_entity' is longer than 256 characters"},
+		{ValidFromAttr, "gisteren", "value of 'valid_from' is not a valid datetime"},
+		{"reason", "overplaatsing", "value of 'reason' must be one of [opname, ontslag]"},
+		{"remark", "te lang", "value of 'remark' is longer than 5 characters"},
+	}
+	for _, test := range tests {
+		t.Run("error - "+test.expected, func(t *testing.T) {
+			err := template.validateValue(test.name, test.value)
+
+			assert.True(t, errors.Is(err, ErrInvalidAttributeValue))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.expected)
+			}
+		})
+	}
+}
+
+func TestAttribute_escape(t *testing.T) {
+	t.Run("ok - html escaping is reverted", func(t *testing.T) {
+		attribute := Attribute{Type: TextAttribute}
+		escaped := attribute.escape(`Zorg & "Co" <B.V.>`)
+
+		assert.Equal(t, "Zorg &amp; &#34;Co&#34; &lt;B.V.&gt;", escaped)
+		assert.Equal(t, `Zorg & "Co" <B.V.>`, attribute.unescape(escaped))
+	})
+
+	t.Run("ok - no escaping", func(t *testing.T) {
+		attribute := Attribute{Type: TextAttribute, Escaping: EscapeNone}
+
+		assert.Equal(t, "Zorg & Co", attribute.escape("Zorg & Co"))
+		assert.Equal(t, "Zorg &amp; Co", attribute.unescape("Zorg &amp; Co"))
+	})
+}
+
+func TestTemplate_RenderAttributes(t *testing.T) {
+	template := attributeTestTemplate(nil)
+	if !assert.NoError(t, template.compile()) {
+		return
+	}
+	store := TemplateStore{"NL": {"Opname": {"v1": template}}}
+
+	t.Run("ok - values are extracted unchanged", func(t *testing.T) {
+		contract, err := template.Render(map[string]string{LegalEntityAttr: "Zorg & Co.", "reason": "opname"}, NowFunc(), 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, contract.RawContractText, "Namens Zorg &amp; Co. voor opname")
+
+		parsed, err := ParseContractString(contract.RawContractText, store)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Zorg & Co.", parsed.Params[LegalEntityAttr])
+		}
+	})
+
+	t.Run("error - invalid value", func(t *testing.T) {
+		_, err := template.Render(map[string]string{LegalEntityAttr: "Zorg\nB.V.", "reason": "opname"}, NowFunc(), 0)
+
+		assert.True(t, errors.Is(err, ErrInvalidAttributeValue))
+	})
+
+	t.Run("error - value which shifts the contract text", func(t *testing.T) {
+		_, err := template.Render(map[string]string{LegalEntityAttr: "Zorg B.V.", "reason": "opname voor spoed"}, NowFunc(), 0)
+
+		assert.True(t, errors.Is(err, ErrInvalidAttributeValue))
+	})
+
+	t.Run("error - parsed text with invalid value", func(t *testing.T) {
+		contract, _ := template.Render(map[string]string{LegalEntityAttr: "Zorg B.V.", "reason": "opname"}, NowFunc(), 0)
+		text := strings.Replace(contract.RawContractText, "Namens Zorg B.V.", "Namens  Zorg B.V.", 1)
+
+		_, err := ParseContractString(text, store)
+
+		assert.True(t, errors.Is(err, ErrInvalidContractText))
+	})
+}
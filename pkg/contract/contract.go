@@ -19,8 +19,12 @@
 package contract
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/goodsign/monday"
@@ -31,10 +35,13 @@ type Contract struct {
 	RawContractText string
 	Template        *Template
 	Params          map[string]string
+	// Warnings contains remarks about a drawn up contract, like the use of a deprecated template
+	Warnings []string
 }
 
-// ParseContractString parses a raw string, finds the contract from the store and extracts the params
-// Note: It does not verify the params
+// ParseContractString parses a raw string, finds the contract from the store and extracts the params.
+// The params are validated against the attribute types of the template.
+// Note: It does not verify the validity period of the contract
 func ParseContractString(rawContractText string, contractTemplates TemplateStore) (*Contract, error) {
 
 	// first, find the contract Template
@@ -59,6 +66,22 @@ func ParseContractString(rawContractText string, contractTemplates TemplateStore
 	return contract, nil
 }
 
+// ErrContractHashMismatch is returned when a signed contract does not match the contract which was drawn up
+var ErrContractHashMismatch = errors.New("signed contract does not match the expected contract hash")
+
+// Hash returns a stable identifier of the contract: the hex encoded SHA-256 hash over the template identifier and the
+// normalized contract text. The text is normalized by replacing every sequence of whitespace by a single space, so the
+// hash does not change when the contract text is wrapped or indented by a means.
+func (sc Contract) Hash() string {
+	identifier := ""
+	if sc.Template != nil {
+		identifier = sc.Template.Identifier()
+	}
+	normalized := strings.Join(strings.Fields(sc.RawContractText), " ")
+	sum := sha256.Sum256([]byte(identifier + "\n" + normalized))
+	return hex.EncodeToString(sum[:])
+}
+
 func (sc *Contract) initParams() error {
 	// extract the params
 	r, _ := regexp.Compile(sc.Template.Regexp)
@@ -74,16 +97,52 @@ func (sc *Contract) initParams() error {
 
 	sc.Params = make(map[string]string, len(matches))
 	for idx, match := range matches {
-		sc.Params[sc.Template.TemplateAttributes[idx]] = string(match)
+		name := sc.Template.TemplateAttributes[idx]
+		value := sc.Template.attribute(name).unescape(string(match))
+		if err := sc.Template.validateValue(name, value); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidContractText, err)
+		}
+		sc.Params[name] = value
 	}
 	return nil
 }
 
 var ErrInvalidPeriod = fmt.Errorf("%w: invalid period", ErrInvalidContractText)
 
-// VerifyForGivenTime checks if the contract is valid for the given moment in time
-// TODO: support of different time zones: https://github.com/nuts-foundation/nuts-auth/issues/152
+// VerifyForGivenTime checks if the contract is valid for the given moment in time.
+// The validity dates are interpreted in the time zone of the template unless they contain an UTC offset.
+// Contracts of a template which is sunset at the given moment are invalid.
 func (sc Contract) VerifyForGivenTime(checkTime time.Time) error {
+	if sc.Template.IsSunset(checkTime) {
+		return sc.Template.sunsetError()
+	}
+
+	validFrom, validTo, err := sc.validityPeriod()
+	if err != nil {
+		return err
+	}
+
+	if checkTime.Before(*validFrom) {
+		return fmt.Errorf("%w: contract is not yet valid", ErrInvalidPeriod)
+	}
+	if checkTime.After(*validTo) {
+		return fmt.Errorf("%w: contract is expired", ErrInvalidPeriod)
+	}
+
+	return nil
+}
+
+// ValidTo returns the moment until which the contract is valid
+func (sc Contract) ValidTo() (time.Time, error) {
+	_, validTo, err := sc.validityPeriod()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return *validTo, nil
+}
+
+// validityPeriod parses the validity dates of the contract
+func (sc Contract) validityPeriod() (*time.Time, *time.Time, error) {
 	var (
 		err                      error
 		ok                       bool
@@ -91,39 +150,36 @@ func (sc Contract) VerifyForGivenTime(checkTime time.Time) error {
 		validFromStr, validToStr string
 	)
 
+	location, err := sc.Template.timeLocation()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidContractText, err)
+	}
+
 	if validFromStr, ok = sc.Params[ValidFromAttr]; !ok {
-		return fmt.Errorf("%w: value for [%s] is missing", ErrInvalidContractText, ValidFromAttr)
+		return nil, nil, fmt.Errorf("%w: value for [%s] is missing", ErrInvalidContractText, ValidFromAttr)
 	}
 
-	validFrom, err = parseTime(validFromStr, sc.Template.Language)
+	validFrom, err = parseTime(validFromStr, sc.Template.Language, location)
 	if err != nil {
-		return fmt.Errorf("%w: unable to parse [%s]: %s", ErrInvalidContractText, ValidFromAttr, err)
+		return nil, nil, fmt.Errorf("%w: unable to parse [%s]: %s", ErrInvalidContractText, ValidFromAttr, err)
 
 	}
 
 	if validToStr, ok = sc.Params[ValidToAttr]; !ok {
-		return fmt.Errorf("%w: value for [%s] is missing", ErrInvalidContractText, ValidToAttr)
+		return nil, nil, fmt.Errorf("%w: value for [%s] is missing", ErrInvalidContractText, ValidToAttr)
 	}
 
-	validTo, err = parseTime(validToStr, sc.Template.Language)
+	validTo, err = parseTime(validToStr, sc.Template.Language, location)
 	if err != nil {
-		return fmt.Errorf("%w: unable to parse [%s]: %s", ErrInvalidContractText, ValidToAttr, err)
+		return nil, nil, fmt.Errorf("%w: unable to parse [%s]: %s", ErrInvalidContractText, ValidToAttr, err)
 	}
 
 	// All parsed, check time range
 	if validFrom.After(*validTo) {
-		return fmt.Errorf("%w: [%s] must become before [%s]", ErrInvalidPeriod, ValidFromAttr, ValidToAttr)
-	}
-
-	amsterdamLocation, _ := time.LoadLocation(AmsterdamTimeZone)
-	if checkTime.In(amsterdamLocation).Before(*validFrom) {
-		return fmt.Errorf("%w: contract is not yet valid", ErrInvalidPeriod)
-	}
-	if checkTime.In(amsterdamLocation).After(*validTo) {
-		return fmt.Errorf("%w: contract is expired", ErrInvalidPeriod)
+		return nil, nil, fmt.Errorf("%w: [%s] must become before [%s]", ErrInvalidPeriod, ValidFromAttr, ValidToAttr)
 	}
 
-	return nil
+	return validFrom, validTo, nil
 }
 
 // Verify verifies the params with the template
@@ -132,17 +188,29 @@ func (sc Contract) Verify() error {
 	return sc.VerifyForGivenTime(now)
 }
 
-// parseTime parses the given timeStr in context of the Europe/Amsterdam time zone and uses the given language.
-// Note that currently only the language "NL" is supported.
-// TODO: support of different time zones: https://github.com/nuts-foundation/nuts-auth/issues/152
-func parseTime(timeStr string, _ Language) (*time.Time, error) {
-	contractIssuerTimezone, _ := time.LoadLocation(AmsterdamTimeZone)
-	// TODO: add support for other languages
-	parsedTime, err := monday.ParseInLocation(timeLayout, timeStr, contractIssuerTimezone, monday.LocaleNlNL)
+// parseTime parses the given timeStr using the locale of the given language. A timeStr with an UTC offset is parsed
+// using that offset, otherwise it is interpreted in the given location.
+// Contracts which are not in Dutch fall back to the Dutch locale, since these were rendered with Dutch day and month names in the past.
+func parseTime(timeStr string, language Language, location *time.Location) (*time.Time, error) {
+	locale, ok := language.locale()
+	if !ok {
+		return nil, fmt.Errorf("invalid time string [%v]: unsupported language '%s'", timeStr, language)
+	}
+	parse := func(locale monday.Locale) (time.Time, error) {
+		if parsedTime, err := monday.ParseInLocation(timeLayoutWithOffset, timeStr, location, locale); err == nil {
+			return parsedTime, nil
+		}
+		return monday.ParseInLocation(timeLayout, timeStr, location, locale)
+	}
+	parsedTime, err := parse(locale)
+	if err != nil && locale != monday.LocaleNlNL {
+		parsedTime, err = parse(monday.LocaleNlNL)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("invalid time string [%v]: %w", timeStr, err)
 	}
 	return &parsedTime, nil
 }
 
+// AmsterdamTimeZone is the time zone of templates which do not define their own time zone
 const AmsterdamTimeZone = "Europe/Amsterdam"
@@ -19,6 +19,7 @@
 package contract
 
 import (
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -31,6 +32,7 @@ import (
 func TestContract_extractParams(t *testing.T) {
 	template := &Template{
 		Type:               "Simple",
+		Language:           "NL",
 		Template:           "ik geef toestemming voor {{voor}} aan {{wie}}.",
 		TemplateAttributes: []string{"voor", "wie"},
 		Regexp:             `^ik geef toestemming voor (.*) aan (.*)\.$`,
@@ -80,6 +82,7 @@ func TestContract_extractParams(t *testing.T) {
 func TestContract_Verify(t *testing.T) {
 	template := &Template{
 		Type:               "Simple",
+		Language:           "NL",
 		Template:           "ik geef toestemming van {{valid_from}} tot {{valid_to}}.",
 		TemplateAttributes: []string{ValidFromAttr, ValidToAttr},
 	}
@@ -211,3 +214,68 @@ func TestContract_Verify(t *testing.T) {
 
 	})
 }
+
+func TestContract_ValidTo(t *testing.T) {
+	template := &Template{
+		Type:               "Simple",
+		Language:           "NL",
+		Template:           "ik geef toestemming van {{valid_from}} tot {{valid_to}}.",
+		TemplateAttributes: []string{ValidFromAttr, ValidToAttr},
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		contract := Contract{
+			Template: template,
+			Params:   map[string]string{ValidFromAttr: "maandag, 1 juni 2020 12:00:00", ValidToAttr: "maandag, 1 juni 2020 13:00:00"},
+		}
+
+		validTo, err := contract.ValidTo()
+
+		if assert.NoError(t, err) {
+			amsterdam, _ := time.LoadLocation(AmsterdamTimeZone)
+			assert.True(t, time.Date(2020, 6, 1, 13, 0, 0, 0, amsterdam).Equal(validTo))
+		}
+	})
+
+	t.Run("error - missing valid_to", func(t *testing.T) {
+		contract := Contract{
+			Template: template,
+			Params:   map[string]string{ValidFromAttr: "maandag, 1 juni 2020 12:00:00"},
+		}
+
+		_, err := contract.ValidTo()
+
+		assert.True(t, errors.Is(err, ErrInvalidContractText))
+	})
+}
+
+func TestContract_Hash(t *testing.T) {
+	template := StandardContractTemplates.Get("PractitionerLogin", "EN", "v3")
+	text := "EN:PractitionerLogin:v3 I hereby declare to act on behalf of verpleeghuis De nootjes. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00."
+	contract := Contract{RawContractText: text, Template: template}
+
+	t.Run("ok - hash is stable", func(t *testing.T) {
+		hash := contract.Hash()
+
+		assert.Len(t, hash, 64)
+		assert.Equal(t, hash, Contract{RawContractText: text, Template: template}.Hash())
+	})
+
+	t.Run("ok - whitespace is normalized", func(t *testing.T) {
+		wrapped := Contract{RawContractText: " " + strings.Replace(text, " This declaration", "\n  This declaration", 1) + "\n", Template: template}
+
+		assert.Equal(t, contract.Hash(), wrapped.Hash())
+	})
+
+	t.Run("ok - other text gives another hash", func(t *testing.T) {
+		other := Contract{RawContractText: strings.Replace(text, "De nootjes", "De notenboom", 1), Template: template}
+
+		assert.NotEqual(t, contract.Hash(), other.Hash())
+	})
+
+	t.Run("ok - other template gives another hash", func(t *testing.T) {
+		other := Contract{RawContractText: text, Template: StandardContractTemplates.Get("PractitionerLogin", "EN", "v1")}
+
+		assert.NotEqual(t, contract.Hash(), other.Hash())
+	})
+}
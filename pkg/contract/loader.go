@@ -0,0 +1,136 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// templateDefinition contains the definition of a contract template as it is read from a file.
+type templateDefinition struct {
+	Type             Type                           `json:"type" yaml:"type"`
+	Version          Version                        `json:"version" yaml:"version"`
+	Language         Language                       `json:"language" yaml:"language"`
+	SignerAttributes []string                       `json:"signerAttributes" yaml:"signerAttributes"`
+	Template         string                         `json:"template" yaml:"template"`
+	TimeZone         string                         `json:"timeZone" yaml:"timeZone"`
+	DeprecatedFrom   *time.Time                     `json:"deprecatedFrom" yaml:"deprecatedFrom"`
+	SunsetAt         *time.Time                     `json:"sunsetAt" yaml:"sunsetAt"`
+	Attributes       map[string]attributeDefinition `json:"attributes" yaml:"attributes"`
+}
+
+// attributeDefinition contains the declaration of a template attribute as it is read from a file.
+type attributeDefinition struct {
+	Type      AttributeType `json:"type" yaml:"type"`
+	Values    []string      `json:"values" yaml:"values"`
+	MaxLength int           `json:"maxLength" yaml:"maxLength"`
+	Escaping  Escaping      `json:"escaping" yaml:"escaping"`
+}
+
+// LoadTemplateStore reads the contract template definitions from the given directory and merges them with the
+// StandardContractTemplates. Every .json, .yaml or .yml file in the directory must contain a single template definition,
+// other files are ignored. When a definition does not list its signerAttributes, the StandardSignerAttributes are used.
+// If path is empty, a copy of the StandardContractTemplates is returned.
+func LoadTemplateStore(path string) (TemplateStore, error) {
+	store := StandardContractTemplates.copy()
+	if path == "" {
+		return store, nil
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read contract templates directory: %w", err)
+	}
+	for _, file := range files {
+		if file.IsDir() || !isTemplateFile(file.Name()) {
+			continue
+		}
+		template, err := ReadTemplateFile(filepath.Join(path, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := store.add(template); err != nil {
+			return nil, fmt.Errorf("unable to load contract template %s: %w", file.Name(), err)
+		}
+	}
+	return store, nil
+}
+
+// ReadTemplateFile reads a single contract template definition in JSON or YAML format from the given file.
+func ReadTemplateFile(fileName string) (*Template, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read contract template %s: %w", filepath.Base(fileName), err)
+	}
+	definition := templateDefinition{}
+	if strings.ToLower(filepath.Ext(fileName)) == ".json" {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&definition)
+	} else {
+		err = yaml.UnmarshalStrict(data, &definition)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to load contract template %s: %w: %s", filepath.Base(fileName), ErrInvalidContractTemplate, err)
+	}
+	if definition.SignerAttributes == nil {
+		definition.SignerAttributes = StandardSignerAttributes
+	}
+	template := &Template{
+		Type:             definition.Type,
+		Version:          definition.Version,
+		Language:         definition.Language,
+		SignerAttributes: definition.SignerAttributes,
+		Template:         strings.TrimSpace(definition.Template),
+		TimeZone:         definition.TimeZone,
+		DeprecatedFrom:   definition.DeprecatedFrom,
+		SunsetAt:         definition.SunsetAt,
+	}
+	if len(definition.Attributes) > 0 {
+		template.Attributes = make(map[string]Attribute, len(definition.Attributes))
+		for name, attribute := range definition.Attributes {
+			template.Attributes[name] = Attribute{
+				Type:      attribute.Type,
+				Values:    attribute.Values,
+				MaxLength: attribute.MaxLength,
+				Escaping:  attribute.Escaping,
+			}
+		}
+	}
+	if err := template.compile(); err != nil {
+		return nil, fmt.Errorf("unable to load contract template %s: %w", filepath.Base(fileName), err)
+	}
+	return template, nil
+}
+
+func isTemplateFile(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package contract
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	testIo "github.com/nuts-foundation/nuts-go-test/io"
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlTemplate = `
+type: Opname
+version: v1
+language: NL
+signerAttributes:
+  - .gemeente.personalData.firstnames
+deprecatedFrom: 2020-10-01T00:00:00Z
+attributes:
+  ward:
+    type: enum
+    values: [Noord, Zuid]
+  legal_entity:
+    type: organizationName
+    maxLength: 64
+    escaping: none
+template: |
+  NL:Opname:v1 Ik verklaar een opname te doen namens {{legal_entity}} op afdeling {{ward}}. Deze verklaring is geldig van {{valid_from}} tot {{valid_to}}.
+`
+
+const jsonTemplate = `{
+  "type": "Intake",
+  "version": "v2",
+  "language": "EN",
+  "timeZone": "America/Kralendijk",
+  "template": "EN:Intake:v2 I declare to perform an intake on behalf of {{legal_entity}} from {{valid_from}} until {{valid_to}}."
+}`
+
+func writeTemplateFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadTemplateStore(t *testing.T) {
+	t.Run("ok - no path returns the standard templates", func(t *testing.T) {
+		store, err := LoadTemplateStore("")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, StandardContractTemplates, store)
+	})
+
+	t.Run("ok - templates are merged with the standard templates", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		writeTemplateFile(t, dir, "opname.yaml", yamlTemplate)
+		writeTemplateFile(t, dir, "intake.json", jsonTemplate)
+		writeTemplateFile(t, dir, "README.md", "not a template")
+
+		store, err := LoadTemplateStore(dir)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NotNil(t, store.Get("PractitionerLogin", "EN", "v3"))
+		opname := store.Get("Opname", "NL", "v1")
+		if assert.NotNil(t, opname) {
+			assert.Equal(t, []string{".gemeente.personalData.firstnames"}, opname.SignerAttributes)
+			assert.Equal(t, []string{LegalEntityAttr, "ward", ValidFromAttr, ValidToAttr}, opname.TemplateAttributes)
+			assert.Equal(t, Attribute{Type: EnumAttribute, Values: []string{"Noord", "Zuid"}}, opname.Attributes["ward"])
+			assert.Equal(t, Attribute{Type: OrganizationNameAttribute, MaxLength: 64, Escaping: EscapeNone}, opname.Attributes[LegalEntityAttr])
+			if assert.NotNil(t, opname.DeprecatedFrom) {
+				assert.True(t, opname.DeprecatedFrom.Equal(time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)))
+			}
+			assert.Nil(t, opname.SunsetAt)
+		}
+		intake := store.Get("Intake", "EN", "v2")
+		if assert.NotNil(t, intake) {
+			assert.Equal(t, StandardSignerAttributes, intake.SignerAttributes)
+			assert.Equal(t, "America/Kralendijk", intake.TimeZone)
+		}
+		// the standard templates must not be altered
+		assert.Nil(t, StandardContractTemplates.Get("Opname", "NL", "v1"))
+	})
+
+	t.Run("ok - a loaded template can be rendered and parsed", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		writeTemplateFile(t, dir, "opname.yml", yamlTemplate)
+		store, _ := LoadTemplateStore(dir)
+
+		drawnUp, err := store.Get("Opname", "NL", "v1").Render(map[string]string{LegalEntityAttr: "Zorg & Co. B.V. (Noord)", "ward": "Zuid"}, NowFunc(), 0)
+		if !assert.NoError(t, err) {
+			return
+		}
+		parsed, err := ParseContractString(drawnUp.RawContractText, store)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, drawnUp.RawContractText, "namens Zorg & Co. B.V. (Noord) op afdeling Zuid.")
+		assert.Equal(t, "Zorg & Co. B.V. (Noord)", parsed.Params[LegalEntityAttr])
+		assert.Equal(t, "Zuid", parsed.Params["ward"])
+	})
+
+	t.Run("error - unknown directory", func(t *testing.T) {
+		_, err := LoadTemplateStore("non-existing")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("error - duplicate template", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		writeTemplateFile(t, dir, "practitioner.yaml", `
+type: PractitionerLogin
+version: v3
+language: EN
+template: "EN:PractitionerLogin:v3 Other text {{legal_entity}} {{valid_from}} {{valid_to}}"
+`)
+
+		_, err := LoadTemplateStore(dir)
+
+		assert.True(t, errors.Is(err, ErrInvalidContractTemplate))
+		assert.EqualError(t, err, "unable to load contract template practitioner.yaml: invalid contract template: EN:PractitionerLogin:v3 is already defined")
+	})
+
+	t.Run("error - invalid template", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		writeTemplateFile(t, dir, "invalid.yaml", `
+type: Opname
+version: v1
+language: NL
+template: "NL:Opname:v1 {{legal_entity}} from {{valid_from}}"
+`)
+
+		_, err := LoadTemplateStore(dir)
+
+		assert.True(t, errors.Is(err, ErrInvalidContractTemplate))
+		assert.EqualError(t, err, "unable to load contract template invalid.yaml: invalid contract template: template text of NL:Opname:v1 is missing the required attribute 'valid_to'")
+	})
+
+	t.Run("error - invalid attribute declaration", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		writeTemplateFile(t, dir, "invalid.yaml", `
+type: Opname
+version: v1
+language: NL
+attributes:
+  ward:
+    type: enum
+template: "NL:Opname:v1 {{legal_entity}} {{ward}} from {{valid_from}} to {{valid_to}}"
+`)
+
+		_, err := LoadTemplateStore(dir)
+
+		assert.True(t, errors.Is(err, ErrInvalidContractTemplate))
+		assert.EqualError(t, err, "unable to load contract template invalid.yaml: invalid contract template: enum attribute 'ward' of NL:Opname:v1 must have values")
+	})
+
+	t.Run("error - unknown field", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		writeTemplateFile(t, dir, "invalid.json", `{"type": "Intake", "regexp": "(.*)"}`)
+
+		_, err := LoadTemplateStore(dir)
+
+		assert.True(t, errors.Is(err, ErrInvalidContractTemplate))
+	})
+}
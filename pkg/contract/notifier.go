@@ -0,0 +1,87 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package contract
+
+import "sync"
+
+// SessionListener is called with the new status of a signing session
+type SessionListener func(result SigningSessionResult)
+
+// SessionNotifier keeps the listeners of signing sessions. Signers use it to implement Subscribe and notify the
+// listeners on every status transition. All methods can be called on a nil SessionNotifier, which has no listeners.
+type SessionNotifier struct {
+	mutex     sync.Mutex
+	nextID    int
+	listeners map[string]map[int]SessionListener
+}
+
+// NewSessionNotifier creates an empty SessionNotifier
+func NewSessionNotifier() *SessionNotifier {
+	return &SessionNotifier{listeners: map[string]map[int]SessionListener{}}
+}
+
+// Subscribe registers the listener for the signing session. The returned func removes the listener.
+func (n *SessionNotifier) Subscribe(sessionID string, listener SessionListener) (unsubscribe func()) {
+	if n == nil {
+		return func() {}
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	id := n.nextID
+	n.nextID++
+	if n.listeners[sessionID] == nil {
+		n.listeners[sessionID] = map[int]SessionListener{}
+	}
+	n.listeners[sessionID][id] = listener
+	return func() {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		delete(n.listeners[sessionID], id)
+		if len(n.listeners[sessionID]) == 0 {
+			delete(n.listeners, sessionID)
+		}
+	}
+}
+
+// HasListeners returns true when the signing session has listeners. It allows signers to skip building a result nobody listens to.
+func (n *SessionNotifier) HasListeners(sessionID string) bool {
+	if n == nil {
+		return false
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return len(n.listeners[sessionID]) > 0
+}
+
+// Notify calls the listeners of the signing session with the new status
+func (n *SessionNotifier) Notify(sessionID string, result SigningSessionResult) {
+	if n == nil {
+		return
+	}
+	n.mutex.Lock()
+	listeners := make([]SessionListener, 0, len(n.listeners[sessionID]))
+	for _, listener := range n.listeners[sessionID] {
+		listeners = append(listeners, listener)
+	}
+	n.mutex.Unlock()
+	// listeners are called without holding the lock, so they can unsubscribe
+	for _, listener := range listeners {
+		listener(result)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package contract
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testSessionResult string
+
+func (t testSessionResult) Status() SessionStatus {
+	return SessionStatus(t)
+}
+
+func (t testSessionResult) NativeStatus() string {
+	return string(t)
+}
+
+func (t testSessionResult) VerifiablePresentation() (VerifiablePresentation, error) {
+	return nil, nil
+}
+
+func TestSessionNotifier(t *testing.T) {
+	t.Run("ok - listeners of the session are notified", func(t *testing.T) {
+		notifier := NewSessionNotifier()
+		var first, second, other []string
+		notifier.Subscribe("123", func(result SigningSessionResult) { first = append(first, result.NativeStatus()) })
+		notifier.Subscribe("123", func(result SigningSessionResult) { second = append(second, result.NativeStatus()) })
+		notifier.Subscribe("456", func(result SigningSessionResult) { other = append(other, result.NativeStatus()) })
+
+		notifier.Notify("123", testSessionResult("in-progress"))
+		notifier.Notify("123", testSessionResult("completed"))
+
+		assert.True(t, notifier.HasListeners("123"))
+		assert.False(t, notifier.HasListeners("789"))
+		assert.Equal(t, []string{"in-progress", "completed"}, first)
+		assert.Equal(t, []string{"in-progress", "completed"}, second)
+		assert.Empty(t, other)
+	})
+
+	t.Run("ok - unsubscribed listener is not notified", func(t *testing.T) {
+		notifier := NewSessionNotifier()
+		var notified []string
+		unsubscribe := notifier.Subscribe("123", func(result SigningSessionResult) { notified = append(notified, result.NativeStatus()) })
+
+		unsubscribe()
+		notifier.Notify("123", testSessionResult("completed"))
+
+		assert.Empty(t, notified)
+		assert.Empty(t, notifier.listeners)
+	})
+
+	t.Run("ok - listener can unsubscribe while being notified", func(t *testing.T) {
+		notifier := NewSessionNotifier()
+		var unsubscribe func()
+		calls := 0
+		unsubscribe = notifier.Subscribe("123", func(result SigningSessionResult) {
+			calls++
+			unsubscribe()
+		})
+
+		notifier.Notify("123", testSessionResult("completed"))
+		notifier.Notify("123", testSessionResult("completed"))
+
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("ok - nil notifier", func(t *testing.T) {
+		var notifier *SessionNotifier
+
+		notifier.Subscribe("123", func(result SigningSessionResult) {})()
+		notifier.Notify("123", testSessionResult("completed"))
+		assert.False(t, notifier.HasListeners("123"))
+	})
+}
@@ -28,9 +28,15 @@ type Signer interface {
 	// SigningSessionStatus returns the current status of the signing session or services.ErrSessionNotFound if not found
 	// todo: name should have been SessionStatus, but its currently in use by the old interface
 	SigningSessionStatus(sessionID string) (SigningSessionResult, error)
-	// StartSession starts a session for the implementing signer
+	// StartSession starts a session for the implementing signer. The params are means specific, they may be nil.
 	// todo: name should have been StartSession, but its currently in use by the old interface
-	StartSigningSession(rawContractText string) (SessionPointer, error)
+	StartSigningSession(rawContractText string, params map[string]interface{}) (SessionPointer, error)
+	// CancelSigningSession cancels the signing session in the signing means or returns services.ErrSessionNotFound if not found.
+	// Later status requests return the cancelled state.
+	CancelSigningSession(sessionID string) error
+	// Subscribe registers a listener which is called on every status transition of the signing session, the final
+	// transition included. The returned func removes the listener.
+	Subscribe(sessionID string, listener SessionListener) (unsubscribe func())
 }
 
 // SessionPointer contains session information for the means how to sign the payload
@@ -41,8 +47,10 @@ type SessionPointer interface {
 
 // SigningSessionResult holds information in the current status of the SigningSession
 type SigningSessionResult interface {
-	// Status returns the current state of the SigningSession
-	Status() string
+	// Status returns the current status of the SigningSession
+	Status() SessionStatus
+	// NativeStatus returns the current state of the SigningSession as used by the signing means
+	NativeStatus() string
 	// VerifiablePresentation returns a VerifiablePresentation holding the presentation proof and disclosed attributes or an error if
 	// no proof is present yet
 	VerifiablePresentation() (VerifiablePresentation, error)
@@ -0,0 +1,47 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package contract
+
+// SessionStatus is the status of a signing session. Every signing means maps its native states onto these statuses,
+// so clients do not need to know the means.
+type SessionStatus string
+
+const (
+	// SessionCreated is the status of a session which is waiting for the user
+	SessionCreated SessionStatus = "created"
+	// SessionInProgress is the status of a session which the user is signing
+	SessionInProgress SessionStatus = "in-progress"
+	// SessionCompleted is the final status of a session in which the contract was signed
+	SessionCompleted SessionStatus = "completed"
+	// SessionCancelled is the final status of a session which was cancelled by the user or the client
+	SessionCancelled SessionStatus = "cancelled"
+	// SessionExpired is the final status of a session which was not finished in time
+	SessionExpired SessionStatus = "expired"
+	// SessionFailed is the final status of a session which ended in an error
+	SessionFailed SessionStatus = "failed"
+)
+
+// Final returns true when the session no longer changes
+func (s SessionStatus) Final() bool {
+	switch s {
+	case SessionCompleted, SessionCancelled, SessionExpired, SessionFailed:
+		return true
+	}
+	return false
+}
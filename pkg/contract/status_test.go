@@ -0,0 +1,35 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package contract
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionStatus_Final(t *testing.T) {
+	assert.False(t, SessionCreated.Final())
+	assert.False(t, SessionInProgress.Final())
+	assert.True(t, SessionCompleted.Final())
+	assert.True(t, SessionCancelled.Final())
+	assert.True(t, SessionExpired.Final())
+	assert.True(t, SessionFailed.Final())
+	assert.False(t, SessionStatus("unknown").Final())
+}
@@ -21,74 +21,130 @@ package contract
 import (
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// actingPartyTemplatesDeprecatedFrom is the moment from which the templates which name the acting party are deprecated.
+// Their support, including the actingPartyCn setting, is removed in 0.17.
+var actingPartyTemplatesDeprecatedFrom = time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC)
+
 // StandardContractTemplates contains a the official contract templates as specified in the Nuts specification
 // EN:PractitionerLogin:v1 Template
-var StandardContractTemplates = TemplateStore{
+var StandardContractTemplates = mustCompileTemplates(TemplateStore{
 	"NL": {"BehandelaarLogin": {
 		"v1": &Template{
-			Type:               "BehandelaarLogin",
-			Version:            "v1",
-			Language:           "NL",
-			SignerAttributes:   []string{".nuts.agb.agbcode"},
-			Template:           `NL:BehandelaarLogin:v1 Ondergetekende geeft toestemming aan {{` + ActingPartyAttr + `}} om namens {{` + LegalEntityAttr + `}} en ondergetekende het Nuts netwerk te bevragen. Deze toestemming is geldig van {{` + ValidFromAttr + `}} tot {{` + ValidToAttr + `}}.`,
-			TemplateAttributes: []string{ActingPartyAttr, LegalEntityAttr, ValidFromAttr, ValidToAttr},
-			Regexp:             `NL:BehandelaarLogin:v1 Ondergetekende geeft toestemming aan (.+) om namens (.+) en ondergetekende het Nuts netwerk te bevragen. Deze toestemming is geldig van (.+) tot (.+).`,
+			Type:             "BehandelaarLogin",
+			Version:          "v1",
+			Language:         "NL",
+			SignerAttributes: []string{".nuts.agb.agbcode"},
+			Template:         `NL:BehandelaarLogin:v1 Ondergetekende geeft toestemming aan {{` + ActingPartyAttr + `}} om namens {{` + LegalEntityAttr + `}} en ondergetekende het Nuts netwerk te bevragen. Deze toestemming is geldig van {{` + ValidFromAttr + `}} tot {{` + ValidToAttr + `}}.`,
+			DeprecatedFrom:   &actingPartyTemplatesDeprecatedFrom,
 		},
 		"v2": &Template{
-			Type:               "BehandelaarLogin",
-			Version:            "v2",
-			Language:           "NL",
-			SignerAttributes:   StandardSignerAttributes,
-			Template:           `NL:BehandelaarLogin:v2 Ondergetekende geeft toestemming aan {{` + ActingPartyAttr + `}} om namens {{` + LegalEntityAttr + `}} en ondergetekende het Nuts netwerk te bevragen. Deze toestemming is geldig van {{` + ValidFromAttr + `}} tot {{` + ValidToAttr + `}}.`,
-			TemplateAttributes: []string{ActingPartyAttr, LegalEntityAttr, ValidFromAttr, ValidToAttr},
-			Regexp:             `NL:BehandelaarLogin:v2 Ondergetekende geeft toestemming aan (.+) om namens (.+) en ondergetekende het Nuts netwerk te bevragen. Deze toestemming is geldig van (.+) tot (.+).`,
+			Type:             "BehandelaarLogin",
+			Version:          "v2",
+			Language:         "NL",
+			SignerAttributes: StandardSignerAttributes,
+			Template:         `NL:BehandelaarLogin:v2 Ondergetekende geeft toestemming aan {{` + ActingPartyAttr + `}} om namens {{` + LegalEntityAttr + `}} en ondergetekende het Nuts netwerk te bevragen. Deze toestemming is geldig van {{` + ValidFromAttr + `}} tot {{` + ValidToAttr + `}}.`,
+			DeprecatedFrom:   &actingPartyTemplatesDeprecatedFrom,
 		},
 		"v3": &Template{
-			Type:               "BehandelaarLogin",
-			Version:            "v3",
-			Language:           "NL",
-			SignerAttributes:   StandardSignerAttributes,
-			Template:           `NL:BehandelaarLogin:v3 Hierbij verklaar ik te handelen in naam van {{` + LegalEntityAttr + `}}. Deze verklaring is geldig van {{` + ValidFromAttr + `}} tot {{` + ValidToAttr + `}}.`,
-			TemplateAttributes: []string{LegalEntityAttr, ValidFromAttr, ValidToAttr},
-			Regexp:             `NL:BehandelaarLogin:v3 Hierbij verklaar ik te handelen in naam van (.+). Deze verklaring is geldig van (.+) tot (.+).`,
+			Type:             "BehandelaarLogin",
+			Version:          "v3",
+			Language:         "NL",
+			SignerAttributes: StandardSignerAttributes,
+			Template:         `NL:BehandelaarLogin:v3 Hierbij verklaar ik te handelen in naam van {{` + LegalEntityAttr + `}}. Deze verklaring is geldig van {{` + ValidFromAttr + `}} tot {{` + ValidToAttr + `}}.`,
+		},
+	}, "BehandelaarPatientToegang": {
+		"v1": &Template{
+			Type:             "BehandelaarPatientToegang",
+			Version:          "v1",
+			Language:         "NL",
+			SignerAttributes: StandardSignerAttributes,
+			Template:         `NL:BehandelaarPatientToegang:v1 Hierbij verklaar ik te handelen in naam van {{` + LegalEntityAttr + `}} en vraag ik toegang tot de gegevens van patiënt {{` + SubjectAttr + `}} voor het doel {{` + PurposeOfUseAttr + `}}. Deze verklaring is geldig van {{` + ValidFromAttr + `}} tot {{` + ValidToAttr + `}}.`,
+			Attributes:       patientContextAttributes,
 		},
 	}},
 	"EN": {"PractitionerLogin": {
 		"v1": &Template{
-			Type:               "PractitionerLogin",
-			Version:            "v1",
-			Language:           "EN",
-			SignerAttributes:   []string{"nuts.agb.agbcode"},
-			Template:           `EN:PractitionerLogin:v1 Undersigned gives permission to {{` + ActingPartyAttr + `}} to make request to the Nuts network on behalf of {{` + LegalEntityAttr + `}} and itself. This permission is valid from {{` + ValidFromAttr + `}} until {{` + ValidToAttr + `}}.`,
-			TemplateAttributes: []string{ActingPartyAttr, LegalEntityAttr, ValidFromAttr, ValidToAttr},
-			Regexp:             `EN:PractitionerLogin:v1 Undersigned gives permission to (.+) to make request to the Nuts network on behalf of (.+) and itself. This permission is valid from (.+) until (.+).`,
+			Type:             "PractitionerLogin",
+			Version:          "v1",
+			Language:         "EN",
+			SignerAttributes: []string{"nuts.agb.agbcode"},
+			Template:         `EN:PractitionerLogin:v1 Undersigned gives permission to {{` + ActingPartyAttr + `}} to make request to the Nuts network on behalf of {{` + LegalEntityAttr + `}} and itself. This permission is valid from {{` + ValidFromAttr + `}} until {{` + ValidToAttr + `}}.`,
+			DeprecatedFrom:   &actingPartyTemplatesDeprecatedFrom,
 		},
 		"v2": &Template{
-			Type:               "PractitionerLogin",
-			Version:            "v2",
-			Language:           "EN",
-			SignerAttributes:   StandardSignerAttributes,
-			Template:           `EN:PractitionerLogin:v2 Undersigned gives permission to {{` + ActingPartyAttr + `}} to make request to the Nuts network on behalf of {{` + LegalEntityAttr + `}} and itself. This permission is valid from {{` + ValidFromAttr + `}} until {{` + ValidToAttr + `}}.`,
-			TemplateAttributes: []string{ActingPartyAttr, LegalEntityAttr, ValidFromAttr, ValidToAttr},
-			Regexp:             `EN:PractitionerLogin:v2 Undersigned gives permission to (.+) to make request to the Nuts network on behalf of (.+) and itself. This permission is valid from (.+) until (.+).`,
+			Type:             "PractitionerLogin",
+			Version:          "v2",
+			Language:         "EN",
+			SignerAttributes: StandardSignerAttributes,
+			Template:         `EN:PractitionerLogin:v2 Undersigned gives permission to {{` + ActingPartyAttr + `}} to make request to the Nuts network on behalf of {{` + LegalEntityAttr + `}} and itself. This permission is valid from {{` + ValidFromAttr + `}} until {{` + ValidToAttr + `}}.`,
+			DeprecatedFrom:   &actingPartyTemplatesDeprecatedFrom,
 		},
 		"v3": &Template{
-			Type:               "PractitionerLogin",
-			Version:            "v3",
-			Language:           "EN",
-			SignerAttributes:   StandardSignerAttributes,
-			Template:           `EN:PractitionerLogin:v3 I hereby declare to act on behalf of {{` + LegalEntityAttr + `}}. This declaration is valid from {{` + ValidFromAttr + `}} until {{` + ValidToAttr + `}}.`,
-			TemplateAttributes: []string{LegalEntityAttr, ValidFromAttr, ValidToAttr},
-			Regexp:             `EN:PractitionerLogin:v3 I hereby declare to act on behalf of (.+). This declaration is valid from (.+) until (.+).`,
+			Type:             "PractitionerLogin",
+			Version:          "v3",
+			Language:         "EN",
+			SignerAttributes: StandardSignerAttributes,
+			Template:         `EN:PractitionerLogin:v3 I hereby declare to act on behalf of {{` + LegalEntityAttr + `}}. This declaration is valid from {{` + ValidFromAttr + `}} until {{` + ValidToAttr + `}}.`,
+		},
+	}, "PractitionerPatientAccess": {
+		"v1": &Template{
+			Type:             "PractitionerPatientAccess",
+			Version:          "v1",
+			Language:         "EN",
+			SignerAttributes: StandardSignerAttributes,
+			Template:         `EN:PractitionerPatientAccess:v1 I hereby declare to act on behalf of {{` + LegalEntityAttr + `}} and request access to the records of patient {{` + SubjectAttr + `}} for purpose of use {{` + PurposeOfUseAttr + `}}. This declaration is valid from {{` + ValidFromAttr + `}} until {{` + ValidToAttr + `}}.`,
+			Attributes:       patientContextAttributes,
 		},
 	}},
-}
+})
 
 // TemplateStore contains a list of Contract templates sorted by language, type and version
 type TemplateStore map[Language]map[Type]map[Version]*Template
 
+// TemplateProvider provides the contract templates known to the node. The set of templates may change at runtime,
+// signing sessions therefore register the contract they sign, so it is verified against the same version until the session is released.
+type TemplateProvider interface {
+	// Templates returns the current set of templates. The returned store must not be altered.
+	Templates() TemplateStore
+	// Acquire registers that the signing session with the given ID signs the given contract.
+	Acquire(sessionID string, c *Contract)
+	// Release removes the registration of a signing session.
+	Release(sessionID string)
+	// SessionTemplates returns the set of templates which was current when the signing session was registered.
+	// If the session is not registered, the current set of templates is returned.
+	SessionTemplates(sessionID string) TemplateStore
+	// TemplatesFor returns the set of templates which was current when the signing session of the contract text was registered.
+	// If no registered session signs the text, the current set of templates is returned.
+	TemplatesFor(rawContractText string) TemplateStore
+}
+
+// Templates returns the store itself. A TemplateStore does not change, so it can be used as a static TemplateProvider.
+func (m TemplateStore) Templates() TemplateStore {
+	return m
+}
+
+// Acquire does nothing since a TemplateStore does not change.
+func (m TemplateStore) Acquire(_ string, _ *Contract) {}
+
+// Release does nothing since a TemplateStore does not change.
+func (m TemplateStore) Release(_ string) {}
+
+// SessionTemplates returns the store itself.
+func (m TemplateStore) SessionTemplates(_ string) TemplateStore {
+	return m
+}
+
+// TemplatesFor returns the store itself.
+func (m TemplateStore) TemplatesFor(_ string) TemplateStore {
+	return m
+}
+
 // Get safely searches the template store. When no version is given, v1 is used.
 // Returns the template or nil
 func (m TemplateStore) Get(cType Type, language Language, version Version) *Template {
@@ -102,6 +158,35 @@ func (m TemplateStore) Get(cType Type, language Language, version Version) *Temp
 	return nil
 }
 
+// List returns all templates in the store ordered by type, language and version
+func (m TemplateStore) List() []*Template {
+	var templates []*Template
+	for _, types := range m {
+		for _, versions := range types {
+			for _, template := range versions {
+				templates = append(templates, template)
+			}
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		a, b := templates[i], templates[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Language != b.Language {
+			return a.Language < b.Language
+		}
+		return versionNumber(a.Version) < versionNumber(b.Version)
+	})
+	return templates
+}
+
+// versionNumber returns the number of a version like "v12", or 0 if the version is not in that form
+func versionNumber(version Version) int {
+	number, _ := strconv.Atoi(strings.TrimPrefix(string(version), "v"))
+	return number
+}
+
 func (m TemplateStore) FindFromRawContractText(rawContractText string) (*Template, error) {
 	r, _ := regexp.Compile(`^(.{2}):(.+):(v\d+)`)
 
@@ -116,3 +201,64 @@ func (m TemplateStore) FindFromRawContractText(rawContractText string) (*Templat
 
 	return m.Get(contractType, language, version), nil
 }
+
+// NewTemplateStore compiles the given templates and returns a store which contains them. It returns an error when a
+// template is invalid or when two templates have the same language, type and version.
+func NewTemplateStore(templates []*Template) (TemplateStore, error) {
+	store := TemplateStore{}
+	for _, template := range templates {
+		if err := template.compile(); err != nil {
+			return nil, err
+		}
+		if err := store.add(template); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// mustCompileTemplates derives the template attributes and regular expression of every template in the store.
+// It panics when one of the templates is invalid, it is meant for the templates defined in code.
+func mustCompileTemplates(store TemplateStore) TemplateStore {
+	for _, types := range store {
+		for _, versions := range types {
+			for _, template := range versions {
+				if err := template.compile(); err != nil {
+					panic(err)
+				}
+			}
+		}
+	}
+	return store
+}
+
+// add adds a template to the store. It returns an error if the store already contains a template with the same
+// language, type and version.
+func (m TemplateStore) add(template *Template) error {
+	if m.Get(template.Type, template.Language, template.Version) != nil {
+		return fmt.Errorf("%w: %s:%s:%s is already defined", ErrInvalidContractTemplate, template.Language, template.Type, template.Version)
+	}
+	if _, ok := m[template.Language]; !ok {
+		m[template.Language] = map[Type]map[Version]*Template{}
+	}
+	if _, ok := m[template.Language][template.Type]; !ok {
+		m[template.Language][template.Type] = map[Version]*Template{}
+	}
+	m[template.Language][template.Type][template.Version] = template
+	return nil
+}
+
+// copy returns a copy of the store. The templates themselves are shared between both stores.
+func (m TemplateStore) copy() TemplateStore {
+	result := TemplateStore{}
+	for language, types := range m {
+		result[language] = map[Type]map[Version]*Template{}
+		for cType, versions := range types {
+			result[language][cType] = map[Version]*Template{}
+			for version, template := range versions {
+				result[language][cType][version] = template
+			}
+		}
+	}
+	return result
+}
@@ -19,6 +19,7 @@
 package contract
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -82,3 +83,64 @@ func TestTemplateStore_Find(t *testing.T) {
 		}
 	})
 }
+
+func TestNewTemplateStore(t *testing.T) {
+	newTemplate := func() *Template {
+		return &Template{Type: "Opname", Language: "NL", Version: "v1", Template: "NL:Opname:v1 Namens {{legal_entity}}, geldig van {{valid_from}} tot {{valid_to}}."}
+	}
+
+	t.Run("ok - templates are compiled", func(t *testing.T) {
+		store, err := NewTemplateStore([]*Template{newTemplate()})
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		template := store.Get("Opname", "NL", "v1")
+		if assert.NotNil(t, template) {
+			assert.Equal(t, []string{LegalEntityAttr, ValidFromAttr, ValidToAttr}, template.TemplateAttributes)
+		}
+	})
+
+	t.Run("error - invalid template", func(t *testing.T) {
+		template := newTemplate()
+		template.Template = "NL:Opname:v1 Namens {{legal_entity}}"
+
+		_, err := NewTemplateStore([]*Template{template})
+
+		assert.True(t, errors.Is(err, ErrInvalidContractTemplate))
+	})
+
+	t.Run("error - duplicate template", func(t *testing.T) {
+		_, err := NewTemplateStore([]*Template{newTemplate(), newTemplate()})
+
+		assert.EqualError(t, err, "invalid contract template: NL:Opname:v1 is already defined")
+	})
+}
+
+func TestTemplateStore_List(t *testing.T) {
+	t.Run("ok - templates are ordered by type, language and version", func(t *testing.T) {
+		store := StandardContractTemplates.copy()
+		_ = store.add(&Template{Type: "BehandelaarLogin", Language: "NL", Version: "v10"})
+
+		var identifiers []string
+		for _, template := range store.List() {
+			identifiers = append(identifiers, template.Identifier())
+		}
+
+		assert.Equal(t, []string{
+			"NL:BehandelaarLogin:v1",
+			"NL:BehandelaarLogin:v2",
+			"NL:BehandelaarLogin:v3",
+			"NL:BehandelaarLogin:v10",
+			"NL:BehandelaarPatientToegang:v1",
+			"EN:PractitionerLogin:v1",
+			"EN:PractitionerLogin:v2",
+			"EN:PractitionerLogin:v3",
+			"EN:PractitionerPatientAccess:v1",
+		}, identifiers)
+	})
+
+	t.Run("ok - empty store", func(t *testing.T) {
+		assert.Empty(t, TemplateStore{}.List())
+	})
+}
@@ -21,6 +21,8 @@ package contract
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/cbroglie/mustache"
@@ -28,6 +30,9 @@ import (
 )
 
 const timeLayout = "Monday, 2 January 2006 15:04:05"
+
+// timeLayoutWithOffset is used for contracts which are drawn up in another time zone than the time zone of the template
+const timeLayoutWithOffset = timeLayout + " -07:00"
 const ValidFromAttr = "valid_from"
 const ValidToAttr = "valid_to"
 
@@ -35,6 +40,12 @@ const ValidToAttr = "valid_to"
 const ActingPartyAttr = "acting_party"
 const LegalEntityAttr = "legal_entity"
 
+// SubjectAttr contains the identifier of the patient whose records are accessed, like a BSN URN
+const SubjectAttr = "subject"
+
+// PurposeOfUseAttr contains the reason why the records of the subject are accessed
+const PurposeOfUseAttr = "purpose_of_use"
+
 // Template stores al properties of a contract template which can result in a signed contract
 type Template struct {
 	Type                 Type     `json:"type"`
@@ -45,17 +56,41 @@ type Template struct {
 	Template             string   `json:"Template"`
 	TemplateAttributes   []string `json:"template_attributes"`
 	Regexp               string   `json:"-"`
+	// TimeZone is the IANA name of the time zone in which the validity of the contract is expressed. Defaults to Europe/Amsterdam.
+	TimeZone string `json:"time_zone"`
+	// DeprecatedFrom is the moment from which contracts should no longer be drawn up using this template.
+	DeprecatedFrom *time.Time `json:"deprecated_from,omitempty"`
+	// SunsetAt is the moment from which contracts signed using this template are no longer accepted.
+	SunsetAt *time.Time `json:"sunset_at,omitempty"`
+	// Attributes declares the type of the template attributes. Attributes which are not declared use the standard
+	// declaration for the attribute name, or are text attributes.
+	Attributes map[string]Attribute `json:"attributes,omitempty"`
 }
 
 // Language of the contract in all caps. example: "NL"
 type Language string
 
+// locale returns the locale used for dates in contracts of the language
+func (l Language) locale() (monday.Locale, bool) {
+	locale, ok := locales[l]
+	return locale, ok
+}
+
 // Type contains type of the contract to sign. Example: "BehandelaarLogin"
 type Type string
 
 // Version of the contract. example: "v1"
 type Version string
 
+// locales maps the supported contract languages to the locale used for formatting and parsing dates.
+// Support for a language can be added by adding its locale here.
+var locales = map[Language]monday.Locale{
+	"NL": monday.LocaleNlNL,
+	"EN": monday.LocaleEnGB,
+	"DE": monday.LocaleDeDE,
+	"FR": monday.LocaleFrFR,
+}
+
 // NowFunc is used to store a function that returns the current time. This can be changed when you want to mock the current time.
 var NowFunc = time.Now
 
@@ -66,19 +101,189 @@ var StandardSignerAttributes = []string{
 	"pbdf.sidn-pbdf.email.email",
 }
 
-func (c Template) timeLocation() *time.Location {
-	loc, _ := time.LoadLocation(AmsterdamTimeZone)
-	return loc
+// templateTagPattern matches the tags in a mustache template like {{legal_entity}}
+var templateTagPattern = regexp.MustCompile(`{{([^}]*)}}`)
+
+// attributeNamePattern defines the allowed names of template attributes
+var attributeNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// languagePattern and versionPattern match the language and version as they are extracted by FindFromRawContractText
+var languagePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+var versionPattern = regexp.MustCompile(`^v\d+$`)
+
+// compile validates the template and derives the TemplateAttributes and the Regexp from the mustache template.
+// Only simple variable tags are supported, every tag results in a capture group in the Regexp.
+func (c *Template) compile() error {
+	if c.Type == "" || strings.Contains(string(c.Type), ":") {
+		return fmt.Errorf("%w: invalid type '%s'", ErrInvalidContractTemplate, c.Type)
+	}
+	if !languagePattern.MatchString(string(c.Language)) {
+		return fmt.Errorf("%w: invalid language '%s', it must consist of 2 capital letters", ErrInvalidContractTemplate, c.Language)
+	}
+	if _, ok := c.Language.locale(); !ok {
+		return fmt.Errorf("%w: unsupported language '%s'", ErrInvalidContractTemplate, c.Language)
+	}
+	if !versionPattern.MatchString(string(c.Version)) {
+		return fmt.Errorf("%w: invalid version '%s', it must be in the form 'v1'", ErrInvalidContractTemplate, c.Version)
+	}
+	if _, err := c.timeLocation(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidContractTemplate, err)
+	}
+	if c.DeprecatedFrom != nil && c.SunsetAt != nil && c.SunsetAt.Before(*c.DeprecatedFrom) {
+		return fmt.Errorf("%w: sunset of %s must not be before its deprecation", ErrInvalidContractTemplate, c.Identifier())
+	}
+	prefix := fmt.Sprintf("%s:%s:%s ", c.Language, c.Type, c.Version)
+	if !strings.HasPrefix(c.Template, prefix) {
+		return fmt.Errorf("%w: template text of %s must start with '%s'", ErrInvalidContractTemplate, c.Identifier(), prefix)
+	}
+	if _, err := mustache.ParseString(c.Template); err != nil {
+		return fmt.Errorf("%w: unable to parse template text of %s: %s", ErrInvalidContractTemplate, c.Identifier(), err)
+	}
+
+	var (
+		attributes []string
+		expr       strings.Builder
+		pos        int
+	)
+	expr.WriteString("^")
+	for _, match := range templateTagPattern.FindAllStringSubmatchIndex(c.Template, -1) {
+		name := strings.TrimSpace(c.Template[match[2]:match[3]])
+		if !attributeNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: unsupported tag '%s' in template text of %s, only simple variable tags are allowed", ErrInvalidContractTemplate, c.Template[match[0]:match[1]], c.Identifier())
+		}
+		expr.WriteString(regexp.QuoteMeta(c.Template[pos:match[0]]))
+		expr.WriteString("(.+)")
+		attributes = append(attributes, name)
+		pos = match[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(c.Template[pos:]))
+	expr.WriteString("$")
+
+	for _, required := range []string{ValidFromAttr, ValidToAttr} {
+		if !containsString(attributes, required) {
+			return fmt.Errorf("%w: template text of %s is missing the required attribute '%s'", ErrInvalidContractTemplate, c.Identifier(), required)
+		}
+	}
+
+	if err := c.compileAttributes(attributes); err != nil {
+		return err
+	}
+
+	c.TemplateAttributes = attributes
+	c.Regexp = expr.String()
+	return nil
+}
+
+// ValidateTemplate checks if a candidate template can be used to draw up contracts. Next to the checks which are
+// performed when templates are loaded, the template is rendered with sample values and the contract text is parsed
+// back. The sample values must be extracted unchanged, otherwise the attributes of the template are ambiguous.
+// It returns the compiled template and the contract which was drawn up with the sample values.
+func ValidateTemplate(candidate Template) (*Template, *Contract, error) {
+	template := &candidate
+	if err := template.compile(); err != nil {
+		return nil, nil, err
+	}
+
+	vars := make(map[string]string, len(template.TemplateAttributes))
+	for _, attribute := range template.TemplateAttributes {
+		vars[attribute] = template.attribute(attribute).sampleValue(attribute)
+	}
+	validFrom := NowFunc()
+	sample, err := template.Render(vars, validFrom, time.Hour)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidContractTemplate, err)
+	}
+	if err := sample.VerifyForGivenTime(validFrom.Add(time.Minute)); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidContractTemplate, err)
+	}
+	return template, sample, nil
+}
+
+// IsDeprecated returns true if contracts should no longer be drawn up using the template at the given moment in time
+func (c Template) IsDeprecated(moment time.Time) bool {
+	return c.DeprecatedFrom != nil && !moment.Before(*c.DeprecatedFrom)
+}
+
+// IsSunset returns true if contracts signed using the template are no longer accepted at the given moment in time
+func (c Template) IsSunset(moment time.Time) bool {
+	return c.SunsetAt != nil && !moment.Before(*c.SunsetAt)
+}
+
+// Identifier returns the language, type and version of the template like "EN:PractitionerLogin:v3"
+func (c Template) Identifier() string {
+	return fmt.Sprintf("%s:%s:%s", c.Language, c.Type, c.Version)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// timeLocation returns the time zone of the template, or Europe/Amsterdam when the template does not define one.
+func (c Template) timeLocation() (*time.Location, error) {
+	timeZone := c.TimeZone
+	if timeZone == "" {
+		timeZone = AmsterdamTimeZone
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone '%s'", timeZone)
+	}
+	return loc, nil
 }
 
 // Render a template using the given templates variables. The combination of validFrom and the duration configure the validFrom and validTo template attributes.
 // The ValidFrom or ValidTo provided in the vars map will be overwritten.
-// Note: For date calculation the Amsterdam timezone and Dutch locale is used.
+// Note: Dates are formatted in the time zone of the template, day and month names are formatted in the language of the template.
 func (c Template) Render(vars map[string]string, validFrom time.Time, validDuration time.Duration) (*Contract, error) {
-	vars[ValidFromAttr] = monday.Format(validFrom.In(c.timeLocation()), timeLayout, monday.LocaleNlNL)
-	vars[ValidToAttr] = monday.Format(validFrom.Add(validDuration).In(c.timeLocation()), timeLayout, monday.LocaleNlNL)
+	return c.RenderInLocation(vars, validFrom, validDuration, nil)
+}
+
+// RenderInLocation renders the template like Render, but formats the dates in the given location. When the location
+// differs from the time zone of the template, the dates contain the UTC offset so the contract can be verified by
+// parties which only know the template. A nil location falls back to the time zone of the template.
+func (c Template) RenderInLocation(vars map[string]string, validFrom time.Time, validDuration time.Duration, location *time.Location) (*Contract, error) {
+	locale, ok := c.Language.locale()
+	if !ok {
+		return nil, fmt.Errorf("could not render contract template: unsupported language '%s'", c.Language)
+	}
+	templateLocation, err := c.timeLocation()
+	if err != nil {
+		return nil, fmt.Errorf("could not render contract template: %w", err)
+	}
+	validTo := validFrom.Add(validDuration)
+	if c.IsSunset(validFrom) {
+		return nil, fmt.Errorf("could not render contract template: %w", c.sunsetError())
+	}
+	layout := timeLayout
+	if location == nil {
+		location = templateLocation
+	} else if location.String() != templateLocation.String() {
+		layout = timeLayoutWithOffset
+	}
+	vars[ValidFromAttr] = monday.Format(validFrom.In(location), layout, locale)
+	vars[ValidToAttr] = monday.Format(validTo.In(location), layout, locale)
+
+	for _, name := range c.TemplateAttributes {
+		if _, ok := vars[name]; !ok {
+			return nil, fmt.Errorf("could not render contract template: %w: value of '%s' is missing", ErrInvalidAttributeValue, name)
+		}
+	}
+	escapedVars := make(map[string]string, len(vars))
+	for name, value := range vars {
+		if containsString(c.TemplateAttributes, name) {
+			if err := c.validateValue(name, value); err != nil {
+				return nil, fmt.Errorf("could not render contract template: %w", err)
+			}
+		}
+		escapedVars[name] = c.attribute(name).escape(value)
+	}
 
-	rawContractText, err := mustache.Render(c.Template, vars)
+	rawContractText, err := mustache.RenderRaw(c.Template, true, escapedVars)
 	if err != nil {
 		return nil, fmt.Errorf("could not render contract template: %w", err)
 	}
@@ -90,16 +295,39 @@ func (c Template) Render(vars map[string]string, validFrom time.Time, validDurat
 	if err := contract.initParams(); err != nil {
 		return nil, err
 	}
+	// the values must be extracted from the contract text as they were given
+	for _, name := range c.TemplateAttributes {
+		if contract.Params[name] != vars[name] {
+			return nil, fmt.Errorf("could not render contract template: %w: value of '%s' can not be distinguished from the rest of the contract text", ErrInvalidAttributeValue, name)
+		}
+	}
+	if c.IsDeprecated(validFrom) {
+		contract.Warnings = append(contract.Warnings, fmt.Sprintf("contract template %s is deprecated since %s", c.Identifier(), c.DeprecatedFrom.Format(time.RFC3339)))
+	}
+	if c.IsSunset(validTo) {
+		contract.Warnings = append(contract.Warnings, fmt.Sprintf("contract template %s is sunset at %s, the contract is not accepted from that moment", c.Identifier(), c.SunsetAt.Format(time.RFC3339)))
+	}
 
 	return contract, nil
 }
 
+// sunsetError returns the error for contracts of a template which is sunset
+func (c Template) sunsetError() error {
+	return fmt.Errorf("%w: %s is sunset since %s", ErrTemplateSunset, c.Identifier(), c.SunsetAt.Format(time.RFC3339))
+}
+
 // ErrUnknownContractFormat is returned when the contract format is unknown
 var ErrUnknownContractFormat = errors.New("unknown contract format")
 
 // ErrInvalidContractFormat indicates tha a contract format is unknown.
 var ErrInvalidContractFormat = errors.New("unknown contract type")
 
+// ErrInvalidContractTemplate is returned when a contract template definition is invalid
+var ErrInvalidContractTemplate = errors.New("invalid contract template")
+
+// ErrTemplateSunset is returned when a contract template is no longer accepted
+var ErrTemplateSunset = errors.New("contract template is sunset")
+
 // ErrContractNotFound is used when a certain combination of type, language and version cannot resolve to a contract
 var ErrContractNotFound = errors.New("contract not found")
 
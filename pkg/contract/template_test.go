@@ -19,15 +19,17 @@
 package contract
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/goodsign/monday"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestContract_RenderTemplate(t *testing.T) {
-	template := &Template{Type: "Simple", Template: "ga je akkoord met {{wat}} van {{valid_from}} tot {{valid_to}}?"}
+	template := &Template{Type: "Simple", Language: "NL", Template: "ga je akkoord met {{wat}} van {{valid_from}} tot {{valid_to}}?"}
 	result, err := template.Render(map[string]string{"wat": "alles"}, time.Now(), 60*time.Minute)
 	if err != nil {
 		t.Error(err)
@@ -43,10 +45,146 @@ func TestContract_RenderTemplate(t *testing.T) {
 	}
 }
 
+func TestTemplate_Render(t *testing.T) {
+	checkTime := time.Date(2020, 10, 5, 11, 30, 0, 0, time.UTC)
+	amsterdam, _ := time.LoadLocation(AmsterdamTimeZone)
+
+	for _, types := range StandardContractTemplates {
+		for _, versions := range types {
+			for _, template := range versions {
+				template := template
+				t.Run("ok - round trip of "+template.Identifier(), func(t *testing.T) {
+					vars := map[string]string{ActingPartyAttr: "Demo EHR", LegalEntityAttr: "verpleeghuis De nootjes", SubjectAttr: "urn:oid:2.16.840.1.113883.2.4.6.3:999999990", PurposeOfUseAttr: "TREAT"}
+
+					rendered, err := template.Render(vars, checkTime, 30*time.Minute)
+					if !assert.NoError(t, err) {
+						return
+					}
+					parsed, err := ParseContractString(rendered.RawContractText, StandardContractTemplates)
+					if !assert.NoError(t, err) {
+						return
+					}
+					assert.Equal(t, template, parsed.Template)
+					assert.Equal(t, rendered.Params, parsed.Params)
+					assert.NoError(t, parsed.VerifyForGivenTime(checkTime.Add(10*time.Minute)))
+					assert.Error(t, parsed.VerifyForGivenTime(checkTime.Add(time.Hour)))
+				})
+			}
+		}
+	}
+
+	t.Run("error - missing attribute", func(t *testing.T) {
+		template := StandardContractTemplates.Get("PractitionerPatientAccess", "EN", "v1")
+
+		_, err := template.Render(map[string]string{LegalEntityAttr: "verpleeghuis De nootjes", PurposeOfUseAttr: "TREAT"}, checkTime, time.Hour)
+
+		assert.True(t, errors.Is(err, ErrInvalidAttributeValue))
+		assert.EqualError(t, err, "could not render contract template: invalid attribute value: value of 'subject' is missing")
+	})
+
+	t.Run("error - unknown purpose of use", func(t *testing.T) {
+		template := StandardContractTemplates.Get("PractitionerPatientAccess", "EN", "v1")
+
+		_, err := template.Render(map[string]string{LegalEntityAttr: "verpleeghuis De nootjes", SubjectAttr: "urn:oid:2.16.840.1.113883.2.4.6.3:999999990", PurposeOfUseAttr: "MARKETING"}, checkTime, time.Hour)
+
+		assert.True(t, errors.Is(err, ErrInvalidAttributeValue))
+	})
+
+	t.Run("ok - dates are formatted in the language of the template", func(t *testing.T) {
+		expected := map[Language]string{
+			"NL": "maandag, 5 oktober 2020 13:30:00",
+			"EN": "Monday, 5 October 2020 13:30:00",
+			"DE": "Montag, 5 Oktober 2020 13:30:00",
+			"FR": "lundi, 5 octobre 2020 13:30:00",
+		}
+		for language, validFrom := range expected {
+			template := &Template{Type: "Simple", Language: language, Version: "v1", Template: string(language) + ":Simple:v1 {{valid_from}} - {{valid_to}}"}
+			if !assert.NoError(t, template.compile()) {
+				continue
+			}
+
+			rendered, err := template.Render(map[string]string{}, checkTime, time.Hour)
+			if !assert.NoError(t, err) {
+				continue
+			}
+			assert.Equal(t, validFrom, rendered.Params[ValidFromAttr])
+
+			parsedTime, err := parseTime(validFrom, language, amsterdam)
+			if assert.NoError(t, err) {
+				assert.True(t, checkTime.Equal(*parsedTime), "expected %s, got %s", checkTime, parsedTime)
+			}
+		}
+	})
+
+	t.Run("ok - dates are formatted in the time zone of the template", func(t *testing.T) {
+		template := &Template{Type: "Simple", Language: "NL", Version: "v1", TimeZone: "America/Kralendijk", Template: "NL:Simple:v1 {{valid_from}} - {{valid_to}}"}
+		if !assert.NoError(t, template.compile()) {
+			return
+		}
+
+		rendered, err := template.Render(map[string]string{}, checkTime, time.Hour)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "maandag, 5 oktober 2020 07:30:00", rendered.Params[ValidFromAttr])
+		assert.NoError(t, rendered.VerifyForGivenTime(checkTime.Add(10*time.Minute)))
+		assert.Error(t, rendered.VerifyForGivenTime(checkTime.Add(-10*time.Minute)))
+	})
+
+	t.Run("ok - dates in another time zone than the template contain the offset", func(t *testing.T) {
+		kralendijk, _ := time.LoadLocation("America/Kralendijk")
+		template := StandardContractTemplates["NL"]["BehandelaarLogin"]["v3"]
+
+		rendered, err := template.RenderInLocation(map[string]string{LegalEntityAttr: "Zorginstelling Bonaire"}, checkTime, time.Hour, kralendijk)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "maandag, 5 oktober 2020 07:30:00 -04:00", rendered.Params[ValidFromAttr])
+		// a node which only knows the template must interpret the dates in the same way
+		parsed, err := ParseContractString(rendered.RawContractText, StandardContractTemplates)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NoError(t, parsed.VerifyForGivenTime(checkTime.Add(10*time.Minute)))
+		assert.Error(t, parsed.VerifyForGivenTime(checkTime.Add(-10*time.Minute)))
+		assert.Error(t, parsed.VerifyForGivenTime(checkTime.Add(70*time.Minute)))
+	})
+
+	t.Run("ok - dates in the time zone of the template do not contain the offset", func(t *testing.T) {
+		template := StandardContractTemplates["NL"]["BehandelaarLogin"]["v3"]
+
+		rendered, err := template.RenderInLocation(map[string]string{LegalEntityAttr: "verpleeghuis De nootjes"}, checkTime, time.Hour, amsterdam)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, "maandag, 5 oktober 2020 13:30:00", rendered.Params[ValidFromAttr])
+		}
+	})
+
+	t.Run("error - unknown time zone", func(t *testing.T) {
+		template := &Template{Type: "Simple", Language: "NL", TimeZone: "Europe/Atlantis", Template: "NL:Simple:v1 {{valid_from}} - {{valid_to}}"}
+
+		_, err := template.Render(map[string]string{}, checkTime, time.Hour)
+
+		assert.EqualError(t, err, "could not render contract template: unknown time zone 'Europe/Atlantis'")
+	})
+
+	t.Run("error - unsupported language", func(t *testing.T) {
+		template := &Template{Type: "Simple", Language: "XX", Template: "XX:Simple:v1 {{valid_from}} - {{valid_to}}"}
+
+		_, err := template.Render(map[string]string{}, checkTime, time.Hour)
+
+		assert.EqualError(t, err, "could not render contract template: unsupported language 'XX'")
+	})
+}
+
 func TestParseTime(t *testing.T) {
+	amsterdam, _ := time.LoadLocation(AmsterdamTimeZone)
+
 	t.Run("parse Dutch time", func(t *testing.T) {
 		contractTime := "Woensdag, 3 April 2019 16:36:06"
-		parsedTime, err := parseTime(contractTime, "NL")
+		parsedTime, err := parseTime(contractTime, "NL", amsterdam)
 		if err != nil {
 			t.Error("expected date to be parsed")
 		}
@@ -61,7 +199,7 @@ func TestParseTime(t *testing.T) {
 
 	t.Run("parse English time", func(t *testing.T) {
 		contractTime := "Wednesday, 3 April 2019 16:36:06"
-		parsedTime, err := parseTime(contractTime, "EN")
+		parsedTime, err := parseTime(contractTime, "EN", amsterdam)
 		if err != nil {
 			t.Error("expected date to be parsed")
 		}
@@ -74,9 +212,52 @@ func TestParseTime(t *testing.T) {
 		}
 	})
 
+	t.Run("parse English time with Dutch names", func(t *testing.T) {
+		parsedTime, err := parseTime("woensdag, 3 april 2019 16:36:06", "EN", amsterdam)
+
+		location, _ := time.LoadLocation(AmsterdamTimeZone)
+		if assert.NoError(t, err) {
+			assert.True(t, parsedTime.Equal(time.Date(2019, 4, 3, 16, 36, 06, 0, location)))
+		}
+	})
+
+	t.Run("parse German time", func(t *testing.T) {
+		parsedTime, err := parseTime("Mittwoch, 3 April 2019 16:36:06", "DE", amsterdam)
+
+		location, _ := time.LoadLocation(AmsterdamTimeZone)
+		if assert.NoError(t, err) {
+			assert.True(t, parsedTime.Equal(time.Date(2019, 4, 3, 16, 36, 06, 0, location)))
+		}
+	})
+
+	t.Run("parse time of unsupported language", func(t *testing.T) {
+		parsedTime, err := parseTime("Wednesday, 3 April 2019 16:36:06", "XX", amsterdam)
+
+		assert.EqualError(t, err, "invalid time string [Wednesday, 3 April 2019 16:36:06]: unsupported language 'XX'")
+		assert.Nil(t, parsedTime)
+	})
+
+	t.Run("parse time with offset", func(t *testing.T) {
+		parsedTime, err := parseTime("woensdag, 3 april 2019 12:36:06 -04:00", "NL", amsterdam)
+
+		if assert.NoError(t, err) {
+			assert.True(t, parsedTime.Equal(time.Date(2019, 4, 3, 16, 36, 06, 0, time.UTC)))
+		}
+	})
+
+	t.Run("parse time in other location", func(t *testing.T) {
+		kralendijk, _ := time.LoadLocation("America/Kralendijk")
+
+		parsedTime, err := parseTime("woensdag, 3 april 2019 12:36:06", "NL", kralendijk)
+
+		if assert.NoError(t, err) {
+			assert.True(t, parsedTime.Equal(time.Date(2019, 4, 3, 16, 36, 06, 0, time.UTC)))
+		}
+	})
+
 	t.Run("parse rubbish", func(t *testing.T) {
 		contractTime := "Today is gonna be the day"
-		parsedTime, err := parseTime(contractTime, "EN")
+		parsedTime, err := parseTime(contractTime, "EN", amsterdam)
 		if err == nil {
 			t.Error("expected an error to occur")
 		}
@@ -89,7 +270,7 @@ func TestParseTime(t *testing.T) {
 	t.Run("parse date with wrong day", func(t *testing.T) {
 		t.Skip("This is not supported by the 'monday' package. Should we build it ourselves?")
 		contractTime := "Dinsdag, 3 April 2019 16:36:06"
-		parsedTime, err := parseTime(contractTime, "NL")
+		parsedTime, err := parseTime(contractTime, "NL", amsterdam)
 		if err == nil {
 			t.Error("expected an error to occur")
 		}
@@ -99,3 +280,159 @@ func TestParseTime(t *testing.T) {
 		}
 	})
 }
+
+func TestTemplate_compile(t *testing.T) {
+	t.Run("ok - attributes and regexp are derived from the template", func(t *testing.T) {
+		template := &Template{
+			Type:     "Simple",
+			Language: "NL",
+			Version:  "v1",
+			Template: "NL:Simple:v1 ik ga akkoord met {{wat}} (en meer) van {{valid_from}} tot {{ valid_to }}.",
+		}
+
+		err := template.compile()
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []string{"wat", ValidFromAttr, ValidToAttr}, template.TemplateAttributes)
+		assert.Equal(t, `^NL:Simple:v1 ik ga akkoord met (.+) \(en meer\) van (.+) tot (.+)\.$`, template.Regexp)
+	})
+
+	t.Run("ok - all standard templates are compiled", func(t *testing.T) {
+		for _, types := range StandardContractTemplates {
+			for _, versions := range types {
+				for _, template := range versions {
+					assert.NotEmpty(t, template.Regexp)
+					assert.NotEmpty(t, template.TemplateAttributes)
+				}
+			}
+		}
+	})
+
+	invalidTemplates := map[string]Template{
+		"invalid language":     {Type: "Simple", Language: "nl", Version: "v1", Template: "nl:Simple:v1 {{valid_from}} {{valid_to}}"},
+		"unsupported language": {Type: "Simple", Language: "XX", Version: "v1", Template: "XX:Simple:v1 {{valid_from}} {{valid_to}}"},
+		"unknown time zone":    {Type: "Simple", Language: "NL", Version: "v1", TimeZone: "Europe/Atlantis", Template: "NL:Simple:v1 {{valid_from}} {{valid_to}}"},
+		"invalid version":      {Type: "Simple", Language: "NL", Version: "1", Template: "NL:Simple:1 {{valid_from}} {{valid_to}}"},
+		"missing type":         {Language: "NL", Version: "v1", Template: "NL::v1 {{valid_from}} {{valid_to}}"},
+		"missing prefix":       {Type: "Simple", Language: "NL", Version: "v1", Template: "{{valid_from}} {{valid_to}}"},
+		"missing valid_from":   {Type: "Simple", Language: "NL", Version: "v1", Template: "NL:Simple:v1 {{valid_to}}"},
+		"section tag":          {Type: "Simple", Language: "NL", Version: "v1", Template: "NL:Simple:v1 {{#a}}x{{/a}} {{valid_from}} {{valid_to}}"},
+		"unclosed tag":         {Type: "Simple", Language: "NL", Version: "v1", Template: "NL:Simple:v1 {{valid_from}} {{valid_to}} {{a"},
+	}
+	for name, template := range invalidTemplates {
+		t.Run("error - "+name, func(t *testing.T) {
+			err := template.compile()
+
+			assert.True(t, errors.Is(err, ErrInvalidContractTemplate), "expected ErrInvalidContractTemplate, got: %v", err)
+		})
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	t.Run("ok - sample contract is returned", func(t *testing.T) {
+		candidate := Template{Type: "Opname", Language: "NL", Version: "v1", Template: "NL:Opname:v1 Namens {{legal_entity}}, geldig van {{valid_from}} tot {{valid_to}}."}
+
+		template, sample, err := ValidateTemplate(candidate)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []string{LegalEntityAttr, ValidFromAttr, ValidToAttr}, template.TemplateAttributes)
+		assert.Equal(t, "[legal_entity]", sample.Params[LegalEntityAttr])
+		assert.Contains(t, sample.RawContractText, "NL:Opname:v1 Namens [legal_entity], geldig van ")
+		// the candidate itself is not altered
+		assert.Empty(t, candidate.Regexp)
+	})
+
+	t.Run("error - invalid template", func(t *testing.T) {
+		_, _, err := ValidateTemplate(Template{Type: "Opname", Language: "NL", Version: "v1", Template: "NL:Opname:v1 {{valid_from}}"})
+
+		assert.True(t, errors.Is(err, ErrInvalidContractTemplate))
+	})
+
+	t.Run("error - ambiguous attributes", func(t *testing.T) {
+		_, _, err := ValidateTemplate(Template{Type: "Opname", Language: "NL", Version: "v1", Template: "NL:Opname:v1 {{legal_entity}}{{acting_party}}. Van {{valid_from}} tot {{valid_to}}."})
+
+		assert.True(t, errors.Is(err, ErrInvalidContractTemplate))
+		assert.Contains(t, err.Error(), "can not be distinguished")
+	})
+}
+
+func TestTemplate_Deprecation(t *testing.T) {
+	deprecatedFrom := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+	sunsetAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	newTemplate := func() *Template {
+		template := &Template{
+			Type:           "Opname",
+			Language:       "NL",
+			Version:        "v1",
+			Template:       "NL:Opname:v1 Namens {{legal_entity}}, geldig van {{valid_from}} tot {{valid_to}}.",
+			DeprecatedFrom: &deprecatedFrom,
+			SunsetAt:       &sunsetAt,
+		}
+		if err := template.compile(); err != nil {
+			t.Fatal(err)
+		}
+		return template
+	}
+
+	t.Run("ok - deprecation and sunset moments", func(t *testing.T) {
+		template := newTemplate()
+
+		assert.False(t, template.IsDeprecated(deprecatedFrom.Add(-time.Second)))
+		assert.True(t, template.IsDeprecated(deprecatedFrom))
+		assert.False(t, template.IsSunset(sunsetAt.Add(-time.Second)))
+		assert.True(t, template.IsSunset(sunsetAt))
+		assert.False(t, Template{}.IsDeprecated(sunsetAt))
+		assert.False(t, Template{}.IsSunset(sunsetAt))
+	})
+
+	t.Run("ok - no warnings before deprecation", func(t *testing.T) {
+		drawnUp, err := newTemplate().Render(map[string]string{LegalEntityAttr: "CareBears"}, deprecatedFrom.Add(-2*time.Hour), time.Hour)
+
+		if assert.NoError(t, err) {
+			assert.Empty(t, drawnUp.Warnings)
+		}
+	})
+
+	t.Run("ok - drawing up a contract of a deprecated template returns a warning", func(t *testing.T) {
+		drawnUp, err := newTemplate().Render(map[string]string{LegalEntityAttr: "CareBears"}, deprecatedFrom, time.Hour)
+
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{"contract template NL:Opname:v1 is deprecated since 2020-10-01T00:00:00Z"}, drawnUp.Warnings)
+		}
+	})
+
+	t.Run("ok - drawing up a contract which outlives the sunset returns a warning", func(t *testing.T) {
+		drawnUp, err := newTemplate().Render(map[string]string{LegalEntityAttr: "CareBears"}, sunsetAt.Add(-30*time.Minute), time.Hour)
+
+		if assert.NoError(t, err) {
+			assert.Len(t, drawnUp.Warnings, 2)
+			assert.Equal(t, "contract template NL:Opname:v1 is sunset at 2021-01-01T00:00:00Z, the contract is not accepted from that moment", drawnUp.Warnings[1])
+		}
+	})
+
+	t.Run("error - drawing up a contract of a sunset template", func(t *testing.T) {
+		_, err := newTemplate().Render(map[string]string{LegalEntityAttr: "CareBears"}, sunsetAt, time.Hour)
+
+		assert.True(t, errors.Is(err, ErrTemplateSunset))
+	})
+
+	t.Run("error - contracts of a sunset template are invalid", func(t *testing.T) {
+		drawnUp, _ := newTemplate().Render(map[string]string{LegalEntityAttr: "CareBears"}, sunsetAt.Add(-30*time.Minute), time.Hour)
+
+		assert.NoError(t, drawnUp.VerifyForGivenTime(sunsetAt.Add(-time.Minute)))
+		err := drawnUp.VerifyForGivenTime(sunsetAt.Add(time.Minute))
+		assert.EqualError(t, err, "contract template is sunset: NL:Opname:v1 is sunset since 2021-01-01T00:00:00Z")
+	})
+
+	t.Run("error - sunset before deprecation", func(t *testing.T) {
+		template := Template{Type: "Opname", Language: "NL", Version: "v1", Template: "NL:Opname:v1 {{valid_from}} {{valid_to}}", DeprecatedFrom: &sunsetAt, SunsetAt: &deprecatedFrom}
+
+		err := template.compile()
+
+		assert.EqualError(t, err, "invalid contract template: sunset of NL:Opname:v1 must not be before its deprecation")
+	})
+}
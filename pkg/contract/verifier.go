@@ -89,8 +89,14 @@ type VPVerificationResult struct {
 	VPType VPType
 	// ContractID contains the identifier string of the signed contract message like: "EN:PractitionerLogin:v3"
 	ContractID string
+	// ContractHash contains the Hash of the signed contract, it can be compared to the hash of the drawn up contract
+	ContractHash string
 	// DisclosedAttributes contain the attributes used to sign this contract
 	DisclosedAttributes map[string]string
 	// ContractAttributes contain the attributes used to fill the contract
 	ContractAttributes map[string]string
+	// ValidTo is the moment until which the signed contract is valid, it is zero when unknown
+	ValidTo time.Time
+	// Reason contains a human readable explanation why the Presentation is INVALID, if known
+	Reason string
 }
@@ -0,0 +1,229 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package contract
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	core "github.com/nuts-foundation/nuts-go-core"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nuts-foundation/nuts-auth/logging"
+)
+
+// reloadDelay is the time the watcher waits for more file changes before it reloads the templates.
+// Editors and deployment tools often produce several events for a single change.
+var reloadDelay = 500 * time.Millisecond
+
+const (
+	reloadSucceeded = "success"
+	reloadRejected  = "rejected"
+	reloadFailed    = "failed"
+)
+
+var templateReloadCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: core.NutsMetricsPrefix + "auth_contract_template_reloads_total",
+	Help: "Number of contract template reloads, labeled by their result",
+}, []string{"result"})
+
+type sessionReference struct {
+	template     *Template
+	contractText string
+	templates    TemplateStore
+}
+
+// TemplateWatcher is a TemplateProvider which loads the templates from a directory and reloads them when the contents
+// of the directory change. Reloads are atomic: a reload either replaces the complete set of templates or nothing at all.
+// A reload which would remove a template still in use by an open signing session is rejected.
+type TemplateWatcher struct {
+	path      string
+	templates atomic.Value
+	// mutex guards the sessions and serializes reloads
+	mutex    sync.Mutex
+	sessions map[string]sessionReference
+	watcher  *fsnotify.Watcher
+	done     chan struct{}
+}
+
+// NewTemplateWatcher creates a TemplateWatcher and loads the templates from the given path.
+// It returns an error if the initial set of templates can not be loaded.
+func NewTemplateWatcher(path string) (*TemplateWatcher, error) {
+	templates, err := LoadTemplateStore(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := prometheus.Register(templateReloadCounter); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			return nil, err
+		}
+	}
+	w := &TemplateWatcher{
+		path:     path,
+		sessions: map[string]sessionReference{},
+	}
+	w.templates.Store(templates)
+	return w, nil
+}
+
+// Templates returns the current set of templates.
+func (w *TemplateWatcher) Templates() TemplateStore {
+	return w.templates.Load().(TemplateStore)
+}
+
+// Acquire registers that the signing session signs the contract. The template of the contract can not be removed until the session is released.
+func (w *TemplateWatcher) Acquire(sessionID string, c *Contract) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.sessions[sessionID] = sessionReference{template: c.Template, contractText: c.RawContractText, templates: w.Templates()}
+}
+
+// Release removes the registration of the signing session.
+func (w *TemplateWatcher) Release(sessionID string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	delete(w.sessions, sessionID)
+}
+
+// SessionTemplates returns the set of templates which was current when the signing session was registered.
+func (w *TemplateWatcher) SessionTemplates(sessionID string) TemplateStore {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if ref, ok := w.sessions[sessionID]; ok {
+		return ref.templates
+	}
+	return w.Templates()
+}
+
+// TemplatesFor returns the set of templates which was current when the signing session of the contract text was registered.
+// If no registered session signs the text, the current set of templates is returned.
+func (w *TemplateWatcher) TemplatesFor(rawContractText string) TemplateStore {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, ref := range w.sessions {
+		if ref.contractText == rawContractText {
+			return ref.templates
+		}
+	}
+	return w.Templates()
+}
+
+// Reload loads the templates from disk and replaces the current set of templates.
+// The current set is kept when the templates can not be loaded or when a template is removed while it's still in use.
+func (w *TemplateWatcher) Reload() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	templates, err := LoadTemplateStore(w.path)
+	if err != nil {
+		templateReloadCounter.WithLabelValues(reloadFailed).Inc()
+		return fmt.Errorf("unable to reload contract templates: %w", err)
+	}
+	for sessionID, ref := range w.sessions {
+		t := ref.template
+		if templates.Get(t.Type, t.Language, t.Version) == nil {
+			templateReloadCounter.WithLabelValues(reloadRejected).Inc()
+			return fmt.Errorf("unable to reload contract templates: template %s is still in use by signing session %s", t.Identifier(), sessionID)
+		}
+	}
+	w.templates.Store(templates)
+	templateReloadCounter.WithLabelValues(reloadSucceeded).Inc()
+	logging.Log().Infof("Reloaded contract templates from %s", w.path)
+	return nil
+}
+
+// Start watches the template directory for changes. Changes to template files trigger a Reload.
+func (w *TemplateWatcher) Start() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("unable to watch contract templates: %w", err)
+	}
+	if err := watcher.Add(w.path); err != nil {
+		_ = watcher.Close()
+		return fmt.Errorf("unable to watch contract templates: %w", err)
+	}
+	w.watcher = watcher
+	w.done = make(chan struct{})
+	go w.watch()
+	return nil
+}
+
+// Stop stops watching the template directory.
+func (w *TemplateWatcher) Stop() error {
+	if w.watcher == nil {
+		return nil
+	}
+	close(w.done)
+	err := w.watcher.Close()
+	w.watcher = nil
+	return err
+}
+
+func (w *TemplateWatcher) watch() {
+	var (
+		timer  *time.Timer
+		reload <-chan time.Time
+	)
+	// the channels are captured so a Stop does not interfere with a running loop
+	events, watchErrors, done := w.watcher.Events, w.watcher.Errors, w.done
+	for {
+		select {
+		case <-done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if !isTemplateFile(event.Name) || strings.HasPrefix(filepath.Base(event.Name), ".") {
+				continue
+			}
+			logging.Log().Debugf("Contract template changed: %s", event)
+			if timer == nil {
+				timer = time.NewTimer(reloadDelay)
+			} else {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(reloadDelay)
+			}
+			reload = timer.C
+		case <-reload:
+			reload = nil
+			if err := w.Reload(); err != nil {
+				logging.Log().WithError(err).Error("Contract templates not reloaded")
+			}
+		case err, ok := <-watchErrors:
+			if !ok {
+				return
+			}
+			logging.Log().WithError(err).Warn("Error while watching contract templates")
+		}
+	}
+}
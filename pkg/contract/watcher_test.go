@@ -0,0 +1,133 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package contract
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	testIo "github.com/nuts-foundation/nuts-go-test/io"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateWatcher_Reload(t *testing.T) {
+	t.Run("ok - added and removed templates are picked up", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		w, err := NewTemplateWatcher(dir)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Nil(t, w.Templates().Get("Opname", "NL", "v1"))
+
+		writeTemplateFile(t, dir, "opname.yaml", yamlTemplate)
+		if !assert.NoError(t, w.Reload()) {
+			return
+		}
+		assert.NotNil(t, w.Templates().Get("Opname", "NL", "v1"))
+
+		_ = os.Remove(filepath.Join(dir, "opname.yaml"))
+		if !assert.NoError(t, w.Reload()) {
+			return
+		}
+		assert.Nil(t, w.Templates().Get("Opname", "NL", "v1"))
+	})
+
+	t.Run("ok - sessions keep using the template they were started with", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		writeTemplateFile(t, dir, "opname.yaml", yamlTemplate)
+		w, _ := NewTemplateWatcher(dir)
+		original := w.Templates().Get("Opname", "NL", "v1")
+		signed := &Contract{Template: original, RawContractText: "NL:Opname:v1 Ik verklaar een opname te doen"}
+		w.Acquire("session", signed)
+
+		writeTemplateFile(t, dir, "opname.yaml", strings.Replace(yamlTemplate, "een opname", "een nieuwe opname", 1))
+		if !assert.NoError(t, w.Reload()) {
+			return
+		}
+
+		assert.NotEqual(t, original, w.Templates().Get("Opname", "NL", "v1"))
+		assert.Equal(t, original, w.SessionTemplates("session").Get("Opname", "NL", "v1"))
+		assert.Equal(t, original, w.TemplatesFor(signed.RawContractText).Get("Opname", "NL", "v1"))
+		assert.Equal(t, w.Templates(), w.TemplatesFor("NL:Opname:v1 Ik verklaar een nieuwe opname te doen"))
+		w.Release("session")
+		assert.Equal(t, w.Templates(), w.SessionTemplates("session"))
+		assert.Equal(t, w.Templates(), w.TemplatesFor(signed.RawContractText))
+	})
+
+	t.Run("error - templates in use can not be removed", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		writeTemplateFile(t, dir, "opname.yaml", yamlTemplate)
+		w, _ := NewTemplateWatcher(dir)
+		w.Acquire("session", &Contract{Template: w.Templates().Get("Opname", "NL", "v1")})
+
+		_ = os.Remove(filepath.Join(dir, "opname.yaml"))
+		err := w.Reload()
+
+		assert.EqualError(t, err, "unable to reload contract templates: template NL:Opname:v1 is still in use by signing session session")
+		assert.NotNil(t, w.Templates().Get("Opname", "NL", "v1"))
+
+		w.Release("session")
+		assert.NoError(t, w.Reload())
+		assert.Nil(t, w.Templates().Get("Opname", "NL", "v1"))
+	})
+
+	t.Run("error - invalid templates are not loaded", func(t *testing.T) {
+		dir := testIo.TestDirectory(t)
+		writeTemplateFile(t, dir, "opname.yaml", yamlTemplate)
+		w, _ := NewTemplateWatcher(dir)
+		current := w.Templates()
+
+		writeTemplateFile(t, dir, "invalid.yaml", "type: Invalid")
+		err := w.Reload()
+
+		assert.Error(t, err)
+		assert.Equal(t, current, w.Templates())
+	})
+}
+
+func TestTemplateWatcher_Start(t *testing.T) {
+	reloadDelay = 10 * time.Millisecond
+	defer func() {
+		reloadDelay = 500 * time.Millisecond
+	}()
+
+	dir := testIo.TestDirectory(t)
+	w, _ := NewTemplateWatcher(dir)
+	if !assert.NoError(t, w.Start()) {
+		return
+	}
+	defer w.Stop()
+
+	writeTemplateFile(t, dir, "opname.yaml", yamlTemplate)
+
+	assert.Eventually(t, func() bool {
+		return w.Templates().Get("Opname", "NL", "v1") != nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNewTemplateWatcher(t *testing.T) {
+	t.Run("error - unknown directory", func(t *testing.T) {
+		_, err := NewTemplateWatcher("non-existing")
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,195 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package audit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nuts-foundation/nuts-auth/logging"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+)
+
+// EventSessionCreated is recorded when a signing session is requested, it is rejected when the session could not be started
+const EventSessionCreated = "session.created"
+
+// EventSessionStatus is recorded for every status transition of a signing session
+const EventSessionStatus = "session.status"
+
+// EventPresentationVerified is recorded when a verifiable presentation is verified
+const EventPresentationVerified = "presentation.verified"
+
+// EventAccessTokenCreated is recorded when an access token is requested
+const EventAccessTokenCreated = "accessToken.created"
+
+// EventAccessTokenRefreshed is recorded when an access token is requested with a refresh token
+const EventAccessTokenRefreshed = "accessToken.refreshed"
+
+// OutcomeAccepted is the outcome of a request which succeeded
+const OutcomeAccepted = "accepted"
+
+// OutcomeRejected is the outcome of a request which was refused, the entry contains the reason
+const OutcomeRejected = "rejected"
+
+// Entry is a single record of the audit log. Only the fields relevant for the event are filled in.
+type Entry struct {
+	// Sequence is the position of the entry in a hash chained log, starting at 1
+	Sequence uint64 `json:"seq,omitempty"`
+	// Time is the moment the event happened, in UTC
+	Time time.Time `json:"time"`
+	// Event is the type of event, like EventSessionCreated
+	Event string `json:"event"`
+	// SessionID identifies the signing session
+	SessionID string `json:"sessionID,omitempty"`
+	// Means is the signing means of the session or the type of the verified presentation
+	Means string `json:"means,omitempty"`
+	// LegalEntity is the identifier or name of the legal entity on whose behalf the contract is signed or the token is requested
+	LegalEntity string `json:"legalEntity,omitempty"`
+	// Custodian identifies the party which is asked for an access token
+	Custodian string `json:"custodian,omitempty"`
+	// ContractHash identifies the contract which is signed, see contract.Contract Hash
+	ContractHash string `json:"contractHash,omitempty"`
+	// Status is the canonical status of a signing session
+	Status string `json:"status,omitempty"`
+	// NativeStatus is the status of a signing session as reported by the signing means
+	NativeStatus string `json:"nativeStatus,omitempty"`
+	// Caller identifies the client which made the request
+	Caller string `json:"caller,omitempty"`
+	// Signer contains the attributes the signer disclosed
+	Signer map[string]string `json:"signer,omitempty"`
+	// Outcome is OutcomeAccepted or OutcomeRejected
+	Outcome string `json:"outcome,omitempty"`
+	// Reason explains why a request was rejected
+	Reason string `json:"reason,omitempty"`
+	// PrevHash is the Hash of the previous entry in a hash chained log, it is empty for the first entry
+	PrevHash string `json:"prevHash,omitempty"`
+	// Hash is the hex encoded SHA-256 hash of the entry without its Hash, in a hash chained log
+	Hash string `json:"hash,omitempty"`
+}
+
+// Sink stores audit entries. A Sink is called for one entry at a time.
+type Sink interface {
+	// Write stores the entry
+	Write(entry Entry) error
+	// Close releases the resources of the sink
+	Close() error
+}
+
+// Auditor records audit entries in its sinks. A nil Auditor records nothing, so it can be used when auditing is disabled.
+type Auditor struct {
+	mutex sync.Mutex
+	sinks []Sink
+	now   func() time.Time
+}
+
+// NewAuditor creates an Auditor which records the entries in the given sinks
+func NewAuditor(sinks ...Sink) *Auditor {
+	return &Auditor{sinks: sinks, now: time.Now}
+}
+
+// AddSink adds a sink, it receives the entries which are recorded from now on
+func (a *Auditor) AddSink(sink Sink) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.sinks = append(a.sinks, sink)
+}
+
+// Enabled returns true when the entries are recorded in at least one sink
+func (a *Auditor) Enabled() bool {
+	if a == nil {
+		return false
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return len(a.sinks) > 0
+}
+
+// Record writes the entry to all sinks. The Time is set when it is zero. A sink which fails is logged, it does not
+// stop the other sinks nor the request which is audited.
+func (a *Auditor) Record(entry Entry) {
+	if a == nil {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if entry.Time.IsZero() {
+		entry.Time = a.now()
+	}
+	entry.Time = entry.Time.UTC()
+	for _, sink := range a.sinks {
+		if err := sink.Write(entry); err != nil {
+			logging.Log().WithError(err).Errorf("unable to write audit entry %s", entry.Event)
+		}
+	}
+}
+
+// TrackSession records the status transitions of the signing session until it reaches its final status.
+// The entry provides the fields which are recorded with every transition.
+func (a *Auditor) TrackSession(sessionID string, entry Entry, signer contract.Signer) {
+	if !a.Enabled() {
+		return
+	}
+	// the listener may be called before Subscribe returns, so the subscription is passed through a channel
+	subscription := make(chan func(), 1)
+	var (
+		mutex        sync.Mutex
+		nativeStatus string
+		done         bool
+	)
+	subscription <- signer.Subscribe(sessionID, func(result contract.SigningSessionResult) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if done || result.NativeStatus() == nativeStatus {
+			return
+		}
+		nativeStatus = result.NativeStatus()
+		transition := entry
+		transition.Event = EventSessionStatus
+		transition.SessionID = sessionID
+		transition.Status = string(result.Status())
+		transition.NativeStatus = nativeStatus
+		transition.Time = time.Time{}
+		a.Record(transition)
+		if result.Status().Final() {
+			done = true
+			// Subscribe may not have returned yet
+			go func() {
+				unsubscribe := <-subscription
+				unsubscribe()
+			}()
+		}
+	})
+}
+
+// Close closes all sinks and returns the first error
+func (a *Auditor) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	var firstErr error
+	for _, sink := range a.sinks {
+		if err := sink.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	a.sinks = nil
+	return firstErr
+}
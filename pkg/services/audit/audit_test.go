@@ -0,0 +1,171 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package audit
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	mock_contract "github.com/nuts-foundation/nuts-auth/mock/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+)
+
+// memorySink keeps the entries in memory
+type memorySink struct {
+	mutex   sync.Mutex
+	entries []Entry
+	err     error
+	closed  bool
+}
+
+func (m *memorySink) Write(entry Entry) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *memorySink) Close() error {
+	m.closed = true
+	return m.err
+}
+
+func (m *memorySink) Entries() []Entry {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]Entry(nil), m.entries...)
+}
+
+func sessionResult(ctrl *gomock.Controller, status contract.SessionStatus, nativeStatus string) contract.SigningSessionResult {
+	result := mock_contract.NewMockSigningSessionResult(ctrl)
+	result.EXPECT().Status().Return(status).AnyTimes()
+	result.EXPECT().NativeStatus().Return(nativeStatus).AnyTimes()
+	return result
+}
+
+func TestAuditor_Record(t *testing.T) {
+	t.Run("ok - entry is written to all sinks in UTC", func(t *testing.T) {
+		first, second := &memorySink{}, &memorySink{}
+		auditor := NewAuditor(first)
+		auditor.AddSink(second)
+		moment := time.Date(2020, 1, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+		auditor.Record(Entry{Time: moment, Event: EventSessionCreated})
+
+		if !assert.Len(t, first.Entries(), 1) || !assert.Len(t, second.Entries(), 1) {
+			return
+		}
+		assert.Equal(t, time.UTC, first.Entries()[0].Time.Location())
+		assert.True(t, moment.Equal(first.Entries()[0].Time))
+	})
+
+	t.Run("ok - time is set when missing", func(t *testing.T) {
+		sink := &memorySink{}
+		auditor := NewAuditor(sink)
+		auditor.now = func() time.Time { return time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC) }
+
+		auditor.Record(Entry{Event: EventSessionCreated})
+
+		assert.Equal(t, auditor.now(), sink.Entries()[0].Time)
+	})
+
+	t.Run("ok - failing sink does not stop other sinks", func(t *testing.T) {
+		failing, sink := &memorySink{err: errors.New("disk full")}, &memorySink{}
+		auditor := NewAuditor(failing, sink)
+
+		auditor.Record(Entry{Event: EventSessionCreated})
+
+		assert.Len(t, sink.Entries(), 1)
+	})
+
+	t.Run("ok - nil auditor records nothing", func(t *testing.T) {
+		var auditor *Auditor
+
+		assert.False(t, auditor.Enabled())
+		auditor.Record(Entry{Event: EventSessionCreated})
+		auditor.TrackSession("123", Entry{}, nil)
+		assert.NoError(t, auditor.Close())
+	})
+
+	t.Run("ok - auditor without sinks is disabled", func(t *testing.T) {
+		assert.False(t, NewAuditor().Enabled())
+		assert.True(t, NewAuditor(&memorySink{}).Enabled())
+	})
+}
+
+func TestAuditor_TrackSession(t *testing.T) {
+	t.Run("ok - transitions are recorded until the final status", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		sink := &memorySink{}
+		auditor := NewAuditor(sink)
+		notifier := contract.NewSessionNotifier()
+		signer := mock_contract.NewMockSigner(ctrl)
+		signer.EXPECT().Subscribe("123", gomock.Any()).DoAndReturn(notifier.Subscribe)
+
+		auditor.TrackSession("123", Entry{Means: "dummy", LegalEntity: "urn:oid:1.2.3:foo"}, signer)
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionInProgress, "in-progress"))
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionInProgress, "in-progress"))
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionCompleted, "completed"))
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionCompleted, "completed"))
+
+		entries := sink.Entries()
+		if !assert.Len(t, entries, 2) {
+			return
+		}
+		assert.Equal(t, EventSessionStatus, entries[0].Event)
+		assert.Equal(t, "123", entries[0].SessionID)
+		assert.Equal(t, "dummy", entries[0].Means)
+		assert.Equal(t, "urn:oid:1.2.3:foo", entries[0].LegalEntity)
+		assert.Equal(t, string(contract.SessionInProgress), entries[0].Status)
+		assert.Equal(t, "in-progress", entries[0].NativeStatus)
+		assert.Equal(t, string(contract.SessionCompleted), entries[1].Status)
+		assert.Equal(t, "completed", entries[1].NativeStatus)
+		assert.Eventually(t, func() bool {
+			return !notifier.HasListeners("123")
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("ok - disabled auditor does not subscribe", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		NewAuditor().TrackSession("123", Entry{}, mock_contract.NewMockSigner(ctrl))
+	})
+}
+
+func TestAuditor_Close(t *testing.T) {
+	first, second := &memorySink{err: errors.New("b0rk")}, &memorySink{}
+	auditor := NewAuditor(first, second)
+
+	err := auditor.Close()
+
+	assert.EqualError(t, err, "b0rk")
+	assert.True(t, first.closed)
+	assert.True(t, second.closed)
+	assert.False(t, auditor.Enabled())
+}
@@ -0,0 +1,190 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrBrokenChain is returned when an entry of a hash chained audit log was changed, removed or inserted
+var ErrBrokenChain = errors.New("audit log chain is broken")
+
+// maxEntrySize is the maximum size of a single line of the audit log
+const maxEntrySize = 1024 * 1024
+
+// FileSink appends the entries as JSON lines to a file. Every entry contains the hash of the previous entry, so changes
+// to the file can be detected with Verify. The file is only appended to.
+type FileSink struct {
+	mutex    sync.Mutex
+	file     *os.File
+	sequence uint64
+	lastHash string
+}
+
+// NewFileSink opens or creates the audit log at the given path. The chain of an existing log continues after its last entry.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log: %w", err)
+	}
+	last, err := lastEntry(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("unable to read audit log %s: %w", path, err)
+	}
+	sink := &FileSink{file: file}
+	if last != nil {
+		sink.sequence = last.Sequence
+		sink.lastHash = last.Hash
+	}
+	return sink, nil
+}
+
+// lastEntry returns the last entry of the log or nil when the log is empty
+func lastEntry(reader io.Reader) (*Entry, error) {
+	var last []byte
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntrySize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, nil
+	}
+	entry := &Entry{}
+	if err := json.Unmarshal(last, entry); err != nil {
+		return nil, fmt.Errorf("%w: last entry is invalid: %s", ErrBrokenChain, err)
+	}
+	return entry, nil
+}
+
+// Write appends the entry to the log, chained to the previous entry. The file is synced before Write returns.
+func (f *FileSink) Write(entry Entry) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	entry.Sequence = f.sequence + 1
+	entry.PrevHash = f.lastHash
+	hash, err := hashEntry(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := f.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := f.file.Sync(); err != nil {
+		return err
+	}
+	f.sequence = entry.Sequence
+	f.lastHash = entry.Hash
+	return nil
+}
+
+// Close closes the file
+func (f *FileSink) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.file.Close()
+}
+
+// hashEntry returns the hex encoded SHA-256 hash of the JSON encoding of the entry without its Hash
+func hashEntry(entry Entry) (string, error) {
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyResult describes a verified audit log
+type VerifyResult struct {
+	// Entries is the number of entries in the log
+	Entries uint64
+	// LastHash is the hash of the last entry, it can be kept elsewhere to detect the removal of the last entries by a later Verify
+	LastHash string
+}
+
+// Verify reads a hash chained audit log and checks that every entry has the expected sequence number, refers to the hash
+// of the previous entry and has not been changed. It returns an error wrapping ErrBrokenChain with the line number
+// of the first entry which does not match. When lastHash is given, the log must contain the entry with this hash, it
+// is the LastHash of an earlier verification which detects the removal of the entries at the end of the log.
+func Verify(reader io.Reader, lastHash string) (*VerifyResult, error) {
+	result := &VerifyResult{}
+	found := lastHash == ""
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEntrySize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return result, fmt.Errorf("%w: line %d is not a valid entry: %s", ErrBrokenChain, line, err)
+		}
+		if entry.Sequence != result.Entries+1 {
+			return result, fmt.Errorf("%w: line %d has sequence %d, expected %d", ErrBrokenChain, line, entry.Sequence, result.Entries+1)
+		}
+		if entry.PrevHash != result.LastHash {
+			return result, fmt.Errorf("%w: line %d does not refer to the previous entry", ErrBrokenChain, line)
+		}
+		hash, err := hashEntry(entry)
+		if err != nil {
+			return result, err
+		}
+		if entry.Hash != hash {
+			return result, fmt.Errorf("%w: line %d has been changed", ErrBrokenChain, line)
+		}
+		// fields which are unknown to the Entry are not part of the hash, the re-encoded entry reveals them
+		if encoded, err := json.Marshal(entry); err != nil || !bytes.Equal(encoded, scanner.Bytes()) {
+			return result, fmt.Errorf("%w: line %d has been changed", ErrBrokenChain, line)
+		}
+		result.Entries = entry.Sequence
+		result.LastHash = entry.Hash
+		found = found || entry.Hash == lastHash
+	}
+	if err := scanner.Err(); err != nil {
+		return result, err
+	}
+	if !found {
+		return result, fmt.Errorf("%w: the entry with hash %s is missing", ErrBrokenChain, lastHash)
+	}
+	return result, nil
+}
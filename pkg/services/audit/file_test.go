@@ -0,0 +1,173 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	testIo "github.com/nuts-foundation/nuts-go-test/io"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeLog writes the given amount of entries to a new audit log and returns its lines
+func writeLog(t *testing.T, entries int) []string {
+	file, err := ioutil.TempFile(testIo.TestDirectory(t), "audit")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	path := file.Name()
+	_ = file.Close()
+	sink, err := NewFileSink(path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for i := 0; i < entries; i++ {
+		assert.NoError(t, sink.Write(Entry{Time: time.Now().UTC(), Event: EventSessionCreated, SessionID: string(rune('a' + i)), Outcome: OutcomeAccepted}))
+	}
+	assert.NoError(t, sink.Close())
+	data, _ := ioutil.ReadFile(path)
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+func verifyLines(lines []string) (*VerifyResult, error) {
+	return Verify(strings.NewReader(strings.Join(lines, "\n")+"\n"), "")
+}
+
+func TestFileSink(t *testing.T) {
+	t.Run("ok - entries are chained", func(t *testing.T) {
+		lines := writeLog(t, 3)
+
+		result, err := verifyLines(lines)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, lines, 3)
+		assert.Equal(t, uint64(3), result.Entries)
+		assert.Len(t, result.LastHash, 64)
+	})
+
+	t.Run("ok - reopened log continues the chain", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "audit.log")
+		for i := 0; i < 2; i++ {
+			sink, err := NewFileSink(path)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NoError(t, sink.Write(Entry{Event: EventSessionCreated}))
+			assert.NoError(t, sink.Close())
+		}
+		data, _ := ioutil.ReadFile(path)
+
+		result, err := Verify(bytes.NewReader(data), "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(2), result.Entries)
+	})
+
+	t.Run("error - last entry is invalid", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "audit.log")
+		_ = ioutil.WriteFile(path, []byte("not json\n"), 0600)
+
+		_, err := NewFileSink(path)
+
+		assert.True(t, errors.Is(err, ErrBrokenChain))
+	})
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("ok - empty log", func(t *testing.T) {
+		result, err := Verify(strings.NewReader(""), "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(0), result.Entries)
+	})
+
+	t.Run("error - changed entry", func(t *testing.T) {
+		lines := writeLog(t, 3)
+		lines[1] = strings.Replace(lines[1], `"sessionID":"b"`, `"sessionID":"x"`, 1)
+
+		result, err := verifyLines(lines)
+
+		assert.True(t, errors.Is(err, ErrBrokenChain))
+		assert.Contains(t, err.Error(), "line 2 has been changed")
+		assert.Equal(t, uint64(1), result.Entries)
+	})
+
+	t.Run("error - added field", func(t *testing.T) {
+		lines := writeLog(t, 1)
+		lines[0] = strings.Replace(lines[0], `{`, `{"extra":"field",`, 1)
+
+		_, err := verifyLines(lines)
+
+		assert.Contains(t, err.Error(), "line 1 has been changed")
+	})
+
+	t.Run("error - removed entry", func(t *testing.T) {
+		lines := writeLog(t, 3)
+
+		_, err := verifyLines(append(lines[:1], lines[2:]...))
+
+		assert.True(t, errors.Is(err, ErrBrokenChain))
+		assert.Contains(t, err.Error(), "line 2 has sequence 3, expected 2")
+	})
+
+	t.Run("error - inserted entry", func(t *testing.T) {
+		lines := writeLog(t, 2)
+		other := writeLog(t, 2)
+
+		_, err := verifyLines([]string{lines[0], other[1], lines[1]})
+
+		assert.True(t, errors.Is(err, ErrBrokenChain))
+		assert.Contains(t, err.Error(), "line 2 does not refer to the previous entry")
+	})
+
+	t.Run("ok - log contains the last hash of an earlier verification", func(t *testing.T) {
+		lines := writeLog(t, 3)
+		earlier, _ := verifyLines(lines[:2])
+
+		result, err := Verify(strings.NewReader(strings.Join(lines, "\n")), earlier.LastHash)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(3), result.Entries)
+	})
+
+	t.Run("error - last entries are removed", func(t *testing.T) {
+		lines := writeLog(t, 3)
+		earlier, _ := verifyLines(lines)
+
+		_, err := Verify(strings.NewReader(strings.Join(lines[:2], "\n")), earlier.LastHash)
+
+		assert.True(t, errors.Is(err, ErrBrokenChain))
+		assert.Contains(t, err.Error(), "is missing")
+	})
+
+	t.Run("error - invalid entry", func(t *testing.T) {
+		_, err := Verify(strings.NewReader("{\n"), "")
+
+		assert.True(t, errors.Is(err, ErrBrokenChain))
+		assert.Contains(t, err.Error(), "line 1 is not a valid entry")
+	})
+}
@@ -0,0 +1,171 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package boltdb
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// LockTimeout is the maximum time to wait for the lock on a file which is held by another process
+const LockTimeout = 5 * time.Second
+
+var (
+	mutex sync.Mutex
+	files = map[string]*file{}
+)
+
+// file is a bbolt file which is shared by all DB's of this process. A file which is not shared with other processes
+// is opened once and kept open. A shared file is opened for every transaction, its lock serializes the transactions of this process.
+type file struct {
+	path   string
+	shared bool
+	db     *bbolt.DB
+	lock   sync.RWMutex
+	refs   int
+}
+
+// DB gives access to the buckets of a bbolt file. The file is opened by the first DB and locked until the last DB
+// which uses it is closed, so the stores of this process can share a file without locking each other out.
+// A DB returned by OpenShared only locks the file for the duration of a transaction, so nodes can share the file on a volume.
+type DB struct {
+	file   *file
+	closed bool
+}
+
+// Open returns a DB for the bbolt file at the given path and creates the given buckets. The file is created when it does not exist.
+// The file is locked until the DB is closed. Every DB must be closed.
+func Open(path string, buckets ...[]byte) (*DB, error) {
+	return open(path, false, buckets)
+}
+
+// OpenShared returns a DB for the bbolt file at the given path like Open, but the file is only opened and locked for the duration
+// of a transaction. Processes which share the file take turns, at the expense of opening the file for every transaction.
+func OpenShared(path string, buckets ...[]byte) (*DB, error) {
+	return open(path, true, buckets)
+}
+
+func open(path string, shared bool, buckets [][]byte) (*DB, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", path, err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	f, ok := files[absPath]
+	if ok && f.shared != shared {
+		return nil, fmt.Errorf("could not open %s: it is already opened by this process with a different sharing mode", path)
+	}
+	if !ok {
+		f = &file{path: absPath, shared: shared}
+		if !shared {
+			if f.db, err = bbolt.Open(absPath, 0600, &bbolt.Options{Timeout: LockTimeout}); err != nil {
+				return nil, fmt.Errorf("could not open %s: %w", path, err)
+			}
+		}
+	}
+	err = f.update(func(tx *bbolt.Tx) error {
+		for _, bucket := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if !ok && !shared {
+			_ = f.db.Close()
+		}
+		return nil, fmt.Errorf("could not open %s: %w", path, err)
+	}
+	f.refs++
+	files[absPath] = f
+	return &DB{file: f}, nil
+}
+
+// Update runs the function in a read-write transaction on the bucket, which must have been created by Open
+func (d *DB) Update(bucket []byte, fn func(bucket *bbolt.Bucket) error) error {
+	return d.file.update(func(tx *bbolt.Tx) error {
+		return fn(tx.Bucket(bucket))
+	})
+}
+
+// UpdateTx runs the function in a read-write transaction on the file, for updates which span several buckets
+func (d *DB) UpdateTx(fn func(tx *bbolt.Tx) error) error {
+	return d.file.update(fn)
+}
+
+// View runs the function in a read-only transaction on the bucket, which must have been created by Open
+func (d *DB) View(bucket []byte, fn func(bucket *bbolt.Bucket) error) error {
+	return d.file.view(func(tx *bbolt.Tx) error {
+		return fn(tx.Bucket(bucket))
+	})
+}
+
+// Close releases the file, it is closed when no other DB uses it
+func (d *DB) Close() error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	f := d.file
+	f.refs--
+	if f.refs > 0 {
+		return nil
+	}
+	delete(files, f.path)
+	if f.shared {
+		return nil
+	}
+	return f.db.Close()
+}
+
+func (f *file) update(fn func(tx *bbolt.Tx) error) error {
+	if !f.shared {
+		return f.db.Update(fn)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	db, err := bbolt.Open(f.path, 0600, &bbolt.Options{Timeout: LockTimeout})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+func (f *file) view(fn func(tx *bbolt.Tx) error) error {
+	if !f.shared {
+		return f.db.View(fn)
+	}
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	db, err := bbolt.Open(f.path, 0600, &bbolt.Options{Timeout: LockTimeout, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
@@ -0,0 +1,143 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package boltdb
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	testIo "github.com/nuts-foundation/nuts-go-test/io"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+var bucket = []byte("bucket")
+
+func TestOpen(t *testing.T) {
+	t.Run("ok - buckets are created", func(t *testing.T) {
+		db, err := Open(filepath.Join(testIo.TestDirectory(t), "test.db"), bucket)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer db.Close()
+
+		err = db.View(bucket, func(b *bbolt.Bucket) error {
+			assert.NotNil(t, b)
+			return nil
+		})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok - DB's of the same file share the file", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "test.db")
+		db1, _ := Open(path, bucket)
+		db2, err := Open(path, []byte("other"))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_ = db1.Update(bucket, func(b *bbolt.Bucket) error {
+			return b.Put([]byte("key"), []byte("value"))
+		})
+		_ = db1.Close()
+		var value []byte
+		err = db2.View(bucket, func(b *bbolt.Bucket) error {
+			value = b.Get([]byte("key"))
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "value", string(value))
+		assert.NoError(t, db2.Close())
+		assert.Empty(t, files)
+	})
+
+	t.Run("ok - closing twice releases the file once", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "test.db")
+		db1, _ := Open(path, bucket)
+		db2, _ := Open(path, bucket)
+		defer db2.Close()
+
+		_ = db1.Close()
+		_ = db1.Close()
+
+		assert.NoError(t, db2.Update(bucket, func(b *bbolt.Bucket) error {
+			return b.Put([]byte("key"), []byte("value"))
+		}))
+	})
+
+	t.Run("ok - a shared file is only locked during a transaction", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "test.db")
+		db, err := OpenShared(path, bucket)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer db.Close()
+
+		// another process can open the file in between the transactions
+		other, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 100 * time.Millisecond})
+		if !assert.NoError(t, err) {
+			return
+		}
+		_ = other.Update(func(tx *bbolt.Tx) error {
+			return tx.Bucket(bucket).Put([]byte("key"), []byte("value"))
+		})
+		_ = other.Close()
+		var value string
+		err = db.View(bucket, func(b *bbolt.Bucket) error {
+			value = string(b.Get([]byte("key")))
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "value", value)
+		assert.NoError(t, db.Update(bucket, func(b *bbolt.Bucket) error {
+			return b.Delete([]byte("key"))
+		}))
+	})
+
+	t.Run("error - file is locked by another process", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "test.db")
+		db, _ := Open(path, bucket)
+		defer db.Close()
+
+		_, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 100 * time.Millisecond})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("error - file is opened with another sharing mode", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "test.db")
+		db, _ := Open(path, bucket)
+		defer db.Close()
+
+		_, err := OpenShared(path, bucket)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("error - file can not be created", func(t *testing.T) {
+		_, err := Open(filepath.Join(testIo.TestDirectory(t), "missing", "test.db"), bucket)
+
+		assert.Error(t, err)
+		assert.Empty(t, files)
+	})
+}
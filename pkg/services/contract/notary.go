@@ -37,14 +37,17 @@ type contractNotaryService struct {
 	Registry         registry.RegistryClient
 	Crypto           nutscrypto.Client
 	ContractValidity time.Duration
+	// TimeZones contains the time zones of legal entities which draw up contracts in another time zone than the template
+	TimeZones map[core.PartyID]*time.Location
 }
 
 // timeNow can be used during tests to overwrite the current time.
 var timeNow = time.Now
 
-// NewContractNotary accepts the registry and crypto Nuts engines and returns a ContractNotary
-func NewContractNotary(reg registry.RegistryClient, crypto nutscrypto.Client, contractValidity time.Duration) services.ContractNotary {
-	return &contractNotaryService{Registry: reg, ContractValidity: contractValidity, Crypto: crypto}
+// NewContractNotary accepts the registry and crypto Nuts engines and returns a ContractNotary.
+// The timeZones override the time zone of the templates for the given legal entities, it may be nil.
+func NewContractNotary(reg registry.RegistryClient, crypto nutscrypto.Client, contractValidity time.Duration, timeZones map[core.PartyID]*time.Location) services.ContractNotary {
+	return &contractNotaryService{Registry: reg, ContractValidity: contractValidity, Crypto: crypto, TimeZones: timeZones}
 }
 
 // organizationNameByID returns the name of an organisation from the registry
@@ -62,9 +65,11 @@ func (s *contractNotaryService) KeyExistsFor(legalEntity core.PartyID) bool {
 }
 
 // DrawUpContract accepts a template and fills in the Party, validFrom time and its duration.
+// Other template attributes, like the subject and purpose of use, are taken from the given attributes.
 // If validFrom is zero, the current time is used.
 // If the duration is 0 than the default duration is used.
-func (s *contractNotaryService) DrawUpContract(template contract.Template, orgID core.PartyID, validFrom time.Time, validDuration time.Duration) (*contract.Contract, error) {
+// The dates are rendered in the time zone of the template, unless a time zone is configured for the organization.
+func (s *contractNotaryService) DrawUpContract(template contract.Template, orgID core.PartyID, validFrom time.Time, validDuration time.Duration, attributes map[string]string) (*contract.Contract, error) {
 	// Test if the org in managed by this node:
 	if !s.KeyExistsFor(orgID) {
 		return nil, fmt.Errorf("could not draw up contract: organization is not managed by this node: %w", validator.ErrMissingOrganizationKey)
@@ -75,9 +80,12 @@ func (s *contractNotaryService) DrawUpContract(template contract.Template, orgID
 	if err != nil {
 		return nil, fmt.Errorf("could not draw up contract: %w", err)
 	}
-	contractAttrs := map[string]string{
-		contract.LegalEntityAttr: orgName,
+	contractAttrs := map[string]string{}
+	for name, value := range attributes {
+		contractAttrs[name] = value
 	}
+	// the legal entity and validity are always determined by the notary
+	contractAttrs[contract.LegalEntityAttr] = orgName
 
 	if validDuration == 0 {
 		validDuration = s.ContractValidity
@@ -86,7 +94,7 @@ func (s *contractNotaryService) DrawUpContract(template contract.Template, orgID
 		validFrom = timeNow()
 	}
 
-	drawnUpContract, err := template.Render(contractAttrs, validFrom, validDuration)
+	drawnUpContract, err := template.RenderInLocation(contractAttrs, validFrom, validDuration, s.TimeZones[orgID])
 	if err != nil {
 		return nil, fmt.Errorf("could not draw up contract: %w", err)
 	}
@@ -107,6 +115,9 @@ func (s *contractNotaryService) ValidateContract(contractToValidate contract.Con
 		return false, errors.New("legalEntity not part of the contract")
 	}
 	le, err := s.Registry.ReverseLookup(legalEntityName)
+	if err != nil {
+		return false, fmt.Errorf("legalEntityName '%s' could not be found: %w", legalEntityName, err)
+	}
 	if le.Identifier != orgID {
 		return false, fmt.Errorf("legalEntityName '%s' does not match as the name for legalEntity with id: '%s':'%s'", legalEntityName, orgID.String(), le.Name)
 	}
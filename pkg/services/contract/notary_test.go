@@ -19,6 +19,7 @@
 package contract
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -64,6 +65,25 @@ func Test_contractNotaryService_ValidateContract(t *testing.T) {
 		assert.True(t, ok)
 		assert.NoError(t, err)
 	})
+
+	t.Run("nok - legal entity name is unknown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		registryMock := mock.NewMockRegistryClient(ctrl)
+		cns := contractNotaryService{Registry: registryMock}
+		contractTemplate, _ := contract.StandardContractTemplates.FindFromRawContractText("EN:PractitionerLogin:v3")
+		orgID, _ := core.ParsePartyID("urn:oid:1.2.3.4:556611")
+		registryMock.EXPECT().ReverseLookup("zorginstelling het hoekje").Return(nil, errors.New("not found"))
+		contractToCheck, _ := contractTemplate.Render(map[string]string{
+			contract.LegalEntityAttr: "zorginstelling het hoekje",
+		}, time.Now().Add(-10*time.Minute), 20*time.Minute)
+
+		ok, err := cns.ValidateContract(*contractToCheck, orgID, time.Now())
+
+		assert.False(t, ok)
+		assert.EqualError(t, err, "legalEntityName 'zorginstelling het hoekje' could not be found: not found")
+	})
 }
 func Test_contractNotaryService_KeyExistsFor(t *testing.T) {
 
@@ -117,6 +137,7 @@ func Test_contractNotaryService_DrawUpContract(t *testing.T) {
 	}
 
 	template := contract.Template{
+		Language: "NL",
 		Template: "Organisation Name: {{legal_entity}}, valid from {{valid_from}} to {{valid_to}}",
 	}
 	orgID := core.PartyID{}
@@ -131,7 +152,7 @@ func Test_contractNotaryService_DrawUpContract(t *testing.T) {
 		ctx.cryptoMock.EXPECT().PrivateKeyExists(gomock.Any()).AnyTimes().Return(true)
 		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).AnyTimes().Return(&db.Organization{Name: "CareBears"}, nil)
 
-		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, duration)
+		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, duration, nil)
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -140,6 +161,82 @@ func Test_contractNotaryService_DrawUpContract(t *testing.T) {
 		assert.Equal(t, "Organisation Name: CareBears, valid from maandag, 1 januari 0001 00:19:33 to maandag, 1 januari 0001 00:29:33", drawnUpContract.RawContractText)
 	})
 
+	t.Run("draw up patient context contract", func(t *testing.T) {
+		ctx := buildContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(gomock.Any()).AnyTimes().Return(true)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).AnyTimes().Return(&db.Organization{Name: "CareBears"}, nil)
+
+		template := contract.StandardContractTemplates.Get("PractitionerPatientAccess", "EN", "v1")
+		attributes := map[string]string{
+			contract.SubjectAttr:      "urn:oid:2.16.840.1.113883.2.4.6.3:999999990",
+			contract.PurposeOfUseAttr: "TREAT",
+			// the legal entity can not be overridden
+			contract.LegalEntityAttr: "Other org",
+		}
+		validFrom := time.Date(2020, 10, 5, 11, 30, 0, 0, time.UTC)
+		drawnUpContract, err := ctx.notary.DrawUpContract(*template, orgID, validFrom, duration, attributes)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, "CareBears", drawnUpContract.Params[contract.LegalEntityAttr])
+		assert.Equal(t, "urn:oid:2.16.840.1.113883.2.4.6.3:999999990", drawnUpContract.Params[contract.SubjectAttr])
+		assert.Equal(t, "TREAT", drawnUpContract.Params[contract.PurposeOfUseAttr])
+	})
+
+	t.Run("draw up contract from a deprecated standard template", func(t *testing.T) {
+		ctx := buildContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(gomock.Any()).AnyTimes().Return(true)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).AnyTimes().Return(&db.Organization{Name: "CareBears"}, nil)
+
+		for _, version := range []contract.Version{"v1", "v2"} {
+			template := contract.StandardContractTemplates.Get("BehandelaarLogin", "NL", version)
+			validFrom := time.Date(2020, 11, 5, 11, 30, 0, 0, time.UTC)
+			drawnUpContract, err := ctx.notary.DrawUpContract(*template, orgID, validFrom, duration, map[string]string{contract.ActingPartyAttr: "Demo EHR"})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, []string{"contract template NL:BehandelaarLogin:" + string(version) + " is deprecated since 2020-11-01T00:00:00Z"}, drawnUpContract.Warnings)
+		}
+	})
+
+	t.Run("draw up patient context contract without subject", func(t *testing.T) {
+		ctx := buildContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(gomock.Any()).AnyTimes().Return(true)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).AnyTimes().Return(&db.Organization{Name: "CareBears"}, nil)
+
+		template := contract.StandardContractTemplates.Get("PractitionerPatientAccess", "EN", "v1")
+		validFrom := time.Date(2020, 10, 5, 11, 30, 0, 0, time.UTC)
+		_, err := ctx.notary.DrawUpContract(*template, orgID, validFrom, duration, map[string]string{contract.PurposeOfUseAttr: "TREAT"})
+
+		assert.True(t, errors.Is(err, contract.ErrInvalidAttributeValue))
+	})
+
+	t.Run("draw up contract in the time zone of the organization", func(t *testing.T) {
+		ctx := buildContext(t)
+		defer ctx.ctrl.Finish()
+		kralendijk, _ := time.LoadLocation("America/Kralendijk")
+		ctx.notary.TimeZones = map[core.PartyID]*time.Location{orgID: kralendijk}
+
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(gomock.Any()).AnyTimes().Return(true)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).AnyTimes().Return(&db.Organization{Name: "CareBears"}, nil)
+
+		validFrom := time.Date(2020, 10, 5, 11, 30, 0, 0, time.UTC)
+		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, duration, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, "Organisation Name: CareBears, valid from maandag, 5 oktober 2020 07:30:00 -04:00 to maandag, 5 oktober 2020 07:40:00 -04:00", drawnUpContract.RawContractText)
+	})
+
 	t.Run("no given duration uses default", func(t *testing.T) {
 		ctx := buildContext(t)
 		defer ctx.ctrl.Finish()
@@ -147,7 +244,7 @@ func Test_contractNotaryService_DrawUpContract(t *testing.T) {
 		ctx.cryptoMock.EXPECT().PrivateKeyExists(gomock.Any()).AnyTimes().Return(true)
 		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).AnyTimes().Return(&db.Organization{Name: "CareBears"}, nil)
 
-		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, 0)
+		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, 0, nil)
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -167,7 +264,7 @@ func Test_contractNotaryService_DrawUpContract(t *testing.T) {
 			return time.Time{}.Add(10 * time.Second)
 		}
 		defer func() { timeNow = time.Now }()
-		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, time.Time{}, 0)
+		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, time.Time{}, 0, nil)
 		if !assert.NoError(t, err) {
 			return
 		}
@@ -182,7 +279,7 @@ func Test_contractNotaryService_DrawUpContract(t *testing.T) {
 
 		ctx.cryptoMock.EXPECT().PrivateKeyExists(gomock.Any()).Return(false)
 
-		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, duration)
+		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, duration, nil)
 		if assert.Error(t, err) {
 			assert.Equal(t, "could not draw up contract: organization is not managed by this node: missing organization private key", err.Error())
 		}
@@ -196,7 +293,7 @@ func Test_contractNotaryService_DrawUpContract(t *testing.T) {
 		ctx.cryptoMock.EXPECT().PrivateKeyExists(gomock.Any()).Return(true)
 		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Return(nil, db.ErrOrganizationNotFound)
 
-		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, duration)
+		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, duration, nil)
 		if assert.Error(t, err) {
 			assert.Equal(t, "could not draw up contract: organization not found", err.Error())
 		}
@@ -211,10 +308,11 @@ func Test_contractNotaryService_DrawUpContract(t *testing.T) {
 		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Return(&db.Organization{Name: "CareBears"}, nil)
 
 		template := contract.Template{
+			Language: "NL",
 			Template: "Organisation Name: {{{legal_entity}}, valid from {{valid_from}} to {{valid_to}}",
 		}
 
-		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, duration)
+		drawnUpContract, err := ctx.notary.DrawUpContract(template, orgID, validFrom, duration, nil)
 		if assert.Error(t, err) {
 			assert.Equal(t, "could not draw up contract: could not render contract template: line 1: unmatched open tag", err.Error())
 		}
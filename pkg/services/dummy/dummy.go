@@ -23,6 +23,8 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/nuts-foundation/nuts-auth/pkg/contract"
@@ -38,23 +40,84 @@ const VerifiablePresentationType = contract.VPType("DummyVerifiablePresentation"
 // NoSignatureType is a VerifiablePresentation Proof type where no signature is given
 const NoSignatureType = "NoSignature"
 
+// InvalidSignatureType is a VerifiablePresentation Proof type which never verifies, it is used by the ScenarioInvalidProof
+const InvalidSignatureType = "InvalidSignature"
+
 // SessionCreated represents the session state after creation
-const SessionCreated = "created"
+const SessionCreated = string(contract.SessionCreated)
 
 // SessionInProgress represents the session state after the first SessionStatus call
-const SessionInProgress = "in-progress"
+const SessionInProgress = string(contract.SessionInProgress)
 
 // SessionCompleted represents the session state after the second SessionStatus call
-const SessionCompleted = "completed"
+const SessionCompleted = string(contract.SessionCompleted)
+
+// PersonaParam is the session param which selects the persona who signs, the DefaultPersonaName is used when omitted
+const PersonaParam = "persona"
+
+// ScenarioParam is the session param which selects how the session ends, the ScenarioCompleted is used when omitted
+const ScenarioParam = "scenario"
+
+const (
+	// ScenarioCompleted lets the persona sign the contract
+	ScenarioCompleted = "completed"
+	// ScenarioCancelled lets the persona cancel the session
+	ScenarioCancelled = "cancelled"
+	// ScenarioTimeout lets the session expire without a signature
+	ScenarioTimeout = "timeout"
+	// ScenarioInvalidProof lets the persona sign the contract with a proof which does not verify
+	ScenarioInvalidProof = "invalid-proof"
+)
+
+// scenarioFinalStates contains the state in which a session of each scenario ends
+var scenarioFinalStates = map[string]string{
+	ScenarioCompleted:    SessionCompleted,
+	ScenarioCancelled:    services.SessionCancelled,
+	ScenarioTimeout:      services.SessionExpired,
+	ScenarioInvalidProof: SessionCompleted,
+}
 
 var errNotEnabled = errors.New("not allowed in strict mode")
 
-// Dummy is a contract signer and verifier that always succeeds unless you try to use it in strict mode
-// The dummy signer is not supposed to be used in a clustered context unless consecutive calls arrive at the same instance
+// ErrInvalidSessionParams is returned when a session is started with an unknown persona or scenario
+var ErrInvalidSessionParams = errors.New("invalid dummy session params")
+
+// Dummy is a contract signer and verifier that always succeeds unless you try to use it in strict mode or select
+// another scenario. The dummy signer can be used in a clustered context when the nodes share a persistent SessionStore.
+// A Dummy must not be copied after first use.
 type Dummy struct {
-	InStrictMode bool
-	Sessions     map[string]string
-	Status       map[string]string
+	InStrictMode      bool
+	Sessions          services.SessionStore
+	ContractTemplates contract.TemplateProvider
+	// Notifier notifies the subscribers of a session about its status transitions
+	Notifier *contract.SessionNotifier
+
+	mutex    sync.RWMutex
+	personas map[string]Persona
+}
+
+// SetPersonas replaces the configured personas. The "I. Tester" persona remains available under the DefaultPersonaName
+// unless it is replaced.
+func (d *Dummy) SetPersonas(personas map[string]Persona) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.personas = make(map[string]Persona, len(personas))
+	for name, persona := range personas {
+		d.personas[name] = persona
+	}
+}
+
+// Persona returns the persona with the given name
+func (d *Dummy) Persona(name string) (Persona, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if persona, ok := d.personas[name]; ok {
+		return persona, true
+	}
+	if name == DefaultPersonaName {
+		return defaultPersona, true
+	}
+	return nil, false
 }
 
 // Presentation is a VerifiablePresentation without valid cryptographic proofs
@@ -78,6 +141,8 @@ type Proof struct {
 	Birthdate string
 	// Email form the signing means
 	Email string
+	// Attributes contains all attributes of the persona who signed
+	Attributes map[string]string `json:",omitempty"`
 }
 
 // SignedToken is the Dummy implementation of a Signed token.
@@ -119,13 +184,20 @@ func (s sessionPointer) MarshalJSON() ([]byte, error) {
 }
 
 type signingSessionResult struct {
-	ID      string
-	State   string
-	Request string
+	ID       string
+	State    string
+	Request  string
+	Persona  Persona
+	Scenario string
 }
 
-// Status returns the current state of the signing session
-func (d signingSessionResult) Status() string {
+// Status returns the current status of the signing session, the dummy states are named after the contract.SessionStatus
+func (d signingSessionResult) Status() contract.SessionStatus {
+	return contract.SessionStatus(d.State)
+}
+
+// NativeStatus returns the current state of the signing session
+func (d signingSessionResult) NativeStatus() string {
 	return d.State
 }
 
@@ -133,9 +205,13 @@ func (d signingSessionResult) Status() string {
 func (d signingSessionResult) VerifiablePresentation() (contract.VerifiablePresentation, error) {
 	// todo: the contract template should be used to select the dummy attributes to add
 
-	if d.Status() != SessionCompleted {
+	if d.State != SessionCompleted {
 		return nil, nil
 	}
+	proofType := NoSignatureType
+	if d.Scenario == ScenarioInvalidProof {
+		proofType = InvalidSignatureType
+	}
 
 	return Presentation{
 		VerifiablePresentationBase: contract.VerifiablePresentationBase{
@@ -143,18 +219,20 @@ func (d signingSessionResult) VerifiablePresentation() (contract.VerifiablePrese
 			Type:    []contract.VPType{contract.VerifiablePresentationType, VerifiablePresentationType},
 		},
 		Proof: Proof{
-			Type:      NoSignatureType,
-			Initials:  "I",
-			Lastname:  "Tester",
-			Birthdate: "1980-01-01",
-			Email:     "tester@example.com",
-			Contract:  d.Request,
+			Type:       proofType,
+			Initials:   d.Persona["initials"],
+			Lastname:   d.Persona["lastname"],
+			Birthdate:  d.Persona["birthdate"],
+			Email:      d.Persona["email"],
+			Attributes: d.Persona,
+			Contract:   d.Request,
 		},
 	}, nil
 }
 
 // VerifyVP check a Dummy VerifiablePresentation. It Returns a verificationResult if all was fine, an error otherwise.
-func (d Dummy) VerifyVP(rawVerifiablePresentation []byte, checkTime *time.Time) (*contract.VPVerificationResult, error) {
+// A presentation with a proof of another type than NoSignatureType is invalid.
+func (d *Dummy) VerifyVP(rawVerifiablePresentation []byte, checkTime *time.Time) (*contract.VPVerificationResult, error) {
 	if d.InStrictMode {
 		return nil, errNotEnabled
 	}
@@ -164,74 +242,199 @@ func (d Dummy) VerifyVP(rawVerifiablePresentation []byte, checkTime *time.Time)
 		return nil, err
 	}
 
-	c, err := contract.ParseContractString(p.Proof.Contract, contract.StandardContractTemplates)
+	c, err := contract.ParseContractString(p.Proof.Contract, d.ContractTemplates.TemplatesFor(p.Proof.Contract))
 	if err != nil {
 		return nil, err
 	}
 
+	if p.Proof.Type != NoSignatureType {
+		return &contract.VPVerificationResult{
+			Validity: contract.Invalid,
+			VPType:   VerifiablePresentationType,
+			Reason:   fmt.Sprintf("invalid proof type: %s", p.Proof.Type),
+		}, nil
+	}
+
+	// the dummy means does not check the validity period, a contract without a valid one has no ValidTo
+	validTo, _ := c.ValidTo()
+
 	return &contract.VPVerificationResult{
-		Validity: contract.Valid,
-		VPType:   VerifiablePresentationType,
-		DisclosedAttributes: map[string]string{
-			"initials":  p.Proof.Initials,
-			"lastname":  p.Proof.Lastname,
-			"birthdate": p.Proof.Birthdate,
-			"email":     p.Proof.Initials,
-		},
-		ContractAttributes: c.Params,
+		Validity:            contract.Valid,
+		VPType:              VerifiablePresentationType,
+		ContractID:          c.Template.Identifier(),
+		ContractHash:        c.Hash(),
+		DisclosedAttributes: p.Proof.disclosedAttributes(),
+		ContractAttributes:  c.Params,
+		ValidTo:             validTo,
 	}, nil
 }
 
+// disclosedAttributes returns the attributes of the persona, presentations without them disclose the standard attributes
+func (p Proof) disclosedAttributes() map[string]string {
+	if len(p.Attributes) > 0 {
+		attributes := make(map[string]string, len(p.Attributes))
+		for name, value := range p.Attributes {
+			attributes[name] = value
+		}
+		return attributes
+	}
+	return map[string]string{
+		"initials":  p.Initials,
+		"lastname":  p.Lastname,
+		"birthdate": p.Birthdate,
+		"email":     p.Email,
+	}
+}
+
 // SigningSessionStatus looks up the session by the provided sessionID param.
-// When the session exists it returns the current state and advances the state to the next one.
-// When the session is SessionComplete, it removes the session from the sessionStore.
-func (d Dummy) SigningSessionStatus(sessionID string) (contract.SigningSessionResult, error) {
+// When the session exists it returns the current state and advances the state to the next one. After in-progress,
+// the session ends in the final state of its scenario. Finished sessions are removed from the sessionStore by the reaper.
+func (d *Dummy) SigningSessionStatus(sessionID string) (contract.SigningSessionResult, error) {
 	if d.InStrictMode {
 		return nil, errNotEnabled
 	}
 
-	state, ok := d.Status[sessionID]
-	if !ok {
-		return nil, services.ErrSessionNotFound
+	var result, next signingSessionResult
+	err := d.Sessions.Update(sessionID, func(session *services.SigningSession) error {
+		if session.Means != ContractFormat {
+			return services.ErrSessionNotFound
+		}
+		result = d.sessionResult(*session)
+		next = result
+		// an expired or cancelled session does not advance
+		if session.Terminated() {
+			return nil
+		}
+		// increase session status everytime this request is made
+		switch session.State {
+		case SessionCreated:
+			session.State = SessionInProgress
+		case SessionInProgress:
+			session.State = SessionCompleted
+			if finalState, ok := scenarioFinalStates[result.Scenario]; ok {
+				session.State = finalState
+			}
+		}
+		next.State = session.State
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	session, ok := d.Sessions[sessionID]
-	if !ok {
-		return nil, services.ErrSessionNotFound
+	if next.State != result.State {
+		d.Notifier.Notify(sessionID, next)
 	}
+	return result, nil
+}
 
-	// increase session status everytime this request is made
-	switch state {
-	case SessionCreated:
-		d.Status[sessionID] = SessionInProgress
-	case SessionInProgress:
-		d.Status[sessionID] = SessionCompleted
-	case SessionCompleted:
-		delete(d.Status, sessionID)
-		delete(d.Sessions, sessionID)
+// CancelSigningSession cancels the Dummy session, later status requests return services.SessionCancelled.
+// This method is not available in strictMode
+func (d *Dummy) CancelSigningSession(sessionID string) error {
+	if d.InStrictMode {
+		return errNotEnabled
 	}
-	return signingSessionResult{
-		ID:      sessionID,
-		State:   state,
-		Request: session,
-	}, nil
+	var cancelled signingSessionResult
+	err := d.Sessions.Update(sessionID, func(session *services.SigningSession) error {
+		if session.Means != ContractFormat {
+			return services.ErrSessionNotFound
+		}
+		session.State = services.SessionCancelled
+		cancelled = d.sessionResult(*session)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if d.ContractTemplates != nil {
+		d.ContractTemplates.Release(sessionID)
+	}
+	d.Notifier.Notify(sessionID, cancelled)
+	return nil
+}
+
+// Subscribe registers a listener for the status transitions of the Dummy session. Since the dummy session advances
+// on every status request, the listener is called when the session status is requested.
+func (d *Dummy) Subscribe(sessionID string, listener contract.SessionListener) func() {
+	return d.Notifier.Subscribe(sessionID, listener)
 }
 
 // StartSigningSession starts a Dummy session. It takes any string and stores it under a random sessionID.
+// The PersonaParam and ScenarioParam select who signs and how the session ends.
 // This method is not available in strictMode
 // returns the sessionPointer with the sessionID
-func (d Dummy) StartSigningSession(rawContractText string) (contract.SessionPointer, error) {
+func (d *Dummy) StartSigningSession(rawContractText string, params map[string]interface{}) (contract.SessionPointer, error) {
 	if d.InStrictMode {
 		return nil, errNotEnabled
 	}
+	persona, err := stringParam(params, PersonaParam, DefaultPersonaName)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := d.Persona(persona); !ok {
+		return nil, fmt.Errorf("%w: unknown persona %s", ErrInvalidSessionParams, persona)
+	}
+	scenario, err := stringParam(params, ScenarioParam, ScenarioCompleted)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := scenarioFinalStates[scenario]; !ok {
+		return nil, fmt.Errorf("%w: unknown scenario %s", ErrInvalidSessionParams, scenario)
+	}
 	sessionBytes := make([]byte, 16)
 	rand.Reader.Read(sessionBytes)
 
 	sessionID := hex.EncodeToString(sessionBytes)
-	d.Status[sessionID] = SessionCreated
-	d.Sessions[sessionID] = rawContractText
+	session := services.SigningSession{
+		ID:        sessionID,
+		Means:     ContractFormat,
+		Contract:  rawContractText,
+		CreatedAt: time.Now(),
+		State:     SessionCreated,
+		Params:    map[string]interface{}{PersonaParam: persona, ScenarioParam: scenario},
+	}
+	// the dummy accepts any text, the legal entity is only known for actual contracts
+	var c *contract.Contract
+	if d.ContractTemplates != nil {
+		if c, err = contract.ParseContractString(rawContractText, d.ContractTemplates.Templates()); err == nil {
+			session.LegalEntity = c.Params[contract.LegalEntityAttr]
+		}
+	}
+	if err := d.Sessions.Put(session); err != nil {
+		return nil, err
+	}
+	// like the other means, keep verifying against the same template version until the session is cancelled or reaped
+	if c != nil {
+		d.ContractTemplates.Acquire(sessionID, c)
+	}
 
 	return sessionPointer{
 		sessionID: sessionID,
 	}, nil
 }
+
+// sessionResult builds the result of the session for its persona and scenario
+func (d *Dummy) sessionResult(session services.SigningSession) signingSessionResult {
+	personaName, _ := stringParam(session.Params, PersonaParam, DefaultPersonaName)
+	scenario, _ := stringParam(session.Params, ScenarioParam, ScenarioCompleted)
+	persona, _ := d.Persona(personaName)
+	return signingSessionResult{
+		ID:       session.ID,
+		State:    session.State,
+		Request:  session.Contract,
+		Persona:  persona,
+		Scenario: scenario,
+	}
+}
+
+// stringParam returns the string value of the session param or the fallback when it is not set
+func stringParam(params map[string]interface{}, name string, fallback string) (string, error) {
+	value, ok := params[name]
+	if !ok || value == nil {
+		return fallback, nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s must be a string", ErrInvalidSessionParams, name)
+	}
+	return str, nil
+}
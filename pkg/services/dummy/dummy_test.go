@@ -20,10 +20,19 @@ package dummy
 
 import (
 	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/nuts-foundation/nuts-auth/pkg/contract"
 	"github.com/nuts-foundation/nuts-auth/pkg/services"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/session"
+	testIo "github.com/nuts-foundation/nuts-go-test/io"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -33,7 +42,7 @@ func TestDummy_StartSigningSession(t *testing.T) {
 			InStrictMode: true,
 		}
 
-		_, err := d.StartSigningSession("")
+		_, err := d.StartSigningSession("", nil)
 
 		assert.Error(t, err)
 		assert.Equal(t, errNotEnabled, err)
@@ -42,11 +51,10 @@ func TestDummy_StartSigningSession(t *testing.T) {
 	t.Run("ok - valid sessionID", func(t *testing.T) {
 		d := Dummy{
 			InStrictMode: false,
-			Sessions:     make(map[string]string, 0),
-			Status:       make(map[string]string, 0),
+			Sessions:     session.NewMemoryStore(),
 		}
 
-		s, err := d.StartSigningSession("")
+		s, err := d.StartSigningSession("", nil)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, s)
@@ -57,17 +65,148 @@ func TestDummy_StartSigningSession(t *testing.T) {
 	t.Run("ok - values are stored", func(t *testing.T) {
 		d := Dummy{
 			InStrictMode: false,
-			Sessions:     make(map[string]string, 0),
-			Status:       make(map[string]string, 0),
+			Sessions:     session.NewMemoryStore(),
 		}
 
-		s, err := d.StartSigningSession("contract")
+		s, err := d.StartSigningSession("contract", nil)
 
 		assert.NoError(t, err)
-		assert.Len(t, d.Sessions, 1)
-		assert.Equal(t, d.Sessions[s.SessionID()], "contract")
-		assert.Len(t, d.Status, 1)
-		assert.Equal(t, d.Status[s.SessionID()], SessionCreated)
+		stored, err := d.Sessions.Get(s.SessionID())
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "contract", stored.Contract)
+		assert.Equal(t, SessionCreated, stored.State)
+		assert.Equal(t, ContractFormat, stored.Means)
+		assert.Empty(t, stored.LegalEntity)
+	})
+
+	t.Run("ok - legal entity of a contract is stored", func(t *testing.T) {
+		d := Dummy{
+			Sessions:          session.NewMemoryStore(),
+			ContractTemplates: contract.StandardContractTemplates,
+		}
+
+		s, err := d.StartSigningSession("EN:PractitionerLogin:v3 I hereby declare to act on behalf of care org. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00.", nil)
+
+		assert.NoError(t, err)
+		stored, _ := d.Sessions.Get(s.SessionID())
+		assert.Equal(t, "care org", stored.LegalEntity)
+	})
+
+	t.Run("ok - persona and scenario are stored", func(t *testing.T) {
+		d := Dummy{Sessions: session.NewMemoryStore()}
+		d.SetPersonas(map[string]Persona{"alice": {"initials": "A"}})
+
+		s, err := d.StartSigningSession("contract", map[string]interface{}{PersonaParam: "alice", ScenarioParam: ScenarioCancelled})
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		stored, _ := d.Sessions.Get(s.SessionID())
+		assert.Equal(t, map[string]interface{}{PersonaParam: "alice", ScenarioParam: ScenarioCancelled}, stored.Params)
+	})
+
+	t.Run("error - invalid params", func(t *testing.T) {
+		d := Dummy{Sessions: session.NewMemoryStore()}
+		for _, params := range []map[string]interface{}{
+			{PersonaParam: "unknown"},
+			{ScenarioParam: "unknown"},
+			{PersonaParam: 1},
+			{ScenarioParam: true},
+		} {
+			_, err := d.StartSigningSession("contract", params)
+
+			assert.True(t, errors.Is(err, ErrInvalidSessionParams), params)
+		}
+	})
+}
+
+func TestDummy_Scenarios(t *testing.T) {
+	run := func(t *testing.T, d *Dummy, params map[string]interface{}) []contract.SigningSessionResult {
+		s, err := d.StartSigningSession("EN:PractitionerLogin:v3 I hereby declare to act on behalf of care org. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00.", params)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		var results []contract.SigningSessionResult
+		for i := 0; i < 4; i++ {
+			result, err := d.SigningSessionStatus(s.SessionID())
+			if err != nil {
+				break
+			}
+			results = append(results, result)
+		}
+		return results
+	}
+	newDummy := func() *Dummy {
+		d := &Dummy{Sessions: session.NewMemoryStore(), ContractTemplates: contract.StandardContractTemplates}
+		d.SetPersonas(map[string]Persona{"alice": {"initials": "A", "lastname": "Jansen", "email": "alice@example.com"}})
+		return d
+	}
+	statuses := func(results []contract.SigningSessionResult) []contract.SessionStatus {
+		var s []contract.SessionStatus
+		for _, result := range results {
+			s = append(s, result.Status())
+		}
+		return s
+	}
+
+	t.Run("ok - persona signs", func(t *testing.T) {
+		d := newDummy()
+
+		results := run(t, d, map[string]interface{}{PersonaParam: "alice"})
+
+		assert.Equal(t, []contract.SessionStatus{contract.SessionCreated, contract.SessionInProgress, contract.SessionCompleted, contract.SessionCompleted}, statuses(results))
+		vp, _ := results[2].VerifiablePresentation()
+		j, _ := json.Marshal(vp)
+		vr, err := d.VerifyVP(j, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, contract.Valid, vr.Validity)
+		assert.Equal(t, map[string]string{"initials": "A", "lastname": "Jansen", "email": "alice@example.com"}, vr.DisclosedAttributes)
+	})
+
+	t.Run("ok - cancelled", func(t *testing.T) {
+		results := run(t, newDummy(), map[string]interface{}{ScenarioParam: ScenarioCancelled})
+
+		assert.Equal(t, []contract.SessionStatus{contract.SessionCreated, contract.SessionInProgress, contract.SessionCancelled, contract.SessionCancelled}, statuses(results))
+	})
+
+	t.Run("ok - timeout", func(t *testing.T) {
+		results := run(t, newDummy(), map[string]interface{}{ScenarioParam: ScenarioTimeout})
+
+		assert.Equal(t, []contract.SessionStatus{contract.SessionCreated, contract.SessionInProgress, contract.SessionExpired, contract.SessionExpired}, statuses(results))
+	})
+
+	t.Run("ok - invalid proof", func(t *testing.T) {
+		d := newDummy()
+
+		results := run(t, d, map[string]interface{}{ScenarioParam: ScenarioInvalidProof})
+
+		assert.Equal(t, []contract.SessionStatus{contract.SessionCreated, contract.SessionInProgress, contract.SessionCompleted, contract.SessionCompleted}, statuses(results))
+		vp, _ := results[2].VerifiablePresentation()
+		j, _ := json.Marshal(vp)
+		vr, err := d.VerifyVP(j, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, contract.Invalid, vr.Validity)
+	})
+
+	t.Run("ok - concurrent sessions", func(t *testing.T) {
+		d := newDummy()
+		wg := sync.WaitGroup{}
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.SetPersonas(map[string]Persona{"alice": {"initials": "A"}})
+				results := run(t, d, map[string]interface{}{PersonaParam: "alice"})
+				assert.Len(t, results, 4)
+			}()
+		}
+		wg.Wait()
 	})
 }
 
@@ -86,8 +225,7 @@ func TestDummy_SigningSessionStatus(t *testing.T) {
 	t.Run("returns error when not found", func(t *testing.T) {
 		d := Dummy{
 			InStrictMode: false,
-			Sessions:     make(map[string]string, 0),
-			Status:       make(map[string]string, 0),
+			Sessions:     session.NewMemoryStore(),
 		}
 
 		_, err := d.SigningSessionStatus("")
@@ -96,43 +234,56 @@ func TestDummy_SigningSessionStatus(t *testing.T) {
 		assert.Equal(t, services.ErrSessionNotFound, err)
 	})
 
+	t.Run("returns error for a session of another signing means", func(t *testing.T) {
+		d := Dummy{
+			Sessions: session.NewMemoryStore(),
+		}
+		_ = d.Sessions.Put(services.SigningSession{ID: "token", Means: "irma", State: "INITIALIZED"})
+
+		_, err := d.SigningSessionStatus("token")
+
+		assert.Equal(t, services.ErrSessionNotFound, err)
+		stored, _ := d.Sessions.Get("token")
+		assert.Equal(t, "INITIALIZED", stored.State)
+	})
+
 	t.Run("ok - returns correct statuses", func(t *testing.T) {
 		d := Dummy{
 			InStrictMode: false,
-			Sessions:     make(map[string]string, 0),
-			Status:       make(map[string]string, 0),
+			Sessions:     session.NewMemoryStore(),
 		}
 
-		s, err := d.StartSigningSession("contract")
+		s, err := d.StartSigningSession("contract", nil)
 		assert.NoError(t, err)
 
 		// created
 		s1, err := d.SigningSessionStatus(s.SessionID())
 		assert.NoError(t, err)
-		assert.Equal(t, SessionCreated, s1.Status())
+		assert.Equal(t, contract.SessionCreated, s1.Status())
 
 		// in progress
 		s1, err = d.SigningSessionStatus(s.SessionID())
 		assert.NoError(t, err)
-		assert.Equal(t, SessionInProgress, s1.Status())
+		assert.Equal(t, contract.SessionInProgress, s1.Status())
 
 		// created
 		s1, err = d.SigningSessionStatus(s.SessionID())
 		assert.NoError(t, err)
-		assert.Equal(t, SessionCompleted, s1.Status())
-		assert.Len(t, d.Sessions, 0)
-		assert.Len(t, d.Status, 0)
+		assert.Equal(t, contract.SessionCompleted, s1.Status())
 
+		// completed sessions are kept until they are reaped
+		s1, err = d.SigningSessionStatus(s.SessionID())
+		assert.NoError(t, err)
+		assert.Equal(t, contract.SessionCompleted, s1.Status())
 	})
 
 	t.Run("ok - returns correct data", func(t *testing.T) {
 		d := Dummy{
 			InStrictMode: false,
-			Sessions:     make(map[string]string, 0),
-			Status:       make(map[string]string, 0),
+			Sessions:     session.NewMemoryStore(),
 		}
 
-		s, err := d.StartSigningSession("contract")
+		s, err := d.StartSigningSession("contract", nil)
 		assert.NoError(t, err)
 
 		// created
@@ -145,6 +296,85 @@ func TestDummy_SigningSessionStatus(t *testing.T) {
 	})
 }
 
+func TestDummy_CancelSigningSession(t *testing.T) {
+	t.Run("returns error when in strictMode", func(t *testing.T) {
+		d := Dummy{
+			InStrictMode: true,
+		}
+
+		err := d.CancelSigningSession("")
+
+		assert.Equal(t, errNotEnabled, err)
+	})
+
+	t.Run("returns error when not found", func(t *testing.T) {
+		d := Dummy{
+			Sessions: session.NewMemoryStore(),
+		}
+
+		err := d.CancelSigningSession("123")
+
+		assert.Equal(t, services.ErrSessionNotFound, err)
+	})
+
+	t.Run("ok - later status requests return cancelled", func(t *testing.T) {
+		d := Dummy{
+			Sessions: session.NewMemoryStore(),
+		}
+		s, _ := d.StartSigningSession("contract", nil)
+
+		err := d.CancelSigningSession(s.SessionID())
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		for i := 0; i < 3; i++ {
+			result, err := d.SigningSessionStatus(s.SessionID())
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, contract.SessionCancelled, result.Status())
+		}
+	})
+}
+
+func TestDummy_Subscribe(t *testing.T) {
+	t.Run("ok - subscribers are notified of transitions", func(t *testing.T) {
+		d := Dummy{
+			Sessions: session.NewMemoryStore(),
+			Notifier: contract.NewSessionNotifier(),
+		}
+		s, _ := d.StartSigningSession("contract", nil)
+		var notified []string
+		d.Subscribe(s.SessionID(), func(result contract.SigningSessionResult) {
+			notified = append(notified, result.NativeStatus())
+		})
+
+		for i := 0; i < 3; i++ {
+			_, _ = d.SigningSessionStatus(s.SessionID())
+		}
+
+		assert.Equal(t, []string{SessionInProgress, SessionCompleted}, notified)
+	})
+
+	t.Run("ok - subscribers are notified of cancellation", func(t *testing.T) {
+		d := Dummy{
+			Sessions: session.NewMemoryStore(),
+			Notifier: contract.NewSessionNotifier(),
+		}
+		s, _ := d.StartSigningSession("contract", nil)
+		var notified []string
+		d.Subscribe(s.SessionID(), func(result contract.SigningSessionResult) {
+			notified = append(notified, result.NativeStatus())
+		})
+
+		_ = d.CancelSigningSession(s.SessionID())
+		_, _ = d.SigningSessionStatus(s.SessionID())
+
+		assert.Equal(t, []string{services.SessionCancelled}, notified)
+	})
+}
+
 func TestDummy_VerifyVP(t *testing.T) {
 	t.Run("error - strictMode", func(t *testing.T) {
 		d := Dummy{
@@ -159,7 +389,8 @@ func TestDummy_VerifyVP(t *testing.T) {
 
 	t.Run("ok", func(t *testing.T) {
 		d := Dummy{
-			InStrictMode: false,
+			InStrictMode:      false,
+			ContractTemplates: contract.StandardContractTemplates,
 		}
 
 		p := Presentation{
@@ -173,7 +404,7 @@ func TestDummy_VerifyVP(t *testing.T) {
 				Lastname:  "Tester",
 				Birthdate: "1980-01-01",
 				Email:     "tester@example.com",
-				Contract:  "EN:PractitionerLogin:v3 I hereby declare to act on behalf of care org. This declaration is valid from maandag 1 oktober 12:00:00 until maandag 1 oktober 13:00:00.",
+				Contract:  "EN:PractitionerLogin:v3 I hereby declare to act on behalf of care org. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00.",
 			},
 		}
 
@@ -183,6 +414,55 @@ func TestDummy_VerifyVP(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, contract.Valid, vr.Validity)
 		assert.Equal(t, VerifiablePresentationType, vr.VPType)
+		signed := contract.Contract{RawContractText: p.Proof.Contract, Template: contract.StandardContractTemplates.Get("PractitionerLogin", "EN", "v3")}
+		assert.Equal(t, signed.Hash(), vr.ContractHash)
+		amsterdam, _ := time.LoadLocation(contract.AmsterdamTimeZone)
+		assert.True(t, time.Date(2012, 10, 1, 13, 0, 0, 0, amsterdam).Equal(vr.ValidTo))
+		assert.Equal(t, map[string]string{
+			"initials":  "I",
+			"lastname":  "Tester",
+			"birthdate": "1980-01-01",
+			"email":     "tester@example.com",
+		}, vr.DisclosedAttributes)
+	})
+
+	t.Run("ok - attributes of the persona are disclosed", func(t *testing.T) {
+		d := Dummy{ContractTemplates: contract.StandardContractTemplates}
+		p := Presentation{
+			Proof: Proof{
+				Type:       NoSignatureType,
+				Attributes: map[string]string{"initials": "A", "lastname": "Jansen", "uzi": "123"},
+				Contract:   "EN:PractitionerLogin:v3 I hereby declare to act on behalf of care org. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00.",
+			},
+		}
+
+		j, _ := json.Marshal(p)
+		vr, err := d.VerifyVP(j, nil)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, contract.Valid, vr.Validity)
+		assert.Equal(t, map[string]string{"initials": "A", "lastname": "Jansen", "uzi": "123"}, vr.DisclosedAttributes)
+	})
+
+	t.Run("ok - invalid proof", func(t *testing.T) {
+		d := Dummy{ContractTemplates: contract.StandardContractTemplates}
+		p := Presentation{
+			Proof: Proof{
+				Type:     InvalidSignatureType,
+				Contract: "EN:PractitionerLogin:v3 I hereby declare to act on behalf of care org. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00.",
+			},
+		}
+
+		j, _ := json.Marshal(p)
+		vr, err := d.VerifyVP(j, nil)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, contract.Invalid, vr.Validity)
+		assert.Equal(t, "invalid proof type: InvalidSignature", vr.Reason)
 	})
 
 	t.Run("error - incorrect json", func(t *testing.T) {
@@ -197,7 +477,8 @@ func TestDummy_VerifyVP(t *testing.T) {
 
 	t.Run("error - incorrect contract", func(t *testing.T) {
 		d := Dummy{
-			InStrictMode: false,
+			InStrictMode:      false,
+			ContractTemplates: contract.StandardContractTemplates,
 		}
 
 		p := Presentation{
@@ -213,12 +494,80 @@ func TestDummy_VerifyVP(t *testing.T) {
 	})
 }
 
+func TestDummy_TemplateReload(t *testing.T) {
+	const template = `
+type: Test
+version: v1
+language: EN
+template: |
+  EN:Test:v1 I act on behalf of {{legal_entity}}. This declaration is valid from {{valid_from}} until {{valid_to}}.
+`
+	const contractText = "EN:Test:v1 I act on behalf of care org. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00."
+	dir := testIo.TestDirectory(t)
+	writeFile(t, dir, "test.yaml", template)
+	watcher, err := contract.NewTemplateWatcher(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	d := Dummy{Sessions: session.NewMemoryStore(), ContractTemplates: watcher}
+
+	t.Run("ok - the contract of an open session verifies against the template it was started with", func(t *testing.T) {
+		s, err := d.StartSigningSession(contractText, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		writeFile(t, dir, "test.yaml", strings.Replace(template, "I act on behalf of", "I act for", 1))
+		if !assert.NoError(t, watcher.Reload()) {
+			return
+		}
+
+		var result contract.SigningSessionResult
+		for i := 0; i < 3; i++ {
+			result, _ = d.SigningSessionStatus(s.SessionID())
+		}
+		vp, _ := result.VerifiablePresentation()
+		j, _ := json.Marshal(vp)
+		vr, err := d.VerifyVP(j, nil)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, contract.Valid, vr.Validity)
+
+		// once released, the contract is verified against the current template
+		watcher.Release(s.SessionID())
+		_, err = d.VerifyVP(j, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("ok - cancelled sessions are released", func(t *testing.T) {
+		writeFile(t, dir, "test.yaml", template)
+		if !assert.NoError(t, watcher.Reload()) {
+			return
+		}
+		s, _ := d.StartSigningSession(contractText, nil)
+
+		_ = d.CancelSigningSession(s.SessionID())
+
+		_ = os.Remove(filepath.Join(dir, "test.yaml"))
+		assert.NoError(t, watcher.Reload())
+	})
+}
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestSigningSessionResult_Status(t *testing.T) {
 	ssr := signingSessionResult{
-		State: "state",
+		State: SessionInProgress,
 	}
 
-	assert.Equal(t, "state", ssr.Status())
+	assert.Equal(t, contract.SessionInProgress, ssr.Status())
+	assert.Equal(t, SessionInProgress, ssr.NativeStatus())
 }
 
 func TestSigningSessionResult_VerifiablePresentation(t *testing.T) {
@@ -232,7 +581,8 @@ func TestSigningSessionResult_VerifiablePresentation(t *testing.T) {
 
 	t.Run("ok - correct data", func(t *testing.T) {
 		ssr := signingSessionResult{
-			State: SessionCompleted,
+			State:   SessionCompleted,
+			Persona: defaultPersona,
 		}
 		vp, _ := ssr.VerifiablePresentation()
 		dvp := vp.(Presentation)
@@ -245,5 +595,17 @@ func TestSigningSessionResult_VerifiablePresentation(t *testing.T) {
 		assert.Equal(t, "I", dvp.Proof.Initials)
 		assert.Equal(t, "Tester", dvp.Proof.Lastname)
 		assert.Equal(t, "NoSignature", dvp.Proof.Type)
+		assert.Equal(t, map[string]string(defaultPersona), dvp.Proof.Attributes)
+	})
+
+	t.Run("ok - invalid proof scenario", func(t *testing.T) {
+		ssr := signingSessionResult{
+			State:    SessionCompleted,
+			Persona:  defaultPersona,
+			Scenario: ScenarioInvalidProof,
+		}
+		vp, _ := ssr.VerifiablePresentation()
+
+		assert.Equal(t, InvalidSignatureType, vp.(Presentation).Proof.Type)
 	})
 }
@@ -0,0 +1,58 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package dummy
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Persona contains the attributes a test user discloses when signing with the dummy means
+type Persona map[string]string
+
+// DefaultPersonaName is the name of the persona which signs when a session does not specify one
+const DefaultPersonaName = "tester"
+
+// defaultPersona is the "I. Tester" persona which signs unless another persona is configured under the DefaultPersonaName
+var defaultPersona = Persona{
+	"initials":  "I",
+	"lastname":  "Tester",
+	"birthdate": "1980-01-01",
+	"email":     "tester@example.com",
+}
+
+// LoadPersonas reads the personas from a YAML or JSON file, which maps the persona name to its attributes
+func LoadPersonas(path string) (map[string]Persona, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read dummy personas: %w", err)
+	}
+	personas := map[string]Persona{}
+	if err := yaml.Unmarshal(data, &personas); err != nil {
+		return nil, fmt.Errorf("unable to parse dummy personas from %s: %w", path, err)
+	}
+	for name, persona := range personas {
+		if len(persona) == 0 {
+			return nil, fmt.Errorf("dummy persona %s has no attributes", name)
+		}
+	}
+	return personas, nil
+}
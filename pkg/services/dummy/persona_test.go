@@ -0,0 +1,103 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package dummy
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	testIo "github.com/nuts-foundation/nuts-go-test/io"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadPersonas(t *testing.T) {
+	writeFile := func(t *testing.T, contents string) string {
+		path := filepath.Join(testIo.TestDirectory(t), "personas.yaml")
+		_ = ioutil.WriteFile(path, []byte(contents), 0600)
+		return path
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		path := writeFile(t, "alice:\n  initials: A\n  lastname: Jansen\nbob:\n  initials: B\n")
+
+		personas, err := LoadPersonas(path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]Persona{
+			"alice": {"initials": "A", "lastname": "Jansen"},
+			"bob":   {"initials": "B"},
+		}, personas)
+	})
+
+	t.Run("ok - JSON", func(t *testing.T) {
+		path := writeFile(t, `{"alice": {"initials": "A"}}`)
+
+		personas, err := LoadPersonas(path)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Persona{"initials": "A"}, personas["alice"])
+	})
+
+	t.Run("error - file does not exist", func(t *testing.T) {
+		_, err := LoadPersonas("non-existing.yaml")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("error - invalid contents", func(t *testing.T) {
+		path := writeFile(t, "alice: A")
+
+		_, err := LoadPersonas(path)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("error - persona without attributes", func(t *testing.T) {
+		path := writeFile(t, "alice:\n")
+
+		_, err := LoadPersonas(path)
+
+		assert.EqualError(t, err, "dummy persona alice has no attributes")
+	})
+}
+
+func TestDummy_Persona(t *testing.T) {
+	t.Run("ok - default persona", func(t *testing.T) {
+		d := Dummy{}
+
+		persona, ok := d.Persona(DefaultPersonaName)
+
+		assert.True(t, ok)
+		assert.Equal(t, "Tester", persona["lastname"])
+	})
+
+	t.Run("ok - configured personas", func(t *testing.T) {
+		d := Dummy{}
+		d.SetPersonas(map[string]Persona{"alice": {"initials": "A"}, DefaultPersonaName: {"initials": "T"}})
+
+		alice, ok := d.Persona("alice")
+		assert.True(t, ok)
+		assert.Equal(t, "A", alice["initials"])
+		tester, _ := d.Persona(DefaultPersonaName)
+		assert.Equal(t, Persona{"initials": "T"}, tester)
+		_, ok = d.Persona("bob")
+		assert.False(t, ok)
+	})
+}
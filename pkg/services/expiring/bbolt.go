@@ -0,0 +1,280 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package expiring
+
+import (
+	"encoding/binary"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/nuts-foundation/nuts-auth/logging"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/boltdb"
+)
+
+// PruneInterval is the time between two removals of the expired keys from a bbolt Set or Map
+const PruneInterval = time.Minute
+
+// countsBucket holds the number of keys of every bucket of the file, so a full bucket is detected without counting its keys
+var countsBucket = []byte("counts")
+
+// bboltBucket keeps expiring keys in a bucket of a bbolt file, every value starts with the time the key expires. The
+// expired keys are removed periodically and when the bucket is full.
+type bboltBucket struct {
+	db      *boltdb.DB
+	bucket  []byte
+	maxSize int
+	done    chan struct{}
+}
+
+// bboltSet keeps the keys of a Set in a bboltBucket
+type bboltSet struct {
+	*bboltBucket
+}
+
+// bboltMap keeps the keys and values of a Map in a bboltBucket
+type bboltMap struct {
+	*bboltBucket
+}
+
+// NewBboltSet returns a Set which holds at most maxSize keys in the named bucket of the bbolt file at the given path.
+// The file is created when it does not exist. Several sets and maps can share a file by using different names.
+// A shared file is only locked for the duration of a transaction, so nodes behind a load balancer can share the set on a volume.
+func NewBboltSet(path string, name string, maxSize int, shared bool) (Set, error) {
+	b, err := openBucket(path, name, maxSize, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &bboltSet{b}, nil
+}
+
+// NewBboltMap returns a Map which holds at most maxSize keys in the named bucket of the bbolt file at the given path.
+// The file is created when it does not exist. Several sets and maps can share a file by using different names.
+// A shared file is only locked for the duration of a transaction, so nodes behind a load balancer can share the map on a volume.
+func NewBboltMap(path string, name string, maxSize int, shared bool) (Map, error) {
+	b, err := openBucket(path, name, maxSize, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &bboltMap{b}, nil
+}
+
+func openBucket(path string, name string, maxSize int, shared bool) (*bboltBucket, error) {
+	open := boltdb.Open
+	if shared {
+		open = boltdb.OpenShared
+	}
+	db, err := open(path, []byte(name), countsBucket)
+	if err != nil {
+		return nil, err
+	}
+	b := &bboltBucket{db: db, bucket: []byte(name), maxSize: maxSize, done: make(chan struct{})}
+	// the keys of a bucket which was created before the keys were counted are counted once
+	err = db.UpdateTx(func(tx *bbolt.Tx) error {
+		counts := tx.Bucket(countsBucket)
+		if counts.Get(b.bucket) != nil {
+			return nil
+		}
+		return counts.Put(b.bucket, encodeCount(tx.Bucket(b.bucket).Stats().KeyN))
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	go b.prunePeriodically(PruneInterval)
+	return b, nil
+}
+
+func (b *bboltSet) Add(key string, expiresAt time.Time) (bool, error) {
+	added := false
+	err := b.update(func(bucket *bbolt.Bucket, count *int) error {
+		now := time.Now()
+		current := bucket.Get([]byte(key))
+		if current != nil && decodeTime(current).After(now) {
+			return nil
+		}
+		if current == nil {
+			if err := b.makeRoom(bucket, count, now); err != nil {
+				return err
+			}
+			*count++
+		}
+		added = true
+		return bucket.Put([]byte(key), encodeTime(expiresAt))
+	})
+	return added, err
+}
+
+func (b *bboltSet) Contains(key string) (bool, error) {
+	found := false
+	err := b.db.View(b.bucket, func(bucket *bbolt.Bucket) error {
+		if expiresAt := bucket.Get([]byte(key)); expiresAt != nil {
+			found = decodeTime(expiresAt).After(time.Now())
+		}
+		return nil
+	})
+	return found, err
+}
+
+func (b *bboltMap) Put(key string, value []byte, expiresAt time.Time) error {
+	return b.update(func(bucket *bbolt.Bucket, count *int) error {
+		if bucket.Get([]byte(key)) == nil {
+			if err := b.makeRoom(bucket, count, time.Now()); err != nil {
+				return err
+			}
+			*count++
+		}
+		return bucket.Put([]byte(key), append(encodeTime(expiresAt), value...))
+	})
+}
+
+func (b *bboltMap) Get(key string) ([]byte, error) {
+	var value []byte
+	err := b.db.View(b.bucket, func(bucket *bbolt.Bucket) error {
+		if data := bucket.Get([]byte(key)); data != nil && decodeTime(data).After(time.Now()) {
+			// the data is only valid during the transaction
+			value = append([]byte{}, data[8:]...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+func (b *bboltMap) Delete(key string) error {
+	return b.update(func(bucket *bbolt.Bucket, count *int) error {
+		if bucket.Get([]byte(key)) == nil {
+			return nil
+		}
+		*count--
+		return bucket.Delete([]byte(key))
+	})
+}
+
+// update runs the function in a read-write transaction on the bucket with the number of keys in the bucket,
+// which the function keeps up to date. The number is stored in the same transaction.
+func (b *bboltBucket) update(fn func(bucket *bbolt.Bucket, count *int) error) error {
+	return b.db.UpdateTx(func(tx *bbolt.Tx) error {
+		counts := tx.Bucket(countsBucket)
+		count := decodeCount(counts.Get(b.bucket))
+		if err := fn(tx.Bucket(b.bucket), &count); err != nil {
+			return err
+		}
+		return counts.Put(b.bucket, encodeCount(count))
+	})
+}
+
+// makeRoom removes the expired keys when the bucket is full and returns ErrFull when that does not make room for a key
+func (b *bboltBucket) makeRoom(bucket *bbolt.Bucket, count *int, now time.Time) error {
+	if *count < b.maxSize {
+		return nil
+	}
+	remaining, err := removeExpired(bucket, now)
+	if err != nil {
+		return err
+	}
+	*count = remaining
+	if remaining >= b.maxSize {
+		return ErrFull
+	}
+	return nil
+}
+
+func (b *bboltBucket) Close() error {
+	select {
+	case <-b.done:
+		return nil
+	default:
+		close(b.done)
+	}
+	return b.db.Close()
+}
+
+// prunePeriodically removes the expired keys at every interval until the bucket is closed
+func (b *bboltBucket) prunePeriodically(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.done:
+			return
+		case moment := <-ticker.C:
+			if err := b.prune(moment); err != nil {
+				logging.Log().Errorf("could not remove the expired keys of %s: %v", b.bucket, err)
+			}
+		}
+	}
+}
+
+// prune removes the keys which expired at the given moment
+func (b *bboltBucket) prune(moment time.Time) error {
+	return b.update(func(bucket *bbolt.Bucket, count *int) error {
+		remaining, err := removeExpired(bucket, moment)
+		*count = remaining
+		return err
+	})
+}
+
+// removeExpired removes the keys which expired at the given moment and returns the number of remaining keys
+func removeExpired(bucket *bbolt.Bucket, moment time.Time) (int, error) {
+	var expired [][]byte
+	remaining := 0
+	err := bucket.ForEach(func(key, value []byte) error {
+		if decodeTime(value).After(moment) {
+			remaining++
+		} else {
+			expired = append(expired, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range expired {
+		if err := bucket.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+	return remaining, nil
+}
+
+func encodeTime(moment time.Time) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(moment.UnixNano()))
+	return data
+}
+
+// decodeTime decodes the time at the start of the data
+func decodeTime(data []byte) time.Time {
+	if len(data) < 8 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data[:8])))
+}
+
+func encodeCount(count int) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(count))
+	return data
+}
+
+func decodeCount(data []byte) int {
+	if len(data) < 8 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(data))
+}
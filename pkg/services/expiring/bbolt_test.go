@@ -0,0 +1,177 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package expiring
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	testIo "github.com/nuts-foundation/nuts-go-test/io"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+
+	"github.com/nuts-foundation/nuts-auth/pkg/services/boltdb"
+)
+
+func TestBboltSet(t *testing.T) {
+	count := 0
+	testSet(t, func(maxSize int) Set {
+		// the sets of the shared tests are created in the same file, every set gets its own bucket
+		count++
+		set, err := NewBboltSet(filepath.Join(testIo.TestDirectory(t), "sets.db"), fmt.Sprintf("set%d", count), maxSize, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return set
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		testSet(t, func(maxSize int) Set {
+			count++
+			set, err := NewBboltSet(filepath.Join(testIo.TestDirectory(t), "sets.db"), fmt.Sprintf("set%d", count), maxSize, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return set
+		})
+	})
+
+	t.Run("ok - keys are counted", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "sets.db")
+		set, _ := NewBboltSet(path, "jti", 10, false)
+		_, _ = set.Add("expired", time.Now().Add(-time.Second))
+		_, _ = set.Add("valid", time.Now().Add(time.Minute))
+		_, _ = set.Add("valid", time.Now().Add(time.Minute))
+		assert.Equal(t, 2, keyCount(t, set.(*bboltSet).bboltBucket))
+
+		_ = set.(*bboltSet).prune(time.Now())
+		assert.Equal(t, 1, keyCount(t, set.(*bboltSet).bboltBucket))
+		_ = set.Close()
+
+		// the count of a bucket which was created before the keys were counted is initialized when it is opened
+		db, _ := boltdb.Open(path, countsBucket)
+		_ = db.Update(countsBucket, func(bucket *bbolt.Bucket) error {
+			return bucket.Delete([]byte("jti"))
+		})
+		_ = db.Close()
+		reopened, err := NewBboltSet(path, "jti", 10, false)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer reopened.Close()
+		assert.Equal(t, 1, keyCount(t, reopened.(*bboltSet).bboltBucket))
+	})
+
+	t.Run("ok - expired keys are pruned", func(t *testing.T) {
+		set, _ := NewBboltSet(filepath.Join(testIo.TestDirectory(t), "sets.db"), "jti", 10, false)
+		defer set.Close()
+		_, _ = set.Add("expired", time.Now().Add(-time.Second))
+		_, _ = set.Add("valid", time.Now().Add(time.Minute))
+
+		err := set.(*bboltSet).prune(time.Now())
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		var keys []string
+		_ = set.(*bboltSet).db.View([]byte("jti"), func(bucket *bbolt.Bucket) error {
+			return bucket.ForEach(func(key, _ []byte) error {
+				keys = append(keys, string(key))
+				return nil
+			})
+		})
+		assert.Equal(t, []string{"valid"}, keys)
+	})
+
+	t.Run("ok - sets in the same file are separate", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "sets.db")
+		set1, _ := NewBboltSet(path, "jti", 10, false)
+		set2, _ := NewBboltSet(path, "revoked", 10, false)
+		defer set1.Close()
+		defer set2.Close()
+
+		_, _ = set1.Add("key", time.Now().Add(time.Minute))
+		added, err := set2.Add("key", time.Now().Add(time.Minute))
+
+		assert.NoError(t, err)
+		assert.True(t, added)
+	})
+
+	t.Run("error - file can not be created", func(t *testing.T) {
+		_, err := NewBboltSet(filepath.Join(testIo.TestDirectory(t), "missing", "sets.db"), "jti", 10, false)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestBboltMap(t *testing.T) {
+	count := 0
+	testMap(t, func(maxSize int) Map {
+		count++
+		m, err := NewBboltMap(filepath.Join(testIo.TestDirectory(t), "maps.db"), fmt.Sprintf("map%d", count), maxSize, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	})
+
+	t.Run("ok - keys are counted", func(t *testing.T) {
+		m, _ := NewBboltMap(filepath.Join(testIo.TestDirectory(t), "maps.db"), "refresh", 10, false)
+		defer m.Close()
+		_ = m.Put("key1", []byte("value"), time.Now().Add(time.Minute))
+		_ = m.Put("key2", []byte("value"), time.Now().Add(time.Minute))
+		_ = m.Put("key2", []byte("other"), time.Now().Add(time.Minute))
+		_ = m.Delete("key1")
+		_ = m.Delete("unknown")
+
+		assert.Equal(t, 1, keyCount(t, m.(*bboltMap).bboltBucket))
+	})
+
+	t.Run("ok - expired keys are pruned", func(t *testing.T) {
+		m, _ := NewBboltMap(filepath.Join(testIo.TestDirectory(t), "maps.db"), "refresh", 10, false)
+		defer m.Close()
+		_ = m.Put("expired", []byte("value"), time.Now().Add(-time.Second))
+		_ = m.Put("valid", []byte("value"), time.Now().Add(time.Minute))
+
+		err := m.(*bboltMap).prune(time.Now())
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		var keys []string
+		_ = m.(*bboltMap).db.View([]byte("refresh"), func(bucket *bbolt.Bucket) error {
+			return bucket.ForEach(func(key, _ []byte) error {
+				keys = append(keys, string(key))
+				return nil
+			})
+		})
+		assert.Equal(t, []string{"valid"}, keys)
+	})
+}
+
+func keyCount(t *testing.T, b *bboltBucket) int {
+	count := 0
+	err := b.db.View(countsBucket, func(bucket *bbolt.Bucket) error {
+		count = decodeCount(bucket.Get(b.bucket))
+		return nil
+	})
+	assert.NoError(t, err)
+	return count
+}
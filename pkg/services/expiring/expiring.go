@@ -0,0 +1,151 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package expiring
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrFull is returned when a key is added to a Set or Map which holds its maximum number of keys that have not expired
+var ErrFull = errors.New("set is full")
+
+// Set holds keys until they expire. It is used to remember the identifiers of tokens for as long as the tokens are valid.
+type Set interface {
+	// Add adds the key, which is kept until expiresAt. It returns false when the key is already in the set and has not expired.
+	// When the set is full, the expired keys are removed. ErrFull is returned when that does not make room for the key.
+	Add(key string, expiresAt time.Time) (bool, error)
+	// Contains returns true when the key is in the set and has not expired
+	Contains(key string) (bool, error)
+	// Close releases the resources of the set
+	Close() error
+}
+
+type memorySet struct {
+	mutex   sync.Mutex
+	maxSize int
+	keys    map[string]time.Time
+}
+
+// NewMemorySet returns a Set which holds at most maxSize keys in memory. The keys are lost when the node stops
+// and are not shared with other nodes.
+func NewMemorySet(maxSize int) Set {
+	return &memorySet{maxSize: maxSize, keys: map[string]time.Time{}}
+}
+
+func (m *memorySet) Add(key string, expiresAt time.Time) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	now := time.Now()
+	if current, ok := m.keys[key]; ok && current.After(now) {
+		return false, nil
+	}
+	if len(m.keys) >= m.maxSize {
+		for k, e := range m.keys {
+			if !e.After(now) {
+				delete(m.keys, k)
+			}
+		}
+		if len(m.keys) >= m.maxSize {
+			return false, ErrFull
+		}
+	}
+	m.keys[key] = expiresAt
+	return true, nil
+}
+
+func (m *memorySet) Contains(key string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	expiresAt, ok := m.keys[key]
+	return ok && expiresAt.After(time.Now()), nil
+}
+
+func (m *memorySet) Close() error {
+	return nil
+}
+
+// Map holds values by key until they expire. It is used to keep the state of tokens which are handed out as opaque handles.
+type Map interface {
+	// Put stores the value under the key until expiresAt, it replaces the current value of the key. When the map is full,
+	// the expired keys are removed. ErrFull is returned when that does not make room for the key.
+	Put(key string, value []byte, expiresAt time.Time) error
+	// Get returns the value of the key, or nil when the key is not in the map or has expired
+	Get(key string) ([]byte, error)
+	// Delete removes the key from the map, unknown keys are ignored
+	Delete(key string) error
+	// Close releases the resources of the map
+	Close() error
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+type memoryMap struct {
+	mutex   sync.Mutex
+	maxSize int
+	entries map[string]memoryEntry
+}
+
+// NewMemoryMap returns a Map which holds at most maxSize keys in memory. The values are lost when the node stops
+// and are not shared with other nodes.
+func NewMemoryMap(maxSize int) Map {
+	return &memoryMap{maxSize: maxSize, entries: map[string]memoryEntry{}}
+}
+
+func (m *memoryMap) Put(key string, value []byte, expiresAt time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if _, ok := m.entries[key]; !ok && len(m.entries) >= m.maxSize {
+		now := time.Now()
+		for k, e := range m.entries {
+			if !e.expiresAt.After(now) {
+				delete(m.entries, k)
+			}
+		}
+		if len(m.entries) >= m.maxSize {
+			return ErrFull
+		}
+	}
+	m.entries[key] = memoryEntry{value: append([]byte(nil), value...), expiresAt: expiresAt}
+	return nil
+}
+
+func (m *memoryMap) Get(key string) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	entry, ok := m.entries[key]
+	if !ok || !entry.expiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return append([]byte(nil), entry.value...), nil
+}
+
+func (m *memoryMap) Delete(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+func (m *memoryMap) Close() error {
+	return nil
+}
@@ -0,0 +1,225 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+package expiring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySet(t *testing.T) {
+	testSet(t, func(maxSize int) Set {
+		return NewMemorySet(maxSize)
+	})
+}
+
+// testSet contains the tests every Set implementation must pass
+func testSet(t *testing.T, createSet func(maxSize int) Set) {
+	valid := time.Now().Add(time.Minute)
+	expired := time.Now().Add(-time.Second)
+
+	t.Run("ok - new key is added", func(t *testing.T) {
+		set := createSet(10)
+		defer set.Close()
+
+		added, err := set.Add("key", valid)
+
+		assert.NoError(t, err)
+		assert.True(t, added)
+	})
+
+	t.Run("ok - known key is not added again", func(t *testing.T) {
+		set := createSet(10)
+		defer set.Close()
+		_, _ = set.Add("key", valid)
+
+		added, err := set.Add("key", valid)
+
+		assert.NoError(t, err)
+		assert.False(t, added)
+	})
+
+	t.Run("ok - expired key is added again", func(t *testing.T) {
+		set := createSet(10)
+		defer set.Close()
+		_, _ = set.Add("key", expired)
+
+		added, err := set.Add("key", valid)
+
+		assert.NoError(t, err)
+		assert.True(t, added)
+	})
+
+	t.Run("ok - expired keys make room", func(t *testing.T) {
+		set := createSet(2)
+		defer set.Close()
+		_, _ = set.Add("1", expired)
+		_, _ = set.Add("2", valid)
+
+		added, err := set.Add("3", valid)
+
+		assert.NoError(t, err)
+		assert.True(t, added)
+	})
+
+	t.Run("error - full", func(t *testing.T) {
+		set := createSet(2)
+		defer set.Close()
+		_, _ = set.Add("1", valid)
+		_, _ = set.Add("2", valid)
+
+		added, err := set.Add("3", valid)
+
+		assert.Equal(t, ErrFull, err)
+		assert.False(t, added)
+	})
+
+	t.Run("ok - known key is reported when full", func(t *testing.T) {
+		set := createSet(1)
+		defer set.Close()
+		_, _ = set.Add("1", valid)
+
+		added, err := set.Add("1", valid)
+
+		assert.NoError(t, err)
+		assert.False(t, added)
+	})
+
+	t.Run("ok - contains added key", func(t *testing.T) {
+		set := createSet(10)
+		defer set.Close()
+		_, _ = set.Add("key", valid)
+
+		found, err := set.Contains("key")
+
+		assert.NoError(t, err)
+		assert.True(t, found)
+	})
+
+	t.Run("ok - does not contain expired key", func(t *testing.T) {
+		set := createSet(10)
+		defer set.Close()
+		_, _ = set.Add("key", expired)
+
+		found, err := set.Contains("key")
+
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("ok - does not contain unknown key", func(t *testing.T) {
+		set := createSet(10)
+		defer set.Close()
+
+		found, err := set.Contains("key")
+
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+}
+
+func TestMemoryMap(t *testing.T) {
+	testMap(t, func(maxSize int) Map {
+		return NewMemoryMap(maxSize)
+	})
+}
+
+// testMap contains the tests every Map implementation must pass
+func testMap(t *testing.T, createMap func(maxSize int) Map) {
+	valid := time.Now().Add(time.Minute)
+	expired := time.Now().Add(-time.Second)
+
+	t.Run("ok - value is stored", func(t *testing.T) {
+		m := createMap(10)
+		defer m.Close()
+
+		err := m.Put("key", []byte("value"), valid)
+
+		assert.NoError(t, err)
+		value, err := m.Get("key")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("value"), value)
+	})
+
+	t.Run("ok - value is replaced", func(t *testing.T) {
+		m := createMap(1)
+		defer m.Close()
+		_ = m.Put("key", []byte("value"), valid)
+
+		err := m.Put("key", []byte("other value"), valid)
+
+		assert.NoError(t, err)
+		value, _ := m.Get("key")
+		assert.Equal(t, []byte("other value"), value)
+	})
+
+	t.Run("ok - no value for expired key", func(t *testing.T) {
+		m := createMap(10)
+		defer m.Close()
+		_ = m.Put("key", []byte("value"), expired)
+
+		value, err := m.Get("key")
+
+		assert.NoError(t, err)
+		assert.Nil(t, value)
+	})
+
+	t.Run("ok - no value for unknown key", func(t *testing.T) {
+		m := createMap(10)
+		defer m.Close()
+
+		value, err := m.Get("key")
+
+		assert.NoError(t, err)
+		assert.Nil(t, value)
+	})
+
+	t.Run("ok - deleted key", func(t *testing.T) {
+		m := createMap(10)
+		defer m.Close()
+		_ = m.Put("key", []byte("value"), valid)
+
+		err := m.Delete("key")
+
+		assert.NoError(t, err)
+		value, _ := m.Get("key")
+		assert.Nil(t, value)
+	})
+
+	t.Run("ok - expired keys make room", func(t *testing.T) {
+		m := createMap(1)
+		defer m.Close()
+		_ = m.Put("expired", []byte("value"), expired)
+
+		err := m.Put("key", []byte("value"), valid)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("error - full", func(t *testing.T) {
+		m := createMap(1)
+		defer m.Close()
+		_ = m.Put("key", []byte("value"), valid)
+
+		err := m.Put("other", []byte("value"), valid)
+
+		assert.Equal(t, ErrFull, err)
+	})
+}
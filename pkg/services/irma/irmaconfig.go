@@ -41,6 +41,9 @@ const ConfSkipAutoUpdateIrmaSchemas = "skipAutoUpdateIrmaSchemas"
 // ConfIrmaConfigPath is the config key to provide the irma configuration path
 const ConfIrmaConfigPath = "irmaConfigPath"
 
+// ConfIrmaPrintQrCode is the config key to print the QR code of every IRMA signing session to stdout, for development
+const ConfIrmaPrintQrCode = "irmaPrintQrCode"
+
 // ConfIrmaSchemeManager allows selecting an IRMA scheme manager. During development this can ben irma-demo. Production should be pdfb
 const ConfIrmaSchemeManager = "irmaSchemeManager"
 
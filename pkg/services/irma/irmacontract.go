@@ -66,7 +66,7 @@ func (s SignedIrmaContract) Contract() contract.Contract {
 //  the acting party named in the contract is the same as the one making the request
 type contractVerifier struct {
 	irmaConfig     *irma.Configuration
-	validContracts contract.TemplateStore
+	validContracts contract.TemplateProvider
 }
 
 // Parse an IRMA Authentication Token. A token is a base64 encoded IRMA contract.
@@ -101,7 +101,7 @@ func (cv *contractVerifier) ParseIrmaContract(jsonIrmaContract []byte) (services
 	signerAttributes := parseSignerAttributes(attributes)
 
 	contractMessage := signedIrmaContract.IrmaContract.Message
-	c, err := contract.ParseContractString(contractMessage, cv.validContracts)
+	c, err := contract.ParseContractString(contractMessage, cv.validContracts.TemplatesFor(contractMessage))
 	if err != nil {
 		return nil, err
 	}
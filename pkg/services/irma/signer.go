@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/mdp/qrterminal/v3"
 	"github.com/nuts-foundation/nuts-auth/logging"
@@ -62,13 +63,14 @@ func (s SessionPtr) MarshalJSON() ([]byte, error) {
 // NutsIrmaSignedContract is the type of proof used in an Irma VP
 const NutsIrmaSignedContract = "NutsIrmaSignedContract"
 
-// StartSigningSession accepts a rawContractText and creates an IRMA signing session.
-func (v Service) StartSigningSession(rawContractText string) (contract.SessionPointer, error) {
+// StartSigningSession accepts a rawContractText and creates an IRMA signing session. IRMA has no session params.
+func (v Service) StartSigningSession(rawContractText string, _ map[string]interface{}) (contract.SessionPointer, error) {
 	// Put the template in an IRMA envelope
 	signatureRequest := irmago.NewSignatureRequest(rawContractText)
 	schemeManager := v.IrmaServiceConfig.IrmaSchemeManager
 
-	c, err := contract.ParseContractString(rawContractText, v.ContractTemplates)
+	templates := v.ContractTemplates.Templates()
+	c, err := contract.ParseContractString(rawContractText, templates)
 	if err != nil {
 		return nil, err
 	}
@@ -90,30 +92,109 @@ func (v Service) StartSigningSession(rawContractText string) (contract.SessionPo
 	// Start an IRMA session
 	sessionPointer, token, err := v.IrmaSessionHandler.StartSession(signatureRequest, func(result *server.SessionResult) {
 		logging.Log().Debugf("session done, result: %s", server.ToJson(result))
+		v.storeSessionResult(result)
+		v.notifyStatus(result.Token)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error while creating session: %w", err)
 	}
 	logging.Log().Debugf("session created with token: %s", token)
+	if v.Sessions != nil {
+		err = v.Sessions.Put(services.SigningSession{
+			ID:          token,
+			Means:       ContractFormat,
+			Contract:    rawContractText,
+			LegalEntity: c.Params[contract.LegalEntityAttr],
+			CreatedAt:   time.Now(),
+			State:       string(server.StatusInitialized),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error while storing session: %w", err)
+		}
+	}
+	// keep verifying against the same template version, even if the templates are reloaded. The session is released
+	// when it is cancelled or when the reaper expires or removes it, so the signed contract can still be verified after the result is fetched.
+	v.ContractTemplates.Acquire(token, c)
 
 	// Return the sessionPointer and sessionId
 	challenge := SessionPtr{
 		ID:         token,
 		QrCodeInfo: *sessionPointer,
 	}
-	jsonResult := challenge.Payload()
-	printQrCode(string(jsonResult))
+	if v.IrmaServiceConfig.PrintQrCode {
+		printQrCode(string(challenge.Payload()))
+	}
 
 	return challenge, nil
 }
 
+// CancelSigningSession cancels the session at the IRMA server and marks the stored session as cancelled.
+// The IRMA server of another node can not be reached, the stored state makes sure the session is reported as cancelled nevertheless.
+func (v Service) CancelSigningSession(sessionID string) error {
+	stored := false
+	if v.Sessions != nil {
+		err := v.Sessions.Update(sessionID, func(session *services.SigningSession) error {
+			if session.Means != ContractFormat {
+				return services.ErrSessionNotFound
+			}
+			session.State = services.SessionCancelled
+			return nil
+		})
+		if err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+			return err
+		}
+		stored = err == nil
+	}
+	if err := v.IrmaSessionHandler.CancelSession(sessionID); err != nil {
+		if !stored {
+			return services.ErrSessionNotFound
+		}
+		logging.Log().Debugf("IRMA session %s could not be cancelled at the IRMA server: %v", sessionID, err)
+	}
+	v.ContractTemplates.Release(sessionID)
+	v.notifyStatus(sessionID)
+	return nil
+}
+
+// Subscribe registers a listener for the status transitions of the IRMA session. The listener is called when the IRMA
+// server reports that the session is done and when a status request observes a new status.
+func (v Service) Subscribe(sessionID string, listener contract.SessionListener) func() {
+	return v.Notifier.Subscribe(sessionID, listener)
+}
+
+// notifyStatus notifies the subscribers of the session about its current status
+func (v Service) notifyStatus(sessionID string) {
+	if !v.Notifier.HasListeners(sessionID) {
+		return
+	}
+	result, err := v.SigningSessionStatus(sessionID)
+	if err != nil {
+		logging.Log().Errorf("could not notify status of session %s: %v", sessionID, err)
+		return
+	}
+	v.Notifier.Notify(sessionID, result)
+}
+
+// SigningSessionStatus returns the status of the IRMA session. When the IRMA server does not know the session, for instance
+// because it was started by another node or before a restart, the status is taken from the SessionStore.
+// An expired or cancelled session keeps that state, regardless of the status reported by the IRMA server.
 func (v Service) SigningSessionStatus(sessionID string) (contract.SigningSessionResult, error) {
-	if result := v.IrmaSessionHandler.GetSessionResult(sessionID); result != nil {
+	if session := v.storedSession(sessionID); session != nil && session.Terminated() {
+		return SigningSessionResult{SessionResult: server.SessionResult{Token: sessionID, Status: server.Status(session.State), Type: irmago.ActionSigning}}, nil
+	}
+	stateChanged := false
+	result := v.IrmaSessionHandler.GetSessionResult(sessionID)
+	if result != nil {
+		stateChanged = v.storeSessionState(sessionID, result.Status)
+	} else {
+		result = v.storedSessionResult(sessionID)
+	}
+	if result != nil {
 		var (
 			token string
 		)
 		if result.Signature != nil {
-			c, err := contract.ParseContractString(result.Signature.Message, v.ContractTemplates)
+			c, err := contract.ParseContractString(result.Signature.Message, v.ContractTemplates.SessionTemplates(sessionID))
 			if err != nil {
 				return nil, err
 			}
@@ -131,11 +212,84 @@ func (v Service) SigningSessionStatus(sessionID string) (contract.SigningSession
 		}
 		result := SigningSessionResult{SessionResult: *result, NutsAuthToken: token}
 		logging.Log().Info(result.NutsAuthToken)
+		if stateChanged {
+			v.Notifier.Notify(sessionID, result)
+		}
 		return result, nil
 	}
 	return nil, services.ErrSessionNotFound
 }
 
+// storeSessionResult stores the result of a finished IRMA session, so every node can report it
+func (v Service) storeSessionResult(result *server.SessionResult) {
+	if v.Sessions == nil {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		logging.Log().Errorf("could not store result of session %s: %v", result.Token, err)
+		return
+	}
+	err = v.Sessions.Update(result.Token, func(session *services.SigningSession) error {
+		if session.Terminated() {
+			return errUnchanged
+		}
+		session.State = string(result.Status)
+		session.Result = data
+		return nil
+	})
+	if err != nil && !errors.Is(err, errUnchanged) {
+		logging.Log().Errorf("could not store result of session %s: %v", result.Token, err)
+	}
+}
+
+// storeSessionState updates the state of the stored session when the IRMA server reports a new status.
+// It returns true when the state changed.
+func (v Service) storeSessionState(sessionID string, status server.Status) bool {
+	if v.Sessions == nil {
+		return false
+	}
+	err := v.Sessions.Update(sessionID, func(session *services.SigningSession) error {
+		if session.Means != ContractFormat || session.Terminated() || session.State == string(status) {
+			return errUnchanged
+		}
+		session.State = string(status)
+		return nil
+	})
+	if err != nil && !errors.Is(err, errUnchanged) && !errors.Is(err, services.ErrSessionNotFound) {
+		logging.Log().Errorf("could not store state of session %s: %v", sessionID, err)
+	}
+	return err == nil
+}
+
+// storedSession returns the IRMA session from the SessionStore, it returns nil when the session is unknown
+func (v Service) storedSession(sessionID string) *services.SigningSession {
+	if v.Sessions == nil {
+		return nil
+	}
+	session, err := v.Sessions.Get(sessionID)
+	if err != nil || session.Means != ContractFormat {
+		return nil
+	}
+	return session
+}
+
+// storedSessionResult builds the IRMA session result from the SessionStore, it returns nil when the session is unknown
+func (v Service) storedSessionResult(sessionID string) *server.SessionResult {
+	session := v.storedSession(sessionID)
+	if session == nil {
+		return nil
+	}
+	result := &server.SessionResult{}
+	if len(session.Result) == 0 || json.Unmarshal(session.Result, result) != nil {
+		result = &server.SessionResult{Token: sessionID, Status: server.Status(session.State), Type: irmago.ActionSigning}
+	}
+	return result
+}
+
+// errUnchanged is used to skip the update of a stored session
+var errUnchanged = errors.New("unchanged")
+
 // SigningSessionResult implements the SigningSessionResult interface and contains the
 // SigningSessionResult from the IRMA means.
 type SigningSessionResult struct {
@@ -144,8 +298,28 @@ type SigningSessionResult struct {
 	NutsAuthToken string `json:"nuts_auth_token"`
 }
 
-// Status returns the IRMA signing status
-func (s SigningSessionResult) Status() string {
+// Status maps the IRMA signing status onto the contract.SessionStatus
+func (s SigningSessionResult) Status() contract.SessionStatus {
+	switch s.NativeStatus() {
+	case string(server.StatusInitialized):
+		return contract.SessionCreated
+	case string(server.StatusConnected):
+		return contract.SessionInProgress
+	case string(server.StatusDone):
+		if s.Err != nil {
+			return contract.SessionFailed
+		}
+		return contract.SessionCompleted
+	case string(server.StatusCancelled), services.SessionCancelled:
+		return contract.SessionCancelled
+	case string(server.StatusTimeout), services.SessionExpired:
+		return contract.SessionExpired
+	}
+	return contract.SessionFailed
+}
+
+// NativeStatus returns the IRMA signing status
+func (s SigningSessionResult) NativeStatus() string {
 	return string(s.SessionResult.Status)
 }
 
@@ -25,6 +25,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/nuts-foundation/nuts-auth/pkg/contract"
 	"github.com/nuts-foundation/nuts-auth/pkg/services"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/session"
 	cryptoTypes "github.com/nuts-foundation/nuts-crypto/pkg/types"
 	cryptoMock "github.com/nuts-foundation/nuts-crypto/test/mock"
 	registryMock "github.com/nuts-foundation/nuts-registry/mock"
@@ -68,7 +69,7 @@ func TestSessionPtr_SessionID(t *testing.T) {
 }
 
 func TestService_StartSigningSession(t *testing.T) {
-	correctContractText := "EN:PractitionerLogin:v3 I hereby declare to act on behalf of verpleeghuis De nootjes. This declaration is valid from maandag 1 oktober 12:00:00 until maandag 1 oktober 13:00:00."
+	correctContractText := "EN:PractitionerLogin:v3 I hereby declare to act on behalf of verpleeghuis De nootjes. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00."
 
 	t.Run("error - malformed contract", func(t *testing.T) {
 		service, ctrl := serviceWithMocks(t)
@@ -76,7 +77,7 @@ func TestService_StartSigningSession(t *testing.T) {
 
 		rawContractText := "not a contract"
 
-		_, err := service.StartSigningSession(rawContractText)
+		_, err := service.StartSigningSession(rawContractText, nil)
 
 		assert.Error(t, err)
 	})
@@ -88,7 +89,7 @@ func TestService_StartSigningSession(t *testing.T) {
 		irmaMock := service.IrmaSessionHandler.(*mockIrmaClient)
 		irmaMock.err = errors.New("some error")
 
-		_, err := service.StartSigningSession(correctContractText)
+		_, err := service.StartSigningSession(correctContractText, nil)
 
 		assert.Error(t, err)
 		assert.Equal(t, "error while creating session: some error", err.Error())
@@ -105,16 +106,190 @@ func TestService_StartSigningSession(t *testing.T) {
 		}
 		irmaMock.sessionToken = "token"
 
-		session, err := service.StartSigningSession(correctContractText)
+		session, err := service.StartSigningSession(correctContractText, nil)
 
 		assert.NoError(t, err)
 		assert.Equal(t, "token", session.SessionID())
 		assert.Equal(t, "{\"u\":\"url\",\"irmaqr\":\"type\"}", string(session.Payload()))
+
+		stored, err := service.Sessions.Get("token")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, ContractFormat, stored.Means)
+		assert.Equal(t, correctContractText, stored.Contract)
+		assert.Equal(t, "verpleeghuis De nootjes", stored.LegalEntity)
+		assert.Equal(t, "INITIALIZED", stored.State)
+	})
+
+	t.Run("ok - result of a finished session is stored", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		irmaMock := service.IrmaSessionHandler.(*mockIrmaClient)
+		irmaMock.irmaQr = &irma.Qr{}
+		irmaMock.sessionToken = "token"
+		_, _ = service.StartSigningSession(correctContractText, nil)
+
+		irmaMock.handler(&irmaservercore.SessionResult{Token: "token", Status: irmaservercore.StatusDone, Signature: &irma.SignedMessage{Message: correctContractText}})
+
+		stored, _ := service.Sessions.Get("token")
+		assert.Equal(t, "DONE", stored.State)
+		assert.Contains(t, string(stored.Result), "verpleeghuis De nootjes")
+	})
+
+	t.Run("ok - templates stay acquired when the session is done", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+		templates := &templateRecorder{TemplateStore: contract.StandardContractTemplates, acquired: map[string]string{}}
+		service.ContractTemplates = templates
+
+		irmaMock := service.IrmaSessionHandler.(*mockIrmaClient)
+		irmaMock.irmaQr = &irma.Qr{}
+		irmaMock.sessionToken = "token"
+		_, _ = service.StartSigningSession(correctContractText, nil)
+		irmaMock.handler(&irmaservercore.SessionResult{Token: "token", Status: irmaservercore.StatusDone, Signature: &irma.SignedMessage{Message: correctContractText}})
+
+		assert.Equal(t, map[string]string{"token": correctContractText}, templates.acquired)
+	})
+}
+
+// templateRecorder is a TemplateProvider which records the contracts of the acquired sessions
+type templateRecorder struct {
+	contract.TemplateStore
+	acquired map[string]string
+}
+
+func (r *templateRecorder) Acquire(sessionID string, c *contract.Contract) {
+	r.acquired[sessionID] = c.RawContractText
+}
+
+func (r *templateRecorder) Release(sessionID string) {
+	delete(r.acquired, sessionID)
+}
+
+func TestService_CancelSigningSession(t *testing.T) {
+	t.Run("ok - session is cancelled at the IRMA server", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		irmaMock := service.IrmaSessionHandler.(*mockIrmaClient)
+		irmaMock.sessionResult = &irmaservercore.SessionResult{Token: "token", Status: irmaservercore.StatusCancelled}
+		_ = service.Sessions.Put(services.SigningSession{ID: "token", Means: ContractFormat, State: "CONNECTED"})
+
+		err := service.CancelSigningSession("token")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []string{"token"}, irmaMock.cancelled)
+		result, err := service.SigningSessionStatus("token")
+		if assert.NoError(t, err) {
+			assert.Equal(t, contract.SessionCancelled, result.Status())
+		}
+	})
+
+	t.Run("ok - session of another node is cancelled in the session store", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		irmaMock := service.IrmaSessionHandler.(*mockIrmaClient)
+		irmaMock.err = errors.New("Unknown session, can't cancel")
+		_ = service.Sessions.Put(services.SigningSession{ID: "token", Means: ContractFormat, State: "CONNECTED"})
+
+		err := service.CancelSigningSession("token")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		stored, _ := service.Sessions.Get("token")
+		assert.Equal(t, services.SessionCancelled, stored.State)
+	})
+
+	t.Run("ok - result of a cancelled session is not stored", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		_ = service.Sessions.Put(services.SigningSession{ID: "token", Means: ContractFormat, State: services.SessionExpired})
+
+		service.storeSessionResult(&irmaservercore.SessionResult{Token: "token", Status: irmaservercore.StatusDone})
+
+		stored, _ := service.Sessions.Get("token")
+		assert.Equal(t, services.SessionExpired, stored.State)
+		assert.Empty(t, stored.Result)
+	})
+
+	t.Run("error - unknown session", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		irmaMock := service.IrmaSessionHandler.(*mockIrmaClient)
+		irmaMock.err = errors.New("Unknown session, can't cancel")
+
+		err := service.CancelSigningSession("token")
+
+		assert.Equal(t, services.ErrSessionNotFound, err)
+	})
+}
+
+func TestService_Subscribe(t *testing.T) {
+	correctContractText := "EN:PractitionerLogin:v3 I hereby declare to act on behalf of verpleeghuis De nootjes. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00."
+
+	t.Run("ok - session done callback notifies the subscribers", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		irmaMock := service.IrmaSessionHandler.(*mockIrmaClient)
+		irmaMock.irmaQr = &irma.Qr{}
+		irmaMock.sessionToken = "token"
+		_, _ = service.StartSigningSession(correctContractText, nil)
+		var notified []string
+		service.Subscribe("token", func(result contract.SigningSessionResult) {
+			notified = append(notified, result.NativeStatus())
+		})
+
+		irmaMock.sessionResult = &irmaservercore.SessionResult{Token: "token", Status: irmaservercore.StatusTimeout}
+		irmaMock.handler(irmaMock.sessionResult)
+
+		assert.Equal(t, []string{"TIMEOUT"}, notified)
+	})
+
+	t.Run("ok - status transitions observed by a status request notify the subscribers", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		_ = service.Sessions.Put(services.SigningSession{ID: "token", Means: ContractFormat, State: "INITIALIZED"})
+		var notified []string
+		service.Subscribe("token", func(result contract.SigningSessionResult) {
+			notified = append(notified, result.NativeStatus())
+		})
+		irmaMock := service.IrmaSessionHandler.(*mockIrmaClient)
+		irmaMock.sessionResult = &irmaservercore.SessionResult{Token: "token", Status: irmaservercore.StatusConnected}
+
+		_, _ = service.SigningSessionStatus("token")
+		_, _ = service.SigningSessionStatus("token")
+
+		assert.Equal(t, []string{"CONNECTED"}, notified)
+	})
+
+	t.Run("ok - cancellation notifies the subscribers", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		_ = service.Sessions.Put(services.SigningSession{ID: "token", Means: ContractFormat, State: "CONNECTED"})
+		var notified []string
+		service.Subscribe("token", func(result contract.SigningSessionResult) {
+			notified = append(notified, result.NativeStatus())
+		})
+
+		_ = service.CancelSigningSession("token")
+
+		assert.Equal(t, []string{services.SessionCancelled}, notified)
 	})
 }
 
 func TestService_SigningSessionStatus(t *testing.T) {
-	correctContractText := "EN:PractitionerLogin:v3 I hereby declare to act on behalf of verpleeghuis De nootjes. This declaration is valid from maandag 1 oktober 12:00:00 until maandag 1 oktober 13:00:00."
+	correctContractText := "EN:PractitionerLogin:v3 I hereby declare to act on behalf of verpleeghuis De nootjes. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00."
 
 	t.Run("error - session not found", func(t *testing.T) {
 		service, ctrl := serviceWithMocks(t)
@@ -129,6 +304,49 @@ func TestService_SigningSessionStatus(t *testing.T) {
 		assert.Equal(t, services.ErrSessionNotFound, err)
 	})
 
+	t.Run("ok - state is taken from the session store when the IRMA server does not know the session", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		_ = service.Sessions.Put(services.SigningSession{ID: "session", Means: ContractFormat, State: "CONNECTED"})
+
+		result, err := service.SigningSessionStatus("session")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "CONNECTED", result.NativeStatus())
+	})
+
+	t.Run("error - session of another signing means", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		_ = service.Sessions.Put(services.SigningSession{ID: "session", Means: "dummy", State: "created"})
+
+		_, err := service.SigningSessionStatus("session")
+
+		assert.Equal(t, services.ErrSessionNotFound, err)
+	})
+
+	t.Run("ok - state reported by the IRMA server is stored", func(t *testing.T) {
+		service, ctrl := serviceWithMocks(t)
+		defer ctrl.Finish()
+
+		_ = service.Sessions.Put(services.SigningSession{ID: "session", Means: ContractFormat, State: "INITIALIZED"})
+		irmaMock := service.IrmaSessionHandler.(*mockIrmaClient)
+		irmaMock.sessionResult = &irmaservercore.SessionResult{Token: "session", Status: irmaservercore.StatusConnected}
+
+		result, err := service.SigningSessionStatus("session")
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "CONNECTED", result.NativeStatus())
+		stored, _ := service.Sessions.Get("session")
+		assert.Equal(t, "CONNECTED", stored.State)
+	})
+
 	t.Run("error - incorrect contract string", func(t *testing.T) {
 		service, ctrl := serviceWithMocks(t)
 		defer ctrl.Finish()
@@ -180,7 +398,7 @@ func TestService_SigningSessionStatus(t *testing.T) {
 		}
 		claims := map[string]interface{}{
 			"iss":  otherOrganizationID.String(),
-			"sig":  "eyJAY29udGV4dCI6IiIsInNpZ25hdHVyZSI6bnVsbCwiaW5kaWNlcyI6bnVsbCwibm9uY2UiOm51bGwsImNvbnRleHQiOm51bGwsIm1lc3NhZ2UiOiJFTjpQcmFjdGl0aW9uZXJMb2dpbjp2MyBJIGhlcmVieSBkZWNsYXJlIHRvIGFjdCBvbiBiZWhhbGYgb2YgdmVycGxlZWdodWlzIERlIG5vb3RqZXMuIFRoaXMgZGVjbGFyYXRpb24gaXMgdmFsaWQgZnJvbSBtYWFuZGFnIDEgb2t0b2JlciAxMjowMDowMCB1bnRpbCBtYWFuZGFnIDEgb2t0b2JlciAxMzowMDowMC4iLCJ0aW1lc3RhbXAiOm51bGx9",
+			"sig":  "eyJAY29udGV4dCI6IiIsInNpZ25hdHVyZSI6bnVsbCwiaW5kaWNlcyI6bnVsbCwibm9uY2UiOm51bGwsImNvbnRleHQiOm51bGwsIm1lc3NhZ2UiOiJFTjpQcmFjdGl0aW9uZXJMb2dpbjp2MyBJIGhlcmVieSBkZWNsYXJlIHRvIGFjdCBvbiBiZWhhbGYgb2YgdmVycGxlZWdodWlzIERlIG5vb3RqZXMuIFRoaXMgZGVjbGFyYXRpb24gaXMgdmFsaWQgZnJvbSBNb25kYXksIDEgT2N0b2JlciAyMDEyIDEyOjAwOjAwIHVudGlsIE1vbmRheSwgMSBPY3RvYmVyIDIwMTIgMTM6MDA6MDAuIiwidGltZXN0YW1wIjpudWxsfQ==",
 			"type": "irma",
 		}
 		rMock := service.Registry.(*registryMock.MockRegistryClient)
@@ -208,7 +426,7 @@ func TestService_SigningSessionStatus(t *testing.T) {
 		}
 		claims := map[string]interface{}{
 			"iss":  otherOrganizationID.String(),
-			"sig":  "eyJAY29udGV4dCI6IiIsInNpZ25hdHVyZSI6bnVsbCwiaW5kaWNlcyI6bnVsbCwibm9uY2UiOm51bGwsImNvbnRleHQiOm51bGwsIm1lc3NhZ2UiOiJFTjpQcmFjdGl0aW9uZXJMb2dpbjp2MyBJIGhlcmVieSBkZWNsYXJlIHRvIGFjdCBvbiBiZWhhbGYgb2YgdmVycGxlZWdodWlzIERlIG5vb3RqZXMuIFRoaXMgZGVjbGFyYXRpb24gaXMgdmFsaWQgZnJvbSBtYWFuZGFnIDEgb2t0b2JlciAxMjowMDowMCB1bnRpbCBtYWFuZGFnIDEgb2t0b2JlciAxMzowMDowMC4iLCJ0aW1lc3RhbXAiOm51bGx9",
+			"sig":  "eyJAY29udGV4dCI6IiIsInNpZ25hdHVyZSI6bnVsbCwiaW5kaWNlcyI6bnVsbCwibm9uY2UiOm51bGwsImNvbnRleHQiOm51bGwsIm1lc3NhZ2UiOiJFTjpQcmFjdGl0aW9uZXJMb2dpbjp2MyBJIGhlcmVieSBkZWNsYXJlIHRvIGFjdCBvbiBiZWhhbGYgb2YgdmVycGxlZWdodWlzIERlIG5vb3RqZXMuIFRoaXMgZGVjbGFyYXRpb24gaXMgdmFsaWQgZnJvbSBNb25kYXksIDEgT2N0b2JlciAyMDEyIDEyOjAwOjAwIHVudGlsIE1vbmRheSwgMSBPY3RvYmVyIDIwMTIgMTM6MDA6MDAuIiwidGltZXN0YW1wIjpudWxsfQ==",
 			"type": "irma",
 		}
 		rMock := service.Registry.(*registryMock.MockRegistryClient)
@@ -219,7 +437,7 @@ func TestService_SigningSessionStatus(t *testing.T) {
 		result, err := service.SigningSessionStatus("session")
 
 		assert.NoError(t, err)
-		assert.Equal(t, "status", string(result.Status()))
+		assert.Equal(t, "status", result.NativeStatus())
 
 		vp, err := result.VerifiablePresentation()
 
@@ -246,5 +464,31 @@ func serviceWithMocks(t *testing.T) (*Service, *gomock.Controller) {
 		Crypto:             cMock,
 		Registry:           rMock,
 		ContractTemplates:  contract.StandardContractTemplates,
+		Sessions:           session.NewMemoryStore(),
+		Notifier:           contract.NewSessionNotifier(),
 	}, ctrl
 }
+
+func TestSigningSessionResult_Status(t *testing.T) {
+	tests := map[string]contract.SessionStatus{
+		string(irmaservercore.StatusInitialized): contract.SessionCreated,
+		string(irmaservercore.StatusConnected):   contract.SessionInProgress,
+		string(irmaservercore.StatusDone):        contract.SessionCompleted,
+		string(irmaservercore.StatusCancelled):   contract.SessionCancelled,
+		string(irmaservercore.StatusTimeout):     contract.SessionExpired,
+		services.SessionCancelled:                contract.SessionCancelled,
+		services.SessionExpired:                  contract.SessionExpired,
+		"unknown":                                contract.SessionFailed,
+	}
+	for native, expected := range tests {
+		result := SigningSessionResult{SessionResult: irmaservercore.SessionResult{Status: irmaservercore.Status(native)}}
+		assert.Equal(t, expected, result.Status(), native)
+		assert.Equal(t, native, result.NativeStatus())
+	}
+
+	t.Run("done with an error is failed", func(t *testing.T) {
+		result := SigningSessionResult{SessionResult: irmaservercore.SessionResult{Status: irmaservercore.StatusDone, Err: &irma.RemoteError{}}}
+
+		assert.Equal(t, contract.SessionFailed, result.Status())
+	})
+}
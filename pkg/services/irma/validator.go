@@ -59,7 +59,11 @@ type Service struct {
 	// todo: remove this when the deprecated ValidateJwt is removed
 	Registry          registry.RegistryClient
 	Crypto            nutscrypto.Client
-	ContractTemplates contract.TemplateStore
+	ContractTemplates contract.TemplateProvider
+	// Sessions stores the metadata of signing sessions, it is optional for the deprecated session handling
+	Sessions services.SessionStore
+	// Notifier notifies the subscribers of a session about its status transitions
+	Notifier *contract.SessionNotifier
 }
 
 // ValidatorConfig holds the configuration for the irma validator.
@@ -74,6 +78,8 @@ type ValidatorConfig struct {
 	IrmaSchemeManager string
 	// Auto update the schemas every x minutes or not?
 	SkipAutoUpdateIrmaSchemas bool
+	// PrintQrCode prints the QR code of every signing session to stdout
+	PrintQrCode bool
 }
 
 // IsInitialized is a helper function to determine if the validator has been initialized properly.
@@ -119,11 +125,17 @@ func (v Service) VerifyVP(rawVerifiablePresentation []byte, checkTime *time.Time
 		return nil, fmt.Errorf("could not verify vp: could not get signer attributes: %w", err)
 	}
 
+	// the validity period is checked by verifyAll, so it can be parsed
+	validTo, _ := signedContract.Contract().ValidTo()
+
 	return &contract.VPVerificationResult{
 		Validity:            contract.State(cvr.ValidationResult),
 		VPType:              contract.VPType(cvr.ContractFormat),
+		ContractID:          signedContract.Contract().Template.Identifier(),
+		ContractHash:        signedContract.Contract().Hash(),
 		DisclosedAttributes: signerAttributes,
 		ContractAttributes:  signedContract.Contract().Params,
+		ValidTo:             validTo,
 	}, nil
 }
 
@@ -207,7 +219,7 @@ func (v Service) SessionStatus(id services.SessionID) (*services.SessionStatusRe
 			token string
 		)
 		if result.Signature != nil {
-			c, err := contract.ParseContractString(result.Signature.Message, v.ContractTemplates)
+			c, err := contract.ParseContractString(result.Signature.Message, v.ContractTemplates.SessionTemplates(string(id)))
 			sic := &SignedIrmaContract{
 				IrmaContract: *result.Signature,
 				contract:     c,
@@ -316,6 +328,7 @@ func parseTokenIssuer(issuer string) (core.PartyID, error) {
 type SessionHandler interface {
 	GetSessionResult(token string) *irmaserver.SessionResult
 	StartSession(request interface{}, handler irmaserver.SessionHandler) (*irma.Qr, string, error)
+	CancelSession(token string) error
 }
 
 // Compile time check if the DefaultIrmaSessionHandler implements the SessionHandler interface
@@ -337,5 +350,10 @@ func (d *DefaultIrmaSessionHandler) StartSession(request interface{}, handler ir
 	return d.I.StartSession(request, handler)
 }
 
+// CancelSession forwards to Irma Server instance
+func (d *DefaultIrmaSessionHandler) CancelSession(token string) error {
+	return d.I.CancelSession(token)
+}
+
 // ErrLegalEntityNotProvided indicates that the legalEntity is missing
 var ErrLegalEntityNotProvided = errors.New("legalEntity not provided")
@@ -323,6 +323,8 @@ type mockIrmaClient struct {
 	sessionResult *irmaservercore.SessionResult
 	irmaQr        *irma.Qr
 	sessionToken  string
+	handler       irmaservercore.SessionHandler
+	cancelled     []string
 }
 
 func (m *mockIrmaClient) GetSessionResult(token string) *irmaservercore.SessionResult {
@@ -337,9 +339,18 @@ func (m *mockIrmaClient) StartSession(request interface{}, handler irmaservercor
 		return nil, "", m.err
 	}
 
+	m.handler = handler
 	return m.irmaQr, m.sessionToken, nil
 }
 
+func (m *mockIrmaClient) CancelSession(token string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.cancelled = append(m.cancelled, token)
+	return nil
+}
+
 // tests using mocks
 func TestDefaultValidator_SessionStatus2(t *testing.T) {
 	serviceConfig := ValidatorConfig{
@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 
 	"github.com/dgrijalva/jwt-go"
+	core "github.com/nuts-foundation/nuts-go-core"
 	irma "github.com/privacybydesign/irmago"
 	"github.com/privacybydesign/irmago/server"
 
@@ -34,6 +35,19 @@ type CreateSessionRequest struct {
 	SigningMeans contract.SigningMeans
 	// Message to sign
 	Message string
+	// Params are passed to the signing means, they may be nil
+	Params map[string]interface{}
+	// Callback is the optional webhook which is called when the session reaches its final status
+	Callback *SessionCallback
+	// ClientID identifies the client which requests the session by its certificate or remote address, it is used to rate
+	// limit the client.
+	ClientID string
+	// Caller is the fingerprint of the verified certificate of the client, the session is bound to it so only the same
+	// client can retrieve the session. It is empty when the client has no certificate, the session is then not bound.
+	Caller string
+	// LegalEntity is the party on whose behalf the session is requested. When set, the Message must be a contract which
+	// is drawn up by this node for the legal entity.
+	LegalEntity core.PartyID
 }
 
 // CreateSessionResult contains the results needed to setup an irma flow
@@ -82,6 +96,29 @@ type CreateJwtBearerTokenRequest struct {
 // AccessTokenResult defines the return value back to the api for the CreateAccessToken method
 type AccessTokenResult struct {
 	AccessToken string
+	// RefreshToken is empty when no refresh token is issued
+	RefreshToken string
+}
+
+// RefreshAccessTokenRequest contains all information to create an access token from a refresh token
+type RefreshAccessTokenRequest struct {
+	RawRefreshToken string
+	// ClientCert is the TLS client certificate the refresh token is bound to, PEM encoded
+	ClientCert string
+}
+
+// RevokeTokenRequest contains the access or refresh token to revoke and the client which revokes it
+type RevokeTokenRequest struct {
+	Token string
+	// ClientCert is the TLS client certificate of the client the token was issued to, PEM encoded
+	ClientCert string
+}
+
+// RevokeContractRequest contains the hash of the signed contract to revoke and the client which revokes it
+type RevokeContractRequest struct {
+	ContractHash string
+	// ClientCert is the TLS client certificate of the actor the contract belongs to, PEM encoded
+	ClientCert string
 }
 
 // JwtBearerTokenResult defines the return value back to the api for the createJwtBearerToken method
@@ -112,6 +149,17 @@ type NutsAccessToken struct {
 	Prefix     string  `json:"prefix"`
 	FamilyName string  `json:"family_name"`
 	Email      string  `json:"email"`
+	// ContractHash identifies the signed contract the access token is derived from, so it can be revoked with the contract
+	ContractHash string `json:"contract_hash,omitempty"`
+}
+
+// NutsRefreshToken is the state of an OAuth 2.0 refresh token, which is kept by the node that issued it. The client only
+// gets a random handle to it. It carries the claims of the access tokens it is exchanged for and is bound to the TLS
+// client certificate of the actor, it expires with the signed contract.
+type NutsRefreshToken struct {
+	NutsAccessToken
+	// CertificateThumbprint is the base64url encoded SHA-256 hash of the DER encoded client certificate
+	CertificateThumbprint string `json:"x5t#S256"`
 }
 
 // AsMap returns the claims from a NutsJwtBearerToken as a map with the json names as keys
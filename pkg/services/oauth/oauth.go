@@ -21,18 +21,28 @@ package oauth
 import (
 	"context"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/nuts-foundation/nuts-auth/logging"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/nuts-foundation/nuts-auth/pkg/contract"
 	"github.com/nuts-foundation/nuts-auth/pkg/services"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/audit"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/expiring"
 	nutsConsentClient "github.com/nuts-foundation/nuts-consent-store/client"
 	nutsConsent "github.com/nuts-foundation/nuts-consent-store/pkg"
 	nutsCrypto "github.com/nuts-foundation/nuts-crypto/pkg"
@@ -50,6 +60,8 @@ var errIncorrectNumberOfEndpoints = errors.New("none or multiple registered endp
 var errMissingCertificate = errors.New("missing x5c header")
 var errInvalidX5cHeader = errors.New("invalid x5c header")
 var errInvalidClientCert = errors.New("invalid TLS client certificate")
+var errMissingJwtID = errors.New("missing jti")
+var errMissingExpiration = errors.New("missing exp")
 
 const errInvalidIssuerFmt = "invalid jwt.issuer: %w"
 const errInvalidSubjectFmt = "invalid jwt.subject: %w"
@@ -61,8 +73,51 @@ type service struct {
 	consent        nutsConsent.ConsentStoreClient
 	oauthKeyEntity nutsCryptoTypes.KeyIdentifier
 	contractClient services.ContractClient
+	config         Config
 }
 
+// Config holds the configuration of the OAuth service
+type Config struct {
+	// Auditor records the outcomes of access token requests, nothing is recorded when nil
+	Auditor *audit.Auditor
+	// SkipAudienceCheck accepts jwt bearer tokens of which the aud is not an OAuth endpoint of the custodian.
+	// The mismatch is only logged, this is meant for the migration of nodes which do not yet set the aud.
+	SkipAudienceCheck bool
+	// ReplayCache holds the issuer and jti of the accepted jwt bearer tokens until they expire. A bounded cache in memory
+	// is used when nil.
+	ReplayCache expiring.Set
+	// RevocationList holds the revoked access tokens and contracts. A bounded list in memory is used when nil.
+	RevocationList expiring.Set
+	// IssuedContracts holds the actor and validity of the signed contracts access tokens are issued for, by contract hash,
+	// until the contracts expire. Only these contracts can be revoked. A bounded map in memory is used when nil.
+	IssuedContracts expiring.Map
+	// RefreshTokens issues a refresh token next to the access token, which is valid for as long as the signed contract
+	RefreshTokens bool
+	// RefreshTokenStore holds the state of the issued refresh tokens by the hash of their handle. A bounded map in memory
+	// is used when nil.
+	RefreshTokenStore expiring.Map
+}
+
+// DefaultReplayCacheSize is the number of jwt bearer tokens which can be remembered at the same time. Since a jwt bearer
+// token is valid for at most OauthBearerTokenMaxValidity seconds, this allows for 2000 access token requests per second.
+const DefaultReplayCacheSize = 10000
+
+// DefaultRevocationListSize is the number of access tokens and contracts which can be revoked at the same time
+const DefaultRevocationListSize = 10000
+
+// DefaultRefreshTokenStoreSize is the number of refresh tokens which can be valid at the same time
+const DefaultRefreshTokenStoreSize = 10000
+
+// accessTokenValidity is the time in which an access token expires
+const accessTokenValidity = 15 * time.Minute
+
+// maxRefreshTokenValidity is the time in which a refresh token expires when the signed contract is valid for longer
+const maxRefreshTokenValidity = 24 * time.Hour
+
+// the keys of the revocation list
+const revokedTokenPrefix = "jti\n"
+const revokedContractPrefix = "contract\n"
+
 type validationContext struct {
 	rawJwtBearerToken          string
 	jwtBearerToken             *services.NutsJwtBearerToken
@@ -72,12 +127,25 @@ type validationContext struct {
 }
 
 // NewOAuthService accepts a vendorID, and several Nuts engines and returns an implementation of services.OAuthClient
-func NewOAuthService(vendorID core.PartyID, cryptoClient nutsCrypto.Client, registryClient nutsRegistry.RegistryClient, contractClient services.ContractClient) services.OAuthClient {
+func NewOAuthService(vendorID core.PartyID, cryptoClient nutsCrypto.Client, registryClient nutsRegistry.RegistryClient, contractClient services.ContractClient, config Config) services.OAuthClient {
+	if config.ReplayCache == nil {
+		config.ReplayCache = expiring.NewMemorySet(DefaultReplayCacheSize)
+	}
+	if config.RevocationList == nil {
+		config.RevocationList = expiring.NewMemorySet(DefaultRevocationListSize)
+	}
+	if config.IssuedContracts == nil {
+		config.IssuedContracts = expiring.NewMemoryMap(DefaultRevocationListSize)
+	}
+	if config.RefreshTokenStore == nil {
+		config.RefreshTokenStore = expiring.NewMemoryMap(DefaultRefreshTokenStoreSize)
+	}
 	return &service{
 		vendorID:       vendorID,
 		crypto:         cryptoClient,
 		registry:       registryClient,
 		contractClient: contractClient,
+		config:         config,
 	}
 }
 
@@ -108,11 +176,37 @@ func (s *service) CreateAccessToken(request services.CreateAccessTokenRequest) (
 	context := validationContext{
 		rawJwtBearerToken: request.RawJwtBearerToken,
 	}
+	result, err := s.createAccessToken(request, &context)
+	if s.config.Auditor.Enabled() {
+		s.config.Auditor.Record(accessTokenEntry(context, err))
+	}
+	return result, err
+}
+
+// accessTokenEntry describes the outcome of an access token request for the audit log
+func accessTokenEntry(context validationContext, err error) audit.Entry {
+	entry := audit.Entry{Event: audit.EventAccessTokenCreated, Outcome: audit.OutcomeAccepted}
+	if context.jwtBearerToken != nil {
+		entry.LegalEntity = context.jwtBearerToken.Issuer
+		entry.Custodian = context.jwtBearerToken.Subject
+	}
+	if context.contractVerificationResult != nil {
+		entry.Means = string(context.contractVerificationResult.VPType)
+		entry.ContractHash = context.contractVerificationResult.ContractHash
+	}
+	if err != nil {
+		entry.Outcome = audit.OutcomeRejected
+		entry.Reason = err.Error()
+	}
+	return entry
+}
+
+func (s *service) createAccessToken(request services.CreateAccessTokenRequest, context *validationContext) (*services.AccessTokenResult, error) {
 
 	// extract the JwtBearerToken, validates according to RFC003 §5.2.1.1
 	// also check if used algorithms are according to spec (ES*** and PS***)
 	// and checks basic validity. Set jwtBearerToken in validationContext
-	if err := s.parseAndValidateJwtBearerToken(&context); err != nil {
+	if err := s.parseAndValidateJwtBearerToken(context); err != nil {
 		return nil, fmt.Errorf("jwt bearer token validation failed: %w", err)
 	}
 
@@ -123,17 +217,23 @@ func (s *service) CreateAccessToken(request services.CreateAccessTokenRequest) (
 
 	// check the actor against the registry, according to RFC003 §5.2.1.3
 	// checks signing certificate and sets vendor, actorName in validationContext
-	if err := s.validateIssuer(&context); err != nil {
+	if err := s.validateIssuer(context); err != nil {
 		return nil, err
 	}
 
 	// check if client certificate is issued by vendor according to RFC003 §5.2.1.2
-	if err := s.validateClientCertificate(&context, request.ClientCert); err != nil {
+	if err := s.validateClientCertificate(context, request.ClientCert); err != nil {
+		return nil, err
+	}
+
+	// the token may only be used once, this is checked after the issuer and client certificate are validated
+	// so only tokens of known vendors take up room in the replay cache
+	if err := s.validateJwtID(context); err != nil {
 		return nil, err
 	}
 
 	// check if the custodian is registered by this vendor, according to RFC003 §5.2.1.8
-	if err := s.validateSubject(&context); err != nil {
+	if err := s.validateSubject(context); err != nil {
 		return nil, err
 	}
 
@@ -144,36 +244,164 @@ func (s *service) CreateAccessToken(request services.CreateAccessTokenRequest) (
 		if decoded, err = base64.StdEncoding.DecodeString(*context.jwtBearerToken.UserIdentity); err != nil {
 			return nil, fmt.Errorf("failed to decode base64 usi field: %w", err)
 		}
-		if context.contractVerificationResult, err = s.contractClient.VerifyVP(decoded, nil); err != nil {
+		if context.contractVerificationResult, err = s.contractClient.VerifyVP(decoded, nil, nil); err != nil {
 			return nil, fmt.Errorf("identity verification failed: %w", err)
 		}
 	}
 	if context.contractVerificationResult.Validity == contract.Invalid {
 		return nil, errors.New("identity validation failed")
 	}
+	if err := s.checkContractRevocation(context.contractVerificationResult.ContractHash); err != nil {
+		return nil, err
+	}
 	// checks if the name from the login contract matches with the registered name of the issuer.
-	if err := s.validateActor(&context); err != nil {
+	if err := s.validateActor(context); err != nil {
+		return nil, err
+	}
+
+	// the patient context the user signed determines the sid and scope of the access token
+	if err := s.validateSignedContext(context); err != nil {
 		return nil, err
 	}
 
 	// validate the endpoint in aud, according to RFC003 §5.2.1.6
 	// the aud field must have the identifier of the endpoint registered by the vendor of this node!
 	// this is needed to prevent relay attacks.
-	// todo: implement when services and endpoints in registry have been implemented (https://github.com/nuts-foundation/nuts-registry/issues/156)
+	if err := s.validateAudience(context); err != nil {
+		return nil, err
+	}
 
 	// validate the legal base, according to RFC003 §5.2.1.7 if sid is present
 	if err = s.validateLegalBase(context.jwtBearerToken); err != nil {
 		return nil, err
 	}
 
-	accessToken, err := s.buildAccessToken(&context)
+	// the contract is remembered so the actor can revoke it
+	if err := s.rememberContract(context); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.buildAccessToken(context)
+	if err != nil {
+		return nil, err
+	}
+	result := &services.AccessTokenResult{AccessToken: accessToken}
+
+	if s.config.RefreshTokens {
+		if result.RefreshToken, err = s.buildRefreshToken(context, request.ClientCert); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// RefreshAccessToken issues a new access token for a refresh token. The refresh token is a handle to the state kept by
+// this node, it must be presented with the client certificate it is bound to, which must still be trusted. The refresh
+// token and its contract must not be revoked and the legal base is checked again, since it may have been withdrawn
+// after the refresh token was issued.
+func (s *service) RefreshAccessToken(request services.RefreshAccessTokenRequest) (*services.AccessTokenResult, error) {
+	refreshToken := &services.NutsRefreshToken{}
+	result, err := s.refreshAccessToken(request, refreshToken)
+	if s.config.Auditor.Enabled() {
+		s.config.Auditor.Record(refreshEntry(refreshToken, err))
+	}
+	return result, err
+}
+
+// refreshEntry describes the outcome of a refresh token request for the audit log
+func refreshEntry(refreshToken *services.NutsRefreshToken, err error) audit.Entry {
+	entry := audit.Entry{
+		Event:        audit.EventAccessTokenRefreshed,
+		LegalEntity:  refreshToken.Subject,
+		Custodian:    refreshToken.Issuer,
+		ContractHash: refreshToken.ContractHash,
+		Outcome:      audit.OutcomeAccepted,
+	}
+	if err != nil {
+		entry.Outcome = audit.OutcomeRejected
+		entry.Reason = err.Error()
+	}
+	return entry
+}
+
+func (s *service) refreshAccessToken(request services.RefreshAccessTokenRequest, refreshToken *services.NutsRefreshToken) (*services.AccessTokenResult, error) {
+	data, err := s.config.RefreshTokenStore.Get(refreshTokenKey(request.RawRefreshToken))
 	if err != nil {
+		return nil, fmt.Errorf("could not retrieve refresh token: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("%w: unknown, expired or revoked", services.ErrInvalidRefreshToken)
+	}
+	if err := json.Unmarshal(data, refreshToken); err != nil {
+		return nil, fmt.Errorf("could not retrieve refresh token: %w", err)
+	}
+
+	if err := s.validateCertificateBinding(refreshToken.CertificateThumbprint, request.ClientCert); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkContractRevocation(refreshToken.ContractHash); err != nil {
+		return nil, err
+	}
+
+	// the access token is issued by the custodian to the actor
+	now := time.Now()
+	legalBaseToken := services.NutsJwtBearerToken{
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt: now.Unix(),
+			Issuer:   refreshToken.Subject,
+			Subject:  refreshToken.Issuer,
+		},
+		SubjectID: refreshToken.SubjectID,
+	}
+	if err := s.validateLegalBase(&legalBaseToken); err != nil {
 		return nil, err
 	}
 
+	// the access token does not outlive the refresh token, which expires with the contract
+	claims := refreshToken.NutsAccessToken
+	jwtID, err := newJwtID()
+	if err != nil {
+		return nil, err
+	}
+	claims.Id = jwtID
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(accessTokenValidity).Unix()
+	if claims.ExpiresAt > refreshToken.ExpiresAt {
+		claims.ExpiresAt = refreshToken.ExpiresAt
+	}
+	accessToken, err := s.signToken(claims)
+	if err != nil {
+		return nil, err
+	}
 	return &services.AccessTokenResult{AccessToken: accessToken}, nil
 }
 
+// validateCertificateBinding checks the client certificate is the certificate the refresh token is bound to
+// and that it is still trusted
+func (s *service) validateCertificateBinding(thumbprint string, pemEncodedCertificate string) error {
+	c, err := cert.PemToX509([]byte(pemEncodedCertificate))
+	if err != nil {
+		logging.Log().Warnf("failed to decoded PEM encoded certificate %s", err.Error())
+		return errInvalidClientCert
+	}
+	if certificateThumbprint(c) != thumbprint {
+		return fmt.Errorf("%w: it is bound to another client certificate", services.ErrInvalidRefreshToken)
+	}
+	chains, err := s.crypto.TrustStore().VerifiedChain(c, time.Now(), []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+	if err != nil || len(chains) == 0 {
+		return errInvalidClientCert
+	}
+	return nil
+}
+
+// certificateThumbprint returns the base64url encoded SHA-256 hash of the DER encoded certificate, like the x5t#S256 of RFC8705
+func certificateThumbprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
 // ErrLegalEntityNotProvided indicates that the legalEntity is missing
 var ErrLegalEntityNotProvided = errors.New("legalEntity not provided")
 
@@ -185,6 +413,35 @@ func (s *service) validateActor(context *validationContext) error {
 	return nil
 }
 
+// ErrSubjectMismatch is returned when the sid of the jwt bearer token differs from the subject in the signed contract
+var ErrSubjectMismatch = errors.New("subject mismatch: sid of the jwt bearer token differs from the subject of the signed contract")
+
+// validateSignedContext applies the patient context of the signed contract to the jwt bearer token. The signed subject
+// is used as sid, so the legal base is checked for the patient the user signed for. The signed purpose of use is added
+// to the scope. Contracts without patient context leave the jwt bearer token as is.
+func (s *service) validateSignedContext(context *validationContext) error {
+	signedAttributes := context.contractVerificationResult.ContractAttributes
+
+	if subject := signedAttributes[contract.SubjectAttr]; subject != "" {
+		sid := context.jwtBearerToken.SubjectID
+		if sid != nil && *sid != "" && *sid != subject {
+			return ErrSubjectMismatch
+		}
+		context.jwtBearerToken.SubjectID = &subject
+	}
+
+	if purposeOfUse := signedAttributes[contract.PurposeOfUseAttr]; purposeOfUse != "" {
+		scopes := strings.Fields(context.jwtBearerToken.Scope)
+		for _, scope := range scopes {
+			if scope == purposeOfUse {
+				return nil
+			}
+		}
+		context.jwtBearerToken.Scope = strings.Join(append(scopes, purposeOfUse), " ")
+	}
+	return nil
+}
+
 // check the actor against the registry, according to RFC003 §5.2.1.3
 // we do this by getting the validation chain for the certificate in the x5c header and check the vendorID SAN from the root
 // with the vendorId of the actor. It returns the actor name which must match the login contract.
@@ -286,6 +543,53 @@ func (s *service) validateSubject(context *validationContext) error {
 	return nil
 }
 
+// validateJwtID checks the jwt bearer token has a jti which has not been used by the issuer before.
+// The jti is remembered until the token expires, after which the token is rejected anyway.
+func (s *service) validateJwtID(context *validationContext) error {
+	token := context.jwtBearerToken
+	if token.Id == "" {
+		return errMissingJwtID
+	}
+	if token.ExpiresAt == 0 {
+		return errMissingExpiration
+	}
+	// the issuer is a valid PartyID, which can not contain a newline
+	added, err := s.config.ReplayCache.Add(token.Issuer+"\n"+token.Id, time.Unix(token.ExpiresAt, 0))
+	if err != nil {
+		return fmt.Errorf("could not check jti: %w", err)
+	}
+	if !added {
+		return fmt.Errorf("%w: jti '%s' of %s", services.ErrReplayedToken, token.Id, token.Issuer)
+	}
+	return nil
+}
+
+// validateAudience checks that the aud of the jwt bearer token identifies an OAuth endpoint of the custodian, according to RFC003 §5.2.1.6.
+// The custodian is registered by the vendor of this node, so a token meant for the endpoint of another node is rejected.
+func (s *service) validateAudience(context *validationContext) error {
+	custodian, err := core.ParsePartyID(context.jwtBearerToken.Subject)
+	if err != nil {
+		return fmt.Errorf(errInvalidSubjectFmt, err)
+	}
+	endpointType := services.OAuthEndpointType
+	endpoints, err := s.registry.EndpointsByOrganizationAndType(custodian, &endpointType)
+	if err != nil {
+		return fmt.Errorf("unable to find the OAuth endpoints of the custodian: %w", err)
+	}
+	audience := context.jwtBearerToken.Audience
+	for _, endpoint := range endpoints {
+		if audience != "" && string(endpoint.Identifier) == audience {
+			return nil
+		}
+	}
+	err = fmt.Errorf("%w: '%s' is not an OAuth endpoint of %s", services.ErrInvalidAudience, audience, custodian)
+	if s.config.SkipAudienceCheck {
+		logging.Log().WithError(err).Warn("accepting jwt bearer token because the audience check is skipped")
+		return nil
+	}
+	return err
+}
+
 // validate the legal base, according to RFC003 §5.2.1.7 if sid is present
 // use consent store
 func (s *service) validateLegalBase(jwtBearerToken *services.NutsJwtBearerToken) error {
@@ -321,7 +625,10 @@ func (s *service) CreateJwtBearerToken(request services.CreateJwtBearerTokenRequ
 		return nil, errIncorrectNumberOfEndpoints
 	}
 
-	jwtBearerToken := claimsFromRequest(request, string(epoints[0].Identifier))
+	jwtBearerToken, err := claimsFromRequest(request, string(epoints[0].Identifier))
+	if err != nil {
+		return nil, err
+	}
 
 	keyVals, err := jwtBearerToken.AsMap()
 	if err != nil {
@@ -339,11 +646,16 @@ func (s *service) CreateJwtBearerToken(request services.CreateJwtBearerTokenRequ
 var timeFunc = time.Now
 
 // standalone func for easier testing
-func claimsFromRequest(request services.CreateJwtBearerTokenRequest, audience string) services.NutsJwtBearerToken {
+func claimsFromRequest(request services.CreateJwtBearerTokenRequest, audience string) (services.NutsJwtBearerToken, error) {
+	jwtID, err := newJwtID()
+	if err != nil {
+		return services.NutsJwtBearerToken{}, err
+	}
 	return services.NutsJwtBearerToken{
 		StandardClaims: jwt.StandardClaims{
 			Audience:  audience,
 			ExpiresAt: timeFunc().Add(5 * time.Second).Unix(),
+			Id:        jwtID,
 			IssuedAt:  timeFunc().Unix(),
 			Issuer:    request.Actor,
 			NotBefore: 0,
@@ -351,7 +663,16 @@ func claimsFromRequest(request services.CreateJwtBearerTokenRequest, audience st
 		},
 		UserIdentity: request.IdentityToken,
 		SubjectID:    request.Subject,
+	}, nil
+}
+
+// newJwtID returns a random jti
+func newJwtID() (string, error) {
+	jwtID := make([]byte, 16)
+	if _, err := rand.Reader.Read(jwtID); err != nil {
+		return "", fmt.Errorf("could not generate jti: %w", err)
 	}
+	return hex.EncodeToString(jwtID), nil
 }
 
 // parseAndValidateJwtBearerToken validates the jwt signature and returns the containing claims
@@ -402,10 +723,205 @@ func getCertificateFromHeaders(token *jwt.Token) (*x509.Certificate, error) {
 	return x509.ParseCertificate(bytes)
 }
 
-// IntrospectAccessToken fills the fields in NutsAccessToken from the given Jwt Access Token
+// IntrospectAccessToken fills the fields in NutsAccessToken from the given Jwt Access Token.
+// It returns services.ErrTokenRevoked when the access token or the contract it is derived from is revoked.
 func (s *service) IntrospectAccessToken(accessToken string) (*services.NutsAccessToken, error) {
+	claims := &services.NutsAccessToken{}
+	if err := s.parseToken(accessToken, claims); err != nil {
+		return nil, err
+	}
+	if err := s.checkRevocation(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkRevocation returns services.ErrTokenRevoked when the token or the contract it is derived from is revoked
+func (s *service) checkRevocation(claims *services.NutsAccessToken) error {
+	revoked, err := s.config.RevocationList.Contains(revokedTokenPrefix + claims.Id)
+	if err != nil {
+		return fmt.Errorf("could not check revocation: %w", err)
+	}
+	if revoked {
+		return fmt.Errorf("%w: jti '%s'", services.ErrTokenRevoked, claims.Id)
+	}
+	return s.checkContractRevocation(claims.ContractHash)
+}
+
+// RevokeAccessToken puts the jti of the access token on the revocation list until the token expires, or removes the
+// state of the refresh token. Like RFC7009 prescribes, the client must authenticate: the client certificate must be
+// trusted and issued by the vendor of the actor the access token was issued to, or be the certificate the refresh token
+// is bound to. Invalid and expired tokens are ignored since they can not be used anymore.
+func (s *service) RevokeAccessToken(request services.RevokeTokenRequest) error {
+	clientCertificate, chains, err := s.verifyClientCertificate(request.ClientCert)
+	if err != nil {
+		return err
+	}
+	claims := &services.NutsAccessToken{}
+	if err := s.parseToken(request.Token, claims); err != nil {
+		// a token which is not an access token of this node may be a refresh token, unknown refresh tokens are ignored
+		logging.Log().WithError(err).Debug("Revoking token as refresh token")
+		return s.revokeRefreshToken(request.Token, clientCertificate)
+	}
+	if claims.Id == "" {
+		return errMissingJwtID
+	}
+	if err := s.authorizeClient(chains, claims.Subject); err != nil {
+		return err
+	}
+	if _, err := s.config.RevocationList.Add(revokedTokenPrefix+claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		return fmt.Errorf("could not revoke access token: %w", err)
+	}
+	logging.Log().Infof("Revoked access token %s of %s", claims.Id, claims.Subject)
+	return nil
+}
+
+// revokeRefreshToken removes the state of the refresh token, when it is bound to the given client certificate
+func (s *service) revokeRefreshToken(handle string, clientCertificate *x509.Certificate) error {
+	key := refreshTokenKey(handle)
+	data, err := s.config.RefreshTokenStore.Get(key)
+	if err != nil {
+		return fmt.Errorf("could not revoke refresh token: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+	refreshToken := services.NutsRefreshToken{}
+	if err := json.Unmarshal(data, &refreshToken); err != nil {
+		return fmt.Errorf("could not revoke refresh token: %w", err)
+	}
+	if refreshToken.CertificateThumbprint != certificateThumbprint(clientCertificate) {
+		return fmt.Errorf("%w: the refresh token is bound to another client certificate", services.ErrUnauthorizedClient)
+	}
+	if err := s.config.RefreshTokenStore.Delete(key); err != nil {
+		return fmt.Errorf("could not revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+// issuedContract is what the node remembers of a signed contract it issued access tokens for
+type issuedContract struct {
+	// Actor is the party which used the contract to request access tokens
+	Actor string `json:"actor"`
+	// ValidTo is the end of the validity of the contract
+	ValidTo time.Time `json:"valid_to"`
+}
+
+// rememberContract remembers the actor and validity of the signed contract of the validated access token request,
+// until the contract or the access token expires
+func (s *service) rememberContract(context *validationContext) error {
+	contractHash := context.contractVerificationResult.ContractHash
+	if contractHash == "" {
+		return nil
+	}
+	record := issuedContract{Actor: context.jwtBearerToken.Issuer, ValidTo: context.contractVerificationResult.ValidTo}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := s.config.IssuedContracts.Put(contractHash, data, revokedUntil(record)); err != nil {
+		return fmt.Errorf("could not remember contract: %w", err)
+	}
+	return nil
+}
+
+// revokedUntil returns the moment until which the contract must be on the revocation list: until it expires, but at
+// least until the access tokens which are issued now expire
+func revokedUntil(record issuedContract) time.Time {
+	if minimum := timeFunc().Add(accessTokenValidity); record.ValidTo.Before(minimum) {
+		return minimum
+	}
+	return record.ValidTo
+}
+
+// RevokeContract puts the contract hash on the revocation list until the contract expires. This revokes the access
+// tokens derived from the signed contract and prevents new access tokens from being issued for it. Only contracts this
+// node issued access tokens for can be revoked, by a client certificate which is issued by the vendor of the actor.
+func (s *service) RevokeContract(request services.RevokeContractRequest) error {
+	if request.ContractHash == "" {
+		return errors.New("missing contract hash")
+	}
+	_, chains, err := s.verifyClientCertificate(request.ClientCert)
+	if err != nil {
+		return err
+	}
+	data, err := s.config.IssuedContracts.Get(request.ContractHash)
+	if err != nil {
+		return fmt.Errorf("could not revoke contract: %w", err)
+	}
+	if data == nil {
+		return fmt.Errorf("%w: no access tokens are issued for contract %s", services.ErrUnknownContract, request.ContractHash)
+	}
+	record := issuedContract{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return fmt.Errorf("could not revoke contract: %w", err)
+	}
+	if err := s.authorizeClient(chains, record.Actor); err != nil {
+		return err
+	}
+	if _, err := s.config.RevocationList.Add(revokedContractPrefix+request.ContractHash, revokedUntil(record)); err != nil {
+		return fmt.Errorf("could not revoke contract: %w", err)
+	}
+	logging.Log().Infof("Revoked contract %s of %s", request.ContractHash, record.Actor)
+	return nil
+}
+
+// verifyClientCertificate returns the PEM encoded client certificate and its verified chains. It returns
+// services.ErrInvalidClient when there is no certificate or when it is not trusted for client authentication.
+func (s *service) verifyClientCertificate(pemEncodedCertificate string) (*x509.Certificate, [][]*x509.Certificate, error) {
+	if pemEncodedCertificate == "" {
+		return nil, nil, fmt.Errorf("%w: a client certificate is required", services.ErrInvalidClient)
+	}
+	c, err := cert.PemToX509([]byte(pemEncodedCertificate))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", services.ErrInvalidClient, errInvalidClientCert)
+	}
+	chains, err := s.crypto.TrustStore().VerifiedChain(c, time.Now(), []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth})
+	if err != nil || len(chains) == 0 {
+		return nil, nil, fmt.Errorf("%w: %s", services.ErrInvalidClient, errInvalidClientCert)
+	}
+	return c, chains, nil
+}
+
+// authorizeClient checks the client certificate of the verified chains is issued by the vendor CA of the given party,
+// like the client certificate of an access token request must be issued by the vendor of the actor.
+func (s *service) authorizeClient(chains [][]*x509.Certificate, party string) error {
+	partyID, err := core.ParsePartyID(party)
+	if err != nil {
+		return fmt.Errorf("%w: %s", services.ErrUnauthorizedClient, err)
+	}
+	organization, err := s.registry.OrganizationById(partyID)
+	if err != nil {
+		return fmt.Errorf("%w: %s", services.ErrUnauthorizedClient, err)
+	}
+	for _, chain := range chains {
+		vendor, err := cert.VendorIDFromCertificate(chain[len(chain)-1])
+		if err == nil && vendor.String() == organization.Vendor.String() {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: the client certificate is not issued by the vendor of %s", services.ErrUnauthorizedClient, party)
+}
+
+// checkContractRevocation returns services.ErrTokenRevoked when the contract with the given hash is revoked
+func (s *service) checkContractRevocation(contractHash string) error {
+	if contractHash == "" {
+		return nil
+	}
+	revoked, err := s.config.RevocationList.Contains(revokedContractPrefix + contractHash)
+	if err != nil {
+		return fmt.Errorf("could not check revocation: %w", err)
+	}
+	if revoked {
+		return fmt.Errorf("%w: contract %s is revoked", services.ErrTokenRevoked, contractHash)
+	}
+	return nil
+}
+
+// parseToken validates the signature and expiry of an access token issued by this node and fills in its claims
+func (s *service) parseToken(rawToken string, claims jwt.Claims) error {
 	parser := &jwt.Parser{ValidMethods: services.ValidJWTAlg}
-	token, err := parser.ParseWithClaims(accessToken, &services.NutsAccessToken{}, func(token *jwt.Token) (i interface{}, e error) {
+	token, err := parser.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (i interface{}, e error) {
 		// Check if the care provider which signed the token is managed by this node
 		if !s.crypto.PrivateKeyExists(s.oauthKeyEntity) {
 			return nil, errors.New("invalid signature")
@@ -421,42 +937,57 @@ func (s *service) IntrospectAccessToken(accessToken string) (*services.NutsAcces
 		return
 	})
 
-	if token != nil && token.Valid {
-		if claims, ok := token.Claims.(*services.NutsAccessToken); ok {
-			return claims, nil
-		}
+	if err != nil {
+		return err
 	}
-	return nil, err
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+	return nil
 }
 
 // todo split this func for easier testing
 // BuildAccessToken builds an access token based on the oauth claims and the identity of the user provided by the identityValidationResult
 // The token gets signed with the custodians private key and returned as a string.
 func (s *service) buildAccessToken(context *validationContext) (string, error) {
+	claims, err := s.accessTokenClaims(context)
+	if err != nil {
+		return "", err
+	}
+	return s.signToken(*claims)
+}
+
+// accessTokenClaims returns the claims of the access token for the validated jwt bearer token and contract
+func (s *service) accessTokenClaims(context *validationContext) (*services.NutsAccessToken, error) {
 	identityValidationResult := context.contractVerificationResult
 	jwtBearerToken := context.jwtBearerToken
 
 	if identityValidationResult.Validity != contract.Valid {
-		return "", fmt.Errorf("could not build accessToken: %w", errors.New("invalid contract"))
+		return nil, fmt.Errorf("could not build accessToken: %w", errors.New("invalid contract"))
 	}
 
 	issuer := jwtBearerToken.Subject
 	if issuer == "" {
-		return "", fmt.Errorf("could not build accessToken: %w", errors.New("subject is missing"))
+		return nil, fmt.Errorf("could not build accessToken: %w", errors.New("subject is missing"))
 	}
 
 	disclosedAttributes := identityValidationResult.DisclosedAttributes
 
+	jwtID, err := newJwtID()
+	if err != nil {
+		return nil, err
+	}
 	at := services.NutsAccessToken{
 		StandardClaims: jwt.StandardClaims{
-			// Expires in 15 minutes
-			ExpiresAt: time.Now().Add(time.Minute * 15).Unix(),
+			ExpiresAt: time.Now().Add(accessTokenValidity).Unix(),
+			Id:        jwtID,
 			IssuedAt:  time.Now().Unix(),
 			Issuer:    issuer,
 			Subject:   jwtBearerToken.Issuer,
 		},
-		SubjectID: jwtBearerToken.SubjectID,
-		Scope:     jwtBearerToken.Scope,
+		SubjectID:    jwtBearerToken.SubjectID,
+		Scope:        jwtBearerToken.Scope,
+		ContractHash: identityValidationResult.ContractHash,
 		// based on
 		// https://privacybydesign.foundation/attribute-index/en/pbdf.gemeente.personalData.html
 		// https://privacybydesign.foundation/attribute-index/en/pbdf.pbdf.email.html
@@ -468,18 +999,139 @@ func (s *service) buildAccessToken(context *validationContext) (string, error) {
 		Name:       disclosedAttributes["gemeente.personalData.fullname"],
 		Email:      disclosedAttributes["sidn-pbdf.email.email"],
 	}
+	return &at, nil
+}
+
+// buildRefreshToken builds a refresh token bound to the client certificate, which expires with the signed contract.
+// The refresh token is a random handle, the claims of the access tokens it is exchanged for are kept by this node.
+// No refresh token is built when the contract expires before the access token does, or when the store is full.
+func (s *service) buildRefreshToken(context *validationContext, pemEncodedCertificate string) (string, error) {
+	validTo := context.contractVerificationResult.ValidTo
+	now := time.Now()
+	if !validTo.After(now.Add(accessTokenValidity)) {
+		return "", nil
+	}
+	if maxValidTo := now.Add(maxRefreshTokenValidity); validTo.After(maxValidTo) {
+		validTo = maxValidTo
+	}
+
+	c, err := cert.PemToX509([]byte(pemEncodedCertificate))
+	if err != nil {
+		return "", fmt.Errorf("could not build refresh token: %w", err)
+	}
+
+	claims, err := s.accessTokenClaims(context)
+	if err != nil {
+		return "", err
+	}
+	claims.ExpiresAt = validTo.Unix()
+	data, err := json.Marshal(services.NutsRefreshToken{
+		NutsAccessToken:       *claims,
+		CertificateThumbprint: certificateThumbprint(c),
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not build refresh token: %w", err)
+	}
+	handle := make([]byte, 32)
+	if _, err := rand.Reader.Read(handle); err != nil {
+		return "", fmt.Errorf("could not build refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(handle)
+	if err := s.config.RefreshTokenStore.Put(refreshTokenKey(refreshToken), data, validTo); err != nil {
+		if errors.Is(err, expiring.ErrFull) {
+			logging.Log().Warn("No refresh token issued, the refresh token store is full")
+			return "", nil
+		}
+		return "", fmt.Errorf("could not store refresh token: %w", err)
+	}
+	return refreshToken, nil
+}
+
+// refreshTokenKey returns the key of the state of the refresh token, the hash of its handle. The handles themselves are
+// not stored, so they can not be read from the store.
+func refreshTokenKey(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
 
+// signToken signs the claims with the oauth key of this node
+func (s *service) signToken(claims interface{}) (string, error) {
 	var keyVals map[string]interface{}
-	inrec, _ := json.Marshal(at)
+	inrec, _ := json.Marshal(claims)
 	if err := json.Unmarshal(inrec, &keyVals); err != nil {
 		return "", err
 	}
 
-	// Sign with the private key of the issuer
-	token, err := s.crypto.SignJWT(keyVals, s.oauthKeyEntity)
+	// Sign with the oauth key of the node, the kid identifies it in the JWKS
+	privateKey, publicKey, err := s.signingKey()
+	if err != nil {
+		return "", fmt.Errorf("could not build accessToken: %w", err)
+	}
+	token, err := nutsCrypto.SignJWT(privateKey, keyVals, map[string]interface{}{jwk.KeyIDKey: publicKey.KeyID()})
 	if err != nil {
 		return token, fmt.Errorf("could not build accessToken: %w", err)
 	}
 
 	return token, err
 }
+
+// SigningKey returns the public key the access tokens of this node are signed with, as JWK with the kid
+// of their header. Resource servers use it to validate access tokens without introspection.
+func (s *service) SigningKey() (jwk.Key, error) {
+	_, publicKey, err := s.signingKey()
+	return publicKey, err
+}
+
+// RefreshTokensEnabled returns true when refresh tokens are issued with the access tokens
+func (s *service) RefreshTokensEnabled() bool {
+	return s.config.RefreshTokens
+}
+
+// signingKey returns the oauth key of the node and its public key as JWK. The kid of the JWK is its RFC7638 thumbprint,
+// so it changes when the key is replaced.
+func (s *service) signingKey() (crypto.Signer, jwk.Key, error) {
+	privateKey, err := s.crypto.GetPrivateKey(s.oauthKeyEntity)
+	if err != nil {
+		return nil, nil, err
+	}
+	algorithm, err := signingAlgorithm(privateKey.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := jwk.New(privateKey.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	thumbprint, err := publicKey.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := publicKey.Set(jwk.KeyIDKey, base64.RawURLEncoding.EncodeToString(thumbprint)); err != nil {
+		return nil, nil, err
+	}
+	if err := publicKey.Set(jwk.AlgorithmKey, algorithm); err != nil {
+		return nil, nil, err
+	}
+	if err := publicKey.Set(jwk.KeyUsageKey, jwk.ForSignature); err != nil {
+		return nil, nil, err
+	}
+	return privateKey, publicKey, nil
+}
+
+// signingAlgorithm returns the algorithm nutsCrypto.SignJWT uses to sign with the key
+func signingAlgorithm(publicKey crypto.PublicKey) (jwa.SignatureAlgorithm, error) {
+	switch k := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwa.PS256, nil
+	case *ecdsa.PublicKey:
+		switch k.Params().BitSize {
+		case 256:
+			return jwa.ES256, nil
+		case 384:
+			return jwa.ES384, nil
+		case 521:
+			return jwa.ES512, nil
+		}
+	}
+	return "", errors.New("unsupported signing key")
+}
@@ -22,6 +22,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
@@ -32,9 +33,12 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/golang/mock/gomock"
+	"github.com/lestrrat-go/jwx/jwa"
 	servicesMock "github.com/nuts-foundation/nuts-auth/mock/services"
 	"github.com/nuts-foundation/nuts-auth/pkg/contract"
 	"github.com/nuts-foundation/nuts-auth/pkg/services"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/audit"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/expiring"
 	consentMock "github.com/nuts-foundation/nuts-consent-store/mock"
 	pkg2 "github.com/nuts-foundation/nuts-consent-store/pkg"
 	"github.com/nuts-foundation/nuts-crypto/pkg"
@@ -47,6 +51,7 @@ import (
 	"github.com/nuts-foundation/nuts-go-test/io"
 	registryMock "github.com/nuts-foundation/nuts-registry/mock"
 	"github.com/nuts-foundation/nuts-registry/pkg/db"
+	registryTypes "github.com/nuts-foundation/nuts-registry/pkg/types"
 	registryTest "github.com/nuts-foundation/nuts-registry/test"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
@@ -64,6 +69,22 @@ func TestAuth_CreateAccessToken(t *testing.T) {
 		}
 	})
 
+	t.Run("rejected request is recorded", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		sink := &auditSink{}
+		ctx.oauthService.config.Auditor = audit.NewAuditor(sink)
+
+		_, _ = ctx.oauthService.CreateAccessToken(services.CreateAccessTokenRequest{RawJwtBearerToken: "foo"})
+
+		if !assert.Len(t, sink.entries, 1) {
+			return
+		}
+		assert.Equal(t, audit.EventAccessTokenCreated, sink.entries[0].Event)
+		assert.Equal(t, audit.OutcomeRejected, sink.entries[0].Outcome)
+		assert.Contains(t, sink.entries[0].Reason, "jwt bearer token validation failed")
+	})
+
 	t.Run("missing client certificate", func(t *testing.T) {
 		ctx := createContext(t)
 		defer ctx.ctrl.Finish()
@@ -86,7 +107,7 @@ func TestAuth_CreateAccessToken(t *testing.T) {
 		defer ctx.ctrl.Finish()
 		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Times(2).Return(&db.Organization{Vendor: vendorID}, nil)
 		ctx.cryptoMock.EXPECT().TrustStore().AnyTimes().Return(testTrustStore{ca: vendorCA(t)})
-		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil).Return(nil, errors.New("identity validation failed"))
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil, nil).Return(nil, errors.New("identity validation failed"))
 
 		tokenCtx := validContext()
 		signToken(tokenCtx)
@@ -117,7 +138,7 @@ func TestAuth_CreateAccessToken(t *testing.T) {
 	t.Run("invalid identity token", func(t *testing.T) {
 		ctx := createContext(t)
 		defer ctx.ctrl.Finish()
-		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil).Return(&contract.VPVerificationResult{Validity: contract.Invalid}, nil)
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil, nil).Return(&contract.VPVerificationResult{Validity: contract.Invalid}, nil)
 		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Times(2).Return(&db.Organization{Vendor: vendorID}, nil)
 		ctx.cryptoMock.EXPECT().TrustStore().AnyTimes().Return(testTrustStore{ca: vendorCA(t)})
 
@@ -131,15 +152,41 @@ func TestAuth_CreateAccessToken(t *testing.T) {
 		}
 	})
 
+	t.Run("legal base is checked for the signed subject", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		signedSubject := "urn:oid:2.16.840.1.113883.2.4.6.3:999999990"
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil, nil).Return(&contract.VPVerificationResult{Validity: contract.Valid, ContractAttributes: map[string]string{contract.SubjectAttr: signedSubject, contract.PurposeOfUseAttr: "TREAT"}}, nil)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Return(&db.Organization{Vendor: vendorID}, nil)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Return(&db.Organization{Vendor: vendorID}, nil)
+		ctx.cryptoMock.EXPECT().TrustStore().AnyTimes().Return(testTrustStore{ca: vendorCA(t)})
+		expectOAuthEndpoint(ctx, "endpoint")
+		ctx.consentMock.EXPECT().QueryConsent(gomock.Any(), gomock.Any(), gomock.Any(), &signedSubject, gomock.Any()).Return([]pkg2.PatientConsent{{}}, nil)
+		expectSigningKey(ctx)
+
+		tokenCtx := validContext()
+		tokenCtx.jwtBearerToken.SubjectID = nil
+		signToken(tokenCtx)
+
+		response, err := ctx.oauthService.CreateAccessToken(services.CreateAccessTokenRequest{RawJwtBearerToken: tokenCtx.rawJwtBearerToken, ClientCert: clientCert(t)})
+		if !assert.NoError(t, err) {
+			return
+		}
+		claims := tokenClaims(t, response.AccessToken)
+		assert.Equal(t, signedSubject, claims["sid"])
+		assert.Equal(t, "TREAT", claims["scope"])
+	})
+
 	t.Run("valid - with legal base", func(t *testing.T) {
 		ctx := createContext(t)
 		defer ctx.ctrl.Finish()
-		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil).Return(&contract.VPVerificationResult{Validity: contract.Valid, DisclosedAttributes: map[string]string{"name": "Henk de Vries"}}, nil)
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil, nil).Return(&contract.VPVerificationResult{Validity: contract.Valid, DisclosedAttributes: map[string]string{"name": "Henk de Vries"}, ContractHash: "hash", ValidTo: time.Now().Add(time.Hour)}, nil)
 		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Return(&db.Organization{Vendor: vendorID}, nil)
 		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Return(&db.Organization{Vendor: vendorID}, nil)
 		ctx.cryptoMock.EXPECT().TrustStore().AnyTimes().Return(testTrustStore{ca: vendorCA(t)})
+		expectOAuthEndpoint(ctx, "endpoint")
 		ctx.consentMock.EXPECT().QueryConsent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]pkg2.PatientConsent{{}}, nil)
-		ctx.cryptoMock.EXPECT().SignJWT(gomock.Any(), gomock.Any()).Return("expectedAT", nil)
+		expectSigningKey(ctx)
 
 		tokenCtx := validContext()
 		signToken(tokenCtx)
@@ -147,9 +194,468 @@ func TestAuth_CreateAccessToken(t *testing.T) {
 		response, err := ctx.oauthService.CreateAccessToken(services.CreateAccessTokenRequest{RawJwtBearerToken: tokenCtx.rawJwtBearerToken, ClientCert: clientCert(t)})
 		assert.Nil(t, err)
 		if assert.NotNil(t, response) {
-			assert.Equal(t, "expectedAT", response.AccessToken)
+			assert.NotEmpty(t, response.AccessToken)
+		}
+		issued, _ := ctx.oauthService.config.IssuedContracts.Get("hash")
+		assert.NotNil(t, issued, "the contract can be revoked by the actor")
+	})
+
+	t.Run("replayed token", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil, nil).Return(&contract.VPVerificationResult{Validity: contract.Valid}, nil)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Times(3).Return(&db.Organization{Vendor: vendorID}, nil)
+		ctx.cryptoMock.EXPECT().TrustStore().AnyTimes().Return(testTrustStore{ca: vendorCA(t)})
+		expectOAuthEndpoint(ctx, "endpoint")
+		ctx.consentMock.EXPECT().QueryConsent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]pkg2.PatientConsent{{}}, nil)
+		expectSigningKey(ctx)
+
+		tokenCtx := validContext()
+		signToken(tokenCtx)
+		request := services.CreateAccessTokenRequest{RawJwtBearerToken: tokenCtx.rawJwtBearerToken, ClientCert: clientCert(t)}
+		if _, err := ctx.oauthService.CreateAccessToken(request); !assert.NoError(t, err) {
+			return
+		}
+
+		response, err := ctx.oauthService.CreateAccessToken(request)
+		assert.Nil(t, response)
+		assert.True(t, errors.Is(err, services.ErrReplayedToken))
+	})
+
+	t.Run("revoked contract", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil, nil).Return(&contract.VPVerificationResult{Validity: contract.Valid, ContractHash: "hash"}, nil)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Times(2).Return(&db.Organization{Vendor: vendorID}, nil)
+		ctx.cryptoMock.EXPECT().TrustStore().AnyTimes().Return(testTrustStore{ca: vendorCA(t)})
+		revokeContract(ctx, "hash")
+
+		tokenCtx := validContext()
+		signToken(tokenCtx)
+
+		response, err := ctx.oauthService.CreateAccessToken(services.CreateAccessTokenRequest{RawJwtBearerToken: tokenCtx.rawJwtBearerToken, ClientCert: clientCert(t)})
+		assert.Nil(t, response)
+		assert.True(t, errors.Is(err, services.ErrTokenRevoked))
+	})
+
+	t.Run("token for the endpoint of another node", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil, nil).Return(&contract.VPVerificationResult{Validity: contract.Valid}, nil)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Times(2).Return(&db.Organization{Vendor: vendorID}, nil)
+		ctx.cryptoMock.EXPECT().TrustStore().AnyTimes().Return(testTrustStore{ca: vendorCA(t)})
+		expectOAuthEndpoint(ctx, "other endpoint")
+
+		tokenCtx := validContext()
+		signToken(tokenCtx)
+
+		response, err := ctx.oauthService.CreateAccessToken(services.CreateAccessTokenRequest{RawJwtBearerToken: tokenCtx.rawJwtBearerToken, ClientCert: clientCert(t)})
+		assert.Nil(t, response)
+		assert.True(t, errors.Is(err, services.ErrInvalidAudience))
+	})
+
+	t.Run("valid - with refresh token", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.oauthService.config.RefreshTokens = true
+		validTo := time.Now().Add(time.Hour)
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil, nil).Return(&contract.VPVerificationResult{Validity: contract.Valid, ValidTo: validTo}, nil)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Times(2).Return(&db.Organization{Vendor: vendorID}, nil)
+		ctx.cryptoMock.EXPECT().TrustStore().AnyTimes().Return(testTrustStore{ca: vendorCA(t)})
+		expectOAuthEndpoint(ctx, "endpoint")
+		ctx.consentMock.EXPECT().QueryConsent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]pkg2.PatientConsent{{}}, nil)
+		expectSigningKey(ctx)
+
+		tokenCtx := validContext()
+		signToken(tokenCtx)
+
+		response, err := ctx.oauthService.CreateAccessToken(services.CreateAccessTokenRequest{RawJwtBearerToken: tokenCtx.rawJwtBearerToken, ClientCert: clientCert(t)})
+		if !assert.NoError(t, err) {
+			return
+		}
+		// the refresh token is an opaque handle, its claims are only kept by the node
+		assert.NotContains(t, response.RefreshToken, ".")
+		data, _ := ctx.oauthService.config.RefreshTokenStore.Get(refreshTokenKey(response.RefreshToken))
+		refreshToken := services.NutsRefreshToken{}
+		if !assert.NoError(t, json.Unmarshal(data, &refreshToken)) {
+			return
+		}
+		assert.Equal(t, validTo.Unix(), refreshToken.ExpiresAt)
+		assert.NotEmpty(t, refreshToken.CertificateThumbprint)
+		_, err = ctx.oauthService.IntrospectAccessToken(response.RefreshToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("valid - no refresh token when the store is full", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.oauthService.config.RefreshTokens = true
+		ctx.oauthService.config.RefreshTokenStore = expiring.NewMemoryMap(0)
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil, nil).Return(&contract.VPVerificationResult{Validity: contract.Valid, ValidTo: time.Now().Add(time.Hour)}, nil)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Times(2).Return(&db.Organization{Vendor: vendorID}, nil)
+		ctx.cryptoMock.EXPECT().TrustStore().AnyTimes().Return(testTrustStore{ca: vendorCA(t)})
+		expectOAuthEndpoint(ctx, "endpoint")
+		ctx.consentMock.EXPECT().QueryConsent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]pkg2.PatientConsent{{}}, nil)
+		expectSigningKey(ctx)
+
+		tokenCtx := validContext()
+		signToken(tokenCtx)
+
+		response, err := ctx.oauthService.CreateAccessToken(services.CreateAccessTokenRequest{RawJwtBearerToken: tokenCtx.rawJwtBearerToken, ClientCert: clientCert(t)})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NotEmpty(t, response.AccessToken)
+		assert.Empty(t, response.RefreshToken)
+	})
+
+	t.Run("valid - no refresh token when the contract expires before the access token", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.oauthService.config.RefreshTokens = true
+		ctx.contractClientMock.EXPECT().VerifyVP(gomock.Any(), nil, nil).Return(&contract.VPVerificationResult{Validity: contract.Valid, ValidTo: time.Now().Add(time.Minute)}, nil)
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Times(2).Return(&db.Organization{Vendor: vendorID}, nil)
+		ctx.cryptoMock.EXPECT().TrustStore().AnyTimes().Return(testTrustStore{ca: vendorCA(t)})
+		expectOAuthEndpoint(ctx, "endpoint")
+		ctx.consentMock.EXPECT().QueryConsent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]pkg2.PatientConsent{{}}, nil)
+		expectSigningKey(ctx)
+
+		tokenCtx := validContext()
+		signToken(tokenCtx)
+
+		response, err := ctx.oauthService.CreateAccessToken(services.CreateAccessTokenRequest{RawJwtBearerToken: tokenCtx.rawJwtBearerToken, ClientCert: clientCert(t)})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NotEmpty(t, response.AccessToken)
+		assert.Empty(t, response.RefreshToken)
+	})
+}
+
+func TestOAuthService_RefreshAccessToken(t *testing.T) {
+	sid := "subject"
+	refreshClaims := func() services.NutsRefreshToken {
+		certificate, _ := x509.ParseCertificate(certBytes)
+		return services.NutsRefreshToken{
+			NutsAccessToken: services.NutsAccessToken{
+				StandardClaims: jwt.StandardClaims{
+					ExpiresAt: time.Now().Add(10 * time.Minute).Unix(),
+					Id:        "1",
+					Issuer:    "urn:oid:2.16.840.1.113883.2.4.6.1:custodian",
+					Subject:   "urn:oid:2.16.840.1.113883.2.4.6.1:actor",
+				},
+				SubjectID:    &sid,
+				ContractHash: "hash",
+			},
+			CertificateThumbprint: certificateThumbprint(certificate),
+		}
+	}
+	expectKey := func(ctx *testContext) {
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(oauthKeyEntity).AnyTimes().Return(true)
+		ctx.cryptoMock.EXPECT().GetPrivateKey(oauthKeyEntity).AnyTimes().Return(key, nil)
+	}
+
+	t.Run("ok - the access token expires with the refresh token", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		sink := &auditSink{}
+		ctx.oauthService.config.Auditor = audit.NewAuditor(sink)
+		expectKey(ctx)
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		ctx.consentMock.EXPECT().QueryConsent(gomock.Any(), gomock.Any(), gomock.Any(), &sid, gomock.Any()).Return([]pkg2.PatientConsent{{}}, nil)
+		claims := refreshClaims()
+
+		response, err := ctx.oauthService.RefreshAccessToken(services.RefreshAccessTokenRequest{RawRefreshToken: storeRefreshToken(ctx, claims), ClientCert: clientCert(t)})
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Empty(t, response.RefreshToken)
+		accessClaims := tokenClaims(t, response.AccessToken)
+		assert.Equal(t, float64(claims.ExpiresAt), accessClaims["exp"])
+		assert.Equal(t, claims.Issuer, accessClaims["iss"])
+		assert.Equal(t, "hash", accessClaims["contract_hash"])
+		assert.NotEqual(t, claims.Id, accessClaims["jti"])
+		assert.NotContains(t, accessClaims, "x5t#S256")
+		if assert.Len(t, sink.entries, 1) {
+			assert.Equal(t, audit.EventAccessTokenRefreshed, sink.entries[0].Event)
+			assert.Equal(t, audit.OutcomeAccepted, sink.entries[0].Outcome)
+			assert.Equal(t, claims.Subject, sink.entries[0].LegalEntity)
+		}
+	})
+
+	t.Run("nok - access token", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		accessToken := signAccessToken(refreshClaims().NutsAccessToken)
+
+		response, err := ctx.oauthService.RefreshAccessToken(services.RefreshAccessTokenRequest{RawRefreshToken: accessToken, ClientCert: clientCert(t)})
+
+		assert.Nil(t, response)
+		assert.True(t, errors.Is(err, services.ErrInvalidRefreshToken))
+	})
+
+	t.Run("nok - expired", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectKey(ctx)
+		claims := refreshClaims()
+		claims.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+
+		response, err := ctx.oauthService.RefreshAccessToken(services.RefreshAccessTokenRequest{RawRefreshToken: storeRefreshToken(ctx, claims), ClientCert: clientCert(t)})
+
+		assert.Nil(t, response)
+		assert.True(t, errors.Is(err, services.ErrInvalidRefreshToken))
+	})
+
+	t.Run("nok - other client certificate", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectKey(ctx)
+		otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		otherCert, _ := x509.ParseCertificate(test.GenerateCertificate(time.Now(), 2, otherKey))
+
+		response, err := ctx.oauthService.RefreshAccessToken(services.RefreshAccessTokenRequest{RawRefreshToken: storeRefreshToken(ctx, refreshClaims()), ClientCert: cert.CertificateToPEM(otherCert)})
+
+		assert.Nil(t, response)
+		assert.True(t, errors.Is(err, services.ErrInvalidRefreshToken))
+	})
+
+	t.Run("nok - client certificate is no longer trusted", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectKey(ctx)
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{err: errors.New("expired")})
+
+		response, err := ctx.oauthService.RefreshAccessToken(services.RefreshAccessTokenRequest{RawRefreshToken: storeRefreshToken(ctx, refreshClaims()), ClientCert: clientCert(t)})
+
+		assert.Nil(t, response)
+		assert.Equal(t, errInvalidClientCert, err)
+	})
+
+	t.Run("nok - revoked contract", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		sink := &auditSink{}
+		ctx.oauthService.config.Auditor = audit.NewAuditor(sink)
+		expectKey(ctx)
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		revokeContract(ctx, "hash")
+
+		response, err := ctx.oauthService.RefreshAccessToken(services.RefreshAccessTokenRequest{RawRefreshToken: storeRefreshToken(ctx, refreshClaims()), ClientCert: clientCert(t)})
+
+		assert.Nil(t, response)
+		assert.True(t, errors.Is(err, services.ErrTokenRevoked))
+		if assert.Len(t, sink.entries, 1) {
+			assert.Equal(t, audit.OutcomeRejected, sink.entries[0].Outcome)
+			assert.Equal(t, "hash", sink.entries[0].ContractHash)
 		}
 	})
+
+	t.Run("nok - revoked refresh token", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectKey(ctx)
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		refreshToken := storeRefreshToken(ctx, refreshClaims())
+		if !assert.NoError(t, ctx.oauthService.RevokeAccessToken(services.RevokeTokenRequest{Token: refreshToken, ClientCert: clientCert(t)})) {
+			return
+		}
+
+		response, err := ctx.oauthService.RefreshAccessToken(services.RefreshAccessTokenRequest{RawRefreshToken: refreshToken, ClientCert: clientCert(t)})
+
+		assert.Nil(t, response)
+		assert.True(t, errors.Is(err, services.ErrInvalidRefreshToken))
+	})
+
+	t.Run("nok - legal base is withdrawn", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectKey(ctx)
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		ctx.consentMock.EXPECT().QueryConsent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]pkg2.PatientConsent{}, nil)
+
+		response, err := ctx.oauthService.RefreshAccessToken(services.RefreshAccessTokenRequest{RawRefreshToken: storeRefreshToken(ctx, refreshClaims()), ClientCert: clientCert(t)})
+
+		assert.Nil(t, response)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "no legal base present")
+		}
+	})
+}
+
+func TestService_validateJwtID(t *testing.T) {
+	issuer := "urn:oid:2.16.840.1.113883.2.4.6.1:actor"
+	expiresAt := time.Now().Add(5 * time.Second).Unix()
+	tokenContext := func(issuer string, jwtID string, expiresAt int64) *validationContext {
+		return &validationContext{jwtBearerToken: &services.NutsJwtBearerToken{StandardClaims: jwt.StandardClaims{Issuer: issuer, Id: jwtID, ExpiresAt: expiresAt}}}
+	}
+	newService := func(maxSize int) *service {
+		return &service{config: Config{ReplayCache: expiring.NewMemorySet(maxSize)}}
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		assert.NoError(t, newService(10).validateJwtID(tokenContext(issuer, "1", expiresAt)))
+	})
+
+	t.Run("ok - same jti of another issuer", func(t *testing.T) {
+		s := newService(10)
+		_ = s.validateJwtID(tokenContext(issuer, "1", expiresAt))
+
+		assert.NoError(t, s.validateJwtID(tokenContext("urn:oid:2.16.840.1.113883.2.4.6.1:other", "1", expiresAt)))
+	})
+
+	t.Run("nok - replayed", func(t *testing.T) {
+		s := newService(10)
+		_ = s.validateJwtID(tokenContext(issuer, "1", expiresAt))
+
+		err := s.validateJwtID(tokenContext(issuer, "1", expiresAt))
+
+		assert.True(t, errors.Is(err, services.ErrReplayedToken))
+	})
+
+	t.Run("nok - missing jti", func(t *testing.T) {
+		assert.Equal(t, errMissingJwtID, newService(10).validateJwtID(tokenContext(issuer, "", expiresAt)))
+	})
+
+	t.Run("nok - missing exp", func(t *testing.T) {
+		assert.Equal(t, errMissingExpiration, newService(10).validateJwtID(tokenContext(issuer, "1", 0)))
+	})
+
+	t.Run("error - cache is full", func(t *testing.T) {
+		s := newService(1)
+		_ = s.validateJwtID(tokenContext(issuer, "1", expiresAt))
+
+		err := s.validateJwtID(tokenContext(issuer, "2", expiresAt))
+
+		assert.True(t, errors.Is(err, expiring.ErrFull))
+	})
+}
+
+// expectOAuthEndpoint registers an OAuth endpoint with the given identifier for the custodian
+func expectOAuthEndpoint(ctx *testContext, identifier string) {
+	custodian, _ := core.ParsePartyID("urn:oid:2.16.840.1.113883.2.4.6.1:custodian")
+	endpointType := services.OAuthEndpointType
+	ctx.registryMock.EXPECT().EndpointsByOrganizationAndType(custodian, &endpointType).Return([]db.Endpoint{{Identifier: registryTypes.EndpointID(identifier)}}, nil)
+}
+
+func TestService_validateAudience(t *testing.T) {
+	custodian := "urn:oid:2.16.840.1.113883.2.4.6.1:custodian"
+	audienceContext := func(audience string) *validationContext {
+		return &validationContext{jwtBearerToken: &services.NutsJwtBearerToken{StandardClaims: jwt.StandardClaims{Subject: custodian, Audience: audience}}}
+	}
+
+	t.Run("ok - one of the endpoints", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.registryMock.EXPECT().EndpointsByOrganizationAndType(gomock.Any(), gomock.Any()).Return([]db.Endpoint{{Identifier: "a"}, {Identifier: "b"}}, nil)
+
+		assert.NoError(t, ctx.oauthService.validateAudience(audienceContext("b")))
+	})
+
+	t.Run("nok - other endpoint", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.registryMock.EXPECT().EndpointsByOrganizationAndType(gomock.Any(), gomock.Any()).Return([]db.Endpoint{{Identifier: "a"}}, nil)
+
+		err := ctx.oauthService.validateAudience(audienceContext("c"))
+
+		assert.True(t, errors.Is(err, services.ErrInvalidAudience))
+	})
+
+	t.Run("nok - missing aud", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.registryMock.EXPECT().EndpointsByOrganizationAndType(gomock.Any(), gomock.Any()).Return([]db.Endpoint{{Identifier: ""}}, nil)
+
+		err := ctx.oauthService.validateAudience(audienceContext(""))
+
+		assert.True(t, errors.Is(err, services.ErrInvalidAudience))
+	})
+
+	t.Run("nok - no endpoints", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.registryMock.EXPECT().EndpointsByOrganizationAndType(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+		err := ctx.oauthService.validateAudience(audienceContext("a"))
+
+		assert.True(t, errors.Is(err, services.ErrInvalidAudience))
+	})
+
+	t.Run("nok - registry error", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.registryMock.EXPECT().EndpointsByOrganizationAndType(gomock.Any(), gomock.Any()).Return(nil, errors.New("b00m!"))
+
+		err := ctx.oauthService.validateAudience(audienceContext("a"))
+
+		assert.EqualError(t, err, "unable to find the OAuth endpoints of the custodian: b00m!")
+	})
+
+	t.Run("ok - mismatch is accepted when the check is skipped", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.oauthService.config.SkipAudienceCheck = true
+		ctx.registryMock.EXPECT().EndpointsByOrganizationAndType(gomock.Any(), gomock.Any()).Return([]db.Endpoint{{Identifier: "a"}}, nil)
+
+		assert.NoError(t, ctx.oauthService.validateAudience(audienceContext("c")))
+	})
+}
+
+func TestService_validateSignedContext(t *testing.T) {
+	signedContext := func(sid *string, scope string, attributes map[string]string) *validationContext {
+		tokenCtx := validContext()
+		tokenCtx.jwtBearerToken.SubjectID = sid
+		tokenCtx.jwtBearerToken.Scope = scope
+		tokenCtx.contractVerificationResult = &contract.VPVerificationResult{Validity: contract.Valid, ContractAttributes: attributes}
+		return tokenCtx
+	}
+	patientContext := map[string]string{contract.SubjectAttr: "urn:oid:2.16.840.1.113883.2.4.6.3:999999990", contract.PurposeOfUseAttr: "TREAT"}
+
+	t.Run("ok - signed subject and purpose of use are used", func(t *testing.T) {
+		tokenCtx := signedContext(nil, "nuts-sso", patientContext)
+
+		err := (&service{}).validateSignedContext(tokenCtx)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		if assert.NotNil(t, tokenCtx.jwtBearerToken.SubjectID) {
+			assert.Equal(t, "urn:oid:2.16.840.1.113883.2.4.6.3:999999990", *tokenCtx.jwtBearerToken.SubjectID)
+		}
+		assert.Equal(t, "nuts-sso TREAT", tokenCtx.jwtBearerToken.Scope)
+	})
+
+	t.Run("ok - matching sid and scope", func(t *testing.T) {
+		sid := "urn:oid:2.16.840.1.113883.2.4.6.3:999999990"
+		tokenCtx := signedContext(&sid, "TREAT", patientContext)
+
+		err := (&service{}).validateSignedContext(tokenCtx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "TREAT", tokenCtx.jwtBearerToken.Scope)
+	})
+
+	t.Run("ok - login contract leaves the token as is", func(t *testing.T) {
+		sid := "subject"
+		tokenCtx := signedContext(&sid, "nuts-sso", map[string]string{contract.LegalEntityAttr: "CareBears"})
+
+		err := (&service{}).validateSignedContext(tokenCtx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "subject", *tokenCtx.jwtBearerToken.SubjectID)
+		assert.Equal(t, "nuts-sso", tokenCtx.jwtBearerToken.Scope)
+	})
+
+	t.Run("error - sid differs from signed subject", func(t *testing.T) {
+		sid := "urn:oid:2.16.840.1.113883.2.4.6.3:123456782"
+		tokenCtx := signedContext(&sid, "", patientContext)
+
+		err := (&service{}).validateSignedContext(tokenCtx)
+
+		assert.Equal(t, ErrSubjectMismatch, err)
+	})
 }
 
 func TestService_validateIssuer(t *testing.T) {
@@ -389,7 +895,7 @@ func TestOAuthService_buildAccessToken(t *testing.T) {
 		ctx := createContext(t)
 		defer ctx.ctrl.Finish()
 
-		ctx.cryptoMock.EXPECT().SignJWT(gomock.Any(), gomock.Any()).Return("expectedAT", nil)
+		expectSigningKey(ctx)
 
 		tokenCtx := &validationContext{
 			contractVerificationResult: &contract.VPVerificationResult{Validity: contract.Valid},
@@ -399,7 +905,25 @@ func TestOAuthService_buildAccessToken(t *testing.T) {
 		token, err := ctx.oauthService.buildAccessToken(tokenCtx)
 
 		assert.Nil(t, err)
-		assert.Equal(t, "expectedAT", token)
+		assert.Equal(t, organizationID.String(), tokenClaims(t, token)["iss"])
+	})
+
+	t.Run("access token has a jti and the contract hash", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectSigningKey(ctx)
+
+		tokenCtx := &validationContext{
+			contractVerificationResult: &contract.VPVerificationResult{Validity: contract.Valid, ContractHash: "hash"},
+			jwtBearerToken:             &services.NutsJwtBearerToken{StandardClaims: jwt.StandardClaims{Subject: organizationID.String()}},
+		}
+
+		token, err := ctx.oauthService.buildAccessToken(tokenCtx)
+
+		assert.NoError(t, err)
+		claims := tokenClaims(t, token)
+		assert.NotEmpty(t, claims["jti"])
+		assert.Equal(t, "hash", claims["contract_hash"])
 	})
 
 	// todo some extra tests needed for claims generation
@@ -472,6 +996,46 @@ func TestOAuthService_CreateJwtBearerToken(t *testing.T) {
 	})
 }
 
+// auditSink keeps the audit entries in memory
+type auditSink struct {
+	entries []audit.Entry
+}
+
+func (a *auditSink) Write(entry audit.Entry) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func (a *auditSink) Close() error {
+	return nil
+}
+
+func Test_accessTokenEntry(t *testing.T) {
+	context := validationContext{
+		jwtBearerToken:             &services.NutsJwtBearerToken{StandardClaims: jwt.StandardClaims{Issuer: "urn:oid:1.2.3:actor", Subject: "urn:oid:1.2.3:custodian"}},
+		contractVerificationResult: &contract.VPVerificationResult{VPType: "NutsIrmaPresentation", ContractHash: "hash"},
+	}
+
+	t.Run("ok - accepted", func(t *testing.T) {
+		entry := accessTokenEntry(context, nil)
+
+		assert.Equal(t, audit.EventAccessTokenCreated, entry.Event)
+		assert.Equal(t, audit.OutcomeAccepted, entry.Outcome)
+		assert.Equal(t, "urn:oid:1.2.3:actor", entry.LegalEntity)
+		assert.Equal(t, "urn:oid:1.2.3:custodian", entry.Custodian)
+		assert.Equal(t, "NutsIrmaPresentation", entry.Means)
+		assert.Equal(t, "hash", entry.ContractHash)
+	})
+
+	t.Run("nok - rejected", func(t *testing.T) {
+		entry := accessTokenEntry(validationContext{}, errors.New("b0rk"))
+
+		assert.Equal(t, audit.OutcomeRejected, entry.Outcome)
+		assert.Equal(t, "b0rk", entry.Reason)
+		assert.Empty(t, entry.LegalEntity)
+	})
+}
+
 func Test_claimsFromRequest(t *testing.T) {
 	ctx := createContext(t)
 	defer ctx.ctrl.Finish()
@@ -493,8 +1057,11 @@ func Test_claimsFromRequest(t *testing.T) {
 			timeFunc = time.Now
 		}()
 
-		claims := claimsFromRequest(request, audience)
+		claims, err := claimsFromRequest(request, audience)
 
+		if !assert.NoError(t, err) {
+			return
+		}
 		assert.Equal(t, audience, claims.Audience)
 		assert.Equal(t, int64(15), claims.ExpiresAt)
 		assert.Equal(t, int64(10), claims.IssuedAt)
@@ -503,6 +1070,9 @@ func Test_claimsFromRequest(t *testing.T) {
 		assert.Equal(t, request.Custodian, claims.Subject)
 		assert.Equal(t, request.IdentityToken, claims.UserIdentity)
 		assert.Equal(t, request.Subject, claims.SubjectID)
+		assert.Len(t, claims.Id, 32)
+		other, _ := claimsFromRequest(request, audience)
+		assert.NotEqual(t, claims.Id, other.Id)
 	})
 }
 
@@ -540,6 +1110,367 @@ func TestOAuthService_IntrospectAccessToken(t *testing.T) {
 		_, err := ctx.oauthService.IntrospectAccessToken(tokenCtx.rawJwtBearerToken)
 		assert.Error(t, err)
 	})
+
+	t.Run("revoked access token", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(oauthKeyEntity).AnyTimes().Return(true)
+		ctx.cryptoMock.EXPECT().GetPrivateKey(oauthKeyEntity).AnyTimes().Return(key, nil)
+		accessToken := signAccessToken(services.NutsAccessToken{StandardClaims: jwt.StandardClaims{Id: "1", ExpiresAt: time.Now().Add(time.Minute).Unix()}})
+		_, _ = ctx.oauthService.config.RevocationList.Add(revokedTokenPrefix+"1", time.Now().Add(time.Minute))
+
+		claims, err := ctx.oauthService.IntrospectAccessToken(accessToken)
+
+		assert.Nil(t, claims)
+		assert.True(t, errors.Is(err, services.ErrTokenRevoked))
+	})
+
+	t.Run("access token derived from a revoked contract", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(oauthKeyEntity).Return(true)
+		ctx.cryptoMock.EXPECT().GetPrivateKey(oauthKeyEntity).Return(key, nil)
+		accessToken := signAccessToken(services.NutsAccessToken{StandardClaims: jwt.StandardClaims{Id: "1", ExpiresAt: time.Now().Add(time.Minute).Unix()}, ContractHash: "hash"})
+		revokeContract(ctx, "hash")
+
+		claims, err := ctx.oauthService.IntrospectAccessToken(accessToken)
+
+		assert.Nil(t, claims)
+		assert.True(t, errors.Is(err, services.ErrTokenRevoked))
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		refreshToken := storeRefreshToken(ctx, services.NutsRefreshToken{
+			NutsAccessToken: services.NutsAccessToken{StandardClaims: jwt.StandardClaims{Id: "1", ExpiresAt: time.Now().Add(time.Minute).Unix()}},
+		})
+
+		claims, err := ctx.oauthService.IntrospectAccessToken(refreshToken)
+
+		assert.Nil(t, claims)
+		assert.Error(t, err)
+	})
+}
+
+func TestOAuthService_RevokeAccessToken(t *testing.T) {
+	const actor = "urn:oid:2.16.840.1.113883.2.4.6.1:actor"
+	expectKey := func(ctx *testContext) {
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(oauthKeyEntity).Return(true)
+		ctx.cryptoMock.EXPECT().GetPrivateKey(oauthKeyEntity).Return(key, nil)
+	}
+
+	t.Run("ok - revoked until the access token expires", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectKey(ctx)
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		ctx.registryMock.EXPECT().OrganizationById(registryTest.OrganizationID("actor")).Return(&db.Organization{Vendor: vendorID}, nil)
+		accessToken := signAccessToken(services.NutsAccessToken{StandardClaims: jwt.StandardClaims{Id: "1", Subject: actor, ExpiresAt: time.Now().Add(time.Minute).Unix()}})
+
+		err := ctx.oauthService.RevokeAccessToken(services.RevokeTokenRequest{Token: accessToken, ClientCert: clientCert(t)})
+
+		assert.NoError(t, err)
+		revoked, _ := ctx.oauthService.config.RevocationList.Contains(revokedTokenPrefix + "1")
+		assert.True(t, revoked)
+	})
+
+	t.Run("ok - invalid access token is ignored", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+
+		assert.NoError(t, ctx.oauthService.RevokeAccessToken(services.RevokeTokenRequest{Token: "foo", ClientCert: clientCert(t)}))
+	})
+
+	t.Run("ok - refresh token is removed", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		certificate, _ := x509.ParseCertificate(certBytes)
+		refreshToken := storeRefreshToken(ctx, services.NutsRefreshToken{
+			NutsAccessToken:       services.NutsAccessToken{StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()}},
+			CertificateThumbprint: certificateThumbprint(certificate),
+		})
+
+		err := ctx.oauthService.RevokeAccessToken(services.RevokeTokenRequest{Token: refreshToken, ClientCert: clientCert(t)})
+
+		assert.NoError(t, err)
+		data, _ := ctx.oauthService.config.RefreshTokenStore.Get(refreshTokenKey(refreshToken))
+		assert.Nil(t, data)
+	})
+
+	t.Run("error - refresh token is bound to another client certificate", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		refreshToken := storeRefreshToken(ctx, services.NutsRefreshToken{
+			NutsAccessToken:       services.NutsAccessToken{StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()}},
+			CertificateThumbprint: "other",
+		})
+
+		err := ctx.oauthService.RevokeAccessToken(services.RevokeTokenRequest{Token: refreshToken, ClientCert: clientCert(t)})
+
+		assert.True(t, errors.Is(err, services.ErrUnauthorizedClient))
+		data, _ := ctx.oauthService.config.RefreshTokenStore.Get(refreshTokenKey(refreshToken))
+		assert.NotNil(t, data)
+	})
+
+	t.Run("error - missing client certificate", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		err := ctx.oauthService.RevokeAccessToken(services.RevokeTokenRequest{Token: "foo"})
+
+		assert.True(t, errors.Is(err, services.ErrInvalidClient))
+	})
+
+	t.Run("error - untrusted client certificate", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{err: errors.New("expired")})
+
+		err := ctx.oauthService.RevokeAccessToken(services.RevokeTokenRequest{Token: "foo", ClientCert: clientCert(t)})
+
+		assert.True(t, errors.Is(err, services.ErrInvalidClient))
+	})
+
+	t.Run("error - access token is issued to another vendor", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectKey(ctx)
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		otherVendor, _ := core.ParsePartyID("urn:oid:1.3.6.1.4.1.54851.4:otherVendor")
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Return(&db.Organization{Vendor: otherVendor}, nil)
+		accessToken := signAccessToken(services.NutsAccessToken{StandardClaims: jwt.StandardClaims{Id: "1", Subject: actor, ExpiresAt: time.Now().Add(time.Minute).Unix()}})
+
+		err := ctx.oauthService.RevokeAccessToken(services.RevokeTokenRequest{Token: accessToken, ClientCert: clientCert(t)})
+
+		assert.True(t, errors.Is(err, services.ErrUnauthorizedClient))
+		revoked, _ := ctx.oauthService.config.RevocationList.Contains(revokedTokenPrefix + "1")
+		assert.False(t, revoked)
+	})
+
+	t.Run("error - access token without jti", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectKey(ctx)
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		accessToken := signAccessToken(services.NutsAccessToken{StandardClaims: jwt.StandardClaims{Subject: actor, ExpiresAt: time.Now().Add(time.Minute).Unix()}})
+
+		assert.Equal(t, errMissingJwtID, ctx.oauthService.RevokeAccessToken(services.RevokeTokenRequest{Token: accessToken, ClientCert: clientCert(t)}))
+	})
+}
+
+func TestOAuthService_RevokeContract(t *testing.T) {
+	const actor = "urn:oid:2.16.840.1.113883.2.4.6.1:actor"
+	issueContract := func(ctx *testContext, validTo time.Time) {
+		context := validContext()
+		context.contractVerificationResult = &contract.VPVerificationResult{ContractHash: "hash", ValidTo: validTo}
+		_ = ctx.oauthService.rememberContract(context)
+	}
+	revokedUntil := func(ctx *testContext) time.Time {
+		return ctx.oauthService.config.RevocationList.(*expiryRecorder).expiresAt[revokedContractPrefix+"hash"]
+	}
+	createContext := func(t *testing.T) *testContext {
+		ctx := createContext(t)
+		ctx.oauthService.config.RevocationList = &expiryRecorder{Set: ctx.oauthService.config.RevocationList, expiresAt: map[string]time.Time{}}
+		return ctx
+	}
+
+	t.Run("ok - revoked until the contract expires", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		ctx.registryMock.EXPECT().OrganizationById(registryTest.OrganizationID("actor")).Return(&db.Organization{Vendor: vendorID}, nil)
+		validTo := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+		issueContract(ctx, validTo)
+
+		err := ctx.oauthService.RevokeContract(services.RevokeContractRequest{ContractHash: "hash", ClientCert: clientCert(t)})
+
+		assert.NoError(t, err)
+		assert.True(t, errors.Is(ctx.oauthService.checkContractRevocation("hash"), services.ErrTokenRevoked))
+		assert.NoError(t, ctx.oauthService.checkContractRevocation("other"))
+		assert.True(t, validTo.Equal(revokedUntil(ctx)))
+	})
+
+	t.Run("ok - expiring contract is revoked until its access tokens expire", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Return(&db.Organization{Vendor: vendorID}, nil)
+		issueContract(ctx, time.Now())
+
+		err := ctx.oauthService.RevokeContract(services.RevokeContractRequest{ContractHash: "hash", ClientCert: clientCert(t)})
+
+		assert.NoError(t, err)
+		assert.False(t, revokedUntil(ctx).Before(time.Now().Add(accessTokenValidity-time.Second)))
+	})
+
+	t.Run("error - missing contract hash", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		assert.Error(t, ctx.oauthService.RevokeContract(services.RevokeContractRequest{ClientCert: clientCert(t)}))
+	})
+
+	t.Run("error - missing client certificate", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		issueContract(ctx, time.Now().Add(time.Hour))
+
+		err := ctx.oauthService.RevokeContract(services.RevokeContractRequest{ContractHash: "hash"})
+
+		assert.True(t, errors.Is(err, services.ErrInvalidClient))
+		assert.NoError(t, ctx.oauthService.checkContractRevocation("hash"))
+	})
+
+	t.Run("error - unknown contract", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+
+		err := ctx.oauthService.RevokeContract(services.RevokeContractRequest{ContractHash: "hash", ClientCert: clientCert(t)})
+
+		assert.True(t, errors.Is(err, services.ErrUnknownContract))
+		assert.NoError(t, ctx.oauthService.checkContractRevocation("hash"))
+	})
+
+	t.Run("error - client certificate of another vendor", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().TrustStore().Return(testTrustStore{ca: vendorCA(t)})
+		otherVendor, _ := core.ParsePartyID("urn:oid:1.3.6.1.4.1.54851.4:otherVendor")
+		ctx.registryMock.EXPECT().OrganizationById(gomock.Any()).Return(&db.Organization{Vendor: otherVendor}, nil)
+		issueContract(ctx, time.Now().Add(time.Hour))
+
+		err := ctx.oauthService.RevokeContract(services.RevokeContractRequest{ContractHash: "hash", ClientCert: clientCert(t)})
+
+		assert.True(t, errors.Is(err, services.ErrUnauthorizedClient))
+		assert.NoError(t, ctx.oauthService.checkContractRevocation("hash"))
+	})
+
+	t.Run("ok - access token request remembers the contract and its actor", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		validTo := time.Now().Add(time.Hour)
+		issueContract(ctx, validTo)
+
+		data, _ := ctx.oauthService.config.IssuedContracts.Get("hash")
+		record := issuedContract{}
+		if !assert.NoError(t, json.Unmarshal(data, &record)) {
+			return
+		}
+		assert.Equal(t, actor, record.Actor)
+		assert.True(t, validTo.Equal(record.ValidTo))
+	})
+}
+
+// expiryRecorder records until when the keys are added to the set
+type expiryRecorder struct {
+	expiring.Set
+	expiresAt map[string]time.Time
+}
+
+func (e *expiryRecorder) Add(key string, expiresAt time.Time) (bool, error) {
+	e.expiresAt[key] = expiresAt
+	return e.Set.Add(key, expiresAt)
+}
+
+// revokeContract puts the contract on the revocation list like RevokeContract does
+func revokeContract(ctx *testContext, contractHash string) {
+	_, _ = ctx.oauthService.config.RevocationList.Add(revokedContractPrefix+contractHash, time.Now().Add(time.Hour))
+}
+
+// signAccessToken signs the claims like the access tokens of this node
+func signAccessToken(claims services.NutsAccessToken) string {
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(key)
+	return token
+}
+
+func TestOAuthService_SigningKey(t *testing.T) {
+	t.Run("ok - the kid of the issued tokens identifies the key", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		expectSigningKey(ctx)
+
+		publicKey, err := ctx.oauthService.SigningKey()
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "ES256", publicKey.Algorithm())
+		assert.Equal(t, "sig", publicKey.KeyUsage())
+		assert.NotEmpty(t, publicKey.KeyID())
+		var raw ecdsa.PublicKey
+		if assert.NoError(t, publicKey.Raw(&raw)) {
+			assert.Equal(t, key.PublicKey, raw)
+		}
+
+		token, err := ctx.oauthService.signToken(services.NutsAccessToken{StandardClaims: jwt.StandardClaims{Id: "1"}})
+		if !assert.NoError(t, err) {
+			return
+		}
+		parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, publicKey.KeyID(), parsed.Header["kid"])
+		}
+	})
+
+	t.Run("error - missing key", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.cryptoMock.EXPECT().GetPrivateKey(oauthKeyEntity).Return(nil, errors.New("not found"))
+
+		publicKey, err := ctx.oauthService.SigningKey()
+
+		assert.Nil(t, publicKey)
+		assert.EqualError(t, err, "not found")
+	})
+}
+
+func Test_signingAlgorithm(t *testing.T) {
+	t.Run("ok - RSA", func(t *testing.T) {
+		rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+		algorithm, err := signingAlgorithm(rsaKey.Public())
+		assert.NoError(t, err)
+		assert.Equal(t, jwa.PS256, algorithm)
+	})
+
+	t.Run("ok - EC P-384", func(t *testing.T) {
+		ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		algorithm, err := signingAlgorithm(ecKey.Public())
+		assert.NoError(t, err)
+		assert.Equal(t, jwa.ES384, algorithm)
+	})
+
+	t.Run("error - unsupported key", func(t *testing.T) {
+		_, err := signingAlgorithm([]byte("secret"))
+		assert.Error(t, err)
+	})
+}
+
+// expectSigningKey lets the service sign tokens with the test key
+func expectSigningKey(ctx *testContext) {
+	ctx.cryptoMock.EXPECT().GetPrivateKey(oauthKeyEntity).AnyTimes().Return(key, nil)
+}
+
+// tokenClaims returns the claims of a token signed with the test key
+func tokenClaims(t *testing.T, token string) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(_ *jwt.Token) (interface{}, error) {
+		return key.Public(), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
+
+// storeRefreshToken stores the refresh token like this node does and returns its handle
+func storeRefreshToken(ctx *testContext, claims services.NutsRefreshToken) string {
+	const refreshToken = "refresh-token-handle"
+	data, _ := json.Marshal(claims)
+	_ = ctx.oauthService.config.RefreshTokenStore.Put(refreshTokenKey(refreshToken), data, time.Unix(claims.ExpiresAt, 0))
+	return refreshToken
 }
 
 func TestAuth_Configure(t *testing.T) {
@@ -659,6 +1590,12 @@ var createContext = func(t *testing.T) *testContext {
 			oauthKeyEntity: oauthKeyEntity,
 			consent:        consentMock,
 			contractClient: contractClientMock,
+			config: Config{
+				ReplayCache:       expiring.NewMemorySet(DefaultReplayCacheSize),
+				RevocationList:    expiring.NewMemorySet(DefaultRevocationListSize),
+				RefreshTokenStore: expiring.NewMemoryMap(DefaultRefreshTokenStoreSize),
+				IssuedContracts:   expiring.NewMemoryMap(DefaultRevocationListSize),
+			},
 		},
 	}
 }
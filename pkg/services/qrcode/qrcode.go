@@ -0,0 +1,115 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package qrcode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+
+	"rsc.io/qr"
+)
+
+// FormatPNG renders the QR code as PNG image
+const FormatPNG = "png"
+
+// FormatSVG renders the QR code as SVG image
+const FormatSVG = "svg"
+
+// DefaultSize is the width and height in pixels of a QR code when no size is requested
+const DefaultSize = 256
+
+// MaxSize is the largest width and height in pixels of a QR code
+const MaxSize = 2048
+
+// quietZone is the number of blank modules around the QR code, as required by the QR specification
+const quietZone = 4
+
+// ErrUnsupportedFormat is returned when a QR code is requested in a format other than FormatPNG or FormatSVG
+var ErrUnsupportedFormat = errors.New("unsupported QR code format")
+
+// ErrInvalidSize is returned when the requested size is not between 1 and MaxSize
+var ErrInvalidSize = errors.New("invalid QR code size")
+
+// Render encodes the payload as QR code image in the given format and returns the image and its content type.
+// The size is the width and height of the image in pixels, including the quiet zone. A PNG image uses a whole number
+// of pixels per module, so it is rounded down to the nearest fit, with a minimum of one pixel per module.
+func Render(payload []byte, format string, size int) ([]byte, string, error) {
+	if size < 1 || size > MaxSize {
+		return nil, "", fmt.Errorf("%w: %d, it must be between 1 and %d", ErrInvalidSize, size, MaxSize)
+	}
+	if format != FormatPNG && format != FormatSVG {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	code, err := qr.Encode(string(payload), qr.M)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to encode QR code: %w", err)
+	}
+	if format == FormatSVG {
+		return svg(code, size), "image/svg+xml", nil
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, bitmap(code, size)); err != nil {
+		return nil, "", fmt.Errorf("unable to encode QR code: %w", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// bitmap draws the code with the largest whole number of pixels per module which fits in the size.
+// The image functions of the qr package are not used, they ignore the scale and quiet zone.
+func bitmap(code *qr.Code, size int) image.Image {
+	modules := code.Size + 2*quietZone
+	scale := size / modules
+	if scale < 1 {
+		scale = 1
+	}
+	img := image.NewGray(image.Rect(0, 0, modules*scale, modules*scale))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				left, top := (x+quietZone)*scale, (y+quietZone)*scale
+				draw.Draw(img, image.Rect(left, top, left+scale, top+scale), image.Black, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return img
+}
+
+// svg draws the modules of the code as a single path, scaled to the size by the viewBox
+func svg(code *qr.Code, size int) []byte {
+	modules := code.Size + 2*quietZone
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, modules, modules)
+	fmt.Fprintf(buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, modules, modules)
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if code.Black(x, y) {
+				fmt.Fprintf(buf, "M%d %dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
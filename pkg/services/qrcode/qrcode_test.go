@@ -0,0 +1,111 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package qrcode
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var payload = []byte(`{"u":"https://nuts.example.com/auth/irmaclient/123","irmaqr":"signing"}`)
+
+func TestRender(t *testing.T) {
+	t.Run("ok - png", func(t *testing.T) {
+		data, contentType, err := Render(payload, FormatPNG, 256)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "image/png", contentType)
+		img, err := png.Decode(bytes.NewReader(data))
+		if !assert.NoError(t, err) {
+			return
+		}
+		width := img.Bounds().Dx()
+		assert.Equal(t, width, img.Bounds().Dy())
+		assert.True(t, width <= 256 && width > 128, "width %d", width)
+		r, _, _, _ := img.At(0, 0).RGBA()
+		assert.Equal(t, uint32(0xffff), r, "quiet zone is white")
+		// the finder pattern in the top left corner starts after a quiet zone of 4 modules
+		corner := 0
+		for r != 0 {
+			corner++
+			r, _, _, _ = img.At(corner, corner).RGBA()
+		}
+		assert.Equal(t, 0, corner%4)
+		assert.Equal(t, 0, width%(corner/4), "modules are a whole number of pixels")
+	})
+
+	t.Run("ok - png is at least one pixel per module", func(t *testing.T) {
+		data, _, err := Render(payload, FormatPNG, 1)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		img, _ := png.Decode(bytes.NewReader(data))
+		assert.True(t, img.Bounds().Dx() > 1)
+	})
+
+	t.Run("ok - svg", func(t *testing.T) {
+		data, contentType, err := Render(payload, FormatSVG, 300)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "image/svg+xml", contentType)
+		image := struct {
+			XMLName xml.Name
+			Width   string `xml:"width,attr"`
+			Path    struct {
+				D string `xml:"d,attr"`
+			} `xml:"path"`
+		}{}
+		if !assert.NoError(t, xml.Unmarshal(data, &image)) {
+			return
+		}
+		assert.Equal(t, "svg", image.XMLName.Local)
+		assert.Equal(t, "300", image.Width)
+		assert.Contains(t, image.Path.D, "M4 4h1v1h-1z", "finder pattern starts after the quiet zone")
+	})
+
+	t.Run("error - unsupported format", func(t *testing.T) {
+		_, _, err := Render(payload, "gif", 256)
+
+		assert.True(t, errors.Is(err, ErrUnsupportedFormat))
+	})
+
+	t.Run("error - invalid size", func(t *testing.T) {
+		for _, size := range []int{0, -1, MaxSize + 1} {
+			_, _, err := Render(payload, FormatPNG, size)
+
+			assert.True(t, errors.Is(err, ErrInvalidSize), size)
+		}
+	})
+
+	t.Run("error - payload too long", func(t *testing.T) {
+		_, _, err := Render(make([]byte, 4000), FormatPNG, 256)
+
+		assert.Error(t, err)
+	})
+}
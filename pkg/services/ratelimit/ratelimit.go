@@ -0,0 +1,178 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ByCertificate limits the clients by the fingerprint of their client certificate
+const ByCertificate = "certificate"
+
+// ByLegalEntity limits the clients by the legal entity in the contract they request to be signed
+const ByLegalEntity = "legalEntity"
+
+// ClientCertHeader is the header in which a TLS terminating proxy passes the URL encoded PEM client certificate
+const ClientCertHeader = "X-Ssl-Client-Cert"
+
+// sweepInterval is the minimal time between two removals of full buckets
+const sweepInterval = time.Minute
+
+// Limiter is a token bucket rate limiter with a bucket per key. Every bucket holds at most burst tokens and is refilled
+// with rate tokens per second. A Limiter is safe for concurrent use.
+type Limiter struct {
+	rate      float64
+	burst     float64
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewLimiter creates a Limiter which allows perMinute requests per minute per key, with bursts of at most burst requests.
+// The perMinute must be positive, a burst smaller than 1 is raised to 1.
+func NewLimiter(perMinute int, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the key. When the bucket is empty, it returns false and the time after which
+// a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, l.rate, l.burst)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / l.rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+func (b *bucket) refill(now time.Time, rate float64, burst float64) {
+	elapsed := now.Sub(b.updated).Seconds()
+	b.updated = now
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens = math.Min(burst, b.tokens+elapsed*rate)
+}
+
+// sweep removes the buckets which are full, they behave the same as new buckets. The caller must hold the lock.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		b.refill(now, l.rate, l.burst)
+		if b.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// ClientCertificate identifies the client of the request by the SHA-256 fingerprint of its certificate, taken like
+// ClientCertificatePEM does. It returns an empty string when the client has no certificate.
+func ClientCertificate(request *http.Request, trustProxy bool) string {
+	cert := ClientCertificatePEM(request, trustProxy)
+	if cert == "" {
+		return ""
+	}
+	if block, _ := pem.Decode([]byte(cert)); block != nil {
+		return fingerprint(block.Bytes)
+	}
+	return fingerprint([]byte(cert))
+}
+
+// ClientCertificatePEM returns the PEM encoded certificate of the client of the request. The certificate is taken from
+// the ClientCertHeader, but only when trustProxy is set because the requests are received from a TLS terminating proxy
+// which sets the header, or else from the TLS connection. Without trustProxy anyone could set the header.
+// It returns an empty string when the client has no certificate.
+func ClientCertificatePEM(request *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if header := request.Header.Get(ClientCertHeader); header != "" {
+			cert, err := url.PathUnescape(header)
+			if err != nil {
+				cert = header
+			}
+			return cert
+		}
+	}
+	if request.TLS != nil && len(request.TLS.PeerCertificates) > 0 {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: request.TLS.PeerCertificates[0].Raw}))
+	}
+	return ""
+}
+
+// ClientID identifies the client of the request for the rate limit: by its ClientCertificate or, when the client has
+// no certificate, by its remote address.
+func ClientID(request *http.Request, trustProxy bool) string {
+	if id := ClientCertificate(request, trustProxy); id != "" {
+		return id
+	}
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		host = request.RemoteAddr
+	}
+	return "address:" + host
+}
+
+func fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RetryAfter formats the duration as the value of a Retry-After header: the number of seconds, rounded up
+func RetryAfter(duration time.Duration) string {
+	seconds := int64(math.Ceil(duration.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
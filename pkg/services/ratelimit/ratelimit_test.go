@@ -0,0 +1,209 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package ratelimit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	newLimiter := func() *Limiter {
+		limiter := NewLimiter(6, 2)
+		limiter.now = func() time.Time {
+			return now
+		}
+		return limiter
+	}
+
+	t.Run("ok - burst is allowed", func(t *testing.T) {
+		limiter := newLimiter()
+
+		ok1, _ := limiter.Allow("a")
+		ok2, _ := limiter.Allow("a")
+
+		assert.True(t, ok1)
+		assert.True(t, ok2)
+	})
+
+	t.Run("nok - bucket is empty", func(t *testing.T) {
+		limiter := newLimiter()
+		limiter.Allow("a")
+		limiter.Allow("a")
+
+		ok, retryAfter := limiter.Allow("a")
+
+		assert.False(t, ok)
+		assert.Equal(t, 10*time.Second, retryAfter)
+	})
+
+	t.Run("ok - keys have their own bucket", func(t *testing.T) {
+		limiter := newLimiter()
+		limiter.Allow("a")
+		limiter.Allow("a")
+
+		ok, _ := limiter.Allow("b")
+
+		assert.True(t, ok)
+	})
+
+	t.Run("ok - bucket is refilled", func(t *testing.T) {
+		limiter := newLimiter()
+		limiter.Allow("a")
+		limiter.Allow("a")
+		limiter.now = func() time.Time {
+			return now.Add(10 * time.Second)
+		}
+
+		ok1, _ := limiter.Allow("a")
+		ok2, retryAfter := limiter.Allow("a")
+
+		assert.True(t, ok1)
+		assert.False(t, ok2)
+		assert.Equal(t, 10*time.Second, retryAfter)
+	})
+
+	t.Run("ok - full buckets are swept", func(t *testing.T) {
+		limiter := newLimiter()
+		limiter.Allow("a")
+		limiter.now = func() time.Time {
+			return now.Add(time.Hour)
+		}
+
+		limiter.Allow("b")
+
+		assert.Len(t, limiter.buckets, 1)
+		assert.Contains(t, limiter.buckets, "b")
+	})
+
+	t.Run("ok - burst is at least 1", func(t *testing.T) {
+		limiter := NewLimiter(1, 0)
+
+		ok, _ := limiter.Allow("a")
+
+		assert.True(t, ok)
+	})
+}
+
+func TestClientCertificate(t *testing.T) {
+	const certPEM = "-----BEGIN CERTIFICATE-----\nY2VydGlmaWNhdGU=\n-----END CERTIFICATE-----\n"
+	const certFingerprint = "03d66dd08835c1ca3f128cceacd1f31ac94163096b20f445ae84285bc0832d72"
+
+	t.Run("ok - certificate from header of trusted proxy", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.Header.Set(ClientCertHeader, url.PathEscape(certPEM))
+
+		id := ClientCertificate(request, true)
+
+		assert.Equal(t, certFingerprint, id)
+	})
+
+	t.Run("ok - same certificate gives same ID", func(t *testing.T) {
+		escaped := httptest.NewRequest(http.MethodPost, "/", nil)
+		escaped.Header.Set(ClientCertHeader, url.PathEscape(certPEM))
+		plain := httptest.NewRequest(http.MethodPost, "/", nil)
+		plain.Header.Set(ClientCertHeader, certPEM)
+
+		assert.Equal(t, ClientCertificate(escaped, true), ClientCertificate(plain, true))
+	})
+
+	t.Run("ok - header is ignored without trusted proxy", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.Header.Set(ClientCertHeader, url.PathEscape(certPEM))
+
+		assert.Empty(t, ClientCertificate(request, false))
+	})
+
+	t.Run("ok - certificate from TLS connection", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Raw: []byte("certificate")}}}
+
+		id := ClientCertificate(request, false)
+
+		assert.Equal(t, certFingerprint, id)
+	})
+
+	t.Run("ok - no certificate", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+
+		assert.Empty(t, ClientCertificate(request, false))
+	})
+}
+
+func TestClientCertificatePEM(t *testing.T) {
+	const certPEM = "-----BEGIN CERTIFICATE-----\nY2VydGlmaWNhdGU=\n-----END CERTIFICATE-----\n"
+
+	t.Run("ok - certificate from header of trusted proxy", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.Header.Set(ClientCertHeader, url.PathEscape(certPEM))
+
+		assert.Equal(t, certPEM, ClientCertificatePEM(request, true))
+	})
+
+	t.Run("ok - certificate from TLS connection", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.Header.Set(ClientCertHeader, "spoofed")
+		request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Raw: []byte("certificate")}}}
+
+		assert.Equal(t, certPEM, ClientCertificatePEM(request, false))
+	})
+
+	t.Run("ok - no certificate", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+
+		assert.Empty(t, ClientCertificatePEM(request, false))
+	})
+}
+
+func TestClientID(t *testing.T) {
+	t.Run("ok - certificate", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Raw: []byte("certificate")}}}
+
+		assert.Equal(t, ClientCertificate(request, false), ClientID(request, false))
+	})
+
+	t.Run("ok - remote address without certificate", func(t *testing.T) {
+		first := httptest.NewRequest(http.MethodPost, "/", nil)
+		first.RemoteAddr = "192.0.2.1:1234"
+		samePort := httptest.NewRequest(http.MethodPost, "/", nil)
+		samePort.RemoteAddr = "192.0.2.1:5678"
+		other := httptest.NewRequest(http.MethodPost, "/", nil)
+		other.RemoteAddr = "192.0.2.2:1234"
+
+		assert.Equal(t, "address:192.0.2.1", ClientID(first, false))
+		assert.Equal(t, ClientID(first, false), ClientID(samePort, false))
+		assert.NotEqual(t, ClientID(first, false), ClientID(other, false))
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, "1", RetryAfter(0))
+	assert.Equal(t, "1", RetryAfter(100*time.Millisecond))
+	assert.Equal(t, "10", RetryAfter(10*time.Second))
+	assert.Equal(t, "11", RetryAfter(10*time.Second+time.Millisecond))
+}
@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/lestrrat-go/jwx/jwk"
 	core "github.com/nuts-foundation/nuts-go-core"
 	irma "github.com/privacybydesign/irmago"
 	"github.com/privacybydesign/irmago/server"
@@ -51,6 +52,16 @@ type OAuthClient interface {
 	CreateAccessToken(request CreateAccessTokenRequest) (*AccessTokenResult, error)
 	CreateJwtBearerToken(request CreateJwtBearerTokenRequest) (*JwtBearerTokenResult, error)
 	IntrospectAccessToken(token string) (*NutsAccessToken, error)
+	// RefreshAccessToken creates a new access token for a refresh token issued with an earlier access token
+	RefreshAccessToken(request RefreshAccessTokenRequest) (*AccessTokenResult, error)
+	// RevokeAccessToken revokes the access or refresh token for the client it was issued to, invalid tokens are ignored
+	RevokeAccessToken(request RevokeTokenRequest) error
+	// RevokeContract revokes the access tokens derived from the signed contract for the actor the contract belongs to
+	RevokeContract(request RevokeContractRequest) error
+	// SigningKey returns the public key the access tokens are signed with as JWK, with the kid of the access tokens
+	SigningKey() (jwk.Key, error)
+	// RefreshTokensEnabled returns true when refresh tokens are issued with the access tokens
+	RefreshTokensEnabled() bool
 	Configure() error
 }
 
@@ -83,14 +94,17 @@ type VPProofValueParser interface {
 // ContractNotary defines the interface to draw up a contract.
 type ContractNotary interface {
 	// DrawUpContract draws up a contract from a template and returns a Contract which than can be signed by the user.
-	DrawUpContract(template contract.Template, orgID core.PartyID, validFrom time.Time, validDuration time.Duration) (*contract.Contract, error)
+	// The attributes contain the values of template attributes which are not filled in by the notary itself, like the subject, it may be nil.
+	DrawUpContract(template contract.Template, orgID core.PartyID, validFrom time.Time, validDuration time.Duration, attributes map[string]string) (*contract.Contract, error)
 	// ValidateContract checks if the contract is syntactically correct and valid at the given moment in time. It does not check the signature.
 	ValidateContract(contractToValidate contract.Contract, orgID core.PartyID, checkTime time.Time) (bool, error)
 }
 
 // ContractClient defines functions for creating and validating verifiable credentials
 type ContractClient interface {
-	contract.VPVerifier
+	// VerifyVP validates a verifiable presentation like the contract.VPVerifier does. When an expectedContractHash
+	// is given, the presentation is only valid if the signed contract has this contract.Contract Hash.
+	VerifyVP(rawVerifiablePresentation []byte, checkTime *time.Time, expectedContractHash *string) (*contract.VPVerificationResult, error)
 
 	// CreateSigningSession creates a signing session for the requested contract and means
 	CreateSigningSession(sessionRequest CreateSessionRequest) (contract.SessionPointer, error)
@@ -98,6 +112,26 @@ type ContractClient interface {
 	// SigningSessionStatus returns the status of the current signing session or ErrSessionNotFound is sessionID is unknown
 	SigningSessionStatus(sessionID string) (contract.SigningSessionResult, error)
 
+	// CancelSigningSession cancels the signing session or returns ErrSessionNotFound if sessionID is unknown
+	CancelSigningSession(sessionID string) error
+
+	// SubscribeSigningSession registers a listener for the status transitions of the signing session or returns ErrSessionNotFound
+	// if sessionID is unknown. The returned func removes the listener.
+	SubscribeSigningSession(sessionID string, listener contract.SessionListener) (unsubscribe func(), err error)
+
+	// AuthorizeSigningSession checks that the signing session was requested by the caller, the fingerprint of its verified
+	// certificate. It returns ErrSessionNotFound when sessionID is unknown, belongs to another caller or is not bound to
+	// a caller, or when caller is empty, so callers can not discover the sessions of others.
+	AuthorizeSigningSession(sessionID string, caller string) error
+
+	// SigningSessionPointer returns the payload of the session pointer of the signing session, which is rendered as
+	// QR code for the user to scan. It returns ErrSessionNotFound if sessionID is unknown.
+	SigningSessionPointer(sessionID string) ([]byte, error)
+
+	// SigningSessionCallbacks returns the delivery log of the callback of the signing session or ErrSessionNotFound
+	// if this node has no callback for sessionID
+	SigningSessionCallbacks(sessionID string) ([]CallbackDelivery, error)
+
 	Configure() error
 
 	// deprecated
@@ -0,0 +1,112 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+)
+
+// SessionExpired is the state of a signing session which was not finished within the session TTL
+const SessionExpired = string(contract.SessionExpired)
+
+// SessionCancelled is the state of a signing session which was cancelled
+const SessionCancelled = string(contract.SessionCancelled)
+
+// SigningSession contains the metadata of a contract signing session. It is shared by all signing means, so every node
+// which uses the same SessionStore gives the same answers about a session.
+type SigningSession struct {
+	// ID is the session identifier as generated by the signing means
+	ID string `json:"id"`
+	// Means is the signing means which handles the session
+	Means contract.SigningMeans `json:"means"`
+	// Contract is the raw contract text which is signed
+	Contract string `json:"contract"`
+	// LegalEntity is the name of the legal entity as it appears in the contract, it is empty when the contract could not be parsed
+	LegalEntity string `json:"legalEntity,omitempty"`
+	// LegalEntityID identifies the legal entity on whose behalf the session was requested
+	LegalEntityID string `json:"legalEntityID,omitempty"`
+	// Caller is the fingerprint of the certificate of the client which requested the session, only this client can
+	// retrieve the session. It is empty when the client had no certificate.
+	Caller string `json:"caller,omitempty"`
+	// CreatedAt is the moment the session was started
+	CreatedAt time.Time `json:"createdAt"`
+	// Pointer is the payload of the session pointer which the user scans to sign the contract
+	Pointer string `json:"pointer,omitempty"`
+	// Params are the means specific parameters the session was started with
+	Params map[string]interface{} `json:"params,omitempty"`
+	// State is the means specific state of the session
+	State string `json:"state"`
+	// Result contains the means specific result of a finished session
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// Terminated returns true when the session was expired or cancelled. A terminated session keeps its state,
+// regardless of what happens in the signing means.
+func (s SigningSession) Terminated() bool {
+	return s.State == SessionExpired || s.State == SessionCancelled
+}
+
+// Finished returns true when the signing means reported the result of the session or when the session is terminated
+func (s SigningSession) Finished() bool {
+	return len(s.Result) > 0 || s.Terminated()
+}
+
+// SessionCallback is a webhook which receives the final status of a signing session
+type SessionCallback struct {
+	// URL is the absolute http(s) URL the final status is posted to
+	URL string
+	// Secret is shared with the receiver of the callback, it is used to sign the request
+	Secret string
+}
+
+// CallbackDelivery is an attempt to deliver the callback of a signing session
+type CallbackDelivery struct {
+	// Attempt is the number of the attempt, starting at 1
+	Attempt int `json:"attempt"`
+	// Time is the moment of the attempt
+	Time time.Time `json:"time"`
+	// Status is the session status which was delivered
+	Status string `json:"status"`
+	// StatusCode is the HTTP status code returned by the receiver, it is 0 when no response was received
+	StatusCode int `json:"statusCode,omitempty"`
+	// Error is the reason why the attempt failed
+	Error string `json:"error,omitempty"`
+	// Delivered is true when the receiver accepted the callback
+	Delivered bool `json:"delivered"`
+}
+
+// SessionStore stores the metadata of signing sessions
+type SessionStore interface {
+	// Put creates or replaces the session
+	Put(session SigningSession) error
+	// Get returns the session with the given ID or ErrSessionNotFound
+	Get(sessionID string) (*SigningSession, error)
+	// Update changes the session with the given ID in a single transaction. It returns ErrSessionNotFound when the session
+	// does not exist. When the update func returns an error, the session is not changed.
+	Update(sessionID string, update func(session *SigningSession) error) error
+	// Delete removes the session, it does nothing when the session does not exist
+	Delete(sessionID string) error
+	// List returns all sessions
+	List() ([]SigningSession, error)
+	// Close releases the resources of the store
+	Close() error
+}
@@ -0,0 +1,134 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/boltdb"
+)
+
+var sessionsBucket = []byte("sessions")
+
+// bboltStore keeps the sessions in a bbolt file. The file is opened when the store is created and locked until it is closed,
+// unless it is shared with other nodes.
+type bboltStore struct {
+	db *boltdb.DB
+}
+
+// NewBboltStore returns a SessionStore which persists the sessions in the bbolt file at the given path.
+// The file is created when it does not exist. A shared file is only locked for the duration of a transaction,
+// so nodes behind a load balancer can share it on a volume and give the same answers about a session.
+func NewBboltStore(path string, shared bool) (services.SessionStore, error) {
+	open := boltdb.Open
+	if shared {
+		open = boltdb.OpenShared
+	}
+	db, err := open(path, sessionsBucket)
+	if err != nil {
+		return nil, fmt.Errorf("could not open session store: %w", err)
+	}
+	return bboltStore{db: db}, nil
+}
+
+func (b bboltStore) Put(session services.SigningSession) error {
+	return b.update(func(bucket *bbolt.Bucket) error {
+		return putSession(bucket, session)
+	})
+}
+
+func (b bboltStore) Get(sessionID string) (*services.SigningSession, error) {
+	var session *services.SigningSession
+	err := b.view(func(bucket *bbolt.Bucket) error {
+		var err error
+		session, err = getSession(bucket, sessionID)
+		return err
+	})
+	return session, err
+}
+
+func (b bboltStore) Update(sessionID string, update func(session *services.SigningSession) error) error {
+	return b.update(func(bucket *bbolt.Bucket) error {
+		session, err := getSession(bucket, sessionID)
+		if err != nil {
+			return err
+		}
+		if err := update(session); err != nil {
+			return err
+		}
+		return putSession(bucket, *session)
+	})
+}
+
+func (b bboltStore) Delete(sessionID string) error {
+	return b.update(func(bucket *bbolt.Bucket) error {
+		return bucket.Delete([]byte(sessionID))
+	})
+}
+
+func (b bboltStore) List() ([]services.SigningSession, error) {
+	var result []services.SigningSession
+	err := b.view(func(bucket *bbolt.Bucket) error {
+		return bucket.ForEach(func(key, _ []byte) error {
+			session, err := getSession(bucket, string(key))
+			if err != nil {
+				return err
+			}
+			result = append(result, *session)
+			return nil
+		})
+	})
+	return result, err
+}
+
+func (b bboltStore) Close() error {
+	return b.db.Close()
+}
+
+func (b bboltStore) update(fn func(bucket *bbolt.Bucket) error) error {
+	return b.db.Update(sessionsBucket, fn)
+}
+
+func (b bboltStore) view(fn func(bucket *bbolt.Bucket) error) error {
+	return b.db.View(sessionsBucket, fn)
+}
+
+func getSession(bucket *bbolt.Bucket, sessionID string) (*services.SigningSession, error) {
+	data := bucket.Get([]byte(sessionID))
+	if data == nil {
+		return nil, services.ErrSessionNotFound
+	}
+	session := &services.SigningSession{}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, fmt.Errorf("could not read session %s: %w", sessionID, err)
+	}
+	return session, nil
+}
+
+func putSession(bucket *bbolt.Bucket, session services.SigningSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(session.ID), data)
+}
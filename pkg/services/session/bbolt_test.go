@@ -0,0 +1,94 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	testIo "github.com/nuts-foundation/nuts-go-test/io"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+)
+
+func TestBboltStore(t *testing.T) {
+	testSessionStore(t, func() services.SessionStore {
+		store, err := NewBboltStore(filepath.Join(testIo.TestDirectory(t), "sessions.db"), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		testSessionStore(t, func() services.SessionStore {
+			store, err := NewBboltStore(filepath.Join(testIo.TestDirectory(t), "sessions.db"), true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return store
+		})
+	})
+
+	t.Run("ok - sessions survive a restart", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "sessions.db")
+		store, _ := NewBboltStore(path, false)
+		_ = store.Put(services.SigningSession{ID: "123", State: "created", CreatedAt: time.Now()})
+		_ = store.Close()
+
+		restarted, err := NewBboltStore(path, false)
+		if !assert.NoError(t, err) {
+			return
+		}
+		session, err := restarted.Get("123")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "created", session.State)
+		}
+	})
+
+	t.Run("ok - nodes sharing the file see the same sessions", func(t *testing.T) {
+		path := filepath.Join(testIo.TestDirectory(t), "sessions.db")
+		node1, _ := NewBboltStore(path, true)
+		defer node1.Close()
+		node2, err := NewBboltStore(path, true)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer node2.Close()
+
+		_ = node1.Put(services.SigningSession{ID: "123", State: "created"})
+		_ = node2.Update("123", func(session *services.SigningSession) error {
+			session.State = "completed"
+			return nil
+		})
+
+		session, err := node1.Get("123")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "completed", session.State)
+		}
+	})
+
+	t.Run("error - file can not be created", func(t *testing.T) {
+		_, err := NewBboltStore(filepath.Join(testIo.TestDirectory(t), "missing", "sessions.db"), false)
+
+		assert.Error(t, err)
+	})
+}
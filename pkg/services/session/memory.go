@@ -0,0 +1,88 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"sync"
+
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+)
+
+type memoryStore struct {
+	mutex    sync.Mutex
+	sessions map[string]services.SigningSession
+}
+
+// NewMemoryStore returns a SessionStore which keeps the sessions in memory. The sessions are lost when the node stops
+// and are not shared with other nodes.
+func NewMemoryStore() services.SessionStore {
+	return &memoryStore{sessions: map[string]services.SigningSession{}}
+}
+
+func (m *memoryStore) Put(session services.SigningSession) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sessions[session.ID] = session
+	return nil
+}
+
+func (m *memoryStore) Get(sessionID string) (*services.SigningSession, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return nil, services.ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+func (m *memoryStore) Update(sessionID string, update func(session *services.SigningSession) error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return services.ErrSessionNotFound
+	}
+	if err := update(&session); err != nil {
+		return err
+	}
+	m.sessions[sessionID] = session
+	return nil
+}
+
+func (m *memoryStore) Delete(sessionID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.sessions, sessionID)
+	return nil
+}
+
+func (m *memoryStore) List() ([]services.SigningSession, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	result := make([]services.SigningSession, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		result = append(result, session)
+	}
+	return result, nil
+}
+
+func (m *memoryStore) Close() error {
+	return nil
+}
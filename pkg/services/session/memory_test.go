@@ -0,0 +1,137 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+)
+
+func TestMemoryStore(t *testing.T) {
+	testSessionStore(t, func() services.SessionStore {
+		return NewMemoryStore()
+	})
+}
+
+// testSessionStore contains the tests every SessionStore implementation must pass
+func testSessionStore(t *testing.T, createStore func() services.SessionStore) {
+	session := services.SigningSession{
+		ID:          "123",
+		Means:       "dummy",
+		Contract:    "EN:PractitionerLogin:v3 I hereby declare to act on behalf of Zorgcentrum.",
+		LegalEntity: "Zorgcentrum",
+		CreatedAt:   time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC),
+		State:       "created",
+	}
+
+	t.Run("ok - put and get", func(t *testing.T) {
+		store := createStore()
+		defer store.Close()
+
+		if !assert.NoError(t, store.Put(session)) {
+			return
+		}
+		stored, err := store.Get(session.ID)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, session.Contract, stored.Contract)
+		assert.Equal(t, session.LegalEntity, stored.LegalEntity)
+		assert.Equal(t, session.Means, stored.Means)
+		assert.Equal(t, session.State, stored.State)
+		assert.True(t, session.CreatedAt.Equal(stored.CreatedAt))
+	})
+
+	t.Run("ok - update", func(t *testing.T) {
+		store := createStore()
+		defer store.Close()
+		_ = store.Put(session)
+
+		err := store.Update(session.ID, func(s *services.SigningSession) error {
+			s.State = "completed"
+			s.Result = []byte(`{"status":"DONE"}`)
+			return nil
+		})
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		stored, _ := store.Get(session.ID)
+		assert.Equal(t, "completed", stored.State)
+		assert.JSONEq(t, `{"status":"DONE"}`, string(stored.Result))
+	})
+
+	t.Run("ok - failed update does not change the session", func(t *testing.T) {
+		store := createStore()
+		defer store.Close()
+		_ = store.Put(session)
+
+		err := store.Update(session.ID, func(s *services.SigningSession) error {
+			s.State = "completed"
+			return errors.New("b00m!")
+		})
+
+		assert.EqualError(t, err, "b00m!")
+		stored, _ := store.Get(session.ID)
+		assert.Equal(t, "created", stored.State)
+	})
+
+	t.Run("ok - delete", func(t *testing.T) {
+		store := createStore()
+		defer store.Close()
+		_ = store.Put(session)
+
+		assert.NoError(t, store.Delete(session.ID))
+		assert.NoError(t, store.Delete(session.ID))
+
+		_, err := store.Get(session.ID)
+		assert.True(t, errors.Is(err, services.ErrSessionNotFound))
+	})
+
+	t.Run("ok - list", func(t *testing.T) {
+		store := createStore()
+		defer store.Close()
+		_ = store.Put(session)
+		_ = store.Put(services.SigningSession{ID: "456", State: "created"})
+
+		sessions, err := store.List()
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, sessions, 2)
+	})
+
+	t.Run("error - unknown session", func(t *testing.T) {
+		store := createStore()
+		defer store.Close()
+
+		_, err := store.Get("unknown")
+		assert.True(t, errors.Is(err, services.ErrSessionNotFound))
+
+		err = store.Update("unknown", func(s *services.SigningSession) error { return nil })
+		assert.True(t, errors.Is(err, services.ErrSessionNotFound))
+	})
+}
@@ -0,0 +1,143 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nuts-foundation/nuts-auth/logging"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+)
+
+// MaxReapInterval is the maximum time between two runs of the Reaper
+const MaxReapInterval = time.Minute
+
+// Reaper expires the signing sessions which are not finished within the session TTL. Sessions are removed from the
+// store once they are twice the TTL old, so polling clients can still learn that their session expired.
+// The contract templates used by a session are released when it expires or is removed.
+type Reaper struct {
+	store     services.SessionStore
+	ttl       time.Duration
+	notifier  *contract.SessionNotifier
+	templates contract.TemplateProvider
+	done      chan struct{}
+}
+
+// NewReaper creates a Reaper for the sessions in the given store. The subscribers of a session are notified when it expires,
+// the notifier may be nil. The sessions are released from the template provider, which may be nil as well.
+func NewReaper(store services.SessionStore, ttl time.Duration, notifier *contract.SessionNotifier, templates contract.TemplateProvider) *Reaper {
+	return &Reaper{store: store, ttl: ttl, notifier: notifier, templates: templates}
+}
+
+// Start runs the Reaper in the background until it is stopped
+func (r *Reaper) Start() {
+	interval := r.ttl
+	if interval > MaxReapInterval {
+		interval = MaxReapInterval
+	}
+	r.done = make(chan struct{})
+	go r.run(interval, r.done)
+}
+
+// Stop stops the Reaper
+func (r *Reaper) Stop() {
+	if r.done == nil {
+		return
+	}
+	close(r.done)
+	r.done = nil
+}
+
+func (r *Reaper) run(interval time.Duration, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case moment := <-ticker.C:
+			if err := r.Reap(moment); err != nil {
+				logging.Log().Errorf("could not reap signing sessions: %v", err)
+			}
+		}
+	}
+}
+
+// Reap expires the sessions which are older than the TTL at the given moment and removes the sessions which are older than twice the TTL
+func (r *Reaper) Reap(moment time.Time) error {
+	sessions, err := r.store.List()
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		age := moment.Sub(session.CreatedAt)
+		if age > 2*r.ttl {
+			if err := r.store.Delete(session.ID); err != nil {
+				return err
+			}
+			r.release(session.ID)
+			continue
+		}
+		if age <= r.ttl || session.Finished() {
+			continue
+		}
+		// the session may have been finished by another request since it was listed
+		err := r.store.Update(session.ID, func(current *services.SigningSession) error {
+			if current.Finished() {
+				return errFinished
+			}
+			current.State = services.SessionExpired
+			return nil
+		})
+		if err == nil {
+			logging.Log().Infof("signing session %s expired", session.ID)
+			r.release(session.ID)
+			r.notifier.Notify(session.ID, expiredResult{})
+		} else if !errors.Is(err, errFinished) && !errors.Is(err, services.ErrSessionNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// release releases the contract templates used by the session
+func (r *Reaper) release(sessionID string) {
+	if r.templates != nil {
+		r.templates.Release(sessionID)
+	}
+}
+
+var errFinished = errors.New("session is finished")
+
+// expiredResult is the contract.SigningSessionResult of an expired session
+type expiredResult struct{}
+
+func (e expiredResult) Status() contract.SessionStatus {
+	return contract.SessionExpired
+}
+
+func (e expiredResult) NativeStatus() string {
+	return services.SessionExpired
+}
+
+func (e expiredResult) VerifiablePresentation() (contract.VerifiablePresentation, error) {
+	return nil, nil
+}
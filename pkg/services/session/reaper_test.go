@@ -0,0 +1,142 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+)
+
+func TestReaper_Reap(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	ttl := 15 * time.Minute
+
+	t.Run("ok - stale sessions are expired", func(t *testing.T) {
+		store := NewMemoryStore()
+		_ = store.Put(services.SigningSession{ID: "fresh", State: "created", CreatedAt: now.Add(-time.Minute)})
+		_ = store.Put(services.SigningSession{ID: "stale", State: "in-progress", CreatedAt: now.Add(-20 * time.Minute)})
+
+		err := NewReaper(store, ttl, nil, nil).Reap(now)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		fresh, _ := store.Get("fresh")
+		assert.Equal(t, "created", fresh.State)
+		stale, _ := store.Get("stale")
+		assert.Equal(t, services.SessionExpired, stale.State)
+	})
+
+	t.Run("ok - subscribers are notified", func(t *testing.T) {
+		store := NewMemoryStore()
+		_ = store.Put(services.SigningSession{ID: "stale", State: "created", CreatedAt: now.Add(-20 * time.Minute)})
+		notifier := contract.NewSessionNotifier()
+		var notified []string
+		notifier.Subscribe("stale", func(result contract.SigningSessionResult) {
+			notified = append(notified, result.NativeStatus())
+		})
+
+		err := NewReaper(store, ttl, notifier, nil).Reap(now)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []string{services.SessionExpired}, notified)
+	})
+
+	t.Run("ok - finished sessions are not expired", func(t *testing.T) {
+		store := NewMemoryStore()
+		_ = store.Put(services.SigningSession{ID: "done", State: "DONE", Result: []byte("{}"), CreatedAt: now.Add(-20 * time.Minute)})
+		_ = store.Put(services.SigningSession{ID: "cancelled", State: services.SessionCancelled, CreatedAt: now.Add(-20 * time.Minute)})
+
+		err := NewReaper(store, ttl, nil, nil).Reap(now)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		done, _ := store.Get("done")
+		assert.Equal(t, "DONE", done.State)
+		cancelled, _ := store.Get("cancelled")
+		assert.Equal(t, services.SessionCancelled, cancelled.State)
+	})
+
+	t.Run("ok - old sessions are removed", func(t *testing.T) {
+		store := NewMemoryStore()
+		_ = store.Put(services.SigningSession{ID: "old", State: services.SessionExpired, CreatedAt: now.Add(-31 * time.Minute)})
+		_ = store.Put(services.SigningSession{ID: "done", State: "DONE", Result: []byte("{}"), CreatedAt: now.Add(-31 * time.Minute)})
+
+		err := NewReaper(store, ttl, nil, nil).Reap(now)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		sessions, _ := store.List()
+		assert.Empty(t, sessions)
+	})
+
+	t.Run("ok - templates of expired and removed sessions are released", func(t *testing.T) {
+		store := NewMemoryStore()
+		_ = store.Put(services.SigningSession{ID: "fresh", State: "created", CreatedAt: now.Add(-time.Minute)})
+		_ = store.Put(services.SigningSession{ID: "stale", State: "created", CreatedAt: now.Add(-20 * time.Minute)})
+		_ = store.Put(services.SigningSession{ID: "old", State: "DONE", Result: []byte("{}"), CreatedAt: now.Add(-31 * time.Minute)})
+		templates := &releaseRecorder{TemplateStore: contract.StandardContractTemplates}
+
+		err := NewReaper(store, ttl, nil, templates).Reap(now)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.ElementsMatch(t, []string{"stale", "old"}, templates.released)
+	})
+}
+
+// releaseRecorder is a TemplateProvider which records the released sessions
+type releaseRecorder struct {
+	contract.TemplateStore
+	released []string
+}
+
+func (r *releaseRecorder) Release(sessionID string) {
+	r.released = append(r.released, sessionID)
+}
+
+func TestReaper_Start(t *testing.T) {
+	t.Run("ok - sessions are reaped in the background", func(t *testing.T) {
+		store := NewMemoryStore()
+		_ = store.Put(services.SigningSession{ID: "old", State: "created", CreatedAt: time.Now().Add(-time.Second)})
+		reaper := NewReaper(store, 100*time.Millisecond, nil, nil)
+
+		reaper.Start()
+		defer reaper.Stop()
+
+		assert.Eventually(t, func() bool {
+			_, err := store.Get("old")
+			return err == services.ErrSessionNotFound
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("ok - stop without start", func(t *testing.T) {
+		NewReaper(NewMemoryStore(), time.Minute, nil, nil).Stop()
+	})
+}
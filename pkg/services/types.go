@@ -20,6 +20,8 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
@@ -50,6 +52,47 @@ type NutsIdentityToken struct {
 // ErrSessionNotFound is returned when there is no contract signing session found for a certain SessionID
 var ErrSessionNotFound = errors.New("session not found")
 
+// ErrInvalidAudience is returned when the aud of a jwt bearer token does not identify an OAuth endpoint of the custodian
+var ErrInvalidAudience = errors.New("invalid audience")
+
+// ErrReplayedToken is returned when the issuer has used the jti of a jwt bearer token before
+var ErrReplayedToken = errors.New("jwt bearer token has already been used")
+
+// ErrTokenRevoked is returned when an access token, or the contract it is derived from, is revoked
+var ErrTokenRevoked = errors.New("token is revoked")
+
+// ErrInvalidRefreshToken is returned when a refresh token is not valid, expired or presented with another client certificate
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrInvalidClient is returned when a client presents no client certificate, or a certificate which is not trusted
+var ErrInvalidClient = errors.New("invalid client")
+
+// ErrUnauthorizedClient is returned when the client certificate is not issued to the party a token or contract belongs to
+var ErrUnauthorizedClient = errors.New("unauthorized client")
+
+// ErrUnknownContract is returned when a contract is revoked for which this node did not issue access tokens
+var ErrUnknownContract = errors.New("unknown contract")
+
+// ErrRateLimited is returned when a client starts more signing sessions than allowed, it is wrapped by a RateLimitError
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitError is returned when a signing session is refused because of a rate limit or quota
+type RateLimitError struct {
+	// Reason explains which limit is exceeded
+	Reason string
+	// RetryAfter is the time after which the client can try again
+	RetryAfter time.Duration
+}
+
+func (e RateLimitError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrRateLimited, e.Reason)
+}
+
+// Unwrap returns ErrRateLimited
+func (e RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
 // SessionID contains a number to uniquely identify a contract signing session
 type SessionID string
 
@@ -91,10 +91,17 @@ func (u Verifier) VerifyVP(rawVerifiablePresentation []byte, _ *time.Time) (*con
 		return nil, fmt.Errorf("could not get disclosed attributes from signed contract: %w", err)
 	}
 
-	return &contract.VPVerificationResult{
+	signedContract := signedToken.Contract()
+	result := &contract.VPVerificationResult{
 		Validity:            contract.Valid,
 		VPType:              VerifiablePresentationType,
 		DisclosedAttributes: disclosedAttributes,
-		ContractAttributes:  signedToken.Contract().Params,
-	}, nil
+		ContractAttributes:  signedContract.Params,
+	}
+	if signedContract.Template != nil {
+		result.ContractID = signedContract.Template.Identifier()
+		result.ContractHash = signedContract.Hash()
+		result.ValidTo, _ = signedContract.ValidTo()
+	}
+	return result, nil
 }
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/nuts-foundation/nuts-auth/logging"
@@ -31,6 +32,7 @@ import (
 	"github.com/nuts-foundation/nuts-auth/pkg/services/x509"
 
 	nutscrypto "github.com/nuts-foundation/nuts-crypto/pkg"
+	cryptoTypes "github.com/nuts-foundation/nuts-crypto/pkg/types"
 	core "github.com/nuts-foundation/nuts-go-core"
 	registry "github.com/nuts-foundation/nuts-registry/pkg"
 	irmago "github.com/privacybydesign/irmago"
@@ -38,7 +40,11 @@ import (
 
 	"github.com/nuts-foundation/nuts-auth/pkg/contract"
 	"github.com/nuts-foundation/nuts-auth/pkg/services"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/audit"
 	"github.com/nuts-foundation/nuts-auth/pkg/services/irma"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/ratelimit"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/session"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/webhook"
 )
 
 // Config holds all the configuration params
@@ -50,8 +56,33 @@ type Config struct {
 	IrmaConfigPath            string
 	IrmaSchemeManager         string
 	SkipAutoUpdateIrmaSchemas bool
+	IrmaPrintQrCode           bool
 	ActingPartyCn             string
 	ContractValidators        []string
+	// ContractTemplates provides the templates used to parse contracts, the StandardContractTemplates are used when nil
+	ContractTemplates contract.TemplateProvider
+	// SessionStore stores the signing sessions of all signers, sessions are kept in memory when nil
+	SessionStore services.SessionStore
+	// SessionNotifier notifies the subscribers of signing sessions, a new notifier is used when nil
+	SessionNotifier *contract.SessionNotifier
+	// DummyPersonas are the personas which can sign with the dummy means next to the default persona
+	DummyPersonas map[string]dummy.Persona
+	// Auditor records the signing sessions and verified presentations in the audit log, nothing is recorded when nil
+	Auditor *audit.Auditor
+	// CallbackDispatcher delivers the callbacks of signing sessions, a new dispatcher is used when nil
+	CallbackDispatcher *webhook.Dispatcher
+	// ContractNotary validates that contracts of signing sessions are drawn up for the legal entity of the session
+	ContractNotary services.ContractNotary
+	// SessionTTL is the time in which a signing session must be finished, it is used to tell clients when an open session slot frees up
+	SessionTTL time.Duration
+	// SessionRateLimit is the number of signing sessions a client can start per minute, 0 disables the limit
+	SessionRateLimit int
+	// SessionRateBurst is the number of signing sessions a client can start at once
+	SessionRateBurst int
+	// SessionRateLimitBy identifies the clients of the rate limit: ratelimit.ByCertificate or ratelimit.ByLegalEntity
+	SessionRateLimitBy string
+	// MaxOpenSessions is the number of signing sessions per signing means which can be open at the same time, 0 means unlimited
+	MaxOpenSessions int
 }
 
 type service struct {
@@ -63,9 +94,16 @@ type service struct {
 	irmaServer        *irmaserver.Server
 	crypto            nutscrypto.Client
 	// todo: remove this when the deprecated ValidateJwt is removed
-	registry  registry.RegistryClient
-	verifiers map[contract.VPType]contract.VPVerifier
-	signers   map[contract.SigningMeans]contract.Signer
+	registry          registry.RegistryClient
+	verifiers         map[contract.VPType]contract.VPVerifier
+	signers           map[contract.SigningMeans]contract.Signer
+	contractTemplates contract.TemplateProvider
+	sessionStore      services.SessionStore
+	callbacks         *webhook.Dispatcher
+	auditor           *audit.Auditor
+	sessionLimiter    *ratelimit.Limiter
+	// openSessionsMutex makes counting and starting sessions atomic when the open sessions are capped
+	openSessionsMutex sync.Mutex
 }
 
 // NewContractInstance accepts a Config and several Nuts engines and returns a new instance of services.ContractClient
@@ -83,6 +121,30 @@ func (s *service) Configure() (err error) {
 		return
 	}
 
+	var contractTemplates contract.TemplateProvider = contract.StandardContractTemplates
+	if s.config.ContractTemplates != nil {
+		contractTemplates = s.config.ContractTemplates
+	}
+	s.contractTemplates = contractTemplates
+
+	sessionStore := s.config.SessionStore
+	if sessionStore == nil {
+		sessionStore = session.NewMemoryStore()
+	}
+	s.sessionStore = sessionStore
+	notifier := s.config.SessionNotifier
+	if notifier == nil {
+		notifier = contract.NewSessionNotifier()
+	}
+	s.callbacks = s.config.CallbackDispatcher
+	if s.callbacks == nil {
+		s.callbacks = webhook.NewDispatcher()
+	}
+	s.auditor = s.config.Auditor
+	if s.config.SessionRateLimit > 0 {
+		s.sessionLimiter = ratelimit.NewLimiter(s.config.SessionRateLimit, s.config.SessionRateBurst)
+	}
+
 	var (
 		irmaConfig *irmago.Configuration
 		irmaServer *irmaserver.Server
@@ -99,7 +161,9 @@ func (s *service) Configure() (err error) {
 		Registry:          s.registry,
 		Crypto:            s.crypto,
 		IrmaServiceConfig: s.irmaServiceConfig,
-		ContractTemplates: contract.StandardContractTemplates,
+		ContractTemplates: contractTemplates,
+		Sessions:          sessionStore,
+		Notifier:          notifier,
 	}
 	// todo refactor and use signer/verifier
 	s.contractSessionHandler = irmaService
@@ -120,17 +184,19 @@ func (s *service) Configure() (err error) {
 	}
 
 	if _, ok := cvMap[dummy.ContractFormat]; ok && !core.NutsConfig().InStrictMode() {
-		d := dummy.Dummy{
-			Sessions: map[string]string{},
-			Status:   map[string]string{},
+		d := &dummy.Dummy{
+			Sessions:          sessionStore,
+			ContractTemplates: contractTemplates,
+			Notifier:          notifier,
 		}
+		d.SetPersonas(s.config.DummyPersonas)
 		s.verifiers[dummy.VerifiablePresentationType] = d
 		s.signers[dummy.ContractFormat] = d
 	}
 
 	if _, ok := cvMap[uzi.ContractFormat]; ok {
 		crlGetter := x509.NewCachedHttpCrlService()
-		uziValidator, err := x509.NewUziValidator(x509.UziAcceptation, &contract.StandardContractTemplates, crlGetter)
+		uziValidator, err := x509.NewUziValidator(x509.UziAcceptation, contractTemplates, crlGetter)
 		uziVerifier := uzi.Verifier{UziValidator: uziValidator}
 
 		if err != nil {
@@ -143,7 +209,35 @@ func (s *service) Configure() (err error) {
 	return
 }
 
-func (s *service) VerifyVP(rawVerifiablePresentation []byte, checkTime *time.Time) (*contract.VPVerificationResult, error) {
+// VerifyVP verifies the presentation with the verifier of its type and records the outcome in the audit log
+func (s *service) VerifyVP(rawVerifiablePresentation []byte, checkTime *time.Time, expectedContractHash *string) (*contract.VPVerificationResult, error) {
+	result, err := s.verifyVP(rawVerifiablePresentation, checkTime, expectedContractHash)
+	if s.auditor.Enabled() {
+		s.auditor.Record(presentationEntry(result, err))
+	}
+	return result, err
+}
+
+// presentationEntry describes the outcome of the verification of a presentation for the audit log
+func presentationEntry(result *contract.VPVerificationResult, err error) audit.Entry {
+	entry := audit.Entry{Event: audit.EventPresentationVerified, Outcome: audit.OutcomeRejected}
+	if err != nil {
+		entry.Reason = err.Error()
+		return entry
+	}
+	entry.Means = string(result.VPType)
+	entry.ContractHash = result.ContractHash
+	entry.LegalEntity = result.ContractAttributes[contract.LegalEntityAttr]
+	if result.Validity != contract.Valid {
+		entry.Reason = result.Reason
+		return entry
+	}
+	entry.Outcome = audit.OutcomeAccepted
+	entry.Signer = result.DisclosedAttributes
+	return entry
+}
+
+func (s *service) verifyVP(rawVerifiablePresentation []byte, checkTime *time.Time, expectedContractHash *string) (*contract.VPVerificationResult, error) {
 	vp := contract.BaseVerifiablePresentation{}
 	if err := json.Unmarshal(rawVerifiablePresentation, &vp); err != nil {
 		return nil, fmt.Errorf("unable to verifyVP: %w", err)
@@ -169,7 +263,41 @@ func (s *service) VerifyVP(rawVerifiablePresentation []byte, checkTime *time.Tim
 		return nil, fmt.Errorf("unknown VerifiablePresentation type: %s", t)
 	}
 
-	return s.verifiers[t].VerifyVP(rawVerifiablePresentation, checkTime)
+	result, err := s.verifiers[t].VerifyVP(rawVerifiablePresentation, checkTime)
+	if err != nil {
+		return nil, err
+	}
+	s.verifyTemplateSunset(result, checkTime)
+	verifyContractHash(result, expectedContractHash)
+	return result, nil
+}
+
+// verifyContractHash marks the verification result as invalid when the signed contract does not match the expected contract hash
+func verifyContractHash(result *contract.VPVerificationResult, expectedContractHash *string) {
+	if result == nil || expectedContractHash == nil || result.ContractHash == *expectedContractHash {
+		return
+	}
+	result.Validity = contract.Invalid
+	result.Reason = contract.ErrContractHashMismatch.Error()
+}
+
+// verifyTemplateSunset marks the verification result as invalid when the template of the signed contract is sunset at the checkTime
+func (s *service) verifyTemplateSunset(result *contract.VPVerificationResult, checkTime *time.Time) {
+	if result == nil || result.ContractID == "" || s.contractTemplates == nil {
+		return
+	}
+	template, err := s.contractTemplates.Templates().FindFromRawContractText(result.ContractID)
+	if err != nil || template == nil {
+		return
+	}
+	moment := time.Now()
+	if checkTime != nil {
+		moment = *checkTime
+	}
+	if template.IsSunset(moment) {
+		result.Validity = contract.Invalid
+		result.Reason = fmt.Sprintf("%s: %s is no longer accepted since %s", contract.ErrTemplateSunset, result.ContractID, template.SunsetAt.Format(time.RFC3339))
+	}
 }
 
 func (s *service) SigningSessionStatus(sessionID string) (contract.SigningSessionResult, error) {
@@ -181,6 +309,60 @@ func (s *service) SigningSessionStatus(sessionID string) (contract.SigningSessio
 	return nil, services.ErrSessionNotFound
 }
 
+func (s *service) CancelSigningSession(sessionID string) error {
+	for _, signer := range s.signers {
+		if err := signer.CancelSigningSession(sessionID); !errors.Is(err, services.ErrSessionNotFound) {
+			return err
+		}
+	}
+	return services.ErrSessionNotFound
+}
+
+func (s *service) SubscribeSigningSession(sessionID string, listener contract.SessionListener) (func(), error) {
+	session, err := s.sessionStore.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := s.signers[session.Means]
+	if !ok {
+		return nil, services.ErrSessionNotFound
+	}
+	return signer.Subscribe(sessionID, listener), nil
+}
+
+// AuthorizeSigningSession returns ErrSessionNotFound when the session does not exist or was requested by another caller.
+// Sessions which are not bound to a caller, and callers without verified identity, are never authorized.
+func (s *service) AuthorizeSigningSession(sessionID string, caller string) error {
+	if caller == "" {
+		return services.ErrSessionNotFound
+	}
+	session, err := s.sessionStore.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.Caller == "" || session.Caller != caller {
+		return services.ErrSessionNotFound
+	}
+	return nil
+}
+
+// SigningSessionPointer returns the payload of the session pointer the signing session was started with
+func (s *service) SigningSessionPointer(sessionID string) ([]byte, error) {
+	session, err := s.sessionStore.Get(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Pointer == "" {
+		return nil, services.ErrSessionNotFound
+	}
+	return []byte(session.Pointer), nil
+}
+
+// SigningSessionCallbacks returns the delivery log of the callback of the signing session
+func (s *service) SigningSessionCallbacks(sessionID string) ([]services.CallbackDelivery, error) {
+	return s.callbacks.Deliveries(sessionID)
+}
+
 // ErrMissingActingParty is returned when the actingPartyCn is missing from the config
 var ErrMissingActingParty = errors.New("missing actingPartyCn")
 
@@ -210,6 +392,7 @@ func (s *service) configureIrma(config Config) (irmaServer *irmaserver.Server, i
 		IrmaConfigPath:            config.IrmaConfigPath,
 		IrmaSchemeManager:         config.IrmaSchemeManager,
 		SkipAutoUpdateIrmaSchemas: config.SkipAutoUpdateIrmaSchemas,
+		PrintQrCode:               config.IrmaPrintQrCode,
 	}
 	if irmaConfig, err = irma.GetIrmaConfig(s.irmaServiceConfig); err != nil {
 		return
@@ -234,10 +417,48 @@ var ErrMissingOrganizationKey = errors.New("missing organization private key")
 // todo move
 var ErrUnknownSigningMeans = errors.New("unknown signing means")
 
+// ErrInvalidSessionContract is returned when a signing session is requested for a message which is not a contract drawn up
+// by this node for the legal entity of the session
+var ErrInvalidSessionContract = errors.New("message is not a contract drawn up by this node for the legal entity")
+
 // CreateSigningSession creates a session based on a contract. This allows the user to permit the application to
 // use the Nuts Network in its name. By signing it with a cryptographic means other
 // nodes in the network can verify the validity of the contract.
+// The request and the status transitions of the session are recorded in the audit log.
 func (s *service) CreateSigningSession(sessionRequest services.CreateSessionRequest) (contract.SessionPointer, error) {
+	sessionPointer, err := s.createSigningSession(sessionRequest)
+	if !s.auditor.Enabled() {
+		return sessionPointer, err
+	}
+	entry := audit.Entry{
+		Means:       string(sessionRequest.SigningMeans),
+		LegalEntity: sessionRequest.LegalEntity.String(),
+		Caller:      sessionRequest.ClientID,
+	}
+	c, parseErr := contract.ParseContractString(sessionRequest.Message, s.contractTemplates.Templates())
+	if parseErr != nil {
+		c = &contract.Contract{RawContractText: sessionRequest.Message}
+	} else if sessionRequest.LegalEntity.IsZero() {
+		entry.LegalEntity = c.Params[contract.LegalEntityAttr]
+	}
+	entry.ContractHash = c.Hash()
+
+	created := entry
+	created.Event = audit.EventSessionCreated
+	if err != nil {
+		created.Outcome = audit.OutcomeRejected
+		created.Reason = err.Error()
+		s.auditor.Record(created)
+		return nil, err
+	}
+	created.SessionID = sessionPointer.SessionID()
+	created.Outcome = audit.OutcomeAccepted
+	s.auditor.Record(created)
+	s.auditor.TrackSession(sessionPointer.SessionID(), entry, s.signers[sessionRequest.SigningMeans])
+	return sessionPointer, nil
+}
+
+func (s *service) createSigningSession(sessionRequest services.CreateSessionRequest) (contract.SessionPointer, error) {
 	if sessionRequest.Message == "" {
 		return nil, errors.New("can not sign an empty message")
 	}
@@ -246,7 +467,132 @@ func (s *service) CreateSigningSession(sessionRequest services.CreateSessionRequ
 	if !ok {
 		return nil, ErrUnknownSigningMeans
 	}
-	return signer.StartSigningSession(sessionRequest.Message)
+	if sessionRequest.Callback != nil {
+		if err := webhook.Validate(*sessionRequest.Callback); err != nil {
+			return nil, err
+		}
+	}
+	if !sessionRequest.LegalEntity.IsZero() {
+		if err := s.checkSessionContract(sessionRequest.Message, sessionRequest.LegalEntity); err != nil {
+			return nil, err
+		}
+	}
+	if s.config.MaxOpenSessions > 0 {
+		s.openSessionsMutex.Lock()
+		defer s.openSessionsMutex.Unlock()
+		if err := s.checkOpenSessions(sessionRequest.SigningMeans); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkSessionRate(sessionRequest); err != nil {
+		return nil, err
+	}
+	sessionPointer, err := signer.StartSigningSession(sessionRequest.Message, sessionRequest.Params)
+	if err != nil {
+		return nil, err
+	}
+	err = s.sessionStore.Update(sessionPointer.SessionID(), func(session *services.SigningSession) error {
+		session.Caller = sessionRequest.Caller
+		session.Pointer = string(sessionPointer.Payload())
+		if !sessionRequest.LegalEntity.IsZero() {
+			session.LegalEntityID = sessionRequest.LegalEntity.String()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while binding session to its caller: %w", err)
+	}
+	if sessionRequest.Callback != nil {
+		s.callbacks.Register(sessionPointer.SessionID(), *sessionRequest.Callback, signer)
+	}
+	return sessionPointer, nil
+}
+
+// checkSessionContract checks that the message is a contract which is valid now and is drawn up for the legal entity,
+// of which the key is managed by this node.
+func (s *service) checkSessionContract(message string, legalEntity core.PartyID) error {
+	if !s.crypto.PrivateKeyExists(cryptoTypes.KeyForEntity(cryptoTypes.LegalEntity{URI: legalEntity.String()})) {
+		return fmt.Errorf("%w: organization is not managed by this node: %s", ErrInvalidSessionContract, legalEntity)
+	}
+	c, err := contract.ParseContractString(message, s.contractTemplates.Templates())
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSessionContract, err)
+	}
+	if s.config.ContractNotary == nil {
+		return fmt.Errorf("%w: no contract notary configured", ErrInvalidSessionContract)
+	}
+	ok, err := s.config.ContractNotary.ValidateContract(*c, legalEntity, contract.NowFunc())
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSessionContract, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: contract is not valid for legal entity %s", ErrInvalidSessionContract, legalEntity)
+	}
+	return nil
+}
+
+// defaultRetryAfter is the time after which a client can retry when no better estimate is available
+const defaultRetryAfter = time.Minute
+
+// checkOpenSessions returns a RateLimitError when the signing means has MaxOpenSessions unfinished sessions. Sessions
+// which exceeded the SessionTTL are not counted, they are expired by the reaper.
+func (s *service) checkOpenSessions(means contract.SigningMeans) error {
+	sessions, err := s.sessionStore.List()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	open := 0
+	retryAfter := time.Duration(0)
+	for _, session := range sessions {
+		if session.Means != means || session.Finished() {
+			continue
+		}
+		if s.config.SessionTTL == 0 {
+			open++
+			continue
+		}
+		remaining := session.CreatedAt.Add(s.config.SessionTTL).Sub(now)
+		if remaining <= 0 {
+			continue
+		}
+		open++
+		if retryAfter == 0 || remaining < retryAfter {
+			retryAfter = remaining
+		}
+	}
+	if open < s.config.MaxOpenSessions {
+		return nil
+	}
+	if retryAfter == 0 {
+		retryAfter = defaultRetryAfter
+	}
+	return services.RateLimitError{
+		Reason:     fmt.Sprintf("too many open sessions for signing means %s", means),
+		RetryAfter: retryAfter,
+	}
+}
+
+// checkSessionRate takes a token from the bucket of the client and returns a RateLimitError when it is empty.
+// Clients are identified by the ClientID of the request or by the legal entity of the contract. Requests of which the
+// legal entity can not be determined are limited by their ClientID.
+func (s *service) checkSessionRate(sessionRequest services.CreateSessionRequest) error {
+	if s.sessionLimiter == nil {
+		return nil
+	}
+	key := sessionRequest.ClientID
+	if s.config.SessionRateLimitBy != ratelimit.ByCertificate {
+		if c, err := contract.ParseContractString(sessionRequest.Message, s.contractTemplates.Templates()); err == nil {
+			key = c.Params[contract.LegalEntityAttr]
+		}
+	}
+	if ok, retryAfter := s.sessionLimiter.Allow(key); !ok {
+		return services.RateLimitError{
+			Reason:     "too many signing sessions started",
+			RetryAfter: retryAfter,
+		}
+	}
+	return nil
 }
 
 // ContractSessionStatus returns the current session status for a given sessionID.
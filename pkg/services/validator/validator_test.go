@@ -22,8 +22,10 @@ import (
 	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	cryptoTypes "github.com/nuts-foundation/nuts-crypto/pkg/types"
 	cryptoMock "github.com/nuts-foundation/nuts-crypto/test/mock"
 	core "github.com/nuts-foundation/nuts-go-core"
 	registryMock "github.com/nuts-foundation/nuts-registry/mock"
@@ -34,7 +36,11 @@ import (
 	servicesMock "github.com/nuts-foundation/nuts-auth/mock/services"
 	"github.com/nuts-foundation/nuts-auth/pkg/contract"
 	"github.com/nuts-foundation/nuts-auth/pkg/services"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/audit"
 	irmaService "github.com/nuts-foundation/nuts-auth/pkg/services/irma"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/ratelimit"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/session"
+	"github.com/nuts-foundation/nuts-auth/pkg/services/webhook"
 )
 
 const qrURL = "https://api.nuts-test.example" + irmaService.IrmaMountPath + "/123-session-ref-123"
@@ -48,7 +54,7 @@ func TestService_CreateContractSession(t *testing.T) {
 			Message:      "message to sign",
 			SigningMeans: irmaService.ContractFormat,
 		}
-		ctx.signerMock.EXPECT().StartSigningSession(gomock.Any()).Return(irmaService.SessionPtr{ID: "abc-sessionid-abc", QrCodeInfo: irma.Qr{URL: qrURL, Type: irma.ActionSigning}}, nil)
+		ctx.signerMock.EXPECT().StartSigningSession(gomock.Any(), gomock.Any()).DoAndReturn(ctx.startSession(irmaService.SessionPtr{ID: "abc-sessionid-abc", QrCodeInfo: irma.Qr{URL: qrURL, Type: irma.ActionSigning}}))
 
 		result, err := ctx.contractService.CreateSigningSession(request)
 
@@ -61,6 +67,372 @@ func TestService_CreateContractSession(t *testing.T) {
 		assert.Equal(t, irmaResult.QrCodeInfo.URL, qrURL, "qrCode should contain the correct URL")
 		assert.Equal(t, irmaResult.QrCodeInfo.Type, irma.ActionSigning, "qrCode type should be signing")
 	})
+
+	t.Run("ok - callback is registered", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		request := services.CreateSessionRequest{
+			Message:      "message to sign",
+			SigningMeans: irmaService.ContractFormat,
+			Callback:     &services.SessionCallback{URL: "https://example.com/callback", Secret: "secret"},
+		}
+		ctx.signerMock.EXPECT().StartSigningSession(gomock.Any(), gomock.Any()).DoAndReturn(ctx.startSession(irmaService.SessionPtr{ID: "abc-sessionid-abc"}))
+		ctx.signerMock.EXPECT().Subscribe("abc-sessionid-abc", gomock.Any()).Return(func() {})
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		deliveries, err := ctx.contractService.SigningSessionCallbacks("abc-sessionid-abc")
+		assert.NoError(t, err)
+		assert.Empty(t, deliveries)
+	})
+
+	t.Run("nok - invalid callback", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		request := services.CreateSessionRequest{
+			Message:      "message to sign",
+			SigningMeans: irmaService.ContractFormat,
+			Callback:     &services.SessionCallback{URL: "/callback", Secret: "secret"},
+		}
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+
+		assert.True(t, errors.Is(err, webhook.ErrInvalidCallback))
+	})
+
+	t.Run("nok - too many open sessions", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		store := session.NewMemoryStore()
+		_ = store.Put(services.SigningSession{ID: "open", Means: irmaService.ContractFormat, CreatedAt: time.Now().Add(-5 * time.Minute)})
+		_ = store.Put(services.SigningSession{ID: "finished", Means: irmaService.ContractFormat, CreatedAt: time.Now(), State: services.SessionCancelled})
+		_ = store.Put(services.SigningSession{ID: "expired", Means: irmaService.ContractFormat, CreatedAt: time.Now().Add(-time.Hour)})
+		_ = store.Put(services.SigningSession{ID: "other", Means: "dummy", CreatedAt: time.Now()})
+		ctx.contractService.sessionStore = store
+		ctx.contractService.config.MaxOpenSessions = 1
+		ctx.contractService.config.SessionTTL = 15 * time.Minute
+
+		_, err := ctx.contractService.CreateSigningSession(services.CreateSessionRequest{
+			Message:      "message to sign",
+			SigningMeans: irmaService.ContractFormat,
+		})
+
+		var rateLimitErr services.RateLimitError
+		if !assert.True(t, errors.As(err, &rateLimitErr)) {
+			return
+		}
+		assert.True(t, errors.Is(err, services.ErrRateLimited))
+		assert.InDelta(t, (10 * time.Minute).Seconds(), rateLimitErr.RetryAfter.Seconds(), 5)
+	})
+
+	t.Run("ok - below the maximum of open sessions", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		store := session.NewMemoryStore()
+		_ = store.Put(services.SigningSession{ID: "open", Means: irmaService.ContractFormat, CreatedAt: time.Now()})
+		ctx.contractService.sessionStore = store
+		ctx.contractService.config.MaxOpenSessions = 2
+		ctx.signerMock.EXPECT().StartSigningSession(gomock.Any(), gomock.Any()).DoAndReturn(ctx.startSession(irmaService.SessionPtr{ID: "abc-sessionid-abc"}))
+
+		_, err := ctx.contractService.CreateSigningSession(services.CreateSessionRequest{
+			Message:      "message to sign",
+			SigningMeans: irmaService.ContractFormat,
+		})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("nok - rate limited by client certificate", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.contractService.config.SessionRateLimitBy = ratelimit.ByCertificate
+		ctx.contractService.sessionLimiter = ratelimit.NewLimiter(1, 1)
+		ctx.signerMock.EXPECT().StartSigningSession(gomock.Any(), gomock.Any()).DoAndReturn(ctx.startSession(irmaService.SessionPtr{ID: "abc-sessionid-abc"})).Times(2)
+		request := services.CreateSessionRequest{
+			Message:      "message to sign",
+			SigningMeans: irmaService.ContractFormat,
+			ClientID:     "client-a",
+		}
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+		assert.NoError(t, err)
+		_, err = ctx.contractService.CreateSigningSession(request)
+		var rateLimitErr services.RateLimitError
+		if assert.True(t, errors.As(err, &rateLimitErr)) {
+			assert.True(t, rateLimitErr.RetryAfter > 0)
+		}
+
+		request.ClientID = "client-b"
+		_, err = ctx.contractService.CreateSigningSession(request)
+		assert.NoError(t, err)
+	})
+
+	t.Run("nok - rate limited by legal entity", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.contractService.config.SessionRateLimitBy = ratelimit.ByLegalEntity
+		ctx.contractService.contractTemplates = contract.StandardContractTemplates
+		ctx.contractService.sessionLimiter = ratelimit.NewLimiter(1, 1)
+		ctx.signerMock.EXPECT().StartSigningSession(gomock.Any(), gomock.Any()).DoAndReturn(ctx.startSession(irmaService.SessionPtr{ID: "abc-sessionid-abc"})).Times(2)
+		request := services.CreateSessionRequest{
+			Message:      "EN:PractitionerLogin:v3 I hereby declare to act on behalf of verpleeghuis De nootjes. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00.",
+			SigningMeans: irmaService.ContractFormat,
+			ClientID:     "client-a",
+		}
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+		assert.NoError(t, err)
+		// another client acting on behalf of the same legal entity shares the limit
+		request.ClientID = "client-b"
+		_, err = ctx.contractService.CreateSigningSession(request)
+		assert.True(t, errors.Is(err, services.ErrRateLimited))
+
+		request.Message = "EN:PractitionerLogin:v3 I hereby declare to act on behalf of ziekenhuis De notenboom. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00."
+		_, err = ctx.contractService.CreateSigningSession(request)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ok - messages without legal entity are limited by client", func(t *testing.T) {
+		ctx := createContext(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.contractService.config.SessionRateLimitBy = ratelimit.ByLegalEntity
+		ctx.contractService.contractTemplates = contract.StandardContractTemplates
+		ctx.contractService.sessionLimiter = ratelimit.NewLimiter(1, 1)
+		ctx.signerMock.EXPECT().StartSigningSession(gomock.Any(), gomock.Any()).DoAndReturn(ctx.startSession(irmaService.SessionPtr{ID: "abc-sessionid-abc"})).Times(2)
+		request := services.CreateSessionRequest{
+			Message:      "message to sign",
+			SigningMeans: irmaService.ContractFormat,
+			ClientID:     "client-a",
+		}
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+		assert.NoError(t, err)
+		request.ClientID = "client-b"
+		_, err = ctx.contractService.CreateSigningSession(request)
+		assert.NoError(t, err)
+		_, err = ctx.contractService.CreateSigningSession(request)
+		assert.True(t, errors.Is(err, services.ErrRateLimited))
+	})
+}
+
+func TestService_CreateSigningSession_LegalEntity(t *testing.T) {
+	const contractText = "EN:PractitionerLogin:v3 I hereby declare to act on behalf of verpleeghuis De nootjes. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00."
+	legalEntity, _ := core.ParsePartyID("urn:oid:2.16.840.1.113883.2.4.6.1:00000001")
+	legalEntityKey := cryptoTypes.KeyForEntity(cryptoTypes.LegalEntity{URI: legalEntity.String()})
+	createContextWithNotary := func(t *testing.T) (*testContext, *servicesMock.MockContractNotary) {
+		ctx := createContext(t)
+		notary := servicesMock.NewMockContractNotary(ctx.ctrl)
+		ctx.contractService.config.ContractNotary = notary
+		ctx.contractService.contractTemplates = contract.StandardContractTemplates
+		return ctx, notary
+	}
+	request := services.CreateSessionRequest{
+		Message:      contractText,
+		SigningMeans: irmaService.ContractFormat,
+		ClientID:     "client-a",
+		Caller:       "certificate-a",
+		LegalEntity:  legalEntity,
+	}
+
+	t.Run("ok - session is bound to the caller and legal entity", func(t *testing.T) {
+		ctx, notary := createContextWithNotary(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(legalEntityKey).Return(true)
+		notary.EXPECT().ValidateContract(gomock.Any(), legalEntity, gomock.Any()).Return(true, nil)
+		ctx.signerMock.EXPECT().StartSigningSession(contractText, gomock.Any()).DoAndReturn(ctx.startSession(irmaService.SessionPtr{ID: "abc-sessionid-abc"}))
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+
+		if !assert.NoError(t, err) {
+			return
+		}
+		stored, _ := ctx.contractService.sessionStore.Get("abc-sessionid-abc")
+		assert.Equal(t, "certificate-a", stored.Caller)
+		assert.Equal(t, legalEntity.String(), stored.LegalEntityID)
+		assert.Equal(t, string(irmaService.SessionPtr{ID: "abc-sessionid-abc"}.Payload()), stored.Pointer)
+	})
+
+	t.Run("nok - organization is not managed by this node", func(t *testing.T) {
+		ctx, _ := createContextWithNotary(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(legalEntityKey).Return(false)
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+
+		assert.True(t, errors.Is(err, ErrInvalidSessionContract))
+	})
+
+	t.Run("nok - message is not a contract", func(t *testing.T) {
+		ctx, _ := createContextWithNotary(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(legalEntityKey).Return(true)
+		invalidRequest := request
+		invalidRequest.Message = "message to sign"
+
+		_, err := ctx.contractService.CreateSigningSession(invalidRequest)
+
+		assert.True(t, errors.Is(err, ErrInvalidSessionContract))
+	})
+
+	t.Run("nok - contract is not drawn up for the legal entity", func(t *testing.T) {
+		ctx, notary := createContextWithNotary(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(legalEntityKey).Return(true)
+		notary.EXPECT().ValidateContract(gomock.Any(), legalEntity, gomock.Any()).Return(false, errors.New("legalEntityName does not match"))
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+
+		assert.True(t, errors.Is(err, ErrInvalidSessionContract))
+		assert.Contains(t, err.Error(), "legalEntityName does not match")
+	})
+
+	t.Run("nok - contract is not valid without reason", func(t *testing.T) {
+		ctx, notary := createContextWithNotary(t)
+		defer ctx.ctrl.Finish()
+
+		ctx.cryptoMock.EXPECT().PrivateKeyExists(legalEntityKey).Return(true)
+		notary.EXPECT().ValidateContract(gomock.Any(), legalEntity, gomock.Any()).Return(false, nil)
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+
+		assert.True(t, errors.Is(err, ErrInvalidSessionContract))
+		assert.NotContains(t, err.Error(), "%!")
+		assert.Contains(t, err.Error(), "contract is not valid for legal entity "+legalEntity.String())
+	})
+}
+
+// auditSink keeps the audit entries in memory
+type auditSink struct {
+	entries []audit.Entry
+}
+
+func (a *auditSink) Write(entry audit.Entry) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func (a *auditSink) Close() error {
+	return nil
+}
+
+func TestService_CreateSigningSession_Audit(t *testing.T) {
+	const contractText = "EN:PractitionerLogin:v3 I hereby declare to act on behalf of verpleeghuis De nootjes. This declaration is valid from Monday, 1 October 2012 12:00:00 until Monday, 1 October 2012 13:00:00."
+	request := services.CreateSessionRequest{
+		Message:      contractText,
+		SigningMeans: irmaService.ContractFormat,
+		ClientID:     "client-a",
+	}
+	createAuditedContext := func(t *testing.T) (*testContext, *auditSink) {
+		ctx := createContext(t)
+		sink := &auditSink{}
+		ctx.contractService.auditor = audit.NewAuditor(sink)
+		ctx.contractService.contractTemplates = contract.StandardContractTemplates
+		return ctx, sink
+	}
+
+	t.Run("ok - created session is recorded and tracked", func(t *testing.T) {
+		ctx, sink := createAuditedContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.signerMock.EXPECT().StartSigningSession(contractText, gomock.Any()).DoAndReturn(ctx.startSession(irmaService.SessionPtr{ID: "abc-sessionid-abc"}))
+		ctx.signerMock.EXPECT().Subscribe("abc-sessionid-abc", gomock.Any()).Return(func() {})
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+
+		if !assert.NoError(t, err) || !assert.Len(t, sink.entries, 1) {
+			return
+		}
+		entry := sink.entries[0]
+		assert.Equal(t, audit.EventSessionCreated, entry.Event)
+		assert.Equal(t, audit.OutcomeAccepted, entry.Outcome)
+		assert.Equal(t, "abc-sessionid-abc", entry.SessionID)
+		assert.Equal(t, "irma", entry.Means)
+		assert.Equal(t, "verpleeghuis De nootjes", entry.LegalEntity)
+		assert.Equal(t, "client-a", entry.Caller)
+		c, _ := contract.ParseContractString(contractText, contract.StandardContractTemplates)
+		assert.Equal(t, c.Hash(), entry.ContractHash)
+	})
+
+	t.Run("nok - rejected session is recorded", func(t *testing.T) {
+		ctx, sink := createAuditedContext(t)
+		defer ctx.ctrl.Finish()
+		ctx.signerMock.EXPECT().StartSigningSession(contractText, gomock.Any()).Return(nil, errors.New("irma server unavailable"))
+
+		_, err := ctx.contractService.CreateSigningSession(request)
+
+		if !assert.Error(t, err) || !assert.Len(t, sink.entries, 1) {
+			return
+		}
+		assert.Equal(t, audit.OutcomeRejected, sink.entries[0].Outcome)
+		assert.Empty(t, sink.entries[0].SessionID)
+		assert.Contains(t, sink.entries[0].Reason, "irma server unavailable")
+	})
+}
+
+func TestService_AuthorizeSigningSession(t *testing.T) {
+	store := session.NewMemoryStore()
+	_ = store.Put(services.SigningSession{ID: "123", Means: "bar", Caller: "client-a"})
+	_ = store.Put(services.SigningSession{ID: "unbound", Means: "bar"})
+	validator := service{sessionStore: store}
+
+	t.Run("ok - same caller", func(t *testing.T) {
+		assert.NoError(t, validator.AuthorizeSigningSession("123", "client-a"))
+	})
+
+	t.Run("nok - other caller", func(t *testing.T) {
+		assert.Equal(t, services.ErrSessionNotFound, validator.AuthorizeSigningSession("123", "client-b"))
+	})
+
+	t.Run("nok - session not found", func(t *testing.T) {
+		assert.Equal(t, services.ErrSessionNotFound, validator.AuthorizeSigningSession("456", "client-a"))
+	})
+
+	t.Run("nok - caller without certificate", func(t *testing.T) {
+		assert.Equal(t, services.ErrSessionNotFound, validator.AuthorizeSigningSession("unbound", ""))
+	})
+
+	t.Run("nok - session is not bound to a caller", func(t *testing.T) {
+		assert.Equal(t, services.ErrSessionNotFound, validator.AuthorizeSigningSession("unbound", "client-a"))
+	})
+}
+
+func TestService_SigningSessionPointer(t *testing.T) {
+	store := session.NewMemoryStore()
+	_ = store.Put(services.SigningSession{ID: "123", Means: "bar", Pointer: `{"u":"https://example.com"}`})
+	_ = store.Put(services.SigningSession{ID: "456", Means: "bar"})
+	validator := service{sessionStore: store}
+
+	t.Run("ok", func(t *testing.T) {
+		pointer, err := validator.SigningSessionPointer("123")
+
+		assert.NoError(t, err)
+		assert.Equal(t, `{"u":"https://example.com"}`, string(pointer))
+	})
+
+	t.Run("nok - session without pointer", func(t *testing.T) {
+		_, err := validator.SigningSessionPointer("456")
+
+		assert.Equal(t, services.ErrSessionNotFound, err)
+	})
+
+	t.Run("nok - session not found", func(t *testing.T) {
+		_, err := validator.SigningSessionPointer("789")
+
+		assert.Equal(t, services.ErrSessionNotFound, err)
+	})
 }
 
 func TestService_ContractSessionStatus(t *testing.T) {
@@ -153,12 +525,12 @@ func TestContract_VerifyVP(t *testing.T) {
 			return
 		}
 
-		mockVerifier := servicesMock.NewMockContractClient(ctrl)
+		mockVerifier := contractMock.NewMockVPVerifier(ctrl)
 		mockVerifier.EXPECT().VerifyVP(rawVP, nil).Return(&contract.VPVerificationResult{Validity: contract.Valid}, nil)
 
 		validator := service{verifiers: map[contract.VPType]contract.VPVerifier{"bar": mockVerifier}}
 
-		validationResult, err := validator.VerifyVP(rawVP, nil)
+		validationResult, err := validator.VerifyVP(rawVP, nil, nil)
 
 		if !assert.NoError(t, err) {
 			return
@@ -169,6 +541,79 @@ func TestContract_VerifyVP(t *testing.T) {
 		assert.Equal(t, contract.Valid, validationResult.Validity)
 	})
 
+	t.Run("ok - verification is recorded", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		rawVP, _ := json.Marshal(struct {
+			Type []string
+		}{Type: []string{"bar"}})
+		mockVerifier := contractMock.NewMockVPVerifier(ctrl)
+		mockVerifier.EXPECT().VerifyVP(rawVP, nil).Return(&contract.VPVerificationResult{Validity: contract.Valid, VPType: "bar"}, nil)
+		sink := &auditSink{}
+		validator := service{verifiers: map[contract.VPType]contract.VPVerifier{"bar": mockVerifier}, auditor: audit.NewAuditor(sink)}
+
+		_, err := validator.VerifyVP(rawVP, nil, nil)
+
+		if !assert.NoError(t, err) || !assert.Len(t, sink.entries, 1) {
+			return
+		}
+		assert.Equal(t, audit.EventPresentationVerified, sink.entries[0].Event)
+		assert.Equal(t, audit.OutcomeAccepted, sink.entries[0].Outcome)
+	})
+
+	t.Run("nok - contract template is sunset", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		rawVP, _ := json.Marshal(struct {
+			Type []string
+		}{Type: []string{"bar"}})
+		sunsetAt := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
+		templates := contract.TemplateStore{"NL": {"Opname": {"v1": &contract.Template{Type: "Opname", Language: "NL", Version: "v1", SunsetAt: &sunsetAt}}}}
+		mockVerifier := contractMock.NewMockVPVerifier(ctrl)
+		mockVerifier.EXPECT().VerifyVP(rawVP, gomock.Any()).AnyTimes().Return(&contract.VPVerificationResult{Validity: contract.Valid, ContractID: "NL:Opname:v1"}, nil)
+		validator := service{verifiers: map[contract.VPType]contract.VPVerifier{"bar": mockVerifier}, contractTemplates: templates}
+
+		checkTime := sunsetAt.Add(-time.Second)
+		validationResult, err := validator.VerifyVP(rawVP, &checkTime, nil)
+		if assert.NoError(t, err) {
+			assert.Equal(t, contract.Valid, validationResult.Validity)
+		}
+
+		checkTime = sunsetAt
+		validationResult, err = validator.VerifyVP(rawVP, &checkTime, nil)
+		if assert.NoError(t, err) {
+			assert.Equal(t, contract.Invalid, validationResult.Validity)
+			assert.Equal(t, "contract template is sunset: NL:Opname:v1 is no longer accepted since 2020-10-01T00:00:00Z", validationResult.Reason)
+		}
+	})
+
+	t.Run("nok - signed contract does not match the expected contract hash", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		rawVP, _ := json.Marshal(struct {
+			Type []string
+		}{Type: []string{"bar"}})
+		mockVerifier := contractMock.NewMockVPVerifier(ctrl)
+		mockVerifier.EXPECT().VerifyVP(rawVP, nil).Times(2).Return(&contract.VPVerificationResult{Validity: contract.Valid, ContractHash: "abc"}, nil)
+		validator := service{verifiers: map[contract.VPType]contract.VPVerifier{"bar": mockVerifier}}
+
+		expected := "abc"
+		validationResult, err := validator.VerifyVP(rawVP, nil, &expected)
+		if assert.NoError(t, err) {
+			assert.Equal(t, contract.Valid, validationResult.Validity)
+		}
+
+		expected = "def"
+		validationResult, err = validator.VerifyVP(rawVP, nil, &expected)
+		if assert.NoError(t, err) {
+			assert.Equal(t, contract.Invalid, validationResult.Validity)
+			assert.Equal(t, "signed contract does not match the expected contract hash", validationResult.Reason)
+		}
+	})
+
 	t.Run("nok - unknown VerifiablePresentation", func(t *testing.T) {
 		validator := service{}
 
@@ -179,7 +624,7 @@ func TestContract_VerifyVP(t *testing.T) {
 			return
 		}
 
-		validationResult, err := validator.VerifyVP(rawVP, nil)
+		validationResult, err := validator.VerifyVP(rawVP, nil, nil)
 		if !assert.Error(t, err) {
 			return
 		}
@@ -199,7 +644,7 @@ func TestContract_VerifyVP(t *testing.T) {
 			return
 		}
 
-		validationResult, err := validator.VerifyVP(rawVP, nil)
+		validationResult, err := validator.VerifyVP(rawVP, nil, nil)
 		if !assert.Error(t, err) {
 			return
 		}
@@ -211,7 +656,7 @@ func TestContract_VerifyVP(t *testing.T) {
 
 	t.Run("nok - invalid rawVP", func(t *testing.T) {
 		validator := service{}
-		validationResult, err := validator.VerifyVP([]byte{}, nil)
+		validationResult, err := validator.VerifyVP([]byte{}, nil, nil)
 		if !assert.Error(t, err) {
 			return
 		}
@@ -222,6 +667,43 @@ func TestContract_VerifyVP(t *testing.T) {
 	})
 }
 
+func TestPresentationEntry(t *testing.T) {
+	t.Run("ok - valid presentation contains the signer", func(t *testing.T) {
+		entry := presentationEntry(&contract.VPVerificationResult{
+			Validity:            contract.Valid,
+			VPType:              "NutsIrmaPresentation",
+			ContractHash:        "hash",
+			ContractAttributes:  map[string]string{contract.LegalEntityAttr: "Zorgcentrum"},
+			DisclosedAttributes: map[string]string{"initials": "I."},
+		}, nil)
+
+		assert.Equal(t, audit.OutcomeAccepted, entry.Outcome)
+		assert.Equal(t, "NutsIrmaPresentation", entry.Means)
+		assert.Equal(t, "hash", entry.ContractHash)
+		assert.Equal(t, "Zorgcentrum", entry.LegalEntity)
+		assert.Equal(t, map[string]string{"initials": "I."}, entry.Signer)
+	})
+
+	t.Run("nok - invalid presentation contains the reason", func(t *testing.T) {
+		entry := presentationEntry(&contract.VPVerificationResult{
+			Validity:            contract.Invalid,
+			Reason:              "contract is expired",
+			DisclosedAttributes: map[string]string{"initials": "I."},
+		}, nil)
+
+		assert.Equal(t, audit.OutcomeRejected, entry.Outcome)
+		assert.Equal(t, "contract is expired", entry.Reason)
+		assert.Nil(t, entry.Signer)
+	})
+
+	t.Run("nok - error", func(t *testing.T) {
+		entry := presentationEntry(nil, errors.New("b0rk"))
+
+		assert.Equal(t, audit.OutcomeRejected, entry.Outcome)
+		assert.Equal(t, "b0rk", entry.Reason)
+	})
+}
+
 func TestContract_SigningSessionStatus(t *testing.T) {
 	t.Run("ok - valid session", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -258,6 +740,56 @@ func TestContract_SigningSessionStatus(t *testing.T) {
 	})
 }
 
+func TestContract_CancelSigningSession(t *testing.T) {
+	t.Run("ok - session is cancelled by its signer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSigner := contractMock.NewMockSigner(ctrl)
+		mockSigner.EXPECT().CancelSigningSession("123").Return(nil)
+		validator := service{signers: map[contract.SigningMeans]contract.Signer{"bar": mockSigner}}
+
+		assert.NoError(t, validator.CancelSigningSession("123"))
+	})
+
+	t.Run("nok - session not found", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockSigner := contractMock.NewMockSigner(ctrl)
+		mockSigner.EXPECT().CancelSigningSession("123").Return(services.ErrSessionNotFound)
+		validator := service{signers: map[contract.SigningMeans]contract.Signer{"bar": mockSigner}}
+
+		assert.Equal(t, services.ErrSessionNotFound, validator.CancelSigningSession("123"))
+	})
+}
+
+func TestContract_SubscribeSigningSession(t *testing.T) {
+	t.Run("ok - listener is registered at the signer of the session", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := session.NewMemoryStore()
+		_ = store.Put(services.SigningSession{ID: "123", Means: "bar"})
+		mockSigner := contractMock.NewMockSigner(ctrl)
+		mockSigner.EXPECT().Subscribe("123", gomock.Any()).Return(func() {})
+		validator := service{signers: map[contract.SigningMeans]contract.Signer{"bar": mockSigner}, sessionStore: store}
+
+		unsubscribe, err := validator.SubscribeSigningSession("123", func(result contract.SigningSessionResult) {})
+
+		assert.NoError(t, err)
+		assert.NotNil(t, unsubscribe)
+	})
+
+	t.Run("nok - session not found", func(t *testing.T) {
+		validator := service{sessionStore: session.NewMemoryStore()}
+
+		_, err := validator.SubscribeSigningSession("123", func(result contract.SigningSessionResult) {})
+
+		assert.Equal(t, services.ErrSessionNotFound, err)
+	})
+}
+
 type testContext struct {
 	ctrl                   *gomock.Controller
 	cryptoMock             *cryptoMock.MockClient
@@ -295,6 +827,21 @@ func createContext(t *testing.T) *testContext {
 			crypto:                 cryptoClient,
 			registry:               registryClient,
 			signers:                signers,
+			sessionStore:           session.NewMemoryStore(),
+			callbacks:              webhook.NewDispatcher(),
 		},
 	}
 }
+
+// startSession returns a StartSigningSession func which stores the session like a signer does
+func (ctx *testContext) startSession(sessionPointer contract.SessionPointer) func(string, map[string]interface{}) (contract.SessionPointer, error) {
+	return func(rawContractText string, params map[string]interface{}) (contract.SessionPointer, error) {
+		err := ctx.contractService.sessionStore.Put(services.SigningSession{
+			ID:        sessionPointer.SessionID(),
+			Means:     irmaService.ContractFormat,
+			Contract:  rawContractText,
+			CreatedAt: time.Now(),
+		})
+		return sessionPointer, err
+	}
+}
@@ -0,0 +1,287 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nuts-foundation/nuts-auth/logging"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+)
+
+// SignatureHeader is the header of a callback request which contains "sha256=" followed by the hex encoded HMAC-SHA256
+// of the TimestampHeader value, a "." and the request body, keyed with the secret of the callback
+const SignatureHeader = "X-Nuts-Signature"
+
+// TimestampHeader is the header of a callback request which contains the moment of the attempt in seconds since the Unix epoch
+const TimestampHeader = "X-Nuts-Timestamp"
+
+// SignatureTolerance is the maximum difference between the timestamp of a callback request and the clock of the receiver.
+// Receivers reject requests outside this window, so a captured request can not be replayed later.
+const SignatureTolerance = 5 * time.Minute
+
+// ErrInvalidCallback is returned when the URL or secret of a callback can not be used
+var ErrInvalidCallback = errors.New("invalid callback")
+
+// ErrInvalidSignature is returned when the signature or timestamp of a callback request is not valid
+var ErrInvalidSignature = errors.New("invalid callback signature")
+
+// Payload is the JSON body of a callback request
+type Payload struct {
+	SessionID              string                          `json:"sessionID"`
+	Status                 contract.SessionStatus          `json:"status"`
+	NativeStatus           string                          `json:"nativeStatus"`
+	VerifiablePresentation contract.VerifiablePresentation `json:"verifiablePresentation,omitempty"`
+	Error                  string                          `json:"error,omitempty"`
+}
+
+// Sign returns the value of the SignatureHeader for the timestamp and body
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a callback request and that its timestamp is within the SignatureTolerance of the given moment.
+// It is meant for receivers of callbacks.
+func Verify(secret string, timestamp string, signature string, body []byte, moment time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if age := moment.Sub(time.Unix(seconds, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return fmt.Errorf("%w: timestamp is outside the tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Validate checks if the callback has an absolute http(s) URL and a secret
+func Validate(callback services.SessionCallback) error {
+	u, err := url.Parse(callback.URL)
+	if err != nil || !u.IsAbs() || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidCallback)
+	}
+	if callback.Secret == "" {
+		return fmt.Errorf("%w: secret is required", ErrInvalidCallback)
+	}
+	return nil
+}
+
+// Dispatcher posts the final status of signing sessions to their callbacks. Failed deliveries are retried with an
+// exponential backoff. The delivery log of every callback is kept for the Retention period.
+type Dispatcher struct {
+	// Client is used to post the callbacks
+	Client *http.Client
+	// MaxAttempts is the maximum number of delivery attempts of a callback
+	MaxAttempts int
+	// InitialBackoff is the time between the first and second attempt, it doubles after every attempt
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum time between two attempts
+	MaxBackoff time.Duration
+	// Retention is the time the delivery log of a callback is kept after it was registered
+	Retention time.Duration
+
+	mutex   sync.Mutex
+	logs    map[string]*deliveryLog
+	done    chan struct{}
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+type deliveryLog struct {
+	registered time.Time
+	deliveries []services.CallbackDelivery
+}
+
+// NewDispatcher creates a Dispatcher which makes at most 6 attempts in about half a minute and keeps the delivery logs for a day
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		Client:         &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts:    6,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Retention:      24 * time.Hour,
+		logs:           map[string]*deliveryLog{},
+		done:           make(chan struct{}),
+	}
+}
+
+// Register subscribes the callback to the final status of the signing session of the signer
+func (d *Dispatcher) Register(sessionID string, callback services.SessionCallback, signer contract.Signer) {
+	d.mutex.Lock()
+	d.prune(time.Now())
+	d.logs[sessionID] = &deliveryLog{registered: time.Now(), deliveries: []services.CallbackDelivery{}}
+	d.mutex.Unlock()
+
+	// the listener may be called before Subscribe returns, the subscription is removed by the delivery routine
+	subscription := make(chan func(), 1)
+	var once sync.Once
+	subscription <- signer.Subscribe(sessionID, func(result contract.SigningSessionResult) {
+		if !result.Status().Final() {
+			return
+		}
+		once.Do(func() {
+			payload := newPayload(sessionID, result)
+			d.mutex.Lock()
+			defer d.mutex.Unlock()
+			if d.stopped {
+				return
+			}
+			d.wg.Add(1)
+			go func() {
+				defer d.wg.Done()
+				unsubscribe := <-subscription
+				unsubscribe()
+				d.deliver(sessionID, callback, payload)
+			}()
+		})
+	})
+}
+
+// Deliveries returns the delivery log of the callback of the signing session or services.ErrSessionNotFound
+func (d *Dispatcher) Deliveries(sessionID string) ([]services.CallbackDelivery, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	log, ok := d.logs[sessionID]
+	if !ok {
+		return nil, fmt.Errorf("no callback for session %s: %w", sessionID, services.ErrSessionNotFound)
+	}
+	return append([]services.CallbackDelivery{}, log.deliveries...), nil
+}
+
+// Stop aborts the pending retries and waits for the running deliveries
+func (d *Dispatcher) Stop() {
+	d.mutex.Lock()
+	if d.stopped {
+		d.mutex.Unlock()
+		return
+	}
+	d.stopped = true
+	close(d.done)
+	d.mutex.Unlock()
+	d.wg.Wait()
+}
+
+func newPayload(sessionID string, result contract.SigningSessionResult) Payload {
+	payload := Payload{SessionID: sessionID, Status: result.Status(), NativeStatus: result.NativeStatus()}
+	if payload.Status != contract.SessionCompleted {
+		return payload
+	}
+	vp, err := result.VerifiablePresentation()
+	if err != nil {
+		payload.Status = contract.SessionFailed
+		payload.Error = fmt.Sprintf("error while building verifiable presentation: %s", err.Error())
+		return payload
+	}
+	payload.VerifiablePresentation = vp
+	return payload
+}
+
+func (d *Dispatcher) deliver(sessionID string, callback services.SessionCallback, payload Payload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logging.Log().Errorf("could not marshal the callback of signing session %s: %v", sessionID, err)
+		return
+	}
+	backoff := d.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		delivery := d.post(callback, body)
+		delivery.Attempt = attempt
+		delivery.Status = string(payload.Status)
+		d.record(sessionID, delivery)
+		if delivery.Delivered {
+			logging.Log().Infof("delivered the callback of signing session %s", sessionID)
+			return
+		}
+		if attempt >= d.MaxAttempts {
+			logging.Log().Errorf("could not deliver the callback of signing session %s after %d attempts: %s", sessionID, attempt, delivery.Error)
+			return
+		}
+		logging.Log().Warnf("could not deliver the callback of signing session %s, retrying in %s: %s", sessionID, backoff, delivery.Error)
+		select {
+		case <-d.done:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > d.MaxBackoff {
+			backoff = d.MaxBackoff
+		}
+	}
+}
+
+// post makes an attempt to deliver the callback, every attempt is signed with its own timestamp
+func (d *Dispatcher) post(callback services.SessionCallback, body []byte) services.CallbackDelivery {
+	delivery := services.CallbackDelivery{Time: time.Now()}
+	request, err := http.NewRequest(http.MethodPost, callback.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	timestamp := strconv.FormatInt(delivery.Time.Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(SignatureHeader, Sign(callback.Secret, timestamp, body))
+	response, err := d.Client.Do(request)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	_ = response.Body.Close()
+	delivery.StatusCode = response.StatusCode
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		delivery.Error = fmt.Sprintf("unexpected status code %d", response.StatusCode)
+		return delivery
+	}
+	delivery.Delivered = true
+	return delivery
+}
+
+func (d *Dispatcher) record(sessionID string, delivery services.CallbackDelivery) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if log, ok := d.logs[sessionID]; ok {
+		log.deliveries = append(log.deliveries, delivery)
+	}
+}
+
+// prune removes the delivery logs which are older than the retention period, the caller must hold the lock
+func (d *Dispatcher) prune(moment time.Time) {
+	for sessionID, log := range d.logs {
+		if moment.Sub(log.registered) > d.Retention {
+			delete(d.logs, sessionID)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+/*
+ * Nuts auth
+ * Copyright (C) 2020. Nuts community
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	mock_contract "github.com/nuts-foundation/nuts-auth/mock/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/contract"
+	"github.com/nuts-foundation/nuts-auth/pkg/services"
+)
+
+// receiver is a webhook which answers with the given status codes and records the requests
+type receiver struct {
+	mutex       sync.Mutex
+	statusCodes []int
+	bodies      [][]byte
+	signatures  []string
+	timestamps  []string
+}
+
+func (r *receiver) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, _ := ioutil.ReadAll(request.Body)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	statusCode := http.StatusOK
+	if len(r.bodies) < len(r.statusCodes) {
+		statusCode = r.statusCodes[len(r.bodies)]
+	}
+	r.bodies = append(r.bodies, body)
+	r.signatures = append(r.signatures, request.Header.Get(SignatureHeader))
+	r.timestamps = append(r.timestamps, request.Header.Get(TimestampHeader))
+	writer.WriteHeader(statusCode)
+}
+
+func testDispatcher() *Dispatcher {
+	dispatcher := NewDispatcher()
+	dispatcher.InitialBackoff = time.Millisecond
+	dispatcher.MaxBackoff = 2 * time.Millisecond
+	dispatcher.MaxAttempts = 3
+	return dispatcher
+}
+
+func sessionResult(ctrl *gomock.Controller, status contract.SessionStatus, nativeStatus string, vp contract.VerifiablePresentation, vpErr error) contract.SigningSessionResult {
+	result := mock_contract.NewMockSigningSessionResult(ctrl)
+	result.EXPECT().Status().Return(status).AnyTimes()
+	result.EXPECT().NativeStatus().Return(nativeStatus).AnyTimes()
+	result.EXPECT().VerifiablePresentation().Return(vp, vpErr).AnyTimes()
+	return result
+}
+
+// register registers the callback at a signer which is backed by a SessionNotifier
+func register(ctrl *gomock.Controller, dispatcher *Dispatcher, callback services.SessionCallback) *contract.SessionNotifier {
+	notifier := contract.NewSessionNotifier()
+	signer := mock_contract.NewMockSigner(ctrl)
+	signer.EXPECT().Subscribe("123", gomock.Any()).DoAndReturn(notifier.Subscribe)
+	dispatcher.Register("123", callback, signer)
+	return notifier
+}
+
+func waitForDeliveries(t *testing.T, dispatcher *Dispatcher, count int) []services.CallbackDelivery {
+	t.Helper()
+	var deliveries []services.CallbackDelivery
+	assert.Eventually(t, func() bool {
+		deliveries, _ = dispatcher.Deliveries("123")
+		return len(deliveries) >= count
+	}, time.Second, time.Millisecond)
+	return deliveries
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, "sha256=b47d7d556e4c1c0dcccf6966669e5315da61f058ad5f082431520ec365df9dff", Sign("secret", "1601553600", []byte("message")))
+}
+
+func TestVerify(t *testing.T) {
+	moment := time.Unix(1601553600, 0)
+	signature := Sign("secret", "1601553600", []byte("message"))
+
+	t.Run("ok", func(t *testing.T) {
+		assert.NoError(t, Verify("secret", "1601553600", signature, []byte("message"), moment.Add(SignatureTolerance)))
+		assert.NoError(t, Verify("secret", "1601553600", signature, []byte("message"), moment.Add(-SignatureTolerance)))
+	})
+
+	t.Run("error - replayed after the tolerance", func(t *testing.T) {
+		err := Verify("secret", "1601553600", signature, []byte("message"), moment.Add(SignatureTolerance+time.Second))
+
+		assert.True(t, errors.Is(err, ErrInvalidSignature))
+	})
+
+	t.Run("error - other timestamp", func(t *testing.T) {
+		err := Verify("secret", "1601553601", signature, []byte("message"), moment)
+
+		assert.Equal(t, ErrInvalidSignature, err)
+	})
+
+	t.Run("error - other body", func(t *testing.T) {
+		err := Verify("secret", "1601553600", signature, []byte("other message"), moment)
+
+		assert.Equal(t, ErrInvalidSignature, err)
+	})
+
+	t.Run("error - invalid timestamp", func(t *testing.T) {
+		err := Verify("secret", "yesterday", signature, []byte("message"), moment)
+
+		assert.True(t, errors.Is(err, ErrInvalidSignature))
+	})
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		assert.NoError(t, Validate(services.SessionCallback{URL: "https://example.com/callback", Secret: "secret"}))
+		assert.NoError(t, Validate(services.SessionCallback{URL: "http://localhost:8080", Secret: "secret"}))
+	})
+
+	t.Run("error - invalid URL", func(t *testing.T) {
+		for _, u := range []string{"", "/callback", "ftp://example.com", "https://", "://"} {
+			err := Validate(services.SessionCallback{URL: u, Secret: "secret"})
+			assert.True(t, errors.Is(err, ErrInvalidCallback), u)
+		}
+	})
+
+	t.Run("error - missing secret", func(t *testing.T) {
+		err := Validate(services.SessionCallback{URL: "https://example.com/callback"})
+
+		assert.True(t, errors.Is(err, ErrInvalidCallback))
+	})
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("ok - signed final status is delivered once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		webhook := &receiver{}
+		server := httptest.NewServer(webhook)
+		defer server.Close()
+		dispatcher := testDispatcher()
+		defer dispatcher.Stop()
+
+		notifier := register(ctrl, dispatcher, services.SessionCallback{URL: server.URL, Secret: "secret"})
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionInProgress, "in-progress", nil, nil))
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionCompleted, "completed", map[string]string{"type": "NutsDummyVerifiablePresentation"}, nil))
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionCompleted, "completed", map[string]string{"type": "NutsDummyVerifiablePresentation"}, nil))
+
+		deliveries := waitForDeliveries(t, dispatcher, 1)
+		if !assert.Len(t, deliveries, 1) {
+			return
+		}
+		assert.True(t, deliveries[0].Delivered)
+		assert.Equal(t, 1, deliveries[0].Attempt)
+		assert.Equal(t, "completed", deliveries[0].Status)
+		assert.Equal(t, http.StatusOK, deliveries[0].StatusCode)
+		assert.False(t, notifier.HasListeners("123"))
+		webhook.mutex.Lock()
+		defer webhook.mutex.Unlock()
+		assert.Len(t, webhook.bodies, 1)
+		assert.JSONEq(t, `{"sessionID":"123","status":"completed","nativeStatus":"completed","verifiablePresentation":{"type":"NutsDummyVerifiablePresentation"}}`, string(webhook.bodies[0]))
+		assert.NoError(t, Verify("secret", webhook.timestamps[0], webhook.signatures[0], webhook.bodies[0], time.Now()))
+	})
+
+	t.Run("ok - delivery is retried", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		server := httptest.NewServer(&receiver{statusCodes: []int{http.StatusInternalServerError, http.StatusBadGateway}})
+		defer server.Close()
+		dispatcher := testDispatcher()
+		defer dispatcher.Stop()
+
+		notifier := register(ctrl, dispatcher, services.SessionCallback{URL: server.URL, Secret: "secret"})
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionCancelled, services.SessionCancelled, nil, nil))
+
+		deliveries := waitForDeliveries(t, dispatcher, 3)
+		if !assert.Len(t, deliveries, 3) {
+			return
+		}
+		assert.False(t, deliveries[0].Delivered)
+		assert.Equal(t, http.StatusInternalServerError, deliveries[0].StatusCode)
+		assert.Equal(t, "unexpected status code 500", deliveries[0].Error)
+		assert.Equal(t, 2, deliveries[1].Attempt)
+		assert.True(t, deliveries[2].Delivered)
+		assert.Equal(t, services.SessionCancelled, deliveries[2].Status)
+	})
+
+	t.Run("ok - delivery is given up after the maximum attempts", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		server := httptest.NewServer(&receiver{statusCodes: []int{500, 500, 500, 500}})
+		defer server.Close()
+		dispatcher := testDispatcher()
+		defer dispatcher.Stop()
+
+		notifier := register(ctrl, dispatcher, services.SessionCallback{URL: server.URL, Secret: "secret"})
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionExpired, services.SessionExpired, nil, nil))
+
+		waitForDeliveries(t, dispatcher, 3)
+		time.Sleep(10 * time.Millisecond)
+		deliveries, _ := dispatcher.Deliveries("123")
+		assert.Len(t, deliveries, 3)
+		for _, delivery := range deliveries {
+			assert.False(t, delivery.Delivered)
+		}
+	})
+
+	t.Run("ok - unreachable receiver", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		server := httptest.NewServer(&receiver{})
+		server.Close()
+		dispatcher := testDispatcher()
+		dispatcher.MaxAttempts = 1
+		defer dispatcher.Stop()
+
+		notifier := register(ctrl, dispatcher, services.SessionCallback{URL: server.URL, Secret: "secret"})
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionExpired, "TIMEOUT", nil, nil))
+
+		deliveries := waitForDeliveries(t, dispatcher, 1)
+		assert.Equal(t, 0, deliveries[0].StatusCode)
+		assert.NotEmpty(t, deliveries[0].Error)
+	})
+
+	t.Run("ok - failed status when the verifiable presentation can not be built", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		webhook := &receiver{}
+		server := httptest.NewServer(webhook)
+		defer server.Close()
+		dispatcher := testDispatcher()
+		defer dispatcher.Stop()
+
+		notifier := register(ctrl, dispatcher, services.SessionCallback{URL: server.URL, Secret: "secret"})
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionCompleted, "DONE", nil, errors.New("b00m!")))
+
+		deliveries := waitForDeliveries(t, dispatcher, 1)
+		assert.Equal(t, string(contract.SessionFailed), deliveries[0].Status)
+		webhook.mutex.Lock()
+		defer webhook.mutex.Unlock()
+		payload := Payload{}
+		_ = json.Unmarshal(webhook.bodies[0], &payload)
+		assert.Equal(t, contract.SessionFailed, payload.Status)
+		assert.Equal(t, "DONE", payload.NativeStatus)
+		assert.Equal(t, "error while building verifiable presentation: b00m!", payload.Error)
+	})
+
+	t.Run("ok - stop aborts retries", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		server := httptest.NewServer(&receiver{statusCodes: []int{500}})
+		defer server.Close()
+		dispatcher := testDispatcher()
+		dispatcher.InitialBackoff = time.Hour
+
+		notifier := register(ctrl, dispatcher, services.SessionCallback{URL: server.URL, Secret: "secret"})
+		notifier.Notify("123", sessionResult(ctrl, contract.SessionCompleted, "DONE", nil, nil))
+		waitForDeliveries(t, dispatcher, 1)
+		dispatcher.Stop()
+		dispatcher.Stop()
+
+		deliveries, _ := dispatcher.Deliveries("123")
+		assert.Len(t, deliveries, 1)
+	})
+
+	t.Run("ok - old delivery logs are pruned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		dispatcher := testDispatcher()
+		dispatcher.Retention = 0
+		defer dispatcher.Stop()
+
+		register(ctrl, dispatcher, services.SessionCallback{URL: "https://example.com", Secret: "secret"})
+		time.Sleep(time.Millisecond)
+		dispatcher.mutex.Lock()
+		dispatcher.prune(time.Now())
+		dispatcher.mutex.Unlock()
+
+		_, err := dispatcher.Deliveries("123")
+		assert.True(t, errors.Is(err, services.ErrSessionNotFound))
+	})
+
+	t.Run("error - unknown session", func(t *testing.T) {
+		_, err := testDispatcher().Deliveries("123")
+
+		assert.True(t, errors.Is(err, services.ErrSessionNotFound))
+	})
+}
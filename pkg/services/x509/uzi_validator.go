@@ -41,7 +41,7 @@ type UziSignedToken struct {
 // It can parse and validate a UziSignedToken which implements the SignedToken interface
 type UziValidator struct {
 	validator         *JwtX509Validator
-	contractTemplates *contract.TemplateStore
+	contractTemplates contract.TemplateProvider
 }
 
 // UziEnv is used to indicate which Uzi environment (e.g. production, acceptation) should be used.
@@ -129,7 +129,7 @@ func validUziSigningAlgs() []jwa.SignatureAlgorithm {
 // It accepts a UziEnv and preloads corresponding certificate tree.
 // It accepts a contract template store which is used to check if the signed contract exists and is valid.
 // It accepts an optional CrlGetter. If non is given, the CachedHttpCrlService is used as default
-func NewUziValidator(env UziEnv, contractTemplates *contract.TemplateStore, crls CrlGetter) (validator *UziValidator, err error) {
+func NewUziValidator(env UziEnv, contractTemplates contract.TemplateProvider, crls CrlGetter) (validator *UziValidator, err error) {
 	var roots []*x509.Certificate
 	var intermediates []*x509.Certificate
 
@@ -201,7 +201,7 @@ func (u UziValidator) Parse(rawProofValue string) (services.SignedToken, error)
 		return nil, fmt.Errorf("token field should contain a string")
 	}
 
-	c, err := contract.ParseContractString(contractText, *u.contractTemplates)
+	c, err := contract.ParseContractString(contractText, u.contractTemplates.Templates())
 	if err != nil {
 		return nil, err
 	}
@@ -23,6 +23,9 @@ import "time"
 // JwtBearerGrantType defines the grant-type to use in the access token request
 const JwtBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
 
+// RefreshTokenGrantType defines the grant-type to use in the access token request with a refresh token
+const RefreshTokenGrantType = "refresh_token"
+
 // AuthConfig holds all the configuration params
 type AuthConfig struct {
 	Mode string
@@ -32,8 +35,48 @@ type AuthConfig struct {
 	IrmaConfigPath            string
 	IrmaSchemeManager         string
 	SkipAutoUpdateIrmaSchemas bool
+	IrmaPrintQrCode           bool
 	ActingPartyCn             string
 	EnableCORS                bool
 	ContractValidators        []string
 	ContractValidDuration     time.Duration
+	ContractTemplatesPath     string
+	ContractTimeZones         []string
+	SessionStorePath          string
+	SessionTTL                time.Duration
+	DummyPersonasPath         string
+	// SharedStores only locks the files of the session store, replay cache, revocation list and refresh token store for the duration of a transaction, so nodes can share them on a volume
+	SharedStores bool
+	// SessionRateLimit is the number of signing sessions a client can start per minute, 0 disables the limit
+	SessionRateLimit int
+	// SessionRateBurst is the number of signing sessions a client can start at once
+	SessionRateBurst int
+	// SessionRateLimitBy identifies the clients of the rate limit, either by certificate or by legalEntity
+	SessionRateLimitBy string
+	// AuditLogPath is the file to which the hash chained audit log is appended, no audit log is kept when empty
+	AuditLogPath string
+	// SkipAudienceCheck only logs jwt bearer tokens of which the aud is not an OAuth endpoint of the custodian, instead of rejecting them
+	SkipAudienceCheck bool
+	// ReplayCachePath is the file in which the jti's of used jwt bearer tokens are remembered, they are kept in memory when empty
+	ReplayCachePath string
+	// ReplayCacheSize is the number of jwt bearer tokens which can be remembered at the same time
+	ReplayCacheSize int
+	// RevocationListPath is the file in which the revoked access tokens and contracts are kept, they are kept in memory when empty
+	RevocationListPath string
+	// RevocationListSize is the number of access tokens and contracts which can be revoked at the same time
+	RevocationListSize int
+	// EnableRefreshTokens issues refresh tokens with the access tokens, they are valid for as long as the signed contract
+	EnableRefreshTokens bool
+	// OAuthIssuer is the public URL of the OAuth authorization server of the node, it is the issuer of the authorization
+	// server metadata and the base URL of its endpoints. The metadata is not published when empty.
+	OAuthIssuer string
+	// RefreshTokenStorePath is the file in which the issued refresh tokens are kept, they are kept in memory when empty
+	RefreshTokenStorePath string
+	// RefreshTokenStoreSize is the number of refresh tokens which can be valid at the same time
+	RefreshTokenStoreSize int
+	// TrustClientCertHeader identifies clients by the certificate in the header set by a TLS terminating proxy instead of
+	// by the certificate of the TLS connection
+	TrustClientCertHeader bool
+	// MaxOpenSessions is the number of signing sessions per signing means which can be open at the same time, 0 means unlimited
+	MaxOpenSessions int
 }